@@ -46,6 +46,84 @@ func (src *BootstrapProvider) ConvertTo(dstRaw conversion.Hub) error {
 	}
 
 	dst.Spec.ManifestPatches = restored.Spec.ManifestPatches
+	dst.Spec.Patches = restored.Spec.Patches
+	dst.Spec.SmokeTest = restored.Spec.SmokeTest
+	dst.Spec.Hooks = restored.Spec.Hooks
+	dst.Spec.ManagementMode = restored.Spec.ManagementMode
+	dst.Spec.Adopt = restored.Spec.Adopt
+	dst.Spec.DeletionPolicy = restored.Spec.DeletionPolicy
+	dst.Spec.DeleteOptions = restored.Spec.DeleteOptions
+	dst.Spec.InstallMode = restored.Spec.InstallMode
+	dst.Spec.TargetNamespace = restored.Spec.TargetNamespace
+	dst.Spec.VersionPolicy = restored.Spec.VersionPolicy
+	dst.Spec.UpgradeTimeout = restored.Spec.UpgradeTimeout
+	dst.Spec.InstallWaitTimeout = restored.Spec.InstallWaitTimeout
+	dst.Spec.SkipWaitForReadiness = restored.Spec.SkipWaitForReadiness
+	dst.Spec.MaintenanceWindow = restored.Spec.MaintenanceWindow
+	dst.Spec.Paused = restored.Spec.Paused
+	dst.Spec.CommonLabels = restored.Spec.CommonLabels
+	dst.Spec.CommonAnnotations = restored.Spec.CommonAnnotations
+	dst.Spec.ConfigMapRef = restored.Spec.ConfigMapRef
+	dst.Spec.ConfigSecrets = restored.Spec.ConfigSecrets
+	dst.Spec.Variables = restored.Spec.Variables
+	dst.Spec.ExternalVariables = restored.Spec.ExternalVariables
+	dst.Spec.PinImageDigests = restored.Spec.PinImageDigests
+	dst.Status.ImageDigests = restored.Status.ImageDigests
+	dst.Status.TargetVersion = restored.Status.TargetVersion
+	dst.Status.UpgradePlan = restored.Status.UpgradePlan
+	dst.Status.UpgradeSteps = restored.Status.UpgradeSteps
+
+	if restored.Spec.FetchConfig != nil && dst.Spec.FetchConfig != nil {
+		dst.Spec.FetchConfig.Namespace = restored.Spec.FetchConfig.Namespace
+		dst.Spec.FetchConfig.Metadata = restored.Spec.FetchConfig.Metadata
+		dst.Spec.FetchConfig.OCI = restored.Spec.FetchConfig.OCI
+		dst.Spec.FetchConfig.OCIPullSecret = restored.Spec.FetchConfig.OCIPullSecret
+		dst.Spec.FetchConfig.Git = restored.Spec.FetchConfig.Git
+		dst.Spec.FetchConfig.Chart = restored.Spec.FetchConfig.Chart
+		dst.Spec.FetchConfig.S3 = restored.Spec.FetchConfig.S3
+		dst.Spec.FetchConfig.LocalPath = restored.Spec.FetchConfig.LocalPath
+		dst.Spec.FetchConfig.Verification = restored.Spec.FetchConfig.Verification
+		dst.Spec.FetchConfig.Checksums = restored.Spec.FetchConfig.Checksums
+		dst.Spec.FetchConfig.Provenance = restored.Spec.FetchConfig.Provenance
+		dst.Spec.FetchConfig.Forge = restored.Spec.FetchConfig.Forge
+		dst.Spec.FetchConfig.Secret = restored.Spec.FetchConfig.Secret
+		dst.Spec.FetchConfig.CABundleRef = restored.Spec.FetchConfig.CABundleRef
+		dst.Spec.FetchConfig.Proxy = restored.Spec.FetchConfig.Proxy
+	}
+
+	if restored.Spec.Manager != nil && dst.Spec.Manager != nil {
+		dst.Spec.Manager.Suspend = restored.Spec.Manager.Suspend
+		dst.Spec.Manager.LogFormat = restored.Spec.Manager.LogFormat
+		dst.Spec.Manager.Metrics.DiagnosticsAddress = restored.Spec.Manager.Metrics.DiagnosticsAddress
+		dst.Spec.Manager.Metrics.InsecureDiagnostics = restored.Spec.Manager.Metrics.InsecureDiagnostics
+	}
+
+	if restored.Spec.Deployment != nil && dst.Spec.Deployment != nil {
+		dst.Spec.Deployment.Labels = restored.Spec.Deployment.Labels
+		dst.Spec.Deployment.Annotations = restored.Spec.Deployment.Annotations
+		dst.Spec.Deployment.PodLabels = restored.Spec.Deployment.PodLabels
+		dst.Spec.Deployment.PodAnnotations = restored.Spec.Deployment.PodAnnotations
+		dst.Spec.Deployment.PriorityClassName = restored.Spec.Deployment.PriorityClassName
+		dst.Spec.Deployment.TopologySpreadConstraints = restored.Spec.Deployment.TopologySpreadConstraints
+		dst.Spec.Deployment.Volumes = restored.Spec.Deployment.Volumes
+		dst.Spec.Deployment.PodSecurityContext = restored.Spec.Deployment.PodSecurityContext
+		dst.Spec.Deployment.RuntimeClassName = restored.Spec.Deployment.RuntimeClassName
+		dst.Spec.Deployment.HostNetwork = restored.Spec.Deployment.HostNetwork
+		dst.Spec.Deployment.DNSPolicy = restored.Spec.Deployment.DNSPolicy
+		dst.Spec.Deployment.DNSConfig = restored.Spec.Deployment.DNSConfig
+		dst.Spec.Deployment.ServiceAccountAnnotations = restored.Spec.Deployment.ServiceAccountAnnotations
+		dst.Spec.Deployment.PodDisruptionBudget = restored.Spec.Deployment.PodDisruptionBudget
+
+		for i := range dst.Spec.Deployment.Containers {
+			if i < len(restored.Spec.Deployment.Containers) {
+				dst.Spec.Deployment.Containers[i].LivenessProbe = restored.Spec.Deployment.Containers[i].LivenessProbe
+				dst.Spec.Deployment.Containers[i].ReadinessProbe = restored.Spec.Deployment.Containers[i].ReadinessProbe
+				dst.Spec.Deployment.Containers[i].StartupProbe = restored.Spec.Deployment.Containers[i].StartupProbe
+				dst.Spec.Deployment.Containers[i].VolumeMounts = restored.Spec.Deployment.Containers[i].VolumeMounts
+				dst.Spec.Deployment.Containers[i].SecurityContext = restored.Spec.Deployment.Containers[i].SecurityContext
+			}
+		}
+	}
 
 	return nil
 }
@@ -107,6 +185,84 @@ func (src *ControlPlaneProvider) ConvertTo(dstRaw conversion.Hub) error {
 	}
 
 	dst.Spec.ManifestPatches = restored.Spec.ManifestPatches
+	dst.Spec.Patches = restored.Spec.Patches
+	dst.Spec.SmokeTest = restored.Spec.SmokeTest
+	dst.Spec.Hooks = restored.Spec.Hooks
+	dst.Spec.ManagementMode = restored.Spec.ManagementMode
+	dst.Spec.Adopt = restored.Spec.Adopt
+	dst.Spec.DeletionPolicy = restored.Spec.DeletionPolicy
+	dst.Spec.DeleteOptions = restored.Spec.DeleteOptions
+	dst.Spec.InstallMode = restored.Spec.InstallMode
+	dst.Spec.TargetNamespace = restored.Spec.TargetNamespace
+	dst.Spec.VersionPolicy = restored.Spec.VersionPolicy
+	dst.Spec.UpgradeTimeout = restored.Spec.UpgradeTimeout
+	dst.Spec.InstallWaitTimeout = restored.Spec.InstallWaitTimeout
+	dst.Spec.SkipWaitForReadiness = restored.Spec.SkipWaitForReadiness
+	dst.Spec.MaintenanceWindow = restored.Spec.MaintenanceWindow
+	dst.Spec.Paused = restored.Spec.Paused
+	dst.Spec.CommonLabels = restored.Spec.CommonLabels
+	dst.Spec.CommonAnnotations = restored.Spec.CommonAnnotations
+	dst.Spec.ConfigMapRef = restored.Spec.ConfigMapRef
+	dst.Spec.ConfigSecrets = restored.Spec.ConfigSecrets
+	dst.Spec.Variables = restored.Spec.Variables
+	dst.Spec.ExternalVariables = restored.Spec.ExternalVariables
+	dst.Spec.PinImageDigests = restored.Spec.PinImageDigests
+	dst.Status.ImageDigests = restored.Status.ImageDigests
+	dst.Status.TargetVersion = restored.Status.TargetVersion
+	dst.Status.UpgradePlan = restored.Status.UpgradePlan
+	dst.Status.UpgradeSteps = restored.Status.UpgradeSteps
+
+	if restored.Spec.FetchConfig != nil && dst.Spec.FetchConfig != nil {
+		dst.Spec.FetchConfig.Namespace = restored.Spec.FetchConfig.Namespace
+		dst.Spec.FetchConfig.Metadata = restored.Spec.FetchConfig.Metadata
+		dst.Spec.FetchConfig.OCI = restored.Spec.FetchConfig.OCI
+		dst.Spec.FetchConfig.OCIPullSecret = restored.Spec.FetchConfig.OCIPullSecret
+		dst.Spec.FetchConfig.Git = restored.Spec.FetchConfig.Git
+		dst.Spec.FetchConfig.Chart = restored.Spec.FetchConfig.Chart
+		dst.Spec.FetchConfig.S3 = restored.Spec.FetchConfig.S3
+		dst.Spec.FetchConfig.LocalPath = restored.Spec.FetchConfig.LocalPath
+		dst.Spec.FetchConfig.Verification = restored.Spec.FetchConfig.Verification
+		dst.Spec.FetchConfig.Checksums = restored.Spec.FetchConfig.Checksums
+		dst.Spec.FetchConfig.Provenance = restored.Spec.FetchConfig.Provenance
+		dst.Spec.FetchConfig.Forge = restored.Spec.FetchConfig.Forge
+		dst.Spec.FetchConfig.Secret = restored.Spec.FetchConfig.Secret
+		dst.Spec.FetchConfig.CABundleRef = restored.Spec.FetchConfig.CABundleRef
+		dst.Spec.FetchConfig.Proxy = restored.Spec.FetchConfig.Proxy
+	}
+
+	if restored.Spec.Manager != nil && dst.Spec.Manager != nil {
+		dst.Spec.Manager.Suspend = restored.Spec.Manager.Suspend
+		dst.Spec.Manager.LogFormat = restored.Spec.Manager.LogFormat
+		dst.Spec.Manager.Metrics.DiagnosticsAddress = restored.Spec.Manager.Metrics.DiagnosticsAddress
+		dst.Spec.Manager.Metrics.InsecureDiagnostics = restored.Spec.Manager.Metrics.InsecureDiagnostics
+	}
+
+	if restored.Spec.Deployment != nil && dst.Spec.Deployment != nil {
+		dst.Spec.Deployment.Labels = restored.Spec.Deployment.Labels
+		dst.Spec.Deployment.Annotations = restored.Spec.Deployment.Annotations
+		dst.Spec.Deployment.PodLabels = restored.Spec.Deployment.PodLabels
+		dst.Spec.Deployment.PodAnnotations = restored.Spec.Deployment.PodAnnotations
+		dst.Spec.Deployment.PriorityClassName = restored.Spec.Deployment.PriorityClassName
+		dst.Spec.Deployment.TopologySpreadConstraints = restored.Spec.Deployment.TopologySpreadConstraints
+		dst.Spec.Deployment.Volumes = restored.Spec.Deployment.Volumes
+		dst.Spec.Deployment.PodSecurityContext = restored.Spec.Deployment.PodSecurityContext
+		dst.Spec.Deployment.RuntimeClassName = restored.Spec.Deployment.RuntimeClassName
+		dst.Spec.Deployment.HostNetwork = restored.Spec.Deployment.HostNetwork
+		dst.Spec.Deployment.DNSPolicy = restored.Spec.Deployment.DNSPolicy
+		dst.Spec.Deployment.DNSConfig = restored.Spec.Deployment.DNSConfig
+		dst.Spec.Deployment.ServiceAccountAnnotations = restored.Spec.Deployment.ServiceAccountAnnotations
+		dst.Spec.Deployment.PodDisruptionBudget = restored.Spec.Deployment.PodDisruptionBudget
+
+		for i := range dst.Spec.Deployment.Containers {
+			if i < len(restored.Spec.Deployment.Containers) {
+				dst.Spec.Deployment.Containers[i].LivenessProbe = restored.Spec.Deployment.Containers[i].LivenessProbe
+				dst.Spec.Deployment.Containers[i].ReadinessProbe = restored.Spec.Deployment.Containers[i].ReadinessProbe
+				dst.Spec.Deployment.Containers[i].StartupProbe = restored.Spec.Deployment.Containers[i].StartupProbe
+				dst.Spec.Deployment.Containers[i].VolumeMounts = restored.Spec.Deployment.Containers[i].VolumeMounts
+				dst.Spec.Deployment.Containers[i].SecurityContext = restored.Spec.Deployment.Containers[i].SecurityContext
+			}
+		}
+	}
 
 	return nil
 }
@@ -168,6 +324,84 @@ func (src *CoreProvider) ConvertTo(dstRaw conversion.Hub) error {
 	}
 
 	dst.Spec.ManifestPatches = restored.Spec.ManifestPatches
+	dst.Spec.Patches = restored.Spec.Patches
+	dst.Spec.SmokeTest = restored.Spec.SmokeTest
+	dst.Spec.Hooks = restored.Spec.Hooks
+	dst.Spec.ManagementMode = restored.Spec.ManagementMode
+	dst.Spec.Adopt = restored.Spec.Adopt
+	dst.Spec.DeletionPolicy = restored.Spec.DeletionPolicy
+	dst.Spec.DeleteOptions = restored.Spec.DeleteOptions
+	dst.Spec.InstallMode = restored.Spec.InstallMode
+	dst.Spec.TargetNamespace = restored.Spec.TargetNamespace
+	dst.Spec.VersionPolicy = restored.Spec.VersionPolicy
+	dst.Spec.UpgradeTimeout = restored.Spec.UpgradeTimeout
+	dst.Spec.InstallWaitTimeout = restored.Spec.InstallWaitTimeout
+	dst.Spec.SkipWaitForReadiness = restored.Spec.SkipWaitForReadiness
+	dst.Spec.MaintenanceWindow = restored.Spec.MaintenanceWindow
+	dst.Spec.Paused = restored.Spec.Paused
+	dst.Spec.CommonLabels = restored.Spec.CommonLabels
+	dst.Spec.CommonAnnotations = restored.Spec.CommonAnnotations
+	dst.Spec.ConfigMapRef = restored.Spec.ConfigMapRef
+	dst.Spec.ConfigSecrets = restored.Spec.ConfigSecrets
+	dst.Spec.Variables = restored.Spec.Variables
+	dst.Spec.ExternalVariables = restored.Spec.ExternalVariables
+	dst.Spec.PinImageDigests = restored.Spec.PinImageDigests
+	dst.Status.ImageDigests = restored.Status.ImageDigests
+	dst.Status.TargetVersion = restored.Status.TargetVersion
+	dst.Status.UpgradePlan = restored.Status.UpgradePlan
+	dst.Status.UpgradeSteps = restored.Status.UpgradeSteps
+
+	if restored.Spec.FetchConfig != nil && dst.Spec.FetchConfig != nil {
+		dst.Spec.FetchConfig.Namespace = restored.Spec.FetchConfig.Namespace
+		dst.Spec.FetchConfig.Metadata = restored.Spec.FetchConfig.Metadata
+		dst.Spec.FetchConfig.OCI = restored.Spec.FetchConfig.OCI
+		dst.Spec.FetchConfig.OCIPullSecret = restored.Spec.FetchConfig.OCIPullSecret
+		dst.Spec.FetchConfig.Git = restored.Spec.FetchConfig.Git
+		dst.Spec.FetchConfig.Chart = restored.Spec.FetchConfig.Chart
+		dst.Spec.FetchConfig.S3 = restored.Spec.FetchConfig.S3
+		dst.Spec.FetchConfig.LocalPath = restored.Spec.FetchConfig.LocalPath
+		dst.Spec.FetchConfig.Verification = restored.Spec.FetchConfig.Verification
+		dst.Spec.FetchConfig.Checksums = restored.Spec.FetchConfig.Checksums
+		dst.Spec.FetchConfig.Provenance = restored.Spec.FetchConfig.Provenance
+		dst.Spec.FetchConfig.Forge = restored.Spec.FetchConfig.Forge
+		dst.Spec.FetchConfig.Secret = restored.Spec.FetchConfig.Secret
+		dst.Spec.FetchConfig.CABundleRef = restored.Spec.FetchConfig.CABundleRef
+		dst.Spec.FetchConfig.Proxy = restored.Spec.FetchConfig.Proxy
+	}
+
+	if restored.Spec.Manager != nil && dst.Spec.Manager != nil {
+		dst.Spec.Manager.Suspend = restored.Spec.Manager.Suspend
+		dst.Spec.Manager.LogFormat = restored.Spec.Manager.LogFormat
+		dst.Spec.Manager.Metrics.DiagnosticsAddress = restored.Spec.Manager.Metrics.DiagnosticsAddress
+		dst.Spec.Manager.Metrics.InsecureDiagnostics = restored.Spec.Manager.Metrics.InsecureDiagnostics
+	}
+
+	if restored.Spec.Deployment != nil && dst.Spec.Deployment != nil {
+		dst.Spec.Deployment.Labels = restored.Spec.Deployment.Labels
+		dst.Spec.Deployment.Annotations = restored.Spec.Deployment.Annotations
+		dst.Spec.Deployment.PodLabels = restored.Spec.Deployment.PodLabels
+		dst.Spec.Deployment.PodAnnotations = restored.Spec.Deployment.PodAnnotations
+		dst.Spec.Deployment.PriorityClassName = restored.Spec.Deployment.PriorityClassName
+		dst.Spec.Deployment.TopologySpreadConstraints = restored.Spec.Deployment.TopologySpreadConstraints
+		dst.Spec.Deployment.Volumes = restored.Spec.Deployment.Volumes
+		dst.Spec.Deployment.PodSecurityContext = restored.Spec.Deployment.PodSecurityContext
+		dst.Spec.Deployment.RuntimeClassName = restored.Spec.Deployment.RuntimeClassName
+		dst.Spec.Deployment.HostNetwork = restored.Spec.Deployment.HostNetwork
+		dst.Spec.Deployment.DNSPolicy = restored.Spec.Deployment.DNSPolicy
+		dst.Spec.Deployment.DNSConfig = restored.Spec.Deployment.DNSConfig
+		dst.Spec.Deployment.ServiceAccountAnnotations = restored.Spec.Deployment.ServiceAccountAnnotations
+		dst.Spec.Deployment.PodDisruptionBudget = restored.Spec.Deployment.PodDisruptionBudget
+
+		for i := range dst.Spec.Deployment.Containers {
+			if i < len(restored.Spec.Deployment.Containers) {
+				dst.Spec.Deployment.Containers[i].LivenessProbe = restored.Spec.Deployment.Containers[i].LivenessProbe
+				dst.Spec.Deployment.Containers[i].ReadinessProbe = restored.Spec.Deployment.Containers[i].ReadinessProbe
+				dst.Spec.Deployment.Containers[i].StartupProbe = restored.Spec.Deployment.Containers[i].StartupProbe
+				dst.Spec.Deployment.Containers[i].VolumeMounts = restored.Spec.Deployment.Containers[i].VolumeMounts
+				dst.Spec.Deployment.Containers[i].SecurityContext = restored.Spec.Deployment.Containers[i].SecurityContext
+			}
+		}
+	}
 
 	return nil
 }
@@ -229,6 +463,84 @@ func (src *InfrastructureProvider) ConvertTo(dstRaw conversion.Hub) error {
 	}
 
 	dst.Spec.ManifestPatches = restored.Spec.ManifestPatches
+	dst.Spec.Patches = restored.Spec.Patches
+	dst.Spec.SmokeTest = restored.Spec.SmokeTest
+	dst.Spec.Hooks = restored.Spec.Hooks
+	dst.Spec.ManagementMode = restored.Spec.ManagementMode
+	dst.Spec.Adopt = restored.Spec.Adopt
+	dst.Spec.DeletionPolicy = restored.Spec.DeletionPolicy
+	dst.Spec.DeleteOptions = restored.Spec.DeleteOptions
+	dst.Spec.InstallMode = restored.Spec.InstallMode
+	dst.Spec.TargetNamespace = restored.Spec.TargetNamespace
+	dst.Spec.VersionPolicy = restored.Spec.VersionPolicy
+	dst.Spec.UpgradeTimeout = restored.Spec.UpgradeTimeout
+	dst.Spec.InstallWaitTimeout = restored.Spec.InstallWaitTimeout
+	dst.Spec.SkipWaitForReadiness = restored.Spec.SkipWaitForReadiness
+	dst.Spec.MaintenanceWindow = restored.Spec.MaintenanceWindow
+	dst.Spec.Paused = restored.Spec.Paused
+	dst.Spec.CommonLabels = restored.Spec.CommonLabels
+	dst.Spec.CommonAnnotations = restored.Spec.CommonAnnotations
+	dst.Spec.ConfigMapRef = restored.Spec.ConfigMapRef
+	dst.Spec.ConfigSecrets = restored.Spec.ConfigSecrets
+	dst.Spec.Variables = restored.Spec.Variables
+	dst.Spec.ExternalVariables = restored.Spec.ExternalVariables
+	dst.Spec.PinImageDigests = restored.Spec.PinImageDigests
+	dst.Status.ImageDigests = restored.Status.ImageDigests
+	dst.Status.TargetVersion = restored.Status.TargetVersion
+	dst.Status.UpgradePlan = restored.Status.UpgradePlan
+	dst.Status.UpgradeSteps = restored.Status.UpgradeSteps
+
+	if restored.Spec.FetchConfig != nil && dst.Spec.FetchConfig != nil {
+		dst.Spec.FetchConfig.Namespace = restored.Spec.FetchConfig.Namespace
+		dst.Spec.FetchConfig.Metadata = restored.Spec.FetchConfig.Metadata
+		dst.Spec.FetchConfig.OCI = restored.Spec.FetchConfig.OCI
+		dst.Spec.FetchConfig.OCIPullSecret = restored.Spec.FetchConfig.OCIPullSecret
+		dst.Spec.FetchConfig.Git = restored.Spec.FetchConfig.Git
+		dst.Spec.FetchConfig.Chart = restored.Spec.FetchConfig.Chart
+		dst.Spec.FetchConfig.S3 = restored.Spec.FetchConfig.S3
+		dst.Spec.FetchConfig.LocalPath = restored.Spec.FetchConfig.LocalPath
+		dst.Spec.FetchConfig.Verification = restored.Spec.FetchConfig.Verification
+		dst.Spec.FetchConfig.Checksums = restored.Spec.FetchConfig.Checksums
+		dst.Spec.FetchConfig.Provenance = restored.Spec.FetchConfig.Provenance
+		dst.Spec.FetchConfig.Forge = restored.Spec.FetchConfig.Forge
+		dst.Spec.FetchConfig.Secret = restored.Spec.FetchConfig.Secret
+		dst.Spec.FetchConfig.CABundleRef = restored.Spec.FetchConfig.CABundleRef
+		dst.Spec.FetchConfig.Proxy = restored.Spec.FetchConfig.Proxy
+	}
+
+	if restored.Spec.Manager != nil && dst.Spec.Manager != nil {
+		dst.Spec.Manager.Suspend = restored.Spec.Manager.Suspend
+		dst.Spec.Manager.LogFormat = restored.Spec.Manager.LogFormat
+		dst.Spec.Manager.Metrics.DiagnosticsAddress = restored.Spec.Manager.Metrics.DiagnosticsAddress
+		dst.Spec.Manager.Metrics.InsecureDiagnostics = restored.Spec.Manager.Metrics.InsecureDiagnostics
+	}
+
+	if restored.Spec.Deployment != nil && dst.Spec.Deployment != nil {
+		dst.Spec.Deployment.Labels = restored.Spec.Deployment.Labels
+		dst.Spec.Deployment.Annotations = restored.Spec.Deployment.Annotations
+		dst.Spec.Deployment.PodLabels = restored.Spec.Deployment.PodLabels
+		dst.Spec.Deployment.PodAnnotations = restored.Spec.Deployment.PodAnnotations
+		dst.Spec.Deployment.PriorityClassName = restored.Spec.Deployment.PriorityClassName
+		dst.Spec.Deployment.TopologySpreadConstraints = restored.Spec.Deployment.TopologySpreadConstraints
+		dst.Spec.Deployment.Volumes = restored.Spec.Deployment.Volumes
+		dst.Spec.Deployment.PodSecurityContext = restored.Spec.Deployment.PodSecurityContext
+		dst.Spec.Deployment.RuntimeClassName = restored.Spec.Deployment.RuntimeClassName
+		dst.Spec.Deployment.HostNetwork = restored.Spec.Deployment.HostNetwork
+		dst.Spec.Deployment.DNSPolicy = restored.Spec.Deployment.DNSPolicy
+		dst.Spec.Deployment.DNSConfig = restored.Spec.Deployment.DNSConfig
+		dst.Spec.Deployment.ServiceAccountAnnotations = restored.Spec.Deployment.ServiceAccountAnnotations
+		dst.Spec.Deployment.PodDisruptionBudget = restored.Spec.Deployment.PodDisruptionBudget
+
+		for i := range dst.Spec.Deployment.Containers {
+			if i < len(restored.Spec.Deployment.Containers) {
+				dst.Spec.Deployment.Containers[i].LivenessProbe = restored.Spec.Deployment.Containers[i].LivenessProbe
+				dst.Spec.Deployment.Containers[i].ReadinessProbe = restored.Spec.Deployment.Containers[i].ReadinessProbe
+				dst.Spec.Deployment.Containers[i].StartupProbe = restored.Spec.Deployment.Containers[i].StartupProbe
+				dst.Spec.Deployment.Containers[i].VolumeMounts = restored.Spec.Deployment.Containers[i].VolumeMounts
+				dst.Spec.Deployment.Containers[i].SecurityContext = restored.Spec.Deployment.Containers[i].SecurityContext
+			}
+		}
+	}
 
 	return nil
 }
@@ -450,6 +762,18 @@ func Convert_v1alpha2_ProviderSpec_To_v1alpha1_ProviderSpec(in *operatorv1.Provi
 	return nil
 }
 
+func Convert_v1alpha2_FetchConfiguration_To_v1alpha1_FetchConfiguration(in *operatorv1.FetchConfiguration, out *FetchConfiguration, s apimachineryconversion.Scope) error {
+	return autoConvert_v1alpha2_FetchConfiguration_To_v1alpha1_FetchConfiguration(in, out, s)
+}
+
+func Convert_v1alpha2_DeploymentSpec_To_v1alpha1_DeploymentSpec(in *operatorv1.DeploymentSpec, out *DeploymentSpec, s apimachineryconversion.Scope) error {
+	return autoConvert_v1alpha2_DeploymentSpec_To_v1alpha1_DeploymentSpec(in, out, s)
+}
+
+func Convert_v1alpha2_ProviderStatus_To_v1alpha1_ProviderStatus(in *operatorv1.ProviderStatus, out *ProviderStatus, s apimachineryconversion.Scope) error {
+	return autoConvert_v1alpha2_ProviderStatus_To_v1alpha1_ProviderStatus(in, out, s)
+}
+
 func Convert_v1alpha1_ContainerSpec_To_v1alpha2_ContainerSpec(in *ContainerSpec, out *operatorv1.ContainerSpec, s apimachineryconversion.Scope) error {
 	if in == nil {
 		return nil
@@ -174,21 +174,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1alpha2.DeploymentSpec)(nil), (*DeploymentSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1alpha2_DeploymentSpec_To_v1alpha1_DeploymentSpec(a.(*v1alpha2.DeploymentSpec), b.(*DeploymentSpec), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*FetchConfiguration)(nil), (*v1alpha2.FetchConfiguration)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_FetchConfiguration_To_v1alpha2_FetchConfiguration(a.(*FetchConfiguration), b.(*v1alpha2.FetchConfiguration), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1alpha2.FetchConfiguration)(nil), (*FetchConfiguration)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1alpha2_FetchConfiguration_To_v1alpha1_FetchConfiguration(a.(*v1alpha2.FetchConfiguration), b.(*FetchConfiguration), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*InfrastructureProvider)(nil), (*v1alpha2.InfrastructureProvider)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_InfrastructureProvider_To_v1alpha2_InfrastructureProvider(a.(*InfrastructureProvider), b.(*v1alpha2.InfrastructureProvider), scope)
 	}); err != nil {
@@ -234,11 +224,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1alpha2.ProviderStatus)(nil), (*ProviderStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1alpha2_ProviderStatus_To_v1alpha1_ProviderStatus(a.(*v1alpha2.ProviderStatus), b.(*ProviderStatus), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddConversionFunc((*ContainerSpec)(nil), (*v1alpha2.ContainerSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_ContainerSpec_To_v1alpha2_ContainerSpec(a.(*ContainerSpec), b.(*v1alpha2.ContainerSpec), scope)
 	}); err != nil {
@@ -259,6 +244,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1alpha2.DeploymentSpec)(nil), (*DeploymentSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha2_DeploymentSpec_To_v1alpha1_DeploymentSpec(a.(*v1alpha2.DeploymentSpec), b.(*DeploymentSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1alpha2.FetchConfiguration)(nil), (*FetchConfiguration)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha2_FetchConfiguration_To_v1alpha1_FetchConfiguration(a.(*v1alpha2.FetchConfiguration), b.(*FetchConfiguration), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddConversionFunc((*v1alpha2.ManagerSpec)(nil), (*ManagerSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha2_ManagerSpec_To_v1alpha1_ManagerSpec(a.(*v1alpha2.ManagerSpec), b.(*ManagerSpec), scope)
 	}); err != nil {
@@ -269,6 +264,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1alpha2.ProviderStatus)(nil), (*ProviderStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha2_ProviderStatus_To_v1alpha1_ProviderStatus(a.(*v1alpha2.ProviderStatus), b.(*ProviderStatus), scope)
+	}); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -432,6 +432,11 @@ func autoConvert_v1alpha2_ContainerSpec_To_v1alpha1_ContainerSpec(in *v1alpha2.C
 	out.Args = *(*map[string]string)(unsafe.Pointer(&in.Args))
 	out.Env = *(*[]v1.EnvVar)(unsafe.Pointer(&in.Env))
 	out.Resources = (*v1.ResourceRequirements)(unsafe.Pointer(in.Resources))
+	// WARNING: in.LivenessProbe requires manual conversion: does not exist in peer-type
+	// WARNING: in.ReadinessProbe requires manual conversion: does not exist in peer-type
+	// WARNING: in.StartupProbe requires manual conversion: does not exist in peer-type
+	// WARNING: in.VolumeMounts requires manual conversion: does not exist in peer-type
+	// WARNING: in.SecurityContext requires manual conversion: does not exist in peer-type
 	out.Command = *(*[]string)(unsafe.Pointer(&in.Command))
 	return nil
 }
@@ -711,6 +716,7 @@ func autoConvert_v1alpha2_DeploymentSpec_To_v1alpha1_DeploymentSpec(in *v1alpha2
 	out.NodeSelector = *(*map[string]string)(unsafe.Pointer(&in.NodeSelector))
 	out.Tolerations = *(*[]v1.Toleration)(unsafe.Pointer(&in.Tolerations))
 	out.Affinity = (*v1.Affinity)(unsafe.Pointer(in.Affinity))
+	// WARNING: in.TopologySpreadConstraints requires manual conversion: does not exist in peer-type
 	if in.Containers != nil {
 		in, out := &in.Containers, &out.Containers
 		*out = make([]ContainerSpec, len(*in))
@@ -723,15 +729,23 @@ func autoConvert_v1alpha2_DeploymentSpec_To_v1alpha1_DeploymentSpec(in *v1alpha2
 		out.Containers = nil
 	}
 	out.ServiceAccountName = in.ServiceAccountName
+	// WARNING: in.ServiceAccountAnnotations requires manual conversion: does not exist in peer-type
 	out.ImagePullSecrets = *(*[]v1.LocalObjectReference)(unsafe.Pointer(&in.ImagePullSecrets))
+	// WARNING: in.Volumes requires manual conversion: does not exist in peer-type
+	// WARNING: in.PodSecurityContext requires manual conversion: does not exist in peer-type
+	// WARNING: in.RuntimeClassName requires manual conversion: does not exist in peer-type
+	// WARNING: in.HostNetwork requires manual conversion: does not exist in peer-type
+	// WARNING: in.DNSPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.DNSConfig requires manual conversion: does not exist in peer-type
+	// WARNING: in.PriorityClassName requires manual conversion: does not exist in peer-type
+	// WARNING: in.Labels requires manual conversion: does not exist in peer-type
+	// WARNING: in.Annotations requires manual conversion: does not exist in peer-type
+	// WARNING: in.PodLabels requires manual conversion: does not exist in peer-type
+	// WARNING: in.PodAnnotations requires manual conversion: does not exist in peer-type
+	// WARNING: in.PodDisruptionBudget requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1alpha2_DeploymentSpec_To_v1alpha1_DeploymentSpec is an autogenerated conversion function.
-func Convert_v1alpha2_DeploymentSpec_To_v1alpha1_DeploymentSpec(in *v1alpha2.DeploymentSpec, out *DeploymentSpec, s conversion.Scope) error {
-	return autoConvert_v1alpha2_DeploymentSpec_To_v1alpha1_DeploymentSpec(in, out, s)
-}
-
 func autoConvert_v1alpha1_FetchConfiguration_To_v1alpha2_FetchConfiguration(in *FetchConfiguration, out *v1alpha2.FetchConfiguration, s conversion.Scope) error {
 	out.URL = in.URL
 	out.Selector = (*metav1.LabelSelector)(unsafe.Pointer(in.Selector))
@@ -745,15 +759,25 @@ func Convert_v1alpha1_FetchConfiguration_To_v1alpha2_FetchConfiguration(in *Fetc
 
 func autoConvert_v1alpha2_FetchConfiguration_To_v1alpha1_FetchConfiguration(in *v1alpha2.FetchConfiguration, out *FetchConfiguration, s conversion.Scope) error {
 	out.URL = in.URL
+	// WARNING: in.Forge requires manual conversion: does not exist in peer-type
 	out.Selector = (*metav1.LabelSelector)(unsafe.Pointer(in.Selector))
+	// WARNING: in.Secret requires manual conversion: does not exist in peer-type
+	// WARNING: in.OCI requires manual conversion: does not exist in peer-type
+	// WARNING: in.OCIPullSecret requires manual conversion: does not exist in peer-type
+	// WARNING: in.Git requires manual conversion: does not exist in peer-type
+	// WARNING: in.Chart requires manual conversion: does not exist in peer-type
+	// WARNING: in.S3 requires manual conversion: does not exist in peer-type
+	// WARNING: in.LocalPath requires manual conversion: does not exist in peer-type
+	// WARNING: in.Namespace requires manual conversion: does not exist in peer-type
+	// WARNING: in.CABundleRef requires manual conversion: does not exist in peer-type
+	// WARNING: in.Proxy requires manual conversion: does not exist in peer-type
+	// WARNING: in.Verification requires manual conversion: does not exist in peer-type
+	// WARNING: in.Provenance requires manual conversion: does not exist in peer-type
+	// WARNING: in.Checksums requires manual conversion: does not exist in peer-type
+	// WARNING: in.Metadata requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1alpha2_FetchConfiguration_To_v1alpha1_FetchConfiguration is an autogenerated conversion function.
-func Convert_v1alpha2_FetchConfiguration_To_v1alpha1_FetchConfiguration(in *v1alpha2.FetchConfiguration, out *FetchConfiguration, s conversion.Scope) error {
-	return autoConvert_v1alpha2_FetchConfiguration_To_v1alpha1_FetchConfiguration(in, out, s)
-}
-
 func autoConvert_v1alpha1_InfrastructureProvider_To_v1alpha2_InfrastructureProvider(in *InfrastructureProvider, out *v1alpha2.InfrastructureProvider, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v1alpha1_InfrastructureProviderSpec_To_v1alpha2_InfrastructureProviderSpec(&in.Spec, &out.Spec, s); err != nil {
@@ -890,7 +914,9 @@ func autoConvert_v1alpha2_ManagerSpec_To_v1alpha1_ManagerSpec(in *v1alpha2.Manag
 	out.ProfilerAddress = in.ProfilerAddress
 	out.MaxConcurrentReconciles = in.MaxConcurrentReconciles
 	out.Verbosity = in.Verbosity
+	// WARNING: in.LogFormat requires manual conversion: does not exist in peer-type
 	out.FeatureGates = *(*map[string]bool)(unsafe.Pointer(&in.FeatureGates))
+	// WARNING: in.Suspend requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -916,13 +942,28 @@ func autoConvert_v1alpha1_ProviderSpec_To_v1alpha2_ProviderSpec(in *ProviderSpec
 	}
 	// WARNING: in.SecretName requires manual conversion: does not exist in peer-type
 	// WARNING: in.SecretNamespace requires manual conversion: does not exist in peer-type
-	out.FetchConfig = (*v1alpha2.FetchConfiguration)(unsafe.Pointer(in.FetchConfig))
+	if in.FetchConfig != nil {
+		in, out := &in.FetchConfig, &out.FetchConfig
+		*out = new(v1alpha2.FetchConfiguration)
+		if err := Convert_v1alpha1_FetchConfiguration_To_v1alpha2_FetchConfiguration(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.FetchConfig = nil
+	}
 	out.AdditionalManifestsRef = (*v1alpha2.ConfigmapReference)(unsafe.Pointer(in.AdditionalManifestsRef))
 	return nil
 }
 
 func autoConvert_v1alpha2_ProviderSpec_To_v1alpha1_ProviderSpec(in *v1alpha2.ProviderSpec, out *ProviderSpec, s conversion.Scope) error {
 	out.Version = in.Version
+	// WARNING: in.VersionPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.UpgradeTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.InstallWaitTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.SkipWaitForReadiness requires manual conversion: does not exist in peer-type
+	// WARNING: in.MaintenanceWindow requires manual conversion: does not exist in peer-type
+	// WARNING: in.Paused requires manual conversion: does not exist in peer-type
+	// WARNING: in.TargetNamespace requires manual conversion: does not exist in peer-type
 	if in.Manager != nil {
 		in, out := &in.Manager, &out.Manager
 		*out = new(ManagerSpec)
@@ -941,10 +982,33 @@ func autoConvert_v1alpha2_ProviderSpec_To_v1alpha1_ProviderSpec(in *v1alpha2.Pro
 	} else {
 		out.Deployment = nil
 	}
+	// WARNING: in.CommonLabels requires manual conversion: does not exist in peer-type
+	// WARNING: in.CommonAnnotations requires manual conversion: does not exist in peer-type
 	// WARNING: in.ConfigSecret requires manual conversion: does not exist in peer-type
-	out.FetchConfig = (*FetchConfiguration)(unsafe.Pointer(in.FetchConfig))
+	// WARNING: in.ConfigSecrets requires manual conversion: does not exist in peer-type
+	// WARNING: in.ConfigMapRef requires manual conversion: does not exist in peer-type
+	// WARNING: in.Variables requires manual conversion: does not exist in peer-type
+	// WARNING: in.ExternalVariables requires manual conversion: does not exist in peer-type
+	if in.FetchConfig != nil {
+		in, out := &in.FetchConfig, &out.FetchConfig
+		*out = new(FetchConfiguration)
+		if err := Convert_v1alpha2_FetchConfiguration_To_v1alpha1_FetchConfiguration(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.FetchConfig = nil
+	}
 	out.AdditionalManifestsRef = (*ConfigmapReference)(unsafe.Pointer(in.AdditionalManifestsRef))
 	// WARNING: in.ManifestPatches requires manual conversion: does not exist in peer-type
+	// WARNING: in.Patches requires manual conversion: does not exist in peer-type
+	// WARNING: in.SmokeTest requires manual conversion: does not exist in peer-type
+	// WARNING: in.Hooks requires manual conversion: does not exist in peer-type
+	// WARNING: in.ManagementMode requires manual conversion: does not exist in peer-type
+	// WARNING: in.Adopt requires manual conversion: does not exist in peer-type
+	// WARNING: in.DeletionPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.DeleteOptions requires manual conversion: does not exist in peer-type
+	// WARNING: in.InstallMode requires manual conversion: does not exist in peer-type
+	// WARNING: in.PinImageDigests requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -966,10 +1030,9 @@ func autoConvert_v1alpha2_ProviderStatus_To_v1alpha1_ProviderStatus(in *v1alpha2
 	out.Conditions = *(*v1beta1.Conditions)(unsafe.Pointer(&in.Conditions))
 	out.ObservedGeneration = in.ObservedGeneration
 	out.InstalledVersion = (*string)(unsafe.Pointer(in.InstalledVersion))
+	// WARNING: in.TargetVersion requires manual conversion: does not exist in peer-type
+	// WARNING: in.ImageDigests requires manual conversion: does not exist in peer-type
+	// WARNING: in.UpgradePlan requires manual conversion: does not exist in peer-type
+	// WARNING: in.UpgradeSteps requires manual conversion: does not exist in peer-type
 	return nil
 }
-
-// Convert_v1alpha2_ProviderStatus_To_v1alpha1_ProviderStatus is an autogenerated conversion function.
-func Convert_v1alpha2_ProviderStatus_To_v1alpha1_ProviderStatus(in *v1alpha2.ProviderStatus, out *ProviderStatus, s conversion.Scope) error {
-	return autoConvert_v1alpha2_ProviderStatus_To_v1alpha1_ProviderStatus(in, out, s)
-}
@@ -38,6 +38,9 @@ const (
 	// FetchConfigValidationError documents that the FetchConfig is configured incorrectly.
 	FetchConfigValidationErrorReason = "FetchConfigValidationError"
 
+	// ProviderNotAllowedReason documents that the provider or its version is not allowed by the ProviderPolicies of the cluster.
+	ProviderNotAllowedReason = "ProviderNotAllowed"
+
 	// UnknownProviderReason documents that the provider name is not the name of a known provider.
 	UnknownProviderReason = "UnknownProvider"
 
@@ -47,12 +50,57 @@ const (
 	// ComponentsFetchErrorReason documents that an error occurred fetching the components.
 	ComponentsFetchErrorReason = "ComponentsFetchError"
 
+	// RateLimitedReason (Severity=Warning) documents that fetching the components was rate limited by the repository,
+	// the fetch is retried when the rate limit resets.
+	RateLimitedReason = "RateLimited"
+
+	// SignatureVerificationFailedReason documents that the signatures of the fetched components could not be verified.
+	SignatureVerificationFailedReason = "SignatureVerificationFailed"
+
+	// ProvenanceVerificationFailedReason documents that the provenance of the fetched components could not be verified.
+	ProvenanceVerificationFailedReason = "ProvenanceVerificationFailed"
+
+	// ImageDigestResolutionFailedReason documents that the digest of a container image of the components couldn't be resolved.
+	ImageDigestResolutionFailedReason = "ImageDigestResolutionFailed"
+
+	// ChecksumMismatchReason documents that the fetched components don't match the checksums pinned for their version.
+	ChecksumMismatchReason = "ChecksumMismatch"
+
+	// MissingVariablesReason documents that variables of the components without a default value are not set
+	// by the configuration variables of the provider.
+	MissingVariablesReason = "MissingVariables"
+
 	// ComponentsUpgradeErrorReason documents that an error occurred while upgrading the components.
 	ComponentsUpgradeErrorReason = "ComponentsUpgradeError"
 
+	// UpgradeTimedOutReason documents that the Deployments of an upgraded provider did not become ready within the upgrade timeout.
+	UpgradeTimedOutReason = "UpgradeTimedOut"
+
+	// WaitingForDeploymentsReason (Severity=Info) documents that the Deployments of a newly installed provider are not ready yet.
+	WaitingForDeploymentsReason = "WaitingForDeployments"
+
+	// InstallTimedOutReason documents that the Deployments of a newly installed provider did not become ready within the install wait timeout.
+	InstallTimedOutReason = "InstallTimedOut"
+
+	// OutsideMaintenanceWindowReason (Severity=Info) documents that a version change of the provider is deferred until its maintenance window opens.
+	OutsideMaintenanceWindowReason = "OutsideMaintenanceWindow"
+
+	// InvalidMaintenanceWindowReason documents that the maintenance window schedule or time zone of the provider is invalid.
+	InvalidMaintenanceWindowReason = "InvalidMaintenanceWindow"
+
+	// RollbackFailedReason documents that the previously installed version of a provider could not be reinstalled after a failed upgrade.
+	RollbackFailedReason = "RollbackFailed"
+
 	// OldComponentsDeletionErrorReason documents that an error occurred deleting the old components prior to upgrading.
 	OldComponentsDeletionErrorReason = "OldComponentsDeletionError"
 
+	// CRDInstancesExistReason documents that the components of a provider deleted with its CRDs are kept until the
+	// instances of the CRDs are deleted.
+	CRDInstancesExistReason = "CRDInstancesExist"
+
+	// WaitingForCRDsReason documents that the provider installing only its CRDs is waiting for them to be established.
+	WaitingForCRDsReason = "WaitingForCRDs"
+
 	// WaitingForCoreProviderReadyReason documents that the provider is waiting for the core provider to be ready.
 	WaitingForCoreProviderReadyReason = "WaitingForCoreProviderReady"
 
@@ -61,6 +109,72 @@ const (
 
 	// NoDeploymentAvailableConditionReason documents that there is no Available condition for provider deployment yet.
 	NoDeploymentAvailableConditionReason = "NoDeploymentAvailableConditionReason"
+
+	// SmokeTestRunningReason documents that the provider smoke test Job has not completed yet.
+	SmokeTestRunningReason = "SmokeTestRunning"
+
+	// SmokeTestFailedReason documents that the provider smoke test Job has failed.
+	SmokeTestFailedReason = "SmokeTestFailed"
+
+	// SmokeTestNotFoundReason documents that the provider smoke test Job was not created yet.
+	SmokeTestNotFoundReason = "SmokeTestNotFound"
+
+	// PreDeleteHookRunningReason documents that a pre-delete hook Job has not completed yet.
+	PreDeleteHookRunningReason = "PreDeleteHookRunning"
+
+	// PreDeleteHookFailedReason documents that a pre-delete hook Job has failed or could not be created.
+	PreDeleteHookFailedReason = "PreDeleteHookFailed"
+
+	// PreInstallHookRunningReason documents that a pre-install hook Job has not completed yet.
+	PreInstallHookRunningReason = "PreInstallHookRunning"
+
+	// PreInstallHookFailedReason documents that a pre-install hook Job has failed or could not be created.
+	PreInstallHookFailedReason = "PreInstallHookFailed"
+
+	// PostInstallHookRunningReason documents that a post-install hook Job has not completed yet.
+	PostInstallHookRunningReason = "PostInstallHookRunning"
+
+	// PostInstallHookFailedReason documents that a post-install hook Job has failed or could not be created.
+	PostInstallHookFailedReason = "PostInstallHookFailed"
+
+	// ExternalProviderNotFoundReason documents that an externally managed provider was not found in the clusterctl inventory.
+	ExternalProviderNotFoundReason = "ExternalProviderNotFound"
+
+	// AdoptionFailedReason documents that the provider installed by clusterctl can't be adopted.
+	AdoptionFailedReason = "AdoptionFailed"
+
+	// InvalidFetchCredentialsReason documents that the fetch credentials are invalid, expired or don't grant access to the provider repository.
+	InvalidFetchCredentialsReason = "InvalidFetchCredentials"
+
+	// FetchCredentialsCheckFailedReason documents that the fetch credentials couldn't be checked because of a transient error.
+	FetchCredentialsCheckFailedReason = "FetchCredentialsCheckFailed"
+
+	// FetchCredentialsExpiringReason (Severity=Warning) documents that the fetch credentials expire soon.
+	FetchCredentialsExpiringReason = "FetchCredentialsExpiring"
+
+	// FetchConfigMapNamespaceNotAllowedReason documents that the fetch config ConfigMaps are in a namespace not allowed on the operator.
+	FetchConfigMapNamespaceNotAllowedReason = "FetchConfigMapNamespaceNotAllowed"
+
+	// ConfigSecretNamespaceNotGrantedReason documents that a configuration secret is in another namespace that doesn't grant access to the provider namespace.
+	ConfigSecretNamespaceNotGrantedReason = "ConfigSecretNamespaceNotGranted"
+
+	// FetchConfigNamespaceNotGrantedReason documents that the fetch config ConfigMaps or Secrets are in another namespace that doesn't grant access to the provider namespace.
+	FetchConfigNamespaceNotGrantedReason = "FetchConfigNamespaceNotGranted"
+
+	// FetchLocalPathNotAllowedReason documents that the fetch config local path is not under a path allowed on the operator.
+	FetchLocalPathNotAllowedReason = "FetchLocalPathNotAllowed"
+
+	// InventoryUpdateErrorReason documents that the inventory of the objects applied for a provider could not be updated.
+	InventoryUpdateErrorReason = "InventoryUpdateError"
+
+	// NamespaceMigrationErrorReason documents that the provider could not be moved from its previous namespace.
+	NamespaceMigrationErrorReason = "NamespaceMigrationError"
+
+	// NoNewVersionReason documents that the installed version of the provider is the newest available one.
+	NoNewVersionReason = "NoNewVersion"
+
+	// NewVersionCheckFailedReason documents that the versions available in the provider repository could not be listed.
+	NewVersionCheckFailedReason = "NewVersionCheckFailed"
 )
 
 const (
@@ -69,4 +183,37 @@ const (
 
 	// ProviderUpgradedCondition documents a Provider that has been recently upgraded.
 	ProviderUpgradedCondition clusterv1.ConditionType = "ProviderUpgraded"
+
+	// RollbackCompletedCondition documents that the previously installed version of a provider was reinstalled after a failed upgrade.
+	RollbackCompletedCondition clusterv1.ConditionType = "RollbackCompleted"
+
+	// SmokeTestPassedCondition documents that the provider smoke test Job has completed successfully.
+	SmokeTestPassedCondition clusterv1.ConditionType = "SmokeTestPassed"
+
+	// PreDeleteHooksSucceededCondition documents that all pre-delete hook Jobs of a provider being deleted have completed successfully.
+	PreDeleteHooksSucceededCondition clusterv1.ConditionType = "PreDeleteHooksSucceeded"
+
+	// PreInstallHooksSucceededCondition documents that all pre-install hook Jobs of the provider spec have completed successfully.
+	PreInstallHooksSucceededCondition clusterv1.ConditionType = "PreInstallHooksSucceeded"
+
+	// PostInstallHooksSucceededCondition documents that all post-install hook Jobs of the provider spec have completed successfully.
+	PostInstallHooksSucceededCondition clusterv1.ConditionType = "PostInstallHooksSucceeded"
+
+	// FetchCredentialsValidCondition documents that the credentials used to fetch the provider components are valid.
+	FetchCredentialsValidCondition clusterv1.ConditionType = "FetchCredentialsValid"
+
+	// ComponentsVerifiedCondition documents that the signatures of the fetched provider components have been verified.
+	ComponentsVerifiedCondition clusterv1.ConditionType = "ComponentsVerified"
+
+	// ProvenanceVerifiedCondition documents that the SLSA provenance of the fetched provider components has been verified.
+	ProvenanceVerifiedCondition clusterv1.ConditionType = "ProvenanceVerified"
+
+	// SuspendedCondition documents that the provider Deployments are scaled down with the manager suspend field.
+	SuspendedCondition clusterv1.ConditionType = "Suspended"
+
+	// PausedCondition documents that the reconciliation of the provider is paused with the paused field or annotation.
+	PausedCondition clusterv1.ConditionType = "Paused"
+
+	// NewVersionAvailableCondition documents that a newer version than the installed one is available in the provider repository.
+	NewVersionAvailableCondition clusterv1.ConditionType = "NewVersionAvailable"
 )
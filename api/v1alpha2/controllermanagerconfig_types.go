@@ -106,6 +106,20 @@ type ControllerMetrics struct {
 	// It can be set to "0" to disable the metrics serving.
 	// +optional
 	BindAddress string `json:"bindAddress,omitempty"`
+
+	// DiagnosticsAddress is the TCP address that the controller should bind to
+	// for serving the metrics and the pprof endpoints, replacing the BindAddress
+	// in recent providers. The port of the container port named "metrics" is
+	// updated to match it.
+	// Controller Manager flag is --diagnostics-address.
+	// +optional
+	DiagnosticsAddress string `json:"diagnosticsAddress,omitempty"`
+
+	// InsecureDiagnostics serves the diagnostics endpoints over HTTP without
+	// authentication and authorization, instead of HTTPS.
+	// Controller Manager flag is --insecure-diagnostics.
+	// +optional
+	InsecureDiagnostics *bool `json:"insecureDiagnostics,omitempty"`
 }
 
 // ControllerHealth defines the health configs.
@@ -113,6 +127,8 @@ type ControllerHealth struct {
 	// HealthProbeBindAddress is the TCP address that the controller should bind to
 	// for serving health probes
 	// It can be set to "0" or "" to disable serving the health probe.
+	// The port of the container port named "healthz" and the probes are updated
+	// to match it.
 	// +optional
 	HealthProbeBindAddress string `json:"healthProbeBindAddress,omitempty"`
 
@@ -128,7 +144,9 @@ type ControllerHealth struct {
 // ControllerWebhook defines the webhook server for the controller.
 type ControllerWebhook struct {
 	// Port is the port that the webhook server serves at.
-	// It is used to set webhook.Server.Port.
+	// It is used to set webhook.Server.Port. The port of the container port named
+	// "webhook-server" is updated to match it, so the webhook Service targeting the
+	// port by name follows it.
 	// +optional
 	Port *int `json:"port,omitempty"`
 
@@ -140,7 +158,8 @@ type ControllerWebhook struct {
 	// CertDir is the directory that contains the server key and certificate.
 	// if not set, webhook server would look up the server key and certificate in
 	// {TempDir}/k8s-webhook-server/serving-certs. The server key and certificate
-	// must be named tls.key and tls.crt, respectively.
+	// must be named tls.key and tls.crt, respectively. The volume mount of the
+	// previous cert dir is moved to it.
 	// +optional
 	CertDir string `json:"certDir,omitempty"`
 }
@@ -19,20 +19,112 @@ package v1alpha2
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
 const (
 	ProviderFinalizer         = "provider.cluster.x-k8s.io"
 	ConfigMapVersionLabelName = "provider.cluster.x-k8s.io/version"
+
+	// ConfigMapChunkLabelName orders the ConfigMaps the components of a single version are split across,
+	// the value is the index of the chunk starting from 0.
+	ConfigMapChunkLabelName = "provider.cluster.x-k8s.io/chunk"
+
+	// SmokeTestJobLabelName is the label set on smoke test Jobs, the value is "<provider type>-<provider name>",
+	// truncated and suffixed with a hash if it is longer than 63 characters.
+	SmokeTestJobLabelName = "operator.cluster.x-k8s.io/smoke-test"
+
+	// HookJobLabelName is the label set on lifecycle hook Jobs, the value is the hook type, e.g. "pre-delete".
+	HookJobLabelName = "operator.cluster.x-k8s.io/hook"
+
+	// RestartedAtAnnotation forces a full fetch and re-install of the current provider version when its value
+	// changes, e.g. after manual changes to the components or to pick up re-published artifacts.
+	RestartedAtAnnotation = "operator.cluster.x-k8s.io/restartedAt"
+
+	// MigrateFromNamespaceAnnotation is set on a provider to move the provider of the same kind and name
+	// installed in the namespace given as the value to the namespace of the annotated provider.
+	MigrateFromNamespaceAnnotation = "operator.cluster.x-k8s.io/migrate-from-namespace"
+
+	// MigrateToNamespaceAnnotation is set on a provider to allow the provider of the same kind and name in the
+	// namespace given as the value to move it there with the migrate-from-namespace annotation.
+	MigrateToNamespaceAnnotation = "operator.cluster.x-k8s.io/migrate-to-namespace"
+
+	// MigratedToNamespaceAnnotation is set by the operator on a provider that was moved to the namespace given as
+	// the value. The components of the provider are left in place when it is deleted.
+	MigratedToNamespaceAnnotation = "operator.cluster.x-k8s.io/migrated-to-namespace"
+
+	// ProviderNamespaceAnnotation is set on the provider components installed into a target namespace other than
+	// the namespace of the provider, which can't own them. The value is the namespace of the provider.
+	ProviderNamespaceAnnotation = "operator.cluster.x-k8s.io/provider-namespace"
+
+	// ConfigSecretGrantAnnotation is set on a namespace to allow providers of other namespaces to reference its
+	// Secrets as configuration secrets, and its ConfigMaps and Secrets with a fetch config selector. The value is
+	// a comma-separated list of the provider namespaces, or "*" for all of them. Grants are only required if
+	// enabled on the operator with the --require-namespace-grants flag.
+	ConfigSecretGrantAnnotation = "operator.cluster.x-k8s.io/config-secret-grant"
+
+	// AllowDowngradeAnnotation is set on a provider to allow changing its version to an older one than the
+	// installed version. Downgrades can corrupt the CRDs of the provider, e.g. if their stored versions are removed.
+	AllowDowngradeAnnotation = "operator.cluster.x-k8s.io/allow-downgrade"
 )
 
 // ProviderSpec is the desired state of the Provider.
 type ProviderSpec struct {
-	// Version indicates the provider version.
+	// Version indicates the provider version. "latest" or an empty version track the newest release of the
+	// provider, which is checked hourly. The resolved version is recorded in status.installedVersion, the
+	// version of the spec is left unchanged.
 	// +optional
 	Version string `json:"version,omitempty"`
 
+	// VersionPolicy allows the operator to upgrade the provider automatically when new versions are
+	// published. The provider is upgraded to the latest available version of the same minor release series
+	// as the version with LatestPatch, or of the same major release series with LatestMinor. The version
+	// is left unchanged, the resolved one is recorded in the status. Available versions are checked
+	// hourly. It's only supported for providers fetched from a URL, a forge, or ConfigMaps and Secrets
+	// selected by the fetch config. Defaults to Pinned.
+	// +kubebuilder:validation:Enum=Pinned;LatestPatch;LatestMinor
+	// +optional
+	VersionPolicy VersionPolicy `json:"versionPolicy,omitempty"`
+
+	// UpgradeTimeout is how long the Deployments of an upgraded provider have to become ready. The previously
+	// installed version is reinstalled if they don't, or if the upgrade fails. Defaults to 10 minutes.
+	// +optional
+	UpgradeTimeout *metav1.Duration `json:"upgradeTimeout,omitempty"`
+
+	// InstallWaitTimeout is how long the Deployments of a newly installed provider have to become ready. The
+	// ProviderInstalled condition is False with the WaitingForDeployments reason until they are, and with the
+	// InstallTimedOut reason if they don't within the timeout, without failing the reconciliation. If it's not
+	// set, the provider is reported as installed once its components are applied.
+	// +optional
+	InstallWaitTimeout *metav1.Duration `json:"installWaitTimeout,omitempty"`
+
+	// SkipWaitForReadiness reports the provider as installed or upgraded once its components are applied,
+	// without waiting for its Deployments to become ready. Their readiness is only reported by the Ready
+	// condition, so the install wait timeout is ignored and upgraded providers are not rolled back when
+	// their Deployments don't become ready within the upgrade timeout.
+	// +optional
+	SkipWaitForReadiness bool `json:"skipWaitForReadiness,omitempty"`
+
+	// MaintenanceWindow restricts version changes of the provider to recurring time windows. Outside of the
+	// window, the installed version is kept while other changes of the spec are still applied.
+	// +optional
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+
+	// Paused stops the reconciliation of the provider, e.g. during incident response, the same way as the
+	// "cluster.x-k8s.io/paused" annotation. The components are left untouched until it is unset, only the
+	// plan of a pending upgrade is published. The provider can still be deleted.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// TargetNamespace is the namespace the provider components are installed into. The namespace is created
+	// with the components and isn't deleted with the provider. Defaults to the namespace of the provider, and
+	// can't be changed after the provider is created.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +optional
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+
 	// Manager defines the properties that can be enabled on the controller manager for the provider.
 	// +optional
 	Manager *ManagerSpec `json:"manager,omitempty"`
@@ -41,6 +133,16 @@ type ProviderSpec struct {
 	// +optional
 	Deployment *DeploymentSpec `json:"deployment,omitempty"`
 
+	// CommonLabels are added to the labels of all the provider components, e.g. for cost allocation or backup
+	// tooling. Labels set by the provider manifests or the deployment customizations are not overridden.
+	// +optional
+	CommonLabels map[string]string `json:"commonLabels,omitempty"`
+
+	// CommonAnnotations are added to the annotations of all the provider components. Annotations set by the
+	// provider manifests or the deployment customizations are not overridden.
+	// +optional
+	CommonAnnotations map[string]string `json:"commonAnnotations,omitempty"`
+
 	// ConfigSecret is the object with name and namespace of the Secret providing
 	// the configuration variables for the current provider instance, like e.g. credentials.
 	// Such configurations will be used when creating or upgrading provider components.
@@ -51,6 +153,32 @@ type ProviderSpec struct {
 	// +optional
 	ConfigSecret *SecretReference `json:"configSecret,omitempty"`
 
+	// ConfigSecrets are additional Secrets providing configuration variables for the current provider
+	// instance, e.g. credentials managed by an external secrets operator and hand-managed feature toggles.
+	// They are merged in order after the ConfigSecret, the last Secret setting a variable takes precedence.
+	// If namespace is not specified, the namespace of the provider will be used.
+	// +optional
+	ConfigSecrets []SecretReference `json:"configSecrets,omitempty"`
+
+	// ConfigMapRef is the object with name and namespace of the ConfigMap providing non-sensitive
+	// configuration variables for the current provider instance, like e.g. EXP_CLUSTER_RESOURCE_SET.
+	// Variables of the ConfigSecret take precedence over the ones with the same name in the ConfigMap.
+	// If namespace is not specified, the namespace of the provider will be used.
+	// +optional
+	ConfigMapRef *ConfigmapReference `json:"configMap,omitempty"`
+
+	// Variables are non-sensitive configuration variables for the current provider instance, set without
+	// a ConfigMap or a Secret. They take precedence over the variables of the ConfigMapRef, but the variables
+	// of the config secrets take precedence over them.
+	// +optional
+	Variables map[string]string `json:"variables,omitempty"`
+
+	// ExternalVariables is the external store providing configuration variables for the current provider
+	// instance, e.g. cloud credentials that must not be stored in Kubernetes Secrets. They take precedence
+	// over the variables of the config secrets.
+	// +optional
+	ExternalVariables *ExternalVariablesSource `json:"externalVariables,omitempty"`
+
 	// FetchConfig determines how the operator will fetch the components and metadata for the provider.
 	// If nil, the operator will try to fetch components according to default
 	// embedded fetch configuration for the given kind and `ObjectMeta.Name`.
@@ -73,6 +201,223 @@ type ProviderSpec struct {
 	// This should be an inline yaml blob-string https://datatracker.ietf.org/doc/html/rfc7396
 	// +optional
 	ManifestPatches []string `json:"manifestPatches,omitempty"`
+
+	// Patches are RFC 6902 JSON patches or strategic merge patches applied to the provider components
+	// matching their target, after the manifest patches. Patches are applied in the order they are specified.
+	// +optional
+	Patches []ManifestPatch `json:"patches,omitempty"`
+
+	// SmokeTest defines an optional validation Job that is run after the provider is installed or upgraded.
+	// The result of the Job is taken into account when computing the provider Ready condition, which
+	// otherwise only reflects the availability of the provider Deployment.
+	// +optional
+	SmokeTest *SmokeTestSpec `json:"smokeTest,omitempty"`
+
+	// Hooks defines Jobs that are run at specific points of the provider lifecycle.
+	// +optional
+	Hooks *ProviderHooks `json:"hooks,omitempty"`
+
+	// ManagementMode defines whether the operator manages the provider components. With the External mode
+	// the components are installed by other tooling, and the operator only tracks the installed version
+	// and health of the provider from the clusterctl inventory and the provider Deployments.
+	// Defaults to Managed.
+	// +kubebuilder:validation:Enum=Managed;External
+	// +optional
+	ManagementMode ManagementMode `json:"managementMode,omitempty"`
+
+	// Adopt takes ownership of the provider components installed by clusterctl when the provider is created.
+	// The version of the clusterctl inventory entry is recorded as the installed one, the existing components
+	// are updated in place instead of being reinstalled, and the inventory entry is removed, so clusterctl no
+	// longer manages the provider. If the version isn't set, the adopted version is kept.
+	// +optional
+	Adopt bool `json:"adopt,omitempty"`
+
+	// DeletionPolicy defines what happens to the provider components when the provider is deleted. With the
+	// Orphan policy the components are left running, e.g. when moving the management cluster or handing the
+	// provider back to clusterctl, and pre-delete hooks are skipped. CRDs and namespaces are always kept.
+	// Defaults to Delete.
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// DeleteOptions defines which of the resources installed with the provider components are also deleted
+	// when the provider is deleted with the Delete deletion policy. By default, CRDs and namespaces are kept.
+	// +optional
+	DeleteOptions *DeleteOptions `json:"deleteOptions,omitempty"`
+
+	// InstallMode defines which of the provider components are installed. With the CRDsOnly mode only
+	// the CRDs and the Services and cert-manager resources serving their conversion webhooks are installed,
+	// e.g. to pre-provision the API types in clusters where the provider controllers run elsewhere.
+	// Defaults to Full.
+	// +kubebuilder:validation:Enum=Full;CRDsOnly
+	// +optional
+	InstallMode InstallMode `json:"installMode,omitempty"`
+
+	// PinImageDigests enables replacing the tags of the container images in the provider components with
+	// the digests they resolve to, after the image registries are rewritten. Images are resolved with the
+	// credentials of the image pull secrets of the deployment, and the resolved digests are recorded in
+	// the provider status and reused while the image references don't change.
+	// +optional
+	PinImageDigests bool `json:"pinImageDigests,omitempty"`
+}
+
+// ManifestPatch is a patch applied to the provider components matching its target.
+type ManifestPatch struct {
+	// Target selects the provider components the patch is applied to.
+	Target PatchTarget `json:"target"`
+
+	// Type is the type of the patch, JSON6902 for RFC 6902 JSON patches or StrategicMerge for
+	// strategic merge patches. Strategic merge patches of kinds unknown to the operator, like
+	// custom resources, are applied as RFC 7386 merge patches.
+	// +kubebuilder:validation:Enum=JSON6902;StrategicMerge
+	Type PatchType `json:"type"`
+
+	// Patch is the YAML or JSON patch. JSON6902 patches are a list of operations,
+	// e.g. `[{"op": "replace", "path": "/spec/replicas", "value": 2}]`.
+	// +kubebuilder:validation:MinLength=1
+	Patch string `json:"patch"`
+}
+
+// PatchTarget selects provider components by group, version, kind, name and namespace.
+// Empty fields match all the components.
+type PatchTarget struct {
+	// Group is the API group of the components, e.g. "apps".
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Version is the API version of the components, e.g. "v1".
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Kind is the kind of the components, e.g. "Deployment".
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+
+	// Name is the name of the components.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Namespace is the namespace of the components.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// PatchType defines the type of a manifest patch.
+type PatchType string
+
+const (
+	// PatchTypeJSON6902 is a RFC 6902 JSON patch.
+	PatchTypeJSON6902 PatchType = "JSON6902"
+
+	// PatchTypeStrategicMerge is a strategic merge patch.
+	PatchTypeStrategicMerge PatchType = "StrategicMerge"
+)
+
+// ManagementMode defines who manages the provider components.
+type ManagementMode string
+
+const (
+	// ManagementModeManaged means the operator installs, upgrades and deletes the provider components.
+	ManagementModeManaged ManagementMode = "Managed"
+
+	// ManagementModeExternal means the provider components are managed by other tooling and the operator only tracks them.
+	ManagementModeExternal ManagementMode = "External"
+)
+
+// DeletionPolicy defines what happens to the provider components when the provider is deleted.
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete means the provider components are deleted with the provider.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+
+	// DeletionPolicyOrphan means the provider components are left running when the provider is deleted.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+// DeleteOptions defines which resources are deleted with the provider components.
+type DeleteOptions struct {
+	// IncludeCRDs deletes the CRDs of the provider. The provider components are kept running until all
+	// instances of the CRDs are deleted, as the operator webhook denies the deletion of CRDs with instances.
+	// +optional
+	IncludeCRDs bool `json:"includeCRDs,omitempty"`
+
+	// IncludeNamespace deletes the namespace the provider components were installed into, when it was created
+	// with them. The namespace of the provider object is never deleted.
+	// +optional
+	IncludeNamespace bool `json:"includeNamespace,omitempty"`
+}
+
+// InstallMode defines which of the provider components are installed.
+type InstallMode string
+
+const (
+	// InstallModeFull means all the provider components are installed.
+	InstallModeFull InstallMode = "Full"
+
+	// InstallModeCRDsOnly means only the provider CRDs and their conversion webhook resources are installed.
+	InstallModeCRDsOnly InstallMode = "CRDsOnly"
+)
+
+// VersionLatest is the version of a provider tracking its newest release.
+const VersionLatest = "latest"
+
+// VersionPolicy defines how the version of a provider is upgraded when new versions are published.
+type VersionPolicy string
+
+const (
+	// VersionPolicyPinned means the provider version is only changed by the user.
+	VersionPolicyPinned VersionPolicy = "Pinned"
+
+	// VersionPolicyLatestPatch means the provider is upgraded to the latest patch release of its minor release series.
+	VersionPolicyLatestPatch VersionPolicy = "LatestPatch"
+
+	// VersionPolicyLatestMinor means the provider is upgraded to the latest minor or patch release of its major release series.
+	VersionPolicyLatestMinor VersionPolicy = "LatestMinor"
+)
+
+// ProviderHooks defines lifecycle hook Jobs of a provider.
+type ProviderHooks struct {
+	// PreInstall is a list of Jobs that are run sequentially before the provider components are fetched and
+	// installed or upgraded, e.g. to back up CustomResourceDefinitions or to validate credentials. The
+	// components are only changed once all of them have completed successfully. The Jobs are run again
+	// every time the provider spec changes.
+	// +optional
+	PreInstall []HookSpec `json:"preInstall,omitempty"`
+
+	// PostInstall is a list of Jobs that are run sequentially after the provider components are installed or
+	// upgraded and their Deployments are ready, e.g. to run provider-specific migrations. The installation is
+	// only completed once all of them have completed successfully. The Jobs are run again every time the
+	// provider spec changes.
+	// +optional
+	PostInstall []HookSpec `json:"postInstall,omitempty"`
+
+	// PreDelete is a list of Jobs that are run sequentially before the provider components are deleted,
+	// e.g. to verify that no Machines remain or to back up provider resources. The provider finalizer
+	// is only removed once all of them have completed successfully.
+	// +optional
+	PreDelete []HookSpec `json:"preDelete,omitempty"`
+}
+
+// HookSpec defines a lifecycle hook Job.
+type HookSpec struct {
+	// Name of the hook, it must be unique within the hook type and is used to generate the Job name.
+	// +kubebuilder:validation:MaxLength=20
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// JobTemplateRef is a reference to a ConfigMap that contains a Job manifest under the `job` key.
+	// If namespace is not specified, the namespace of the provider will be used.
+	JobTemplateRef ConfigmapReference `json:"jobTemplateRef"`
+}
+
+// SmokeTestSpec defines a provider-specific validation Job.
+type SmokeTestSpec struct {
+	// JobTemplateRef is a reference to a ConfigMap that contains a Job manifest under the `job` key.
+	// The Job is created in the provider namespace every time a release is installed or upgraded,
+	// for example to create and delete a dry-run template resource. If namespace is not specified,
+	// the namespace of the provider will be used.
+	JobTemplateRef ConfigmapReference `json:"jobTemplateRef"`
 }
 
 // ConfigmapReference contains enough information to locate the configmap.
@@ -113,16 +458,29 @@ type ManagerSpec struct {
 	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
 
 	// Verbosity set the logs verbosity. Defaults to 1.
-	// Controller Manager flag is --verbosity.
+	// Controller Manager flag is --v.
 	// +optional
 	// +kubebuilder:default=1
 	// +kubebuilder:validation:Minimum=0
 	Verbosity int `json:"verbosity,omitempty"`
 
+	// LogFormat sets the logs format, e.g. json to ship the provider logs to a log aggregation system.
+	// Defaults to the format of the provider, usually text.
+	// Controller Manager flag is --logging-format.
+	// +optional
+	// +kubebuilder:validation:Enum=text;json
+	LogFormat string `json:"logFormat,omitempty"`
+
 	// FeatureGates define provider specific feature flags that will be passed
 	// in as container args to the provider's controller manager.
-	// Controller Manager flag is --feature-gates.
+	// Controller Manager flag is --feature-gates. The feature gates set by the provider manifests
+	// are kept, except for the ones overridden here.
 	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+
+	// Suspend scales the provider Deployments down to zero replicas without uninstalling the provider,
+	// e.g. during maintenance windows. The replicas are restored when it is unset.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
 }
 
 // DeploymentSpec defines the properties that can be enabled on the Deployment for the provider.
@@ -146,6 +504,11 @@ type DeploymentSpec struct {
 	// +optional
 	Affinity *corev1.Affinity `json:"affinity,omitempty"`
 
+	// If specified, the pod's topology spread constraints, e.g. to spread the replicas of the provider
+	// across zones or nodes.
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
 	// List of containers specified in the Deployment
 	// +optional
 	Containers []ContainerSpec `json:"containers,omitempty"`
@@ -154,9 +517,82 @@ type DeploymentSpec struct {
 	// +optional
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
 
+	// ServiceAccountAnnotations are added to the annotations of the ServiceAccounts of the provider components,
+	// e.g. "eks.amazonaws.com/role-arn" to grant the provider an IAM role. Annotations set by the provider
+	// manifests are kept, except for the ones overridden here.
+	// +optional
+	ServiceAccountAnnotations map[string]string `json:"serviceAccountAnnotations,omitempty"`
+
 	// List of image pull secrets specified in the Deployment
 	// +optional
 	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// Volumes are added to the volumes of the pod, replacing the volumes of the provider manifests
+	// with the same name. They can be mounted in the containers with ContainerSpec.VolumeMounts.
+	// +optional
+	Volumes []corev1.Volume `json:"volumes,omitempty"`
+
+	// If specified, the pod's security context, which replaces the one of the provider manifests.
+	// +optional
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+
+	// If specified, the pod's runtime class, e.g. to run the provider in a sandboxed container runtime.
+	// +optional
+	RuntimeClassName *string `json:"runtimeClassName,omitempty"`
+
+	// HostNetwork runs the pods in the host network namespace, e.g. for providers that have to reach
+	// networks only routed to the nodes. The DNS policy should be set to ClusterFirstWithHostNet to
+	// still resolve cluster services.
+	// +optional
+	HostNetwork *bool `json:"hostNetwork,omitempty"`
+
+	// If specified, the pod's DNS policy.
+	// +kubebuilder:validation:Enum=ClusterFirstWithHostNet;ClusterFirst;Default;None
+	// +optional
+	DNSPolicy corev1.DNSPolicy `json:"dnsPolicy,omitempty"`
+
+	// If specified, the pod's DNS parameters, merged with the ones generated from the DNS policy.
+	// +optional
+	DNSConfig *corev1.PodDNSConfig `json:"dnsConfig,omitempty"`
+
+	// If specified, the priority class of the pods. Setting a high priority class keeps the provider
+	// controllers from being evicted before other workloads when the nodes are under pressure.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// Labels are added to the labels of the Deployment. Labels set by the provider manifests are kept,
+	// except for the ones overridden here.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the annotations of the Deployment. Annotations set by the provider manifests
+	// are kept, except for the ones overridden here.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// PodLabels are added to the labels of the Deployment pods. Labels set by the provider manifests are kept,
+	// except for the ones overridden here. Labels of the Deployment selector can't be overridden.
+	// +optional
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+
+	// PodAnnotations are added to the annotations of the Deployment pods. Annotations set by the provider
+	// manifests are kept, except for the ones overridden here.
+	// +optional
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+
+	// If specified, a PodDisruptionBudget is generated for the provider Deployment, so voluntary disruptions
+	// like node drains don't evict all the provider pods at once.
+	// +optional
+	PodDisruptionBudget *PodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+}
+
+// PodDisruptionBudgetSpec defines the properties of the PodDisruptionBudget generated for a provider Deployment.
+type PodDisruptionBudgetSpec struct {
+	// MinAvailable is the number or percentage of the provider pods that must remain available during
+	// voluntary disruptions. Defaults to 1. It should be lower than the number of replicas, otherwise
+	// the provider pods can't be evicted, which blocks node drains.
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
 }
 
 // ContainerSpec defines the properties available to override for each
@@ -187,6 +623,28 @@ type ContainerSpec struct {
 	// +optional
 	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
 
+	// LivenessProbe overrides the liveness probe of the container. The handler of the provider manifests
+	// is kept if the override has none, and only the timeouts and thresholds set in the override are changed.
+	// +optional
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty"`
+
+	// ReadinessProbe overrides the readiness probe of the container, like LivenessProbe.
+	// +optional
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+
+	// StartupProbe overrides the startup probe of the container, like LivenessProbe.
+	// +optional
+	StartupProbe *corev1.Probe `json:"startupProbe,omitempty"`
+
+	// VolumeMounts are added to the volume mounts of the container, replacing the mounts of the provider
+	// manifests with the same mount path.
+	// +optional
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+
+	// Security context of the container, which replaces the one of the provider manifests.
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+
 	// Command allows override container's entrypoint array.
 	Command []string `json:"command,omitempty"`
 }
@@ -197,9 +655,23 @@ type FetchConfiguration struct {
 	// For example, https://github.com/{owner}/{repository}/releases
 	// You must set `providerSpec.Version` field for operator to pick up
 	// desired version of the release from GitHub.
+	// GitLab releases are supported with https://{host}/{project path}/-/releases/{latest|version}/{components file} URLs.
+	// GitHub Enterprise Server releases are supported with https://{host}/{owner}/{repository}/releases/{latest|version}/{components file}
+	// URLs, with the access token read from the GITHUB_ENTERPRISE_TOKEN variable of the config secret and the API base URL
+	// from the optional GITHUB_ENTERPRISE_API_URL variable, https://{host}/api/v3 by default.
+	// Google Cloud Storage buckets, Amazon S3 buckets and Azure Blob Storage containers are supported with
+	// gs://{bucket}/{prefix}/{latest|version}/{components file}, s3://{bucket}/{prefix}/{latest|version}/{components file} and
+	// azblob://{account}/{container}/{prefix}/{latest|version}/{components file} URLs.
 	// +optional
 	URL string `json:"url,omitempty"`
 
+	// Forge is the type of the forge hosting the releases of URL. It is detected from URL when not set,
+	// so it is only required for Gitea and Forgejo instances, whose release URLs have the same form as GitHub ones,
+	// for GitHub Enterprise Server instances not hosted on a github.* domain and for static web servers. The Gitea access token is read from the GITEA_TOKEN variable of the config secret.
+	// +kubebuilder:validation:Enum=GitHub;GitLab;Gitea;Static
+	// +optional
+	Forge ForgeType `json:"forge,omitempty"`
+
 	// Selector to be used for fetching provider’s components and metadata from
 	// ConfigMaps stored inside the cluster. Each ConfigMap is expected to contain
 	// components and metadata for a specific version only.
@@ -207,6 +679,327 @@ type FetchConfiguration struct {
 	// add a label like the following: provider.cluster.x-k8s.io/version=v1.4.3
 	// +optional
 	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Secret is a label selector to be used for fetching provider’s components and metadata from
+	// Secrets stored inside the cluster, the same as Selector does for ConfigMaps. It can be used when
+	// the manifests contain sensitive values, or when RBAC on ConfigMaps is too broad.
+	// +optional
+	Secret *metav1.LabelSelector `json:"secret,omitempty"`
+
+	// OCI is the OCI artifact repository to be used for fetching the provider’s components and metadata,
+	// e.g. registry.example.com/org/provider-components. The artifact tagged with the provider version must
+	// contain the metadata.yaml and components.yaml files, the latest semver tag is used if no version is set.
+	// Registry credentials are read from OCIPullSecret, or from the OCI_USERNAME and OCI_PASSWORD, or
+	// OCI_ACCESS_TOKEN variables of the config secret.
+	// +optional
+	OCI string `json:"oci,omitempty"`
+
+	// OCIPullSecret references a Secret of type kubernetes.io/dockerconfigjson in the provider namespace, e.g. an
+	// image pull secret, with the credentials of the registries of OCI and Helm charts in OCI registries. They take
+	// precedence over the OCI_USERNAME, OCI_PASSWORD and OCI_ACCESS_TOKEN variables of the config secret.
+	// +optional
+	OCIPullSecret *corev1.LocalObjectReference `json:"ociPullSecret,omitempty"`
+
+	// Git is the Git repository to be used for fetching the provider’s components and metadata.
+	// It can be used to install forks or unreleased builds of a provider.
+	// +optional
+	Git *GitSource `json:"git,omitempty"`
+
+	// Chart is the Helm chart to be rendered into the provider’s components. It can be used for providers
+	// that are only published as Helm charts.
+	// +optional
+	Chart *ChartSource `json:"chart,omitempty"`
+
+	// S3 is the S3-compatible bucket to be used for fetching the provider’s components and metadata.
+	// +optional
+	S3 *S3Source `json:"s3,omitempty"`
+
+	// LocalPath is a directory in the operator pod, e.g. a mounted persistent volume or hostPath, to be used for
+	// fetching the provider’s components and metadata in disconnected environments. Each release is a subdirectory
+	// named after the provider version with the metadata.yaml and components.yaml files, the latest semver
+	// subdirectory is used if no version is set. The directory must be under one of the paths allowed on the
+	// operator with the --fetch-local-paths flag.
+	// +optional
+	LocalPath string `json:"localPath,omitempty"`
+
+	// Namespace of the ConfigMaps matched by Selector, or of the Secrets matched by Secret. If not specified,
+	// the namespace of the provider will be used. Other namespaces must be allowed on the operator with
+	// the --fetch-configmap-namespaces flag.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// CABundleRef references a PEM encoded CA bundle that is trusted, in addition to the system CAs, when fetching
+	// the provider’s components and metadata over HTTPS, e.g. from mirrors using a private CA.
+	// +optional
+	CABundleRef *CABundleReference `json:"caBundleRef,omitempty"`
+
+	// Proxy configures the proxies used to fetch the provider’s components and metadata, replacing the
+	// proxy environment variables of the operator for this provider.
+	// +optional
+	Proxy *ProxyConfiguration `json:"proxy,omitempty"`
+
+	// Verification configures the verification of the cosign signatures of the provider’s components and metadata
+	// fetched from URL, S3 or LocalPath. Manifests whose signatures can't be verified are not installed.
+	// +optional
+	Verification *VerificationConfiguration `json:"verification,omitempty"`
+
+	// Provenance configures the verification of the SLSA provenance attestations of the provider’s components
+	// and metadata fetched from URL, S3 or LocalPath. Manifests without a valid provenance are not installed.
+	// +optional
+	Provenance *ProvenanceVerification `json:"provenance,omitempty"`
+
+	// Checksums pins the SHA256 digests of the provider’s components and metadata per version. When set, only
+	// versions with pinned checksums are installed, and only if the fetched files match them.
+	// +optional
+	Checksums []ManifestChecksums `json:"checksums,omitempty"`
+
+	// Metadata overrides the provider metadata (metadata.yaml) of the fetched release. It can be used
+	// to install forked or experimental provider builds whose release artifacts lack or mis-state it.
+	// +optional
+	Metadata *ProviderMetadata `json:"metadata,omitempty"`
+}
+
+// ExternalVariablesSource defines an external store providing configuration variables.
+type ExternalVariablesSource struct {
+	// Vault reads the variables from a KV version 2 secret of HashiCorp Vault.
+	// +optional
+	Vault *VaultSource `json:"vault,omitempty"`
+
+	// RefreshInterval is how often the variables are read again, the provider is re-installed when they
+	// change. If not set, they are only read when the provider is installed or its spec changes.
+	// +optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+}
+
+// VaultSource defines a KV version 2 secret of HashiCorp Vault, read with the Kubernetes auth method.
+type VaultSource struct {
+	// Address of the Vault server, e.g. https://vault.example.com:8200.
+	// +kubebuilder:validation:MinLength=1
+	Address string `json:"address"`
+
+	// Path of the secret, including the mount of the secrets engine and the data prefix, e.g.
+	// secret/data/cluster-api/aws. All the keys of the secret are used as variables.
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+
+	// Role of the Kubernetes auth method to log in with.
+	// +kubebuilder:validation:MinLength=1
+	Role string `json:"role"`
+
+	// AuthMountPath is the mount path of the Kubernetes auth method. Defaults to kubernetes.
+	// +optional
+	AuthMountPath string `json:"authMountPath,omitempty"`
+
+	// ServiceAccountName is the ServiceAccount of the provider namespace whose short-lived token
+	// is used to log in. Defaults to default.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Audience of the ServiceAccount token, if the Vault role requires one.
+	// +optional
+	Audience string `json:"audience,omitempty"`
+
+	// CABundleRef references a PEM encoded CA bundle that is trusted, in addition to the system CAs,
+	// when connecting to the Vault server.
+	// +optional
+	CABundleRef *CABundleReference `json:"caBundleRef,omitempty"`
+}
+
+// CABundleReference contains enough information to locate a CA bundle stored in a configmap or a secret.
+type CABundleReference struct {
+	// Kind of the object with the CA bundle, ConfigMap or Secret. Defaults to ConfigMap.
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name defines the name of the configmap or secret.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace defines the namespace of the configmap or secret. If not specified, the namespace
+	// of the provider will be used.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key of the CA bundle in the configmap or secret data. Defaults to ca.crt.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// ProxyConfiguration defines the proxies to use for HTTP and HTTPS requests, in the format of the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+type ProxyConfiguration struct {
+	// HTTPProxy is the URL of the proxy for HTTP requests. HTTP requests are not proxied if empty.
+	// +optional
+	HTTPProxy string `json:"httpProxy,omitempty"`
+
+	// HTTPSProxy is the URL of the proxy for HTTPS requests. HTTPS requests are not proxied if empty.
+	// +optional
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+
+	// NoProxy is a comma-separated list of hosts, domains, IP addresses and CIDRs that are not proxied.
+	// +optional
+	NoProxy string `json:"noProxy,omitempty"`
+}
+
+// ManifestChecksums are the SHA256 digests of the components and metadata of a provider version.
+type ManifestChecksums struct {
+	// Version of the provider the digests are pinned for.
+	// +kubebuilder:validation:MinLength=1
+	Version string `json:"version"`
+
+	// Components is the hex encoded SHA256 digest of the components file.
+	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{64}$`
+	// +optional
+	Components string `json:"components,omitempty"`
+
+	// Metadata is the hex encoded SHA256 digest of the metadata.yaml file. It isn't checked when the metadata
+	// is overridden with Metadata.
+	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{64}$`
+	// +optional
+	Metadata string `json:"metadata,omitempty"`
+}
+
+// VerificationConfiguration defines how the cosign signatures of the provider’s components and metadata are verified.
+// The signature of each file is read from the file with the .sig suffix next to it, as written by cosign sign-blob.
+// Keyless signatures are not supported, as they can't be trusted without checking the transparency log.
+type VerificationConfiguration struct {
+	// PublicKey is the PEM encoded cosign public key the signatures are verified with.
+	// +kubebuilder:validation:MinLength=1
+	PublicKey string `json:"publicKey"`
+}
+
+// ProvenanceVerification defines how the in-toto attestations with the SLSA provenance of the provider’s components
+// and metadata are verified. The attestations are read as DSSE envelopes, one per line, from the attestation file
+// next to the manifests, as released by the SLSA GitHub generator. Each file must be a subject of an attestation
+// built by the builder. The attestations must be signed with a key pair, keyless signatures are not supported.
+type ProvenanceVerification struct {
+	// Attestation is the name of the file with the attestations. Defaults to multiple.intoto.jsonl.
+	// +optional
+	Attestation string `json:"attestation,omitempty"`
+
+	// PublicKey is the PEM encoded public key the attestations are signed with.
+	// +kubebuilder:validation:MinLength=1
+	PublicKey string `json:"publicKey"`
+
+	// BuilderID is the ID of the builder of the manifests, e.g.
+	// https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml.
+	// Any version of the builder is trusted if the ID has no @ suffix with its version.
+	// +kubebuilder:validation:MinLength=1
+	BuilderID string `json:"builderID"`
+
+	// SourceRepository is the repository the manifests must be built from, e.g.
+	// https://github.com/kubernetes-sigs/cluster-api-provider-aws. The source isn't checked if not set.
+	// +optional
+	SourceRepository string `json:"sourceRepository,omitempty"`
+}
+
+// ForgeType is the type of a forge hosting provider releases.
+type ForgeType string
+
+const (
+	// ForgeGitHub means the releases are hosted on github.com or a GitHub Enterprise Server instance.
+	ForgeGitHub ForgeType = "GitHub"
+
+	// ForgeGitLab means the releases are hosted on gitlab.com or a self-hosted GitLab instance.
+	ForgeGitLab ForgeType = "GitLab"
+
+	// ForgeGitea means the releases are hosted on a Gitea or Forgejo instance.
+	ForgeGitea ForgeType = "Gitea"
+
+	// ForgeStatic means the releases are served by a plain web server, in versioned directories listed
+	// by an index.yaml file next to them.
+	ForgeStatic ForgeType = "Static"
+)
+
+// GitSource is a Git repository with the provider components and metadata.
+type GitSource struct {
+	// URL of the Git repository, e.g. https://github.com/org/repo.git or ssh://git@github.com/org/repo.git.
+	// Credentials are read from the GIT_USERNAME and GIT_PASSWORD (or token), or GIT_SSH_PRIVATE_KEY
+	// and GIT_SSH_KNOWN_HOSTS variables of the config secret.
+	// +kubebuilder:validation:MinLength=1
+	URL string `json:"url"`
+
+	// Ref is the branch or tag to check out. If not specified, the tag matching the provider version is
+	// used, or the latest semver tag if no version is set. The provider version must be set when a
+	// branch is used.
+	// +optional
+	Ref string `json:"ref,omitempty"`
+
+	// Path to the directory with the metadata.yaml and components.yaml files, relative to the
+	// repository root. If not specified, the files are read from the repository root.
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// ChartSource is a Helm chart rendered into the provider components.
+type ChartSource struct {
+	// Repository is the URL of the Helm chart repository, e.g. https://charts.example.com, or the reference
+	// of the chart in an OCI registry, e.g. oci://registry.example.com/charts/provider. Chart repository
+	// credentials are read from the CHART_USERNAME and CHART_PASSWORD variables of the config secret, OCI
+	// registry credentials from the OCI pull secret or the same variables as for OCI fetch configurations.
+	// +kubebuilder:validation:MinLength=1
+	Repository string `json:"repository"`
+
+	// Name of the chart in the Helm chart repository. It is required for Helm chart repositories and
+	// not used for OCI registries, where the chart is referenced by the repository.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Version of the chart. If not specified, the provider version is used, or the latest chart version
+	// if no provider version is set, in which case the chart version becomes the provider version.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Values is a YAML document with the values used to render the chart.
+	// +optional
+	Values string `json:"values,omitempty"`
+}
+
+// S3Source is an S3-compatible bucket with the provider components and metadata.
+type S3Source struct {
+	// Endpoint of the S3-compatible storage, e.g. s3.amazonaws.com or minio.example.com:9000.
+	// +kubebuilder:validation:MinLength=1
+	Endpoint string `json:"endpoint"`
+
+	// Bucket with the provider releases. The metadata.yaml and components.yaml files of a release are
+	// read from the <prefix>/<version>/ directory of the bucket, the latest version directory is used
+	// if no version is set.
+	// +kubebuilder:validation:MinLength=1
+	Bucket string `json:"bucket"`
+
+	// Prefix of the release directories in the bucket. If not specified, they are read from the bucket root.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Region of the bucket. If not specified, it is looked up from the endpoint.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// Insecure connects to the endpoint over plain HTTP.
+	// +optional
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// ProviderMetadata maps the release series of a provider to the Cluster API contracts they support.
+type ProviderMetadata struct {
+	// ReleaseSeries maps a provider release series (major/minor) to a Cluster API contract.
+	// +kubebuilder:validation:MinItems=1
+	ReleaseSeries []ReleaseSeries `json:"releaseSeries"`
+}
+
+// ReleaseSeries maps a provider release series (major/minor) to a Cluster API contract.
+type ReleaseSeries struct {
+	// Major version of the release series.
+	// +kubebuilder:validation:Minimum=0
+	Major int32 `json:"major"`
+
+	// Minor version of the release series.
+	// +kubebuilder:validation:Minimum=0
+	Minor int32 `json:"minor"`
+
+	// Contract is the Cluster API contract supported by the release series, e.g. v1beta1.
+	Contract string `json:"contract"`
 }
 
 // ProviderStatus defines the observed state of the Provider.
@@ -227,4 +1020,86 @@ type ProviderStatus struct {
 	// InstalledVersion is the version of the provider that is installed.
 	// +optional
 	InstalledVersion *string `json:"installedVersion,omitempty"`
+
+	// TargetVersion is the version of the provider that is being installed or upgraded to.
+	// It equals InstalledVersion once the installation is complete.
+	// +optional
+	TargetVersion *string `json:"targetVersion,omitempty"`
+
+	// ImageDigests are the digests the container images of the provider components are pinned to.
+	// +optional
+	ImageDigests []ImageDigest `json:"imageDigests,omitempty"`
+
+	// UpgradePlan describes the changes of a pending upgrade of the provider. It's published before the upgrade
+	// is applied, so it can be reviewed while the provider is paused.
+	// +optional
+	UpgradePlan *UpgradePlan `json:"upgradePlan,omitempty"`
+
+	// UpgradeSteps are the versions the provider is upgraded through when its upgrade crosses Cluster API
+	// contracts, the last one is the target version. They are cleared once the target version is installed.
+	// +optional
+	UpgradeSteps []UpgradeStep `json:"upgradeSteps,omitempty"`
+}
+
+// UpgradeStep is a version installed by a multi-step upgrade of the provider.
+type UpgradeStep struct {
+	// Version is the version installed by the step.
+	Version string `json:"version"`
+
+	// Contract is the Cluster API contract of the version.
+	// +optional
+	Contract string `json:"contract,omitempty"`
+
+	// Completed is true once the version is installed.
+	// +optional
+	Completed bool `json:"completed,omitempty"`
+}
+
+// MaintenanceWindow is a recurring time window the provider version can be changed in.
+type MaintenanceWindow struct {
+	// Schedule is the cron schedule the window opens at, e.g. "0 2 * * SAT" for every Saturday at 2 AM.
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Duration is how long the window stays open.
+	Duration metav1.Duration `json:"duration"`
+
+	// TimeZone is the IANA time zone of the schedule, e.g. "Europe/Berlin". Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// UpgradePlan describes the changes of an upgrade of the provider.
+type UpgradePlan struct {
+	// CurrentVersion is the installed version of the provider.
+	CurrentVersion string `json:"currentVersion"`
+
+	// TargetVersion is the version the provider is upgraded to.
+	TargetVersion string `json:"targetVersion"`
+
+	// CurrentContract is the Cluster API contract of the installed version.
+	// +optional
+	CurrentContract string `json:"currentContract,omitempty"`
+
+	// TargetContract is the Cluster API contract of the version the provider is upgraded to.
+	// +optional
+	TargetContract string `json:"targetContract,omitempty"`
+
+	// AddedCRDs are the names of the CustomResourceDefinitions only installed by the target version.
+	// +optional
+	AddedCRDs []string `json:"addedCRDs,omitempty"`
+
+	// RemovedCRDs are the names of the installed CustomResourceDefinitions not part of the target version.
+	// They are kept with the custom resources stored in them.
+	// +optional
+	RemovedCRDs []string `json:"removedCRDs,omitempty"`
+}
+
+// ImageDigest is the digest a container image reference is pinned to.
+type ImageDigest struct {
+	// Image is the container image reference in the provider components, e.g. "registry.k8s.io/cluster-api/cluster-api-controller:v1.5.3".
+	Image string `json:"image"`
+
+	// Digest is the digest of the image manifest or index, e.g. "sha256:9f86d08...".
+	Digest string `json:"digest"`
 }
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ProviderPolicySpec defines the providers allowed by a ProviderPolicy.
+type ProviderPolicySpec struct {
+	// Rules allow the providers matching any of them.
+	// +kubebuilder:validation:MinItems=1
+	Rules []ProviderPolicyRule `json:"rules"`
+}
+
+// ProviderPolicyRule allows the providers of a type with one of the names and a version in the range.
+type ProviderPolicyRule struct {
+	// Type of the allowed providers. Providers of all types are allowed if not set.
+	// +kubebuilder:validation:Enum=CoreProvider;BootstrapProvider;ControlPlaneProvider;InfrastructureProvider;AddonProvider;IPAMProvider
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Names of the allowed providers. Providers with any name are allowed if empty.
+	// +optional
+	Names []string `json:"names,omitempty"`
+
+	// Versions is the range of the allowed provider versions, as semver constraints, e.g. ">= v2.3.0, < v3.0.0"
+	// or "~v2.3". Pre-release versions are only allowed by constraints with a pre-release. The version of
+	// providers created without one is checked once it's resolved, before they are installed. All versions are
+	// allowed if not set.
+	// +optional
+	Versions string `json:"versions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=providerpolicies,shortName=capp,scope=Cluster
+
+// ProviderPolicy is the Schema for the providerpolicies API. When ProviderPolicies exist, only the providers
+// allowed by at least one of them can be created and installed.
+type ProviderPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ProviderPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ProviderPolicyList contains a list of ProviderPolicy.
+type ProviderPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProviderPolicy `json:"items"`
+}
+
+func init() {
+	objectTypes = append(objectTypes, &ProviderPolicy{}, &ProviderPolicyList{})
+}
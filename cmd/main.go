@@ -21,14 +21,17 @@ import (
 	"fmt"
 	"os"
 	goruntime "runtime"
+	"strings"
 	"time"
 
 	"github.com/spf13/pflag"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/klog/v2"
 	"k8s.io/klog/v2/klogr"
 	"sigs.k8s.io/cluster-api-operator/internal/webhook"
@@ -67,6 +70,20 @@ var (
 	webhookPort                 int
 	webhookCertDir              string
 	healthAddr                  string
+	imageRewriteRules           []string
+	ipFamily                    string
+	fetchConfigMapNamespaces    []string
+	requireNamespaceGrants      bool
+	fetchConfigMapRequiredLabel string
+	fetchLocalPaths             []string
+	fetchTLSMinVersion          string
+	fetchTLSCipherSuites        []string
+	backoffBaseDelay            time.Duration
+	backoffMaxDelay             time.Duration
+	backoffJitter               float64
+	componentsCacheSize         int
+	componentsCacheTTL          time.Duration
+	newVersionCheckInterval     time.Duration
 	diagnosticsOptions          = flags.DiagnosticsOptions{}
 )
 
@@ -121,6 +138,51 @@ func InitFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&healthAddr, "health-addr", ":9440",
 		"The address the health endpoint binds to.")
 
+	fs.StringSliceVar(&imageRewriteRules, "image-rewrite-rules", []string{},
+		"Comma-separated list of <from>=<to> image prefix rewrite rules applied to all provider components (e.g. registry.k8s.io=registry.corp.local/k8s)")
+
+	fs.StringVar(&ipFamily, "ip-family", string(providercontroller.IPFamilyModeIPv4),
+		"IP family of the management cluster, provider Services and bind addresses are adjusted to it. One of IPv4, IPv6, DualStack")
+
+	fs.StringSliceVar(&fetchConfigMapNamespaces, "fetch-configmap-namespaces", []string{},
+		"Comma-separated list of namespaces, other than the provider namespace, from which provider components can be fetched with fetchConfig.namespace")
+
+	fs.BoolVar(&requireNamespaceGrants, "require-namespace-grants", false,
+		"Require the namespaces of the configuration secrets and fetchConfig.namespace, other than the provider namespace, to grant access to the provider namespace with the "+
+			operatorv1.ConfigSecretGrantAnnotation+" annotation")
+
+	fs.StringVar(&fetchConfigMapRequiredLabel, "fetch-configmap-required-label", "",
+		"Label, as <key> or <key>=<value>, required on the ConfigMaps matched by provider fetchConfig selectors (e.g. provider-components=trusted)")
+
+	fs.StringSliceVar(&fetchLocalPaths, "fetch-local-paths", []string{},
+		"Comma-separated list of directories of the operator pod, e.g. mounted volumes, under which provider components can be read with fetchConfig.localPath")
+
+	fs.StringVar(&fetchTLSMinVersion, "fetch-tls-min-version", "VersionTLS12",
+		"Minimum TLS version of the connections fetching provider components and resolving image digests. "+
+			fmt.Sprintf("Possible values are %s", strings.Join(cliflag.TLSPossibleVersions(), ", ")))
+
+	fs.StringSliceVar(&fetchTLSCipherSuites, "fetch-tls-cipher-suites", []string{},
+		"Comma-separated list of cipher suites of the connections fetching provider components, the default Go cipher suites are used if empty. "+
+			"Preferred values: "+strings.Join(cliflag.PreferredTLSCipherNames(), ", "))
+
+	fs.DurationVar(&backoffBaseDelay, "provider-backoff-base-delay", providercontroller.DefaultBackoffBaseDelay,
+		"Delay before retrying a failed provider reconciliation, doubled on every consecutive failure")
+
+	fs.DurationVar(&backoffMaxDelay, "provider-backoff-max-delay", providercontroller.DefaultBackoffMaxDelay,
+		"Maximum delay between retries of a failed provider reconciliation")
+
+	fs.Float64Var(&backoffJitter, "provider-backoff-jitter", 0,
+		"Maximum fraction of the retry delay randomly added to it, e.g. 0.2 adds up to 20%")
+
+	fs.IntVar(&componentsCacheSize, "components-cache-size", providercontroller.DefaultComponentsCacheSize,
+		"Number of provider versions whose downloaded manifests are kept in memory across reconciles, 0 disables the cache")
+
+	fs.DurationVar(&componentsCacheTTL, "components-cache-ttl", providercontroller.DefaultComponentsCacheTTL,
+		"How long the downloaded manifests of a provider version are kept in memory")
+
+	fs.DurationVar(&newVersionCheckInterval, "new-version-check-interval", 0,
+		"How often the provider repositories are checked for versions newer than the installed ones, 0 disables the check")
+
 	flags.AddDiagnosticsOptions(fs, &diagnosticsOptions)
 }
 
@@ -210,62 +272,152 @@ func setupChecks(mgr ctrl.Manager) {
 }
 
 func setupReconcilers(mgr ctrl.Manager) {
+	if err := providercontroller.SetFetchTLSOptions(fetchTLSMinVersion, fetchTLSCipherSuites); err != nil {
+		setupLog.Error(err, "unable to set fetch TLS options")
+		os.Exit(1)
+	}
+
+	rewriteRules, err := providercontroller.ParseImageRewriteRules(imageRewriteRules)
+	if err != nil {
+		setupLog.Error(err, "unable to parse image rewrite rules")
+		os.Exit(1)
+	}
+
+	ipFamilyMode, err := providercontroller.ParseIPFamilyMode(ipFamily)
+	if err != nil {
+		setupLog.Error(err, "unable to parse IP family")
+		os.Exit(1)
+	}
+
+	var requiredLabel *labels.Requirement
+	if fetchConfigMapRequiredLabel != "" {
+		if requiredLabel, err = providercontroller.ParseLabelRequirement(fetchConfigMapRequiredLabel); err != nil {
+			setupLog.Error(err, "unable to parse fetch ConfigMap required label")
+			os.Exit(1)
+		}
+	}
+
+	backoff := providercontroller.Backoff{
+		BaseDelay: backoffBaseDelay,
+		MaxDelay:  backoffMaxDelay,
+		Jitter:    backoffJitter,
+	}
+
+	if err := backoff.Validate(); err != nil {
+		setupLog.Error(err, "invalid provider backoff")
+		os.Exit(1)
+	}
+
+	// The cache is shared by all provider reconcilers, so its size bounds the memory used by all of them.
+	var componentsCache *providercontroller.ComponentsCache
+	if componentsCacheSize > 0 {
+		componentsCache = providercontroller.NewComponentsCache(componentsCacheSize, componentsCacheTTL)
+	}
+
 	if err := (&providercontroller.GenericProviderReconciler{
-		Provider:     &operatorv1.CoreProvider{},
-		ProviderList: &operatorv1.CoreProviderList{},
-		Client:       mgr.GetClient(),
-		Config:       mgr.GetConfig(),
-	}).SetupWithManager(mgr, concurrency(concurrencyNumber)); err != nil {
+		Provider:                    &operatorv1.CoreProvider{},
+		ProviderList:                &operatorv1.CoreProviderList{},
+		Client:                      mgr.GetClient(),
+		Config:                      mgr.GetConfig(),
+		ImageRewriteRules:           rewriteRules,
+		IPFamilyMode:                ipFamilyMode,
+		FetchConfigMapNamespaces:    fetchConfigMapNamespaces,
+		RequireNamespaceGrants:      requireNamespaceGrants,
+		FetchConfigMapRequiredLabel: requiredLabel,
+		FetchLocalPaths:             fetchLocalPaths,
+		ComponentsCache:             componentsCache,
+		NewVersionCheckInterval:     newVersionCheckInterval,
+	}).SetupWithManager(mgr, providerOptions(backoff)); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "CoreProvider")
 		os.Exit(1)
 	}
 
 	if err := (&providercontroller.GenericProviderReconciler{
-		Provider:     &operatorv1.InfrastructureProvider{},
-		ProviderList: &operatorv1.InfrastructureProviderList{},
-		Client:       mgr.GetClient(),
-		Config:       mgr.GetConfig(),
-	}).SetupWithManager(mgr, concurrency(concurrencyNumber)); err != nil {
+		Provider:                    &operatorv1.InfrastructureProvider{},
+		ProviderList:                &operatorv1.InfrastructureProviderList{},
+		Client:                      mgr.GetClient(),
+		Config:                      mgr.GetConfig(),
+		ImageRewriteRules:           rewriteRules,
+		IPFamilyMode:                ipFamilyMode,
+		FetchConfigMapNamespaces:    fetchConfigMapNamespaces,
+		RequireNamespaceGrants:      requireNamespaceGrants,
+		FetchConfigMapRequiredLabel: requiredLabel,
+		FetchLocalPaths:             fetchLocalPaths,
+		ComponentsCache:             componentsCache,
+		NewVersionCheckInterval:     newVersionCheckInterval,
+	}).SetupWithManager(mgr, providerOptions(backoff)); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "InfrastructureProvider")
 		os.Exit(1)
 	}
 
 	if err := (&providercontroller.GenericProviderReconciler{
-		Provider:     &operatorv1.BootstrapProvider{},
-		ProviderList: &operatorv1.BootstrapProviderList{},
-		Client:       mgr.GetClient(),
-		Config:       mgr.GetConfig(),
-	}).SetupWithManager(mgr, concurrency(concurrencyNumber)); err != nil {
+		Provider:                    &operatorv1.BootstrapProvider{},
+		ProviderList:                &operatorv1.BootstrapProviderList{},
+		Client:                      mgr.GetClient(),
+		Config:                      mgr.GetConfig(),
+		ImageRewriteRules:           rewriteRules,
+		IPFamilyMode:                ipFamilyMode,
+		FetchConfigMapNamespaces:    fetchConfigMapNamespaces,
+		RequireNamespaceGrants:      requireNamespaceGrants,
+		FetchConfigMapRequiredLabel: requiredLabel,
+		FetchLocalPaths:             fetchLocalPaths,
+		ComponentsCache:             componentsCache,
+		NewVersionCheckInterval:     newVersionCheckInterval,
+	}).SetupWithManager(mgr, providerOptions(backoff)); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "BootstrapProvider")
 		os.Exit(1)
 	}
 
 	if err := (&providercontroller.GenericProviderReconciler{
-		Provider:     &operatorv1.ControlPlaneProvider{},
-		ProviderList: &operatorv1.ControlPlaneProviderList{},
-		Client:       mgr.GetClient(),
-		Config:       mgr.GetConfig(),
-	}).SetupWithManager(mgr, concurrency(concurrencyNumber)); err != nil {
+		Provider:                    &operatorv1.ControlPlaneProvider{},
+		ProviderList:                &operatorv1.ControlPlaneProviderList{},
+		Client:                      mgr.GetClient(),
+		Config:                      mgr.GetConfig(),
+		ImageRewriteRules:           rewriteRules,
+		IPFamilyMode:                ipFamilyMode,
+		FetchConfigMapNamespaces:    fetchConfigMapNamespaces,
+		RequireNamespaceGrants:      requireNamespaceGrants,
+		FetchConfigMapRequiredLabel: requiredLabel,
+		FetchLocalPaths:             fetchLocalPaths,
+		ComponentsCache:             componentsCache,
+		NewVersionCheckInterval:     newVersionCheckInterval,
+	}).SetupWithManager(mgr, providerOptions(backoff)); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ControlPlaneProvider")
 		os.Exit(1)
 	}
 
 	if err := (&providercontroller.GenericProviderReconciler{
-		Provider:     &operatorv1.AddonProvider{},
-		ProviderList: &operatorv1.AddonProviderList{},
-		Client:       mgr.GetClient(),
-		Config:       mgr.GetConfig(),
-	}).SetupWithManager(mgr, concurrency(concurrencyNumber)); err != nil {
+		Provider:                    &operatorv1.AddonProvider{},
+		ProviderList:                &operatorv1.AddonProviderList{},
+		Client:                      mgr.GetClient(),
+		Config:                      mgr.GetConfig(),
+		ImageRewriteRules:           rewriteRules,
+		IPFamilyMode:                ipFamilyMode,
+		FetchConfigMapNamespaces:    fetchConfigMapNamespaces,
+		RequireNamespaceGrants:      requireNamespaceGrants,
+		FetchConfigMapRequiredLabel: requiredLabel,
+		FetchLocalPaths:             fetchLocalPaths,
+		ComponentsCache:             componentsCache,
+		NewVersionCheckInterval:     newVersionCheckInterval,
+	}).SetupWithManager(mgr, providerOptions(backoff)); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AddonProvider")
 		os.Exit(1)
 	}
 
 	if err := (&providercontroller.GenericProviderReconciler{
-		Provider:     &operatorv1.IPAMProvider{},
-		ProviderList: &operatorv1.IPAMProviderList{},
-		Client:       mgr.GetClient(),
-		Config:       mgr.GetConfig(),
-	}).SetupWithManager(mgr, concurrency(concurrencyNumber)); err != nil {
+		Provider:                    &operatorv1.IPAMProvider{},
+		ProviderList:                &operatorv1.IPAMProviderList{},
+		Client:                      mgr.GetClient(),
+		Config:                      mgr.GetConfig(),
+		ImageRewriteRules:           rewriteRules,
+		IPFamilyMode:                ipFamilyMode,
+		FetchConfigMapNamespaces:    fetchConfigMapNamespaces,
+		RequireNamespaceGrants:      requireNamespaceGrants,
+		FetchConfigMapRequiredLabel: requiredLabel,
+		FetchLocalPaths:             fetchLocalPaths,
+		ComponentsCache:             componentsCache,
+		NewVersionCheckInterval:     newVersionCheckInterval,
+	}).SetupWithManager(mgr, providerOptions(backoff)); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "IPAMProvider")
 		os.Exit(1)
 	}
@@ -279,37 +431,51 @@ func setupReconcilers(mgr ctrl.Manager) {
 }
 
 func setupWebhooks(mgr ctrl.Manager) {
-	if err := (&webhook.CoreProviderWebhook{}).SetupWebhookWithManager(mgr); err != nil {
+	if err := (&webhook.CoreProviderWebhook{Client: mgr.GetClient()}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "CoreProvider")
 		os.Exit(1)
 	}
 
-	if err := (&webhook.BootstrapProviderWebhook{}).SetupWebhookWithManager(mgr); err != nil {
+	if err := (&webhook.BootstrapProviderWebhook{Client: mgr.GetClient()}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "BootstrapProvider")
 		os.Exit(1)
 	}
 
-	if err := (&webhook.ControlPlaneProviderWebhook{}).SetupWebhookWithManager(mgr); err != nil {
+	if err := (&webhook.ControlPlaneProviderWebhook{Client: mgr.GetClient()}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "ControlPlaneProvider")
 		os.Exit(1)
 	}
 
-	if err := (&webhook.InfrastructureProviderWebhook{}).SetupWebhookWithManager(mgr); err != nil {
+	if err := (&webhook.InfrastructureProviderWebhook{Client: mgr.GetClient()}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "InfrastructureProvider")
 		os.Exit(1)
 	}
 
-	if err := (&webhook.AddonProviderWebhook{}).SetupWebhookWithManager(mgr); err != nil {
+	if err := (&webhook.AddonProviderWebhook{Client: mgr.GetClient()}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "AddonProvider")
 		os.Exit(1)
 	}
 
-	if err := (&webhook.IPAMProviderWebhook{}).SetupWebhookWithManager(mgr); err != nil {
+	if err := (&webhook.IPAMProviderWebhook{Client: mgr.GetClient()}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "IPAMProvider")
 		os.Exit(1)
 	}
+
+	if err := (&webhook.CustomResourceDefinitionWebhook{Reader: mgr.GetAPIReader()}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "CustomResourceDefinition")
+		os.Exit(1)
+	}
 }
 
 func concurrency(c int) controller.Options {
 	return controller.Options{MaxConcurrentReconciles: c}
 }
+
+// providerOptions returns the options of a provider controller. Every controller gets its own
+// rate limiter, as providers of different types can have the same namespace and name.
+func providerOptions(backoff providercontroller.Backoff) controller.Options {
+	options := concurrency(concurrencyNumber)
+	options.RateLimiter = backoff.RateLimiter()
+
+	return options
+}
@@ -19,6 +19,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -391,7 +392,7 @@ func deployCAPIOperator(ctx context.Context, opts *initOptions) error {
 	repoCtx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
 
-	repo, err := util.RepositoryFactory(repoCtx, providerConfig, configClient.Variables())
+	repo, err := util.RepositoryFactory(repoCtx, providerConfig, "", http.DefaultClient, configClient.Variables())
 	if err != nil {
 		return fmt.Errorf("cannot create repository: %w", err)
 	}
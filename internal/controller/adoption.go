@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-operator/util"
+)
+
+// adoptClusterctlProvider takes ownership of the components of a provider installed by clusterctl, before the
+// operator installs it. The version of the clusterctl inventory entry is recorded as the installed one, so the
+// components are updated in place, or upgraded if another version is set. The inventory entry is kept and
+// synchronized with the provider from then on.
+func (p *phaseReconciler) adoptClusterctlProvider(ctx context.Context) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if !p.provider.GetSpec().Adopt || p.provider.GetStatus().InstalledVersion != nil {
+		return reconcile.Result{}, nil
+	}
+
+	key := clusterctlProviderName(p.provider)
+
+	inventoryProvider := &clusterctlv1.Provider{}
+	if err := p.ctrlClient.Get(ctx, key, inventoryProvider); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return reconcile.Result{}, fmt.Errorf("failed to get clusterctl inventory provider %s: %w", key, err)
+		}
+
+		log.Info("Provider not found in the clusterctl inventory, installing it", "inventoryProvider", key)
+
+		return reconcile.Result{}, nil
+	}
+
+	if inventoryProvider.Type != string(util.ClusterctlProviderType(p.provider)) {
+		err := fmt.Errorf("clusterctl inventory provider %s has type %s, not %s", key, inventoryProvider.Type, util.ClusterctlProviderType(p.provider))
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.AdoptionFailedReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	log.Info("Adopting provider installed by clusterctl", "inventoryProvider", key, "version", inventoryProvider.Version)
+
+	spec := p.provider.GetSpec()
+	if spec.Version == "" {
+		spec.Version = inventoryProvider.Version
+		p.provider.SetSpec(spec)
+	}
+
+	status := p.provider.GetStatus()
+	status.InstalledVersion = &inventoryProvider.Version
+	p.provider.SetStatus(status)
+
+	return reconcile.Result{}, nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestAdoptClusterctlProvider(t *testing.T) {
+	newInventoryProvider := func(providerType string) *clusterctlv1.Provider {
+		return &clusterctlv1.Provider{
+			ObjectMeta:   metav1.ObjectMeta{Name: "infrastructure-docker", Namespace: "capd-system"},
+			ProviderName: "docker",
+			Type:         providerType,
+			Version:      "v1.6.0",
+		}
+	}
+
+	testCases := []struct {
+		name                 string
+		adopt                bool
+		version              string
+		inventoryProvider    *clusterctlv1.Provider
+		wantVersion          string
+		wantInstalledVersion string
+		wantErr              bool
+	}{
+		{
+			name:              "adoption disabled",
+			inventoryProvider: newInventoryProvider(string(clusterctlv1.InfrastructureProviderType)),
+		},
+		{
+			name:  "not installed by clusterctl",
+			adopt: true,
+		},
+		{
+			name:                 "adopted version is kept",
+			adopt:                true,
+			inventoryProvider:    newInventoryProvider(string(clusterctlv1.InfrastructureProviderType)),
+			wantVersion:          "v1.6.0",
+			wantInstalledVersion: "v1.6.0",
+		},
+		{
+			name:                 "adopted provider is upgraded",
+			adopt:                true,
+			version:              "v1.7.0",
+			inventoryProvider:    newInventoryProvider(string(clusterctlv1.InfrastructureProviderType)),
+			wantVersion:          "v1.7.0",
+			wantInstalledVersion: "v1.6.0",
+		},
+		{
+			name:              "provider type mismatch",
+			adopt:             true,
+			inventoryProvider: newInventoryProvider(string(clusterctlv1.BootstrapProviderType)),
+			wantErr:           true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			provider := &operatorv1.InfrastructureProvider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "docker",
+					Namespace: "capd-system",
+				},
+				Spec: operatorv1.InfrastructureProviderSpec{
+					ProviderSpec: operatorv1.ProviderSpec{
+						Version: tc.version,
+						Adopt:   tc.adopt,
+					},
+				},
+			}
+
+			builder := fake.NewClientBuilder().WithScheme(setupScheme())
+			if tc.inventoryProvider != nil {
+				builder = builder.WithObjects(tc.inventoryProvider)
+			}
+
+			fakeclient := builder.Build()
+
+			p := &phaseReconciler{
+				ctrlClient: fakeclient,
+				provider:   provider,
+			}
+
+			_, err := p.adoptClusterctlProvider(context.TODO())
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(provider.Spec.Version).To(Equal(tc.wantVersion))
+
+			if tc.wantInstalledVersion == "" {
+				g.Expect(provider.Status.InstalledVersion).To(BeNil())
+
+				return
+			}
+
+			g.Expect(provider.Status.InstalledVersion).To(HaveValue(Equal(tc.wantInstalledVersion)))
+
+			// The clusterctl inventory entry is kept once the provider is adopted.
+			g.Expect(fakeclient.Get(context.TODO(), client.ObjectKeyFromObject(tc.inventoryProvider), &clusterctlv1.Provider{})).To(Succeed())
+		})
+	}
+}
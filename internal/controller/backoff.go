@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// DefaultBackoffBaseDelay is the delay before the first retry of a failed provider reconciliation.
+	DefaultBackoffBaseDelay = 5 * time.Millisecond
+
+	// DefaultBackoffMaxDelay is the maximum delay between retries of a failed provider reconciliation.
+	DefaultBackoffMaxDelay = 1000 * time.Second
+)
+
+// Backoff configures how failed provider reconciliations are retried: with an exponential backoff from
+// BaseDelay to MaxDelay, each delay increased by a random fraction, up to Jitter, of itself so that
+// providers failing together don't retry together.
+type Backoff struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Jitter    float64
+}
+
+// Validate checks that the backoff parameters are consistent.
+func (b Backoff) Validate() error {
+	if b.BaseDelay <= 0 {
+		return fmt.Errorf("backoff base delay must be positive, got %s", b.BaseDelay)
+	}
+
+	if b.MaxDelay < b.BaseDelay {
+		return fmt.Errorf("backoff max delay %s must not be less than the base delay %s", b.MaxDelay, b.BaseDelay)
+	}
+
+	if b.Jitter < 0 {
+		return fmt.Errorf("backoff jitter must not be negative, got %v", b.Jitter)
+	}
+
+	return nil
+}
+
+// RateLimiter returns a new rate limiter implementing the backoff. The overall rate of retries is
+// limited as with the controller-runtime default rate limiter.
+func (b Backoff) RateLimiter() workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		&jitterRateLimiter{
+			RateLimiter: workqueue.NewItemExponentialFailureRateLimiter(b.BaseDelay, b.MaxDelay),
+			jitter:      b.Jitter,
+		},
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
+}
+
+// jitterRateLimiter adds a random jitter to the delays of the wrapped rate limiter.
+type jitterRateLimiter struct {
+	workqueue.RateLimiter
+	jitter float64
+}
+
+func (r *jitterRateLimiter) When(item interface{}) time.Duration {
+	delay := r.RateLimiter.When(item)
+	if r.jitter == 0 {
+		return delay
+	}
+
+	return wait.Jitter(delay, r.jitter)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name         string
+		baseDelay    time.Duration
+		maxDelay     time.Duration
+		jitter       float64
+		wantDelays   []time.Duration
+		wantErr      bool
+		wantJittered bool
+	}{
+		{
+			name:       "exponential backoff capped at the max delay",
+			baseDelay:  time.Second,
+			maxDelay:   3 * time.Second,
+			wantDelays: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+		{
+			name:         "jitter increases the delays",
+			baseDelay:    time.Second,
+			maxDelay:     time.Minute,
+			jitter:       0.5,
+			wantDelays:   []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+			wantJittered: true,
+		},
+		{
+			name:      "non positive base delay",
+			baseDelay: 0,
+			maxDelay:  time.Second,
+			wantErr:   true,
+		},
+		{
+			name:      "max delay less than base delay",
+			baseDelay: time.Minute,
+			maxDelay:  time.Second,
+			wantErr:   true,
+		},
+		{
+			name:      "negative jitter",
+			baseDelay: time.Second,
+			maxDelay:  time.Minute,
+			jitter:    -1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			backoff := Backoff{BaseDelay: tt.baseDelay, MaxDelay: tt.maxDelay, Jitter: tt.jitter}
+
+			err := backoff.Validate()
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+
+			limiter := backoff.RateLimiter()
+
+			for _, want := range tt.wantDelays {
+				got := limiter.When("provider")
+				if tt.wantJittered {
+					g.Expect(got).To(BeNumerically(">=", want))
+					g.Expect(got).To(BeNumerically("<", want+time.Duration(tt.jitter*float64(want))))
+				} else {
+					g.Expect(got).To(Equal(want))
+				}
+			}
+
+			limiter.Forget("provider")
+			g.Expect(limiter.When("provider")).To(BeNumerically("<", 2*tt.baseDelay))
+		})
+	}
+}
@@ -0,0 +1,290 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const (
+	// chartUsernameKey and chartPasswordKey are the config secret variables with the Helm chart repository credentials.
+	chartUsernameKey = "CHART_USERNAME"
+	chartPasswordKey = "CHART_PASSWORD"
+)
+
+// downloadChartManifests renders the provider components from a Helm chart and stores them in a ConfigMap
+// like the manifests downloaded from a GitHub release.
+func (p *phaseReconciler) downloadChartManifests(ctx context.Context) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	spec := p.provider.GetSpec()
+	source := spec.FetchConfig.Chart
+
+	version := source.Version
+	if version == "" {
+		version = spec.Version
+	}
+
+	log.Info("Rendering provider manifests from Helm chart", "repository", source.Repository, "name", source.Name, "version", version)
+
+	httpClient, err := p.fetchHTTPClient(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to create HTTP client for provider %q: %w", p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	credentials, err := p.ociPullCredentials(ctx)
+	if err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	ch, err := pullChart(ctx, source.Repository, source.Name, version, p.configClient.Variables(), credentials, httpClient)
+	if err != nil {
+		err = fmt.Errorf("failed to pull Helm chart from %s for provider %q: %w", source.Repository, p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if spec.Version == "" {
+		// User didn't set the version, use the version of the latest chart.
+		spec.Version = ch.Metadata.Version
+
+		// Add version to the provider spec.
+		p.provider.SetSpec(spec)
+	}
+
+	components, err := renderChart(ch, p.provider.GetName(), targetNamespace(p.provider), source.Values)
+	if err != nil {
+		err = fmt.Errorf("failed to render Helm chart %s-%s for provider %q: %w", ch.Name(), ch.Metadata.Version, p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	// Metadata set in the provider spec replaces the one from the chart, where it is usually missing, when loaded.
+	metadata := chartFile(ch, metadataFile)
+
+	if metadata == nil && !hasMetadataOverride(spec) {
+		err = fmt.Errorf("no %q file in Helm chart %s-%s for provider %q, fetchConfig.metadata must be set", metadataFile, ch.Name(), ch.Metadata.Version, p.provider.GetName())
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if err := p.createManifestsConfigMap(ctx, metadata, components, needToCompress(metadata, components)); err != nil {
+		err = fmt.Errorf("failed to create config map for provider %q: %w", p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// pullChart returns the chart with the given version, or the latest chart if no version is given, from a Helm chart
+// repository or an OCI registry.
+func pullChart(ctx context.Context, repository, name, version string, variables configclient.VariablesClient, credentials map[string]auth.Credential,
+	httpClient *http.Client,
+) (*chart.Chart, error) {
+	if registry.IsOCI(repository) {
+		return pullOCIChart(ctx, strings.TrimPrefix(repository, fmt.Sprintf("%s://", registry.OCIScheme)), version, variables, credentials, httpClient)
+	}
+
+	if name == "" {
+		return nil, errors.New("chart name must be set for Helm chart repositories")
+	}
+
+	// A missing variable is returned as an error, the credential is left empty then.
+	username, _ := variables.Get(chartUsernameKey)
+	password, _ := variables.Get(chartPasswordKey)
+
+	indexData, err := getChartRepositoryFile(ctx, httpClient, strings.TrimSuffix(repository, "/")+"/index.yaml", username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &repo.IndexFile{}
+	if err := yaml.Unmarshal(indexData, index); err != nil {
+		return nil, fmt.Errorf("failed to decode index of Helm chart repository %s: %w", repository, err)
+	}
+
+	index.SortEntries()
+
+	chartVersion, err := index.Get(name, version)
+	if err != nil {
+		return nil, fmt.Errorf("chart %q version %q not found: %w", name, version, err)
+	}
+
+	if len(chartVersion.URLs) == 0 {
+		return nil, fmt.Errorf("chart %q version %q has no downloadable URLs", name, chartVersion.Version)
+	}
+
+	chartURL, err := repo.ResolveReferenceURL(repository, chartVersion.URLs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// Like Helm, the credentials are only passed to charts hosted by the repository itself, not to any host
+	// the index refers to.
+	sameHost, err := sameChartRepositoryHost(repository, chartURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sameHost {
+		username, password = "", ""
+	}
+
+	archive, err := getChartRepositoryFile(ctx, httpClient, chartURL, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return loader.LoadArchive(bytes.NewReader(archive))
+}
+
+// pullOCIChart returns the chart with the given version, or the latest chart if no version is given, from an OCI registry.
+func pullOCIChart(ctx context.Context, reference, version string, variables configclient.VariablesClient, credentials map[string]auth.Credential,
+	httpClient *http.Client,
+) (*chart.Chart, error) {
+	ociRepo, err := newOCIRepository(reference, variables, credentials, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == "" {
+		version, err = latestOCITag(ctx, ociRepo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// OCI tags don't allow "+", Helm replaces it with "_" when pushing charts.
+	manifest, err := fetchOCIManifest(ctx, ociRepo, strings.ReplaceAll(version, "+", "_"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != registry.ChartLayerMediaType && layer.MediaType != registry.LegacyChartLayerMediaType {
+			continue
+		}
+
+		archive, err := content.FetchAll(ctx, ociRepo, layer)
+		if err != nil {
+			return nil, err
+		}
+
+		return loader.LoadArchive(bytes.NewReader(archive))
+	}
+
+	return nil, fmt.Errorf("OCI artifact %s:%s is not a Helm chart", reference, version)
+}
+
+// sameChartRepositoryHost returns true if the chart URL has the same scheme and host as the repository URL.
+func sameChartRepositoryHost(repository, chartURL string) (bool, error) {
+	repositoryURL, err := url.Parse(repository)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse Helm chart repository URL %s: %w", repository, err)
+	}
+
+	u, err := url.Parse(chartURL)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse chart URL %s: %w", chartURL, err)
+	}
+
+	return strings.EqualFold(u.Scheme, repositoryURL.Scheme) && strings.EqualFold(u.Host, repositoryURL.Host), nil
+}
+
+// getChartRepositoryFile returns the file at the URL of a Helm chart repository.
+func getChartRepositoryFile(ctx context.Context, httpClient *http.Client, url, username, password string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if username != "" || password != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get %s: %s", url, resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// renderChart returns the manifests of the chart, including its CRDs, rendered with the given values.
+// Helm hooks are not rendered.
+func renderChart(ch *chart.Chart, releaseName, namespace, values string) ([]byte, error) {
+	vals, err := chartutil.ReadValues([]byte(values))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse chart values: %w", err)
+	}
+
+	install := action.NewInstall(&action.Configuration{Log: func(string, ...interface{}) {}})
+	install.DryRun = true
+	install.ClientOnly = true
+	install.Replace = true
+	install.IncludeCRDs = true
+	install.ReleaseName = releaseName
+	install.Namespace = namespace
+
+	release, err := install.Run(ch, vals)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(release.Manifest), nil
+}
+
+// chartFile returns the data of a file in the chart root, or nil if there is no such file.
+func chartFile(ch *chart.Chart, name string) []byte {
+	for _, file := range ch.Files {
+		if file.Name == name {
+			return file.Data
+		}
+	}
+
+	return nil
+}
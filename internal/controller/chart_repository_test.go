@@ -0,0 +1,269 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	testChartCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+`
+	testChartDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}-controller-manager
+  namespace: {{ .Release.Namespace }}
+spec:
+  replicas: {{ .Values.replicas }}
+`
+)
+
+// newFakeChartRepository returns a Helm chart repository serving the test chart in the given versions from the
+// directory. Requests must use the given basic auth credentials.
+func newFakeChartRepository(g *WithT, dir string, versions []string, username, password string) *httptest.Server {
+	for _, version := range versions {
+		ch := &chart.Chart{
+			Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "provider", Version: version},
+			Templates: []*chart.File{
+				{Name: "templates/deployment.yaml", Data: []byte(testChartDeployment)},
+			},
+			Values: map[string]interface{}{"replicas": 1},
+			Files: []*chart.File{
+				{Name: "crds/widgets.yaml", Data: []byte(testChartCRD)},
+				{Name: metadataFile, Data: []byte("metadata " + version)},
+			},
+		}
+
+		_, err := chartutil.Save(ch, dir)
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+
+	index, err := repo.IndexDirectory(dir, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(index.WriteFile(filepath.Join(dir, "index.yaml"), 0o600)).To(Succeed())
+
+	files := http.FileServer(http.Dir(dir))
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != username || pass != password {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		files.ServeHTTP(w, r)
+	}))
+}
+
+func TestChartRepository(t *testing.T) {
+	testCases := []struct {
+		name         string
+		chartName    string
+		version      string
+		values       string
+		variables    map[string]string
+		wantVersion  string
+		wantMetadata string
+		wantErr      bool
+	}{
+		{
+			name:         "latest chart",
+			chartName:    "provider",
+			variables:    map[string]string{chartUsernameKey: "user", chartPasswordKey: "password"},
+			wantVersion:  "1.10.0",
+			wantMetadata: "metadata 1.10.0",
+		},
+		{
+			name:         "chart version with values",
+			chartName:    "provider",
+			version:      "v1.9.0",
+			values:       "replicas: 3",
+			variables:    map[string]string{chartUsernameKey: "user", chartPasswordKey: "password"},
+			wantVersion:  "1.9.0",
+			wantMetadata: "metadata 1.9.0",
+		},
+		{
+			name:      "missing chart version",
+			chartName: "provider",
+			version:   "2.0.0",
+			variables: map[string]string{chartUsernameKey: "user", chartPasswordKey: "password"},
+			wantErr:   true,
+		},
+		{
+			name:      "missing chart name",
+			variables: map[string]string{chartUsernameKey: "user", chartPasswordKey: "password"},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid credentials",
+			chartName: "provider",
+			variables: map[string]string{chartUsernameKey: "user", chartPasswordKey: "wrong"},
+			wantErr:   true,
+		},
+	}
+
+	g := NewWithT(t)
+
+	chartRepository := newFakeChartRepository(g, t.TempDir(), []string{"1.0.0", "1.9.0", "1.10.0"}, "user", "password")
+	defer chartRepository.Close()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mr := configclient.NewMemoryReader()
+			for key, value := range tc.variables {
+				mr.Set(key, value)
+			}
+
+			configClient, err := configclient.New(context.TODO(), "", configclient.InjectReader(mr))
+			g.Expect(err).ToNot(HaveOccurred())
+
+			ch, err := pullChart(context.TODO(), chartRepository.URL, tc.chartName, tc.version, configClient.Variables(), nil, http.DefaultClient)
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(ch.Metadata.Version).To(Equal(tc.wantVersion))
+			g.Expect(string(chartFile(ch, metadataFile))).To(Equal(tc.wantMetadata))
+
+			components, err := renderChart(ch, "my-provider", "my-provider-system", tc.values)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(components)).To(ContainSubstring("name: widgets.example.com"))
+			g.Expect(string(components)).To(ContainSubstring("name: my-provider-controller-manager\n  namespace: my-provider-system"))
+
+			if tc.values != "" {
+				g.Expect(string(components)).To(ContainSubstring("replicas: 3"))
+			}
+		})
+	}
+}
+
+func TestChartRepositoryCredentialsCrossHost(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+
+	ch := &chart.Chart{
+		Metadata:  &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "provider", Version: "1.0.0"},
+		Templates: []*chart.File{{Name: "templates/deployment.yaml", Data: []byte(testChartDeployment)}},
+		Values:    map[string]interface{}{"replicas": 1},
+	}
+
+	_, err := chartutil.Save(ch, dir)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// The charts are hosted on another host than the index and must not receive the repository credentials.
+	var chartHostAuthorization []string
+
+	files := http.FileServer(http.Dir(dir))
+
+	chartHost := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chartHostAuthorization = append(chartHostAuthorization, r.Header.Get("Authorization"))
+
+		files.ServeHTTP(w, r)
+	}))
+	defer chartHost.Close()
+
+	index, err := repo.IndexDirectory(dir, chartHost.URL)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	indexData, err := yaml.Marshal(index)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	chartRepository := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		_, _ = w.Write(indexData)
+	}))
+	defer chartRepository.Close()
+
+	mr := configclient.NewMemoryReader()
+	mr.Set(chartUsernameKey, "user")
+	mr.Set(chartPasswordKey, "password")
+
+	configClient, err := configclient.New(context.TODO(), "", configclient.InjectReader(mr))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	pulled, err := pullChart(context.TODO(), chartRepository.URL, "provider", "", configClient.Variables(), nil, http.DefaultClient)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(pulled.Metadata.Version).To(Equal("1.0.0"))
+	g.Expect(chartHostAuthorization).To(Equal([]string{""}))
+}
+
+func TestSameChartRepositoryHost(t *testing.T) {
+	testCases := []struct {
+		name       string
+		repository string
+		chartURL   string
+		want       bool
+	}{
+		{
+			name:       "same host",
+			repository: "https://charts.example.com/stable",
+			chartURL:   "https://charts.example.com/stable/provider-1.0.0.tgz",
+			want:       true,
+		},
+		{
+			name:       "other host",
+			repository: "https://charts.example.com/stable",
+			chartURL:   "https://downloads.example.com/provider-1.0.0.tgz",
+		},
+		{
+			name:       "other port",
+			repository: "https://charts.example.com/stable",
+			chartURL:   "https://charts.example.com:8443/stable/provider-1.0.0.tgz",
+		},
+		{
+			name:       "other scheme",
+			repository: "https://charts.example.com/stable",
+			chartURL:   "http://charts.example.com/stable/provider-1.0.0.tgz",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, err := sameChartRepositoryHost(tc.repository, tc.chartURL)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got).To(Equal(tc.want))
+		})
+	}
+}
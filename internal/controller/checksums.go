@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// manifestDigests are the hex encoded SHA256 digests of the components and metadata of a provider version as
+// fetched, before additional manifests are appended. The metadata digest is empty if the metadata is overridden
+// by the provider spec.
+type manifestDigests struct {
+	components string
+	metadata   string
+}
+
+// sha256Digest returns the hex encoded SHA256 digest of the data.
+func sha256Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// validateChecksums checks the digests of the manifests of the version against the checksums pinned for it in
+// the provider fetch config. Any version is valid if no checksums are pinned.
+func (p *phaseReconciler) validateChecksums(version string) error {
+	spec := p.provider.GetSpec()
+	if spec.FetchConfig == nil || len(spec.FetchConfig.Checksums) == 0 {
+		return nil
+	}
+
+	digests, ok := p.manifestDigests[version]
+	if !ok {
+		return fmt.Errorf("no manifests were loaded for version %s of provider %q", version, p.provider.GetName())
+	}
+
+	for _, checksums := range spec.FetchConfig.Checksums {
+		if checksums.Version != version {
+			continue
+		}
+
+		if checksums.Components != "" && !strings.EqualFold(checksums.Components, digests.components) {
+			return fmt.Errorf("components of version %s of provider %q have SHA256 digest %s, expected %s",
+				version, p.provider.GetName(), digests.components, strings.ToLower(checksums.Components))
+		}
+
+		if checksums.Metadata != "" && digests.metadata != "" && !strings.EqualFold(checksums.Metadata, digests.metadata) {
+			return fmt.Errorf("metadata of version %s of provider %q has SHA256 digest %s, expected %s",
+				version, p.provider.GetName(), digests.metadata, strings.ToLower(checksums.Metadata))
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("no checksums are pinned for version %s of provider %q", version, p.provider.GetName())
+}
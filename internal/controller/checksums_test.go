@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestValidateChecksums(t *testing.T) {
+	metadata := "metadata v2.3.0"
+	components := "components v2.3.0"
+	additionalManifests := "additional manifests"
+
+	configMaps := []corev1.ConfigMap{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "v2.3.0", Namespace: "capa-system"},
+			Data:       map[string]string{metadataConfigMapKey: metadata, componentsConfigMapKey: components},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		checksums        []operatorv1.ManifestChecksums
+		metadataOverride *operatorv1.ProviderMetadata
+		wantErr          string
+	}{
+		{
+			name: "no checksums",
+		},
+		{
+			name: "matching checksums",
+			checksums: []operatorv1.ManifestChecksums{
+				{Version: "v2.2.0", Components: sha256Digest([]byte("components v2.2.0"))},
+				{Version: "v2.3.0", Components: strings.ToUpper(sha256Digest([]byte(components))), Metadata: sha256Digest([]byte(metadata))},
+			},
+		},
+		{
+			name: "tampered components",
+			checksums: []operatorv1.ManifestChecksums{
+				{Version: "v2.3.0", Components: sha256Digest([]byte("components v2.2.0"))},
+			},
+			wantErr: "components of version v2.3.0",
+		},
+		{
+			name: "tampered metadata",
+			checksums: []operatorv1.ManifestChecksums{
+				{Version: "v2.3.0", Components: sha256Digest([]byte(components)), Metadata: sha256Digest([]byte("metadata v2.2.0"))},
+			},
+			wantErr: "metadata of version v2.3.0",
+		},
+		{
+			name: "overridden metadata",
+			checksums: []operatorv1.ManifestChecksums{
+				{Version: "v2.3.0", Components: sha256Digest([]byte(components)), Metadata: sha256Digest([]byte("metadata v2.2.0"))},
+			},
+			metadataOverride: &operatorv1.ProviderMetadata{
+				ReleaseSeries: []operatorv1.ReleaseSeries{{Major: 2, Minor: 3, Contract: "v1beta1"}},
+			},
+		},
+		{
+			name: "version not pinned",
+			checksums: []operatorv1.ManifestChecksums{
+				{Version: "v2.2.0", Components: sha256Digest([]byte("components v2.2.0"))},
+			},
+			wantErr: "no checksums are pinned for version v2.3.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			p := &phaseReconciler{
+				provider: &operatorv1.InfrastructureProvider{
+					ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "capa-system"},
+					Spec: operatorv1.InfrastructureProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							FetchConfig: &operatorv1.FetchConfiguration{
+								Selector:  &metav1.LabelSelector{MatchLabels: map[string]string{"provider-components": "aws"}},
+								Checksums: tt.checksums,
+								Metadata:  tt.metadataOverride,
+							},
+						},
+					},
+				},
+			}
+
+			// Checksums are validated against the fetched components, not the ones with the additional manifests.
+			_, err := p.memoryRepository(configMaps, additionalManifests)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			err = p.validateChecksums("v2.3.0")
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
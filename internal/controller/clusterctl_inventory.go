@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// syncClusterctlInventory keeps the clusterctl inventory entry of the provider in sync with the installed version,
+// so clusterctl move, upgrade plan and describe work on clusters managed by the operator.
+func (p *phaseReconciler) syncClusterctlInventory(ctx context.Context) (reconcile.Result, error) {
+	// The inventory of externally managed providers is maintained by the tooling that installed them.
+	if isExternallyManaged(p.provider) || p.provider.GetStatus().InstalledVersion == nil {
+		return reconcile.Result{}, nil
+	}
+
+	desired := clusterctlInventoryProvider(p.provider)
+
+	inventoryProvider := &clusterctlv1.Provider{}
+	inventoryProvider.Name = desired.Name
+	inventoryProvider.Namespace = desired.Namespace
+
+	result, err := controllerutil.CreateOrUpdate(ctx, p.ctrlClient, inventoryProvider, func() error {
+		labels := inventoryProvider.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+
+		for key, value := range desired.Labels {
+			labels[key] = value
+		}
+
+		inventoryProvider.SetLabels(labels)
+		inventoryProvider.Type = desired.Type
+		inventoryProvider.ProviderName = desired.ProviderName
+		inventoryProvider.Version = desired.Version
+		inventoryProvider.WatchedNamespace = desired.WatchedNamespace
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to update clusterctl inventory provider %s: %w", client.ObjectKeyFromObject(inventoryProvider), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.InventoryUpdateErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if result != controllerutil.OperationResultNone {
+		ctrl.LoggerFrom(ctx).Info("Updated clusterctl inventory provider", "inventoryProvider", client.ObjectKeyFromObject(inventoryProvider),
+			"version", inventoryProvider.Version, "operation", result)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// deleteClusterctlInventory deletes the clusterctl inventory entry of a deleted provider.
+func (p *phaseReconciler) deleteClusterctlInventory(ctx context.Context) (reconcile.Result, error) {
+	if isExternallyManaged(p.provider) {
+		return reconcile.Result{}, nil
+	}
+
+	inventoryProvider := &clusterctlv1.Provider{}
+	inventoryProvider.Name = clusterctlProviderName(p.provider).Name
+	inventoryProvider.Namespace = clusterctlProviderName(p.provider).Namespace
+
+	if err := p.ctrlClient.Delete(ctx, inventoryProvider); client.IgnoreNotFound(err) != nil {
+		err = fmt.Errorf("failed to delete clusterctl inventory provider %s: %w", client.ObjectKeyFromObject(inventoryProvider), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.OldComponentsDeletionErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// clusterctlInventoryProvider returns the clusterctl inventory entry of the installed provider, labeled like the
+// ones created by clusterctl init.
+func clusterctlInventoryProvider(provider operatorv1.GenericProvider) clusterctlv1.Provider {
+	inventoryProvider := getProvider(provider, "")
+
+	inventoryProvider.Labels = map[string]string{
+		clusterctlv1.ClusterctlLabel:     "",
+		clusterctlv1.ClusterctlCoreLabel: clusterctlv1.ClusterctlCoreLabelInventoryValue,
+		clusterv1.ProviderNameLabel:      inventoryProvider.ManifestLabel(),
+	}
+
+	if manager := provider.GetSpec().Manager; manager != nil {
+		inventoryProvider.WatchedNamespace = manager.CacheNamespace
+	}
+
+	return inventoryProvider
+}
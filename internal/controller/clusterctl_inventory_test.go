@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestSyncClusterctlInventory(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "docker",
+			Namespace: "capd-system",
+		},
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				Version: "v1.6.1",
+				Manager: &operatorv1.ManagerSpec{
+					ControllerManagerConfiguration: operatorv1.ControllerManagerConfiguration{CacheNamespace: "clusters"},
+				},
+			},
+		},
+		Status: operatorv1.InfrastructureProviderStatus{
+			ProviderStatus: operatorv1.ProviderStatus{InstalledVersion: pointer.String("v1.6.0")},
+		},
+	}
+
+	fakeclient := fake.NewClientBuilder().WithScheme(setupScheme()).Build()
+
+	p := &phaseReconciler{
+		ctrlClient: fakeclient,
+		provider:   provider,
+	}
+
+	key := client.ObjectKey{Name: "infrastructure-docker", Namespace: "capd-system"}
+
+	// The entry is created for the installed version.
+	_, err := p.syncClusterctlInventory(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	inventoryProvider := &clusterctlv1.Provider{}
+	g.Expect(fakeclient.Get(context.TODO(), key, inventoryProvider)).To(Succeed())
+	g.Expect(inventoryProvider.Type).To(Equal(string(clusterctlv1.InfrastructureProviderType)))
+	g.Expect(inventoryProvider.ProviderName).To(Equal("docker"))
+	g.Expect(inventoryProvider.Version).To(Equal("v1.6.0"))
+	g.Expect(inventoryProvider.WatchedNamespace).To(Equal("clusters"))
+	g.Expect(inventoryProvider.Labels).To(HaveKeyWithValue(clusterctlv1.ClusterctlCoreLabel, clusterctlv1.ClusterctlCoreLabelInventoryValue))
+	g.Expect(inventoryProvider.Labels).To(HaveKeyWithValue(clusterv1.ProviderNameLabel, "infrastructure-docker"))
+
+	// The entry is updated once the provider is upgraded.
+	provider.Status.InstalledVersion = pointer.String("v1.6.1")
+	provider.Spec.Manager = nil
+
+	_, err = p.syncClusterctlInventory(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(fakeclient.Get(context.TODO(), key, inventoryProvider)).To(Succeed())
+	g.Expect(inventoryProvider.Version).To(Equal("v1.6.1"))
+	g.Expect(inventoryProvider.WatchedNamespace).To(BeEmpty())
+
+	// The entry is deleted with the provider.
+	_, err = p.deleteClusterctlInventory(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	err = fakeclient.Get(context.TODO(), key, inventoryProvider)
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+	// Externally managed providers are left alone.
+	provider.Spec.ManagementMode = operatorv1.ManagementModeExternal
+
+	_, err = p.syncClusterctlInventory(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	err = fakeclient.Get(context.TODO(), key, inventoryProvider)
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+}
@@ -18,14 +18,20 @@ package controller
 
 import (
 	"fmt"
+	"net"
+	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/scheme"
 	configv1alpha1 "k8s.io/component-base/config/v1alpha1"
 	"k8s.io/utils/pointer"
@@ -36,8 +42,15 @@ import (
 const (
 	deploymentKind       = "Deployment"
 	namespaceKind        = "Namespace"
+	serviceAccountKind   = "ServiceAccount"
+	pdbKind              = "PodDisruptionBudget"
 	managerContainerName = "manager"
-	defaultVerbosity     = 1
+	metricsPortName      = "metrics"
+	webhookPortName      = "webhook-server"
+	healthPortName       = "healthz"
+	// defaultWebhookCertDir is the default cert dir of the controller-runtime webhook server.
+	defaultWebhookCertDir = "/tmp/k8s-webhook-server/serving-certs"
+	defaultVerbosity      = 1
 )
 
 var bool2Str = map[bool]string{true: "true", false: "false"}
@@ -49,15 +62,33 @@ func customizeObjectsFn(provider operatorv1.GenericProvider) func(objs []unstruc
 
 		isMultipleDeployments := isMultipleDeployments(objs)
 
+		// The generated PodDisruptionBudgets are customized like the objects of the provider manifests.
+		pdbs, err := podDisruptionBudgets(provider.GetSpec(), objs, isMultipleDeployments)
+		if err != nil {
+			return nil, err
+		}
+
+		objs = append(objs[:len(objs):len(objs)], pdbs...)
+
 		for i := range objs {
 			o := objs[i]
 
-			if o.GetKind() == namespaceKind {
-				// filter out namespaces as the targetNamespace already exists as the provider object is in it.
+			if o.GetKind() == namespaceKind && o.GetName() == provider.GetNamespace() {
+				// filter out the namespace of the provider as it already exists, a different target namespace
+				// is created with the components.
 				continue
 			}
 
-			if o.GetNamespace() != "" {
+			if o.GetNamespace() != "" && o.GetNamespace() != provider.GetNamespace() {
+				// objects can't be owned by a provider in another namespace, so they are annotated with it instead.
+				annotations := o.GetAnnotations()
+				if annotations == nil {
+					annotations = map[string]string{}
+				}
+
+				annotations[operatorv1.ProviderNamespaceAnnotation] = provider.GetNamespace()
+				o.SetAnnotations(annotations)
+			} else if o.GetNamespace() != "" {
 				// only set the ownership on namespaced objects.
 				ownerReferences := o.GetOwnerReferences()
 				if ownerReferences == nil {
@@ -73,6 +104,10 @@ func customizeObjectsFn(provider operatorv1.GenericProvider) func(objs []unstruc
 					}))
 			}
 
+			if o.GetKind() == serviceAccountKind && provider.GetSpec().Deployment != nil {
+				o.SetAnnotations(mergeMetadata(o.GetAnnotations(), provider.GetSpec().Deployment.ServiceAccountAnnotations, nil))
+			}
+
 			if o.GetKind() == deploymentKind {
 				// We need to skip the deployment customization if there are several deployments available
 				// and the deployment name doesn't follow "ca*-controller-manager" pattern.
@@ -100,6 +135,10 @@ func customizeObjectsFn(provider operatorv1.GenericProvider) func(objs []unstruc
 				}
 			}
 
+			// Common labels and annotations are added last, so they don't override more specific ones.
+			o.SetLabels(mergeMetadata(o.GetLabels(), provider.GetSpec().CommonLabels, o.GetLabels()))
+			o.SetAnnotations(mergeMetadata(o.GetAnnotations(), provider.GetSpec().CommonAnnotations, o.GetAnnotations()))
+
 			results = append(results, o)
 		}
 
@@ -107,6 +146,63 @@ func customizeObjectsFn(provider operatorv1.GenericProvider) func(objs []unstruc
 	}
 }
 
+// podDisruptionBudgets generates a PodDisruptionBudget for each of the provider Deployments. The PodDisruptionBudgets
+// have the same name, namespace, labels and selector as their Deployment.
+func podDisruptionBudgets(pSpec operatorv1.ProviderSpec, objs []unstructured.Unstructured, isMultipleDeployments bool) ([]unstructured.Unstructured, error) {
+	if pSpec.Deployment == nil || pSpec.Deployment.PodDisruptionBudget == nil {
+		return nil, nil
+	}
+
+	minAvailable := intstr.FromInt(1)
+	if pSpec.Deployment.PodDisruptionBudget.MinAvailable != nil {
+		minAvailable = *pSpec.Deployment.PodDisruptionBudget.MinAvailable
+	}
+
+	pdbs := []unstructured.Unstructured{}
+
+	for i := range objs {
+		o := objs[i]
+
+		// Like the deployment customization, skip the deployments that don't belong to the provider manager.
+		if o.GetKind() != deploymentKind || (isMultipleDeployments && !isProviderManagerDeploymentName(o.GetName())) {
+			continue
+		}
+
+		d := &appsv1.Deployment{}
+		if err := scheme.Scheme.Convert(&o, d, nil); err != nil {
+			return nil, err
+		}
+
+		pdb := &policyv1.PodDisruptionBudget{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: policyv1.SchemeGroupVersion.String(),
+				Kind:       pdbKind,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      d.Name,
+				Namespace: d.Namespace,
+				Labels:    d.Labels,
+			},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable: &minAvailable,
+				Selector:     d.Spec.Selector,
+			},
+		}
+
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pdb)
+		if err != nil {
+			return nil, err
+		}
+
+		// The status is set by the API server.
+		unstructured.RemoveNestedField(content, "status")
+
+		pdbs = append(pdbs, unstructured.Unstructured{Object: content})
+	}
+
+	return pdbs, nil
+}
+
 // customizeDeployment customize provider deployment base on provider spec input.
 func customizeDeployment(pSpec operatorv1.ProviderSpec, d *appsv1.Deployment) error {
 	// Customize deployment spec first.
@@ -146,6 +242,10 @@ func customizeDeploymentSpec(pSpec operatorv1.ProviderSpec, d *appsv1.Deployment
 		d.Spec.Template.Spec.Tolerations = dSpec.Tolerations
 	}
 
+	if dSpec.TopologySpreadConstraints != nil {
+		d.Spec.Template.Spec.TopologySpreadConstraints = dSpec.TopologySpreadConstraints
+	}
+
 	if dSpec.ServiceAccountName != "" {
 		d.Spec.Template.Spec.ServiceAccountName = dSpec.ServiceAccountName
 	}
@@ -154,11 +254,74 @@ func customizeDeploymentSpec(pSpec operatorv1.ProviderSpec, d *appsv1.Deployment
 		d.Spec.Template.Spec.ImagePullSecrets = dSpec.ImagePullSecrets
 	}
 
+	for _, v := range dSpec.Volumes {
+		d.Spec.Template.Spec.Volumes = removeVolume(d.Spec.Template.Spec.Volumes, v.Name)
+		d.Spec.Template.Spec.Volumes = append(d.Spec.Template.Spec.Volumes, v)
+	}
+
+	if dSpec.PodSecurityContext != nil {
+		d.Spec.Template.Spec.SecurityContext = dSpec.PodSecurityContext
+	}
+
+	if dSpec.RuntimeClassName != nil {
+		d.Spec.Template.Spec.RuntimeClassName = dSpec.RuntimeClassName
+	}
+
+	if dSpec.HostNetwork != nil {
+		d.Spec.Template.Spec.HostNetwork = *dSpec.HostNetwork
+	}
+
+	if dSpec.DNSPolicy != "" {
+		d.Spec.Template.Spec.DNSPolicy = dSpec.DNSPolicy
+	}
+
+	if dSpec.DNSConfig != nil {
+		d.Spec.Template.Spec.DNSConfig = dSpec.DNSConfig
+	}
+
+	if dSpec.PriorityClassName != "" {
+		d.Spec.Template.Spec.PriorityClassName = dSpec.PriorityClassName
+	}
+
+	d.Labels = mergeMetadata(d.Labels, dSpec.Labels, nil)
+	d.Annotations = mergeMetadata(d.Annotations, dSpec.Annotations, nil)
+
+	// Selector labels are kept so the Deployment still selects its pods.
+	var selectorLabels map[string]string
+	if d.Spec.Selector != nil {
+		selectorLabels = d.Spec.Selector.MatchLabels
+	}
+
+	d.Spec.Template.Labels = mergeMetadata(d.Spec.Template.Labels, dSpec.PodLabels, selectorLabels)
+	d.Spec.Template.Annotations = mergeMetadata(d.Spec.Template.Annotations, dSpec.PodAnnotations, nil)
+
 	for _, pc := range dSpec.Containers {
 		customizeContainer(pc, d)
 	}
 }
 
+// mergeMetadata adds the overrides to the labels or annotations of an object. Keys of the protected map
+// are not overridden.
+func mergeMetadata(metadata, overrides, protected map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return metadata
+	}
+
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+
+	for k, v := range overrides {
+		if _, found := protected[k]; found {
+			continue
+		}
+
+		metadata[k] = v
+	}
+
+	return metadata
+}
+
 // findManagerContainer finds manager container in the provider deployment.
 func findManagerContainer(dSpec *appsv1.DeploymentSpec) *corev1.Container {
 	for ic := range dSpec.Template.Spec.Containers {
@@ -194,6 +357,10 @@ func customizeManagerContainer(mSpec *operatorv1.ManagerSpec, c *corev1.Containe
 
 	if mSpec.Health.HealthProbeBindAddress != "" {
 		c.Args = setArgs(c.Args, "--health-addr", mSpec.Health.HealthProbeBindAddress)
+
+		if port, ok := addressPort(mSpec.Health.HealthProbeBindAddress); ok {
+			setContainerPort(c, healthPortName, port)
+		}
 	}
 
 	if mSpec.Health.LivenessEndpointName != "" && c.LivenessProbe != nil && c.LivenessProbe.HTTPGet != nil {
@@ -212,6 +379,18 @@ func customizeManagerContainer(mSpec *operatorv1.ManagerSpec, c *corev1.Containe
 		c.Args = setArgs(c.Args, "--metrics-bind-addr", mSpec.Metrics.BindAddress)
 	}
 
+	if mSpec.Metrics.DiagnosticsAddress != "" {
+		c.Args = setArgs(c.Args, "--diagnostics-address", mSpec.Metrics.DiagnosticsAddress)
+
+		if port, ok := addressPort(mSpec.Metrics.DiagnosticsAddress); ok {
+			setContainerPort(c, metricsPortName, port)
+		}
+	}
+
+	if mSpec.Metrics.InsecureDiagnostics != nil {
+		c.Args = setArgs(c.Args, "--insecure-diagnostics", bool2Str[*mSpec.Metrics.InsecureDiagnostics])
+	}
+
 	// webhooks
 	if mSpec.Webhook.Host != "" {
 		c.Args = setArgs(c.Args, "--webhook-host", mSpec.Webhook.Host)
@@ -219,9 +398,22 @@ func customizeManagerContainer(mSpec *operatorv1.ManagerSpec, c *corev1.Containe
 
 	if mSpec.Webhook.Port != nil {
 		c.Args = setArgs(c.Args, "--webhook-port", fmt.Sprint(*mSpec.Webhook.Port))
+		setContainerPort(c, webhookPortName, int32(*mSpec.Webhook.Port))
 	}
 
 	if mSpec.Webhook.CertDir != "" {
+		// Move the mount of the serving certificates to the new cert dir.
+		certDir := defaultWebhookCertDir
+		if v, ok := getArg(c.Args, "--webhook-cert-dir"); ok {
+			certDir = v
+		}
+
+		for i := range c.VolumeMounts {
+			if path.Clean(c.VolumeMounts[i].MountPath) == path.Clean(certDir) {
+				c.VolumeMounts[i].MountPath = mSpec.Webhook.CertDir
+			}
+		}
+
 		c.Args = setArgs(c.Args, "--webhook-cert-dir", mSpec.Webhook.CertDir)
 	}
 
@@ -241,16 +433,45 @@ func customizeManagerContainer(mSpec *operatorv1.ManagerSpec, c *corev1.Containe
 		c.Args = setArgs(c.Args, "--v", fmt.Sprint(mSpec.Verbosity))
 	}
 
+	if mSpec.LogFormat != "" {
+		c.Args = setArgs(c.Args, "--logging-format", mSpec.LogFormat)
+	}
+
 	if len(mSpec.FeatureGates) > 0 {
-		fgValue := []string{}
+		c.Args = setArgs(c.Args, "--feature-gates", mergeFeatureGates(c.Args, mSpec.FeatureGates))
+	}
+}
 
-		for fg, val := range mSpec.FeatureGates {
-			fgValue = append(fgValue, fg+"="+bool2Str[val])
+// mergeFeatureGates returns the value of the --feature-gates arg with the feature gates of the manager spec,
+// keeping the other feature gates set in the provider manifests, e.g. "MachinePool=${EXP_MACHINE_POOL:=false}".
+func mergeFeatureGates(args []string, featureGates map[string]bool) string {
+	gates := map[string]string{}
+
+	for _, a := range args {
+		value, found := strings.CutPrefix(a, "--feature-gates=")
+		if !found {
+			continue
 		}
 
-		sort.Strings(fgValue)
-		c.Args = setArgs(c.Args, "--feature-gates", strings.Join(fgValue, ","))
+		for _, gate := range strings.Split(value, ",") {
+			if name, val, ok := strings.Cut(gate, "="); ok {
+				gates[strings.TrimSpace(name)] = strings.TrimSpace(val)
+			}
+		}
+	}
+
+	for fg, val := range featureGates {
+		gates[fg] = bool2Str[val]
+	}
+
+	fgValue := make([]string, 0, len(gates))
+	for fg, val := range gates {
+		fgValue = append(fgValue, fg+"="+val)
 	}
+
+	sort.Strings(fgValue)
+
+	return strings.Join(fgValue, ",")
 }
 
 // customizeContainer customize provider container base on provider spec input.
@@ -275,6 +496,19 @@ func customizeContainer(cSpec operatorv1.ContainerSpec, d *appsv1.Deployment) {
 				c.Resources = *cSpec.Resources
 			}
 
+			c.LivenessProbe = mergeProbe(c.LivenessProbe, cSpec.LivenessProbe)
+			c.ReadinessProbe = mergeProbe(c.ReadinessProbe, cSpec.ReadinessProbe)
+			c.StartupProbe = mergeProbe(c.StartupProbe, cSpec.StartupProbe)
+
+			for _, vm := range cSpec.VolumeMounts {
+				c.VolumeMounts = removeVolumeMount(c.VolumeMounts, vm.MountPath)
+				c.VolumeMounts = append(c.VolumeMounts, vm)
+			}
+
+			if cSpec.SecurityContext != nil {
+				c.SecurityContext = cSpec.SecurityContext
+			}
+
 			if cSpec.ImageURL != nil {
 				c.Image = *cSpec.ImageURL
 			}
@@ -301,6 +535,60 @@ func setArgs(args []string, name, value string) []string {
 	return append(args, name+"="+value)
 }
 
+// getArg returns the value of the named arg.
+func getArg(args []string, name string) (string, bool) {
+	for _, a := range args {
+		if value, found := strings.CutPrefix(a, name+"="); found {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// addressPort returns the port of a bind address. Addresses without a valid port, like "0" to disable
+// serving, are ignored.
+func addressPort(address string) (int32, bool) {
+	_, p, err := net.SplitHostPort(address)
+	if err != nil {
+		return 0, false
+	}
+
+	port, err := strconv.ParseInt(p, 10, 32)
+	if err != nil || port <= 0 {
+		return 0, false
+	}
+
+	return int32(port), true
+}
+
+// setContainerPort sets the port of the named container port, so the Services, the webhook configurations and
+// the probes targeting the port by name follow it. The probes targeting the previous port by number are updated.
+func setContainerPort(c *corev1.Container, name string, port int32) {
+	for i := range c.Ports {
+		if c.Ports[i].Name != name {
+			continue
+		}
+
+		previous := intstr.FromInt(int(c.Ports[i].ContainerPort))
+		c.Ports[i].ContainerPort = port
+
+		for _, probe := range []*corev1.Probe{c.LivenessProbe, c.ReadinessProbe, c.StartupProbe} {
+			if probe == nil {
+				continue
+			}
+
+			if probe.HTTPGet != nil && probe.HTTPGet.Port == previous {
+				probe.HTTPGet.Port = intstr.FromInt(int(port))
+			}
+
+			if probe.TCPSocket != nil && probe.TCPSocket.Port == previous {
+				probe.TCPSocket.Port = intstr.FromInt(int(port))
+			}
+		}
+	}
+}
+
 // removeEnv remove container environment.
 func removeEnv(envs []corev1.EnvVar, name string) []corev1.EnvVar {
 	for i, a := range envs {
@@ -314,6 +602,71 @@ func removeEnv(envs []corev1.EnvVar, name string) []corev1.EnvVar {
 	return envs
 }
 
+// mergeProbe returns the probe with the handler and the non-zero fields of the override.
+func mergeProbe(probe, override *corev1.Probe) *corev1.Probe {
+	if override == nil {
+		return probe
+	}
+
+	if probe == nil || override.Exec != nil || override.HTTPGet != nil || override.TCPSocket != nil || override.GRPC != nil {
+		return override
+	}
+
+	merged := probe.DeepCopy()
+
+	if override.InitialDelaySeconds != 0 {
+		merged.InitialDelaySeconds = override.InitialDelaySeconds
+	}
+
+	if override.TimeoutSeconds != 0 {
+		merged.TimeoutSeconds = override.TimeoutSeconds
+	}
+
+	if override.PeriodSeconds != 0 {
+		merged.PeriodSeconds = override.PeriodSeconds
+	}
+
+	if override.SuccessThreshold != 0 {
+		merged.SuccessThreshold = override.SuccessThreshold
+	}
+
+	if override.FailureThreshold != 0 {
+		merged.FailureThreshold = override.FailureThreshold
+	}
+
+	if override.TerminationGracePeriodSeconds != nil {
+		merged.TerminationGracePeriodSeconds = override.TerminationGracePeriodSeconds
+	}
+
+	return merged
+}
+
+// removeVolume removes the pod volume with the name.
+func removeVolume(volumes []corev1.Volume, name string) []corev1.Volume {
+	for i, v := range volumes {
+		if v.Name == name {
+			copy(volumes[i:], volumes[i+1:])
+
+			return volumes[:len(volumes)-1]
+		}
+	}
+
+	return volumes
+}
+
+// removeVolumeMount removes the container volume mount with the mount path.
+func removeVolumeMount(volumeMounts []corev1.VolumeMount, mountPath string) []corev1.VolumeMount {
+	for i, vm := range volumeMounts {
+		if vm.MountPath == mountPath {
+			copy(volumeMounts[i:], volumeMounts[i+1:])
+
+			return volumeMounts[:len(volumeMounts)-1]
+		}
+	}
+
+	return volumeMounts
+}
+
 // leaderElectionArgs set leader election flags.
 func leaderElectionArgs(lec *configv1alpha1.LeaderElectionConfiguration, args []string) []string {
 	args = setArgs(args, "--leader-elect", bool2Str[*lec.LeaderElect])
@@ -22,8 +22,10 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -203,6 +205,80 @@ func TestCustomizeDeployment(t *testing.T) {
 				return expectedDS, reflect.DeepEqual(inputDS.Template.Spec.Affinity, expectedDS.Template.Spec.Affinity)
 			},
 		},
+		{
+			name: "only runtimeClassName modified",
+			inputDeploymentSpec: &operatorv1.DeploymentSpec{
+				RuntimeClassName: pointer.String("gvisor"),
+			},
+			expectedDeploymentSpec: func(inputDS *appsv1.DeploymentSpec) (*appsv1.DeploymentSpec, bool) {
+				expectedDS := &appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							RuntimeClassName: pointer.String("gvisor"),
+						},
+					},
+				}
+
+				return expectedDS, reflect.DeepEqual(inputDS.Template.Spec.RuntimeClassName, expectedDS.Template.Spec.RuntimeClassName)
+			},
+		},
+		{
+			name: "only host network and DNS modified",
+			inputDeploymentSpec: &operatorv1.DeploymentSpec{
+				HostNetwork: pointer.Bool(true),
+				DNSPolicy:   corev1.DNSClusterFirstWithHostNet,
+				DNSConfig: &corev1.PodDNSConfig{
+					Nameservers: []string{"192.168.0.53"},
+				},
+			},
+			expectedDeploymentSpec: func(inputDS *appsv1.DeploymentSpec) (*appsv1.DeploymentSpec, bool) {
+				expectedDS := &appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							HostNetwork: true,
+							DNSPolicy:   corev1.DNSClusterFirstWithHostNet,
+							DNSConfig: &corev1.PodDNSConfig{
+								Nameservers: []string{"192.168.0.53"},
+							},
+						},
+					},
+				}
+				if inputDS.Template.Spec.HostNetwork != expectedDS.Template.Spec.HostNetwork || inputDS.Template.Spec.DNSPolicy != expectedDS.Template.Spec.DNSPolicy {
+					return expectedDS, false
+				}
+
+				return expectedDS, reflect.DeepEqual(inputDS.Template.Spec.DNSConfig, expectedDS.Template.Spec.DNSConfig)
+			},
+		},
+		{
+			name: "only topologySpreadConstraints modified",
+			inputDeploymentSpec: &operatorv1.DeploymentSpec{
+				TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+					{
+						MaxSkew:           1,
+						TopologyKey:       "topology.kubernetes.io/zone",
+						WhenUnsatisfiable: corev1.ScheduleAnyway,
+					},
+				},
+			},
+			expectedDeploymentSpec: func(inputDS *appsv1.DeploymentSpec) (*appsv1.DeploymentSpec, bool) {
+				expectedDS := &appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+								{
+									MaxSkew:           1,
+									TopologyKey:       "topology.kubernetes.io/zone",
+									WhenUnsatisfiable: corev1.ScheduleAnyway,
+								},
+							},
+						},
+					},
+				}
+
+				return expectedDS, reflect.DeepEqual(inputDS.Template.Spec.TopologySpreadConstraints, expectedDS.Template.Spec.TopologySpreadConstraints)
+			},
+		},
 		{
 			name: "only serviceAccountName modified",
 			inputDeploymentSpec: &operatorv1.DeploymentSpec{
@@ -245,6 +321,23 @@ func TestCustomizeDeployment(t *testing.T) {
 				return expectedDS, reflect.DeepEqual(inputDS.Template.Spec.ImagePullSecrets, expectedDS.Template.Spec.ImagePullSecrets)
 			},
 		},
+		{
+			name: "only priorityClassName modified",
+			inputDeploymentSpec: &operatorv1.DeploymentSpec{
+				PriorityClassName: "system-cluster-critical",
+			},
+			expectedDeploymentSpec: func(inputDS *appsv1.DeploymentSpec) (*appsv1.DeploymentSpec, bool) {
+				expectedDS := &appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							PriorityClassName: "system-cluster-critical",
+						},
+					},
+				}
+
+				return expectedDS, inputDS.Template.Spec.PriorityClassName == expectedDS.Template.Spec.PriorityClassName
+			},
+		},
 		{
 			name: "only containers modified",
 			inputDeploymentSpec: &operatorv1.DeploymentSpec{
@@ -321,6 +414,104 @@ func TestCustomizeDeployment(t *testing.T) {
 				return expectedDS, true
 			},
 		},
+		{
+			name: "only probes modified",
+			inputDeploymentSpec: &operatorv1.DeploymentSpec{
+				Containers: []operatorv1.ContainerSpec{
+					{
+						Name: "manager",
+						LivenessProbe: &corev1.Probe{
+							TimeoutSeconds:   5,
+							FailureThreshold: 6,
+						},
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(9440)},
+							},
+							PeriodSeconds: 20,
+						},
+					},
+				},
+			},
+			expectedDeploymentSpec: func(inputDS *appsv1.DeploymentSpec) (*appsv1.DeploymentSpec, bool) {
+				expectedDS := &appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name: "manager",
+									LivenessProbe: &corev1.Probe{
+										ProbeHandler: corev1.ProbeHandler{
+											HTTPGet: &corev1.HTTPGetAction{
+												Path: "/healthz",
+												Port: intstr.FromString("healthz"),
+											},
+										},
+										TimeoutSeconds:   5,
+										FailureThreshold: 6,
+									},
+									ReadinessProbe: &corev1.Probe{
+										ProbeHandler: corev1.ProbeHandler{
+											TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(9440)},
+										},
+										PeriodSeconds: 20,
+									},
+								},
+							},
+						},
+					},
+				}
+				if !reflect.DeepEqual(inputDS.Template.Spec.Containers[0].LivenessProbe, expectedDS.Template.Spec.Containers[0].LivenessProbe) {
+					return expectedDS, false
+				}
+
+				return expectedDS, reflect.DeepEqual(inputDS.Template.Spec.Containers[0].ReadinessProbe, expectedDS.Template.Spec.Containers[0].ReadinessProbe)
+			},
+		},
+		{
+			name: "only security contexts modified",
+			inputDeploymentSpec: &operatorv1.DeploymentSpec{
+				PodSecurityContext: &corev1.PodSecurityContext{
+					RunAsNonRoot:   pointer.Bool(true),
+					SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+				},
+				Containers: []operatorv1.ContainerSpec{
+					{
+						Name: "manager",
+						SecurityContext: &corev1.SecurityContext{
+							AllowPrivilegeEscalation: pointer.Bool(false),
+							Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+						},
+					},
+				},
+			},
+			expectedDeploymentSpec: func(inputDS *appsv1.DeploymentSpec) (*appsv1.DeploymentSpec, bool) {
+				expectedDS := &appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							SecurityContext: &corev1.PodSecurityContext{
+								RunAsNonRoot:   pointer.Bool(true),
+								SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+							},
+							Containers: []corev1.Container{
+								{
+									Name: "manager",
+									SecurityContext: &corev1.SecurityContext{
+										AllowPrivilegeEscalation: pointer.Bool(false),
+										Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+									},
+								},
+							},
+						},
+					},
+				}
+				if !reflect.DeepEqual(inputDS.Template.Spec.SecurityContext, expectedDS.Template.Spec.SecurityContext) {
+					return expectedDS, false
+				}
+
+				return expectedDS, reflect.DeepEqual(inputDS.Template.Spec.Containers[0].SecurityContext, expectedDS.Template.Spec.Containers[0].SecurityContext)
+			},
+		},
 		{
 			name: "all deployment options",
 			inputDeploymentSpec: &operatorv1.DeploymentSpec{
@@ -469,6 +660,7 @@ func TestCustomizeDeployment(t *testing.T) {
 				FeatureGates:    map[string]bool{"TEST": true, "ANOTHER": false},
 				ProfilerAddress: "localhost:1234",
 				Verbosity:       5,
+				LogFormat:       "json",
 				ControllerManagerConfiguration: operatorv1.ControllerManagerConfiguration{
 					CacheNamespace: "testNS",
 					SyncPeriod:     &metav1.Duration{Duration: sevenHours},
@@ -525,6 +717,7 @@ func TestCustomizeDeployment(t *testing.T) {
 										"--sync-period=25200s",
 										"--profiler-address=localhost:1234",
 										"--v=5",
+										"--logging-format=json",
 										"--feature-gates=ANOTHER=false,TEST=true",
 									},
 									LivenessProbe: &corev1.Probe{
@@ -571,6 +764,260 @@ func TestCustomizeDeployment(t *testing.T) {
 	}
 }
 
+func TestCustomizeManagerEndpoints(t *testing.T) {
+	certMount := corev1.VolumeMount{Name: "cert", MountPath: "/tmp/k8s-webhook-server/serving-certs", ReadOnly: true}
+
+	tests := []struct {
+		name                 string
+		config               operatorv1.ControllerManagerConfiguration
+		expectedArgs         []string
+		expectedPorts        []corev1.ContainerPort
+		expectedVolumeMounts []corev1.VolumeMount
+		expectedProbePorts   []intstr.IntOrString
+	}{
+		{
+			name: "diagnostics address and insecure diagnostics",
+			config: operatorv1.ControllerManagerConfiguration{
+				Metrics: operatorv1.ControllerMetrics{DiagnosticsAddress: ":8080", InsecureDiagnostics: pointer.Bool(true)},
+			},
+			expectedArgs: []string{
+				"--leader-elect",
+				"--diagnostics-address=:8080",
+				"--insecure-diagnostics=true",
+			},
+			expectedPorts: []corev1.ContainerPort{
+				{Name: "webhook-server", ContainerPort: 9443},
+				{Name: "healthz", ContainerPort: 9440},
+				{Name: "metrics", ContainerPort: 8080},
+			},
+			expectedVolumeMounts: []corev1.VolumeMount{certMount},
+			expectedProbePorts:   []intstr.IntOrString{intstr.FromString("healthz"), intstr.FromInt(9440)},
+		},
+		{
+			name: "disabled diagnostics",
+			config: operatorv1.ControllerManagerConfiguration{
+				Metrics: operatorv1.ControllerMetrics{DiagnosticsAddress: "0"},
+			},
+			expectedArgs: []string{
+				"--leader-elect",
+				"--diagnostics-address=0",
+			},
+			expectedPorts: []corev1.ContainerPort{
+				{Name: "webhook-server", ContainerPort: 9443},
+				{Name: "healthz", ContainerPort: 9440},
+				{Name: "metrics", ContainerPort: 8443},
+			},
+			expectedVolumeMounts: []corev1.VolumeMount{certMount},
+			expectedProbePorts:   []intstr.IntOrString{intstr.FromString("healthz"), intstr.FromInt(9440)},
+		},
+		{
+			name: "webhook port and cert dir",
+			config: operatorv1.ControllerManagerConfiguration{
+				Webhook: operatorv1.ControllerWebhook{Port: pointer.Int(10443), CertDir: "/certs"},
+			},
+			expectedArgs: []string{
+				"--leader-elect",
+				"--webhook-port=10443",
+				"--webhook-cert-dir=/certs",
+			},
+			expectedPorts: []corev1.ContainerPort{
+				{Name: "webhook-server", ContainerPort: 10443},
+				{Name: "healthz", ContainerPort: 9440},
+				{Name: "metrics", ContainerPort: 8443},
+			},
+			expectedVolumeMounts: []corev1.VolumeMount{{Name: "cert", MountPath: "/certs", ReadOnly: true}},
+			expectedProbePorts:   []intstr.IntOrString{intstr.FromString("healthz"), intstr.FromInt(9440)},
+		},
+		{
+			name: "health probe address",
+			config: operatorv1.ControllerManagerConfiguration{
+				Health: operatorv1.ControllerHealth{HealthProbeBindAddress: ":9441"},
+			},
+			expectedArgs: []string{
+				"--leader-elect",
+				"--health-addr=:9441",
+			},
+			expectedPorts: []corev1.ContainerPort{
+				{Name: "webhook-server", ContainerPort: 9443},
+				{Name: "healthz", ContainerPort: 9441},
+				{Name: "metrics", ContainerPort: 8443},
+			},
+			expectedVolumeMounts: []corev1.VolumeMount{certMount},
+			expectedProbePorts:   []intstr.IntOrString{intstr.FromString("healthz"), intstr.FromInt(9441)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := &corev1.Container{
+				Name: managerContainerName,
+				Args: []string{"--leader-elect"},
+				Ports: []corev1.ContainerPort{
+					{Name: "webhook-server", ContainerPort: 9443},
+					{Name: "healthz", ContainerPort: 9440},
+					{Name: "metrics", ContainerPort: 8443},
+				},
+				VolumeMounts: []corev1.VolumeMount{certMount},
+				LivenessProbe: &corev1.Probe{
+					ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromString("healthz")}},
+				},
+				ReadinessProbe: &corev1.Probe{
+					ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/readyz", Port: intstr.FromInt(9440)}},
+				},
+			}
+
+			customizeManagerContainer(&operatorv1.ManagerSpec{
+				Verbosity:                      defaultVerbosity,
+				ControllerManagerConfiguration: tc.config,
+			}, c)
+
+			g.Expect(c.Args).To(Equal(tc.expectedArgs))
+			g.Expect(c.Ports).To(Equal(tc.expectedPorts))
+			g.Expect(c.VolumeMounts).To(Equal(tc.expectedVolumeMounts))
+			g.Expect([]intstr.IntOrString{c.LivenessProbe.HTTPGet.Port, c.ReadinessProbe.HTTPGet.Port}).To(Equal(tc.expectedProbePorts))
+		})
+	}
+}
+
+func TestMergeFeatureGates(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		featureGates map[string]bool
+		expected     string
+	}{
+		{
+			name:         "no feature gates arg",
+			args:         []string{"--leader-elect"},
+			featureGates: map[string]bool{"MachinePool": true, "ClusterTopology": false},
+			expected:     "ClusterTopology=false,MachinePool=true",
+		},
+		{
+			name:         "feature gates of the manifests are kept",
+			args:         []string{"--leader-elect", "--feature-gates=MachinePool=false,ClusterResourceSet=true"},
+			featureGates: map[string]bool{"ClusterTopology": true},
+			expected:     "ClusterResourceSet=true,ClusterTopology=true,MachinePool=false",
+		},
+		{
+			name:         "feature gates of the manifests are overridden",
+			args:         []string{"--feature-gates=MachinePool=${EXP_MACHINE_POOL:=false},ClusterTopology=false"},
+			featureGates: map[string]bool{"MachinePool": true},
+			expected:     "ClusterTopology=false,MachinePool=true",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(mergeFeatureGates(tc.args, tc.featureGates)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestCustomizeDeploymentMetadata(t *testing.T) {
+	g := NewWithT(t)
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "capa-controller-manager",
+			Namespace:   "capa-system",
+			Labels:      map[string]string{"cluster.x-k8s.io/provider": "infrastructure-aws", "control-plane": "capa-controller-manager"},
+			Annotations: map[string]string{"deployment.kubernetes.io/revision": "1"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"control-plane": "capa-controller-manager"},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"control-plane": "capa-controller-manager", "app": "capa"},
+				},
+			},
+		},
+	}
+
+	g.Expect(customizeDeployment(operatorv1.ProviderSpec{
+		Deployment: &operatorv1.DeploymentSpec{
+			Labels:         map[string]string{"cost-center": "platform", "control-plane": "other"},
+			Annotations:    map[string]string{"owner": "platform-team"},
+			PodLabels:      map[string]string{"cost-center": "platform", "control-plane": "other", "app": "other"},
+			PodAnnotations: map[string]string{"sidecar.istio.io/inject": "false"},
+		},
+	}, deployment)).To(Succeed())
+
+	g.Expect(deployment.Labels).To(Equal(map[string]string{
+		"cluster.x-k8s.io/provider": "infrastructure-aws",
+		"control-plane":             "other",
+		"cost-center":               "platform",
+	}))
+	g.Expect(deployment.Annotations).To(Equal(map[string]string{
+		"deployment.kubernetes.io/revision": "1",
+		"owner":                             "platform-team",
+	}))
+	g.Expect(deployment.Spec.Template.Labels).To(Equal(map[string]string{
+		"control-plane": "capa-controller-manager",
+		"app":           "other",
+		"cost-center":   "platform",
+	}))
+	g.Expect(deployment.Spec.Template.Annotations).To(Equal(map[string]string{
+		"sidecar.istio.io/inject": "false",
+	}))
+}
+
+func TestCustomizeDeploymentVolumes(t *testing.T) {
+	g := NewWithT(t)
+
+	deployment := &appsv1.Deployment{
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name: "manager",
+						VolumeMounts: []corev1.VolumeMount{
+							{Name: "cert", MountPath: "/tmp/k8s-webhook-server/serving-certs", ReadOnly: true},
+							{Name: "credentials", MountPath: "/home/.aws"},
+						},
+					}},
+					Volumes: []corev1.Volume{
+						{Name: "cert", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "capa-webhook-service-cert"}}},
+						{Name: "credentials", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "capa-manager-bootstrap-credentials"}}},
+					},
+				},
+			},
+		},
+	}
+
+	caBundle := corev1.Volume{Name: "ca-bundle", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "ca-bundle"}}}}
+	credentials := corev1.Volume{Name: "credentials", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "aws-credentials"}}}
+
+	g.Expect(customizeDeployment(operatorv1.ProviderSpec{
+		Deployment: &operatorv1.DeploymentSpec{
+			Volumes: []corev1.Volume{caBundle, credentials},
+			Containers: []operatorv1.ContainerSpec{{
+				Name: "manager",
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "ca-bundle", MountPath: "/etc/ssl/certs", ReadOnly: true},
+					{Name: "credentials", MountPath: "/home/.aws", ReadOnly: true},
+				},
+			}},
+		},
+	}, deployment)).To(Succeed())
+
+	g.Expect(deployment.Spec.Template.Spec.Volumes).To(Equal([]corev1.Volume{
+		{Name: "cert", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "capa-webhook-service-cert"}}},
+		caBundle,
+		credentials,
+	}))
+	g.Expect(deployment.Spec.Template.Spec.Containers[0].VolumeMounts).To(Equal([]corev1.VolumeMount{
+		{Name: "cert", MountPath: "/tmp/k8s-webhook-server/serving-certs", ReadOnly: true},
+		{Name: "ca-bundle", MountPath: "/etc/ssl/certs", ReadOnly: true},
+		{Name: "credentials", MountPath: "/home/.aws", ReadOnly: true},
+	}))
+}
+
 func TestCustomizeMultipleDeployment(t *testing.T) {
 	tests := []struct {
 		name                     string
@@ -664,3 +1111,179 @@ func TestCustomizeMultipleDeployment(t *testing.T) {
 		})
 	}
 }
+
+func TestCustomizePodDisruptionBudgets(t *testing.T) {
+	g := NewWithT(t)
+
+	deployment := func(name string) unstructured.Unstructured {
+		d := &appsv1.Deployment{
+			TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: deploymentKind},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "capz-system",
+				Labels:    map[string]string{"cluster.x-k8s.io/provider": "infrastructure-azure"},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"control-plane": name}},
+			},
+		}
+
+		o := unstructured.Unstructured{}
+		g.Expect(scheme.Scheme.Convert(d, &o, nil)).To(Succeed())
+
+		return o
+	}
+
+	minAvailable := intstr.FromString("50%")
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "azure", Namespace: "capz-system"},
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				CommonLabels: map[string]string{"team": "platform"},
+				Deployment: &operatorv1.DeploymentSpec{
+					PodDisruptionBudget: &operatorv1.PodDisruptionBudgetSpec{MinAvailable: &minAvailable},
+				},
+			},
+		},
+	}
+
+	objs, err := customizeObjectsFn(provider)([]unstructured.Unstructured{
+		deployment("capz-controller-manager"),
+		deployment("azureserviceoperator-controller-manager"),
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(objs).To(HaveLen(3))
+
+	// Only the provider manager deployment gets a PodDisruptionBudget.
+	g.Expect(objs[2].GetKind()).To(Equal(pdbKind))
+
+	pdb := &policyv1.PodDisruptionBudget{}
+	g.Expect(scheme.Scheme.Convert(&objs[2], pdb, nil)).To(Succeed())
+	g.Expect(pdb.Name).To(Equal("capz-controller-manager"))
+	g.Expect(pdb.Namespace).To(Equal("capz-system"))
+	g.Expect(pdb.Labels).To(Equal(map[string]string{"cluster.x-k8s.io/provider": "infrastructure-azure", "team": "platform"}))
+	g.Expect(pdb.Spec.MinAvailable).To(Equal(&minAvailable))
+	g.Expect(pdb.Spec.Selector.MatchLabels).To(Equal(map[string]string{"control-plane": "capz-controller-manager"}))
+}
+
+func TestCustomizeServiceAccountAnnotations(t *testing.T) {
+	g := NewWithT(t)
+
+	serviceAccount := unstructured.Unstructured{}
+	serviceAccount.SetAPIVersion("v1")
+	serviceAccount.SetKind(serviceAccountKind)
+	serviceAccount.SetName("capa-controller-manager")
+	serviceAccount.SetNamespace("capa-system")
+	serviceAccount.SetAnnotations(map[string]string{"eks.amazonaws.com/role-arn": "${AWS_CONTROLLER_IAM_ROLE:=\"\"}", "owner": "capa"})
+
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "capa-system"},
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				Deployment: &operatorv1.DeploymentSpec{
+					ServiceAccountAnnotations: map[string]string{"eks.amazonaws.com/role-arn": "arn:aws:iam::123456789012:role/capa"},
+				},
+			},
+		},
+	}
+
+	objs, err := customizeObjectsFn(provider)([]unstructured.Unstructured{serviceAccount})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(objs).To(HaveLen(1))
+	g.Expect(objs[0].GetAnnotations()).To(Equal(map[string]string{
+		"eks.amazonaws.com/role-arn": "arn:aws:iam::123456789012:role/capa",
+		"owner":                      "capa",
+	}))
+}
+
+func TestCustomizeCommonMetadata(t *testing.T) {
+	g := NewWithT(t)
+
+	serviceAccount := unstructured.Unstructured{}
+	serviceAccount.SetAPIVersion("v1")
+	serviceAccount.SetKind(serviceAccountKind)
+	serviceAccount.SetName("capa-controller-manager")
+	serviceAccount.SetNamespace("capa-system")
+	serviceAccount.SetLabels(map[string]string{"cluster.x-k8s.io/provider": "infrastructure-aws"})
+
+	role := unstructured.Unstructured{}
+	role.SetAPIVersion("rbac.authorization.k8s.io/v1")
+	role.SetKind("ClusterRole")
+	role.SetName("capa-manager-role")
+
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "capa-system"},
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				CommonLabels:      map[string]string{"team": "platform", "cluster.x-k8s.io/provider": "aws"},
+				CommonAnnotations: map[string]string{"backup.example.com/exclude": "true"},
+				Deployment: &operatorv1.DeploymentSpec{
+					ServiceAccountAnnotations: map[string]string{"backup.example.com/exclude": "false"},
+				},
+			},
+		},
+	}
+
+	objs, err := customizeObjectsFn(provider)([]unstructured.Unstructured{serviceAccount, role})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(objs).To(HaveLen(2))
+	g.Expect(objs[0].GetLabels()).To(Equal(map[string]string{"cluster.x-k8s.io/provider": "infrastructure-aws", "team": "platform"}))
+	g.Expect(objs[0].GetAnnotations()).To(Equal(map[string]string{"backup.example.com/exclude": "false"}))
+	g.Expect(objs[1].GetLabels()).To(Equal(map[string]string{"cluster.x-k8s.io/provider": "aws", "team": "platform"}))
+	g.Expect(objs[1].GetAnnotations()).To(Equal(map[string]string{"backup.example.com/exclude": "true"}))
+}
+
+func TestCustomizeObjectsTargetNamespace(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := func(name string) unstructured.Unstructured {
+		o := unstructured.Unstructured{}
+		o.SetAPIVersion("v1")
+		o.SetKind(namespaceKind)
+		o.SetName(name)
+
+		return o
+	}
+
+	serviceAccount := unstructured.Unstructured{}
+	serviceAccount.SetAPIVersion("v1")
+	serviceAccount.SetKind(serviceAccountKind)
+	serviceAccount.SetName("capa-controller-manager")
+
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "operators", UID: "uid"},
+	}
+	provider.SetGroupVersionKind(operatorv1.GroupVersion.WithKind("InfrastructureProvider"))
+
+	t.Run("provider namespace", func(t *testing.T) {
+		g := NewWithT(t)
+
+		sa := serviceAccount.DeepCopy()
+		sa.SetNamespace("operators")
+
+		objs, err := customizeObjectsFn(provider)([]unstructured.Unstructured{namespace("operators"), *sa})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(objs).To(HaveLen(1))
+		g.Expect(objs[0].GetOwnerReferences()).To(HaveLen(1))
+		g.Expect(objs[0].GetAnnotations()).ToNot(HaveKey(operatorv1.ProviderNamespaceAnnotation))
+	})
+
+	t.Run("other target namespace", func(t *testing.T) {
+		g := NewWithT(t)
+
+		sa := serviceAccount.DeepCopy()
+		sa.SetNamespace("capa-system")
+
+		objs, err := customizeObjectsFn(provider)([]unstructured.Unstructured{namespace("capa-system"), *sa})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(objs).To(HaveLen(2))
+		g.Expect(objs[0].GetName()).To(Equal("capa-system"))
+		g.Expect(objs[1].GetOwnerReferences()).To(BeEmpty())
+		g.Expect(objs[1].GetAnnotations()).To(HaveKeyWithValue(operatorv1.ProviderNamespaceAnnotation, "operators"))
+	})
+
+	g.Expect(targetNamespace(provider)).To(Equal("operators"))
+
+	provider.Spec.TargetNamespace = "capa-system"
+	g.Expect(targetNamespace(provider)).To(Equal("capa-system"))
+}
@@ -40,3 +40,17 @@ func applyPatches(ctx context.Context, provider operatorv1.GenericProvider) func
 		return patch.ApplyPatches(objs, provider.GetSpec().ManifestPatches)
 	}
 }
+
+func applyTargetedPatches(ctx context.Context, provider operatorv1.GenericProvider) func(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	return func(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+		if len(provider.GetSpec().Patches) == 0 {
+			return objs, nil
+		}
+
+		log.V(5).Info("Applying targeted resource patches")
+
+		return patch.ApplyTargetedPatches(objs, provider.GetSpec().Patches)
+	}
+}
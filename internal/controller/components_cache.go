@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"time"
+
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+)
+
+const (
+	// DefaultComponentsCacheSize is the number of provider versions whose downloaded manifests are kept in memory.
+	DefaultComponentsCacheSize = 64
+
+	// DefaultComponentsCacheTTL is how long the downloaded manifests of a provider version are kept in memory.
+	DefaultComponentsCacheTTL = time.Hour
+)
+
+// ComponentsCache keeps the provider metadata and components downloaded from remote repositories in memory, so the
+// same provider version isn't downloaded again by later reconciles, e.g. when creating its manifests ConfigMap failed
+// or the ConfigMap was deleted. A nil cache is valid and never holds any manifests.
+type ComponentsCache struct {
+	cache *utilcache.LRUExpireCache
+	ttl   time.Duration
+}
+
+// cachedManifests are the manifests of a provider version, metadata is nil if it was overridden by the provider spec.
+type cachedManifests struct {
+	metadata   []byte
+	components []byte
+}
+
+// NewComponentsCache returns a cache holding the manifests of up to size provider versions for the ttl.
+func NewComponentsCache(size int, ttl time.Duration) *ComponentsCache {
+	return &ComponentsCache{
+		cache: utilcache.NewLRUExpireCache(size),
+		ttl:   ttl,
+	}
+}
+
+// componentsCacheKey returns the cache key of the manifests fetched from the url for the version. The restartedAt
+// annotation of the provider is part of the key, so a requested restart downloads the manifests again. So is a hash
+// of the credentials the manifests were fetched with, so manifests of private repositories are never served to
+// providers that can't fetch them themselves, e.g. of another tenant.
+func componentsCacheKey(url, version, restartedAt string, credentials []string) string {
+	return url + "@" + version + "#" + restartedAt + "#" + credentialsHash(credentials)
+}
+
+// credentialsHash returns a hash of the credentials, regardless of their order, or an empty string if there are none.
+func credentialsHash(credentials []string) string {
+	if len(credentials) == 0 {
+		return ""
+	}
+
+	sorted := append([]string{}, credentials...)
+	sort.Strings(sorted)
+
+	hash := sha256.New()
+	for _, credential := range sorted {
+		// The length prefix keeps the boundaries between credentials, which may contain any character.
+		fmt.Fprintf(hash, "%d:%s", len(credential), credential)
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+func (c *ComponentsCache) get(key string) (cachedManifests, bool) {
+	if c == nil {
+		return cachedManifests{}, false
+	}
+
+	value, ok := c.cache.Get(key)
+	if !ok {
+		return cachedManifests{}, false
+	}
+
+	manifests, ok := value.(cachedManifests)
+
+	return manifests, ok
+}
+
+func (c *ComponentsCache) add(key string, manifests cachedManifests) {
+	if c == nil {
+		return
+	}
+
+	c.cache.Add(key, manifests, c.ttl)
+}
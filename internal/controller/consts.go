@@ -23,6 +23,22 @@ const (
 	// if some preflight check has failed.
 	preflightFailedRequeueAfter = 30 * time.Second
 
+	// hookRunningRequeueAfter is how long to wait before checking a running hook Job again.
+	hookRunningRequeueAfter = 5 * time.Second
+
+	// hookFailedRequeueAfter is how long to wait before checking a failed hook Job again,
+	// the Job can be deleted to run the hook one more time.
+	hookFailedRequeueAfter = 30 * time.Second
+
+	// externalProviderRequeueAfter is how often the clusterctl inventory of an externally managed provider is checked.
+	externalProviderRequeueAfter = time.Minute
+
+	// crdsEstablishedRequeueAfter is how long to wait before checking again if the CRDs of a provider are established.
+	crdsEstablishedRequeueAfter = 5 * time.Second
+
+	// crdInstancesRequeueAfter is how long to wait before checking again for instances of the CRDs of a provider being deleted.
+	crdInstancesRequeueAfter = 30 * time.Second
+
 	// configPath is the path to the clusterctl config file.
 	configPath = "/config/clusterctl.yaml"
 )
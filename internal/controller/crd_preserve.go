@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	customResourceDefinitionKind = "CustomResourceDefinition"
+
+	// placeholderCABundle is the base64 encoded newline used by providers as an empty caBundle value in their manifests.
+	placeholderCABundle = "Cg=="
+)
+
+var (
+	crdGVK = schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: customResourceDefinitionKind}
+
+	conversionCABundlePath = []string{"spec", "conversion", "webhook", "clientConfig", "caBundle"}
+)
+
+// preserveCRDFieldsFn keeps the CRD annotations and conversion webhook CA bundle that were added to the
+// existing CRDs by other controllers, e.g. cert-manager CA injection, so they are not reset during upgrades.
+func preserveCRDFieldsFn(ctx context.Context, cl client.Client) func(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	return func(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+		for i := range objs {
+			if objs[i].GetKind() != customResourceDefinitionKind {
+				continue
+			}
+
+			current := &unstructured.Unstructured{}
+			current.SetGroupVersionKind(crdGVK)
+
+			if err := cl.Get(ctx, client.ObjectKey{Name: objs[i].GetName()}, current); err != nil {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+
+				return nil, fmt.Errorf("failed to get CustomResourceDefinition %s: %w", objs[i].GetName(), err)
+			}
+
+			log.V(5).Info("Preserving fields of existing CRD", "name", objs[i].GetName())
+
+			// Annotations from the provider manifest take precedence, all others are kept.
+			annotations := objs[i].GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+
+			for k, v := range current.GetAnnotations() {
+				if _, ok := annotations[k]; !ok {
+					annotations[k] = v
+				}
+			}
+
+			if len(annotations) > 0 {
+				objs[i].SetAnnotations(annotations)
+			}
+
+			if err := preserveConversionCABundle(&objs[i], current); err != nil {
+				return nil, err
+			}
+		}
+
+		return objs, nil
+	}
+}
+
+// preserveConversionCABundle copies the injected conversion webhook CA bundle from the current CRD
+// if the desired one only contains an empty or placeholder value.
+func preserveConversionCABundle(desired, current *unstructured.Unstructured) error {
+	currentCABundle, _, err := unstructured.NestedString(current.Object, conversionCABundlePath...)
+	if err != nil {
+		return err
+	}
+
+	if currentCABundle == "" || currentCABundle == placeholderCABundle {
+		return nil
+	}
+
+	// Nothing to preserve if the new CRD doesn't use a conversion webhook.
+	if _, found, err := unstructured.NestedMap(desired.Object, conversionCABundlePath[:len(conversionCABundlePath)-1]...); err != nil || !found {
+		return err
+	}
+
+	desiredCABundle, _, err := unstructured.NestedString(desired.Object, conversionCABundlePath...)
+	if err != nil {
+		return err
+	}
+
+	if desiredCABundle != "" && desiredCABundle != placeholderCABundle {
+		return nil
+	}
+
+	return unstructured.SetNestedField(desired.Object, currentCABundle, conversionCABundlePath...)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPreserveCRDFields(t *testing.T) {
+	newCRD := func(name string, annotations map[string]string, caBundle string) unstructured.Unstructured {
+		crd := unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"conversion": map[string]interface{}{
+					"strategy": "Webhook",
+					"webhook": map[string]interface{}{
+						"clientConfig": map[string]interface{}{
+							"caBundle": caBundle,
+						},
+					},
+				},
+			},
+		}}
+		crd.SetGroupVersionKind(crdGVK)
+		crd.SetName(name)
+		crd.SetAnnotations(annotations)
+
+		return crd
+	}
+
+	tests := []struct {
+		name                string
+		existing            []apiextensionsv1.CustomResourceDefinition
+		desired             unstructured.Unstructured
+		expectedAnnotations map[string]string
+		expectedCABundle    string
+	}{
+		{
+			name:                "new CRD is not changed",
+			desired:             newCRD("clusters.cluster.x-k8s.io", map[string]string{"a": "b"}, placeholderCABundle),
+			expectedAnnotations: map[string]string{"a": "b"},
+			expectedCABundle:    placeholderCABundle,
+		},
+		{
+			name: "existing annotations and injected CA bundle are preserved",
+			existing: []apiextensionsv1.CustomResourceDefinition{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "clusters.cluster.x-k8s.io",
+						Annotations: map[string]string{
+							"cert-manager.io/inject-ca-from":        "capi-system/capi-serving-cert",
+							"controller-gen.kubebuilder.io/version": "v0.12.0",
+						},
+					},
+					Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+						Conversion: &apiextensionsv1.CustomResourceConversion{
+							Strategy: apiextensionsv1.WebhookConverter,
+							Webhook: &apiextensionsv1.WebhookConversion{
+								ClientConfig: &apiextensionsv1.WebhookClientConfig{CABundle: []byte("injected")},
+							},
+						},
+					},
+				},
+			},
+			desired: newCRD("clusters.cluster.x-k8s.io", map[string]string{"controller-gen.kubebuilder.io/version": "v0.13.0"}, placeholderCABundle),
+			expectedAnnotations: map[string]string{
+				"cert-manager.io/inject-ca-from":        "capi-system/capi-serving-cert",
+				"controller-gen.kubebuilder.io/version": "v0.13.0",
+			},
+			expectedCABundle: "aW5qZWN0ZWQ=",
+		},
+		{
+			name: "CA bundle from the manifest takes precedence",
+			existing: []apiextensionsv1.CustomResourceDefinition{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "clusters.cluster.x-k8s.io"},
+					Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+						Conversion: &apiextensionsv1.CustomResourceConversion{
+							Strategy: apiextensionsv1.WebhookConverter,
+							Webhook: &apiextensionsv1.WebhookConversion{
+								ClientConfig: &apiextensionsv1.WebhookClientConfig{CABundle: []byte("injected")},
+							},
+						},
+					},
+				},
+			},
+			desired:          newCRD("clusters.cluster.x-k8s.io", nil, "bmV3"),
+			expectedCABundle: "bmV3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			scheme := runtime.NewScheme()
+			utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+
+			builder := fake.NewClientBuilder().WithScheme(scheme)
+			for i := range tt.existing {
+				builder = builder.WithObjects(&tt.existing[i])
+			}
+
+			objs, err := preserveCRDFieldsFn(context.TODO(), builder.Build())([]unstructured.Unstructured{tt.desired})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(objs).To(HaveLen(1))
+
+			g.Expect(objs[0].GetAnnotations()).To(Equal(tt.expectedAnnotations))
+
+			caBundle, _, err := unstructured.NestedString(objs[0].Object, conversionCABundlePath...)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(caBundle).To(Equal(tt.expectedCABundle))
+		})
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// isExternallyManaged returns true if the provider components are managed by other tooling.
+func isExternallyManaged(provider operatorv1.GenericProvider) bool {
+	return provider.GetSpec().ManagementMode == operatorv1.ManagementModeExternal
+}
+
+// trackExternalProvider reports the installed version of an externally managed provider from the clusterctl inventory.
+// The inventory is checked periodically, as it is updated by the tooling that manages the provider.
+func (p *phaseReconciler) trackExternalProvider(ctx context.Context) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	key := clusterctlProviderName(p.provider)
+
+	inventoryProvider := &clusterctlv1.Provider{}
+	if err := p.ctrlClient.Get(ctx, key, inventoryProvider); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return reconcile.Result{}, fmt.Errorf("failed to get clusterctl inventory provider %s: %w", key, err)
+		}
+
+		log.Info("Externally managed provider not found in the clusterctl inventory", "inventoryProvider", key)
+		conditions.Set(p.provider, conditions.FalseCondition(operatorv1.ProviderInstalledCondition, operatorv1.ExternalProviderNotFoundReason,
+			clusterv1.ConditionSeverityWarning, "Provider %s not found in the clusterctl inventory", key))
+
+		return reconcile.Result{RequeueAfter: externalProviderRequeueAfter}, nil
+	}
+
+	status := p.provider.GetStatus()
+	status.InstalledVersion = &inventoryProvider.Version
+	p.provider.SetStatus(status)
+
+	conditions.MarkTrue(p.provider, operatorv1.ProviderInstalledCondition)
+
+	return reconcile.Result{RequeueAfter: externalProviderRequeueAfter}, nil
+}
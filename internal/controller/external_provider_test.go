@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestTrackExternalProvider(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "docker",
+			Namespace: "capd-system",
+		},
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				ManagementMode: operatorv1.ManagementModeExternal,
+			},
+		},
+	}
+
+	fakeclient := fake.NewClientBuilder().WithScheme(setupScheme()).Build()
+
+	p := &phaseReconciler{
+		ctrlClient: fakeclient,
+		provider:   provider,
+	}
+
+	// The provider is not in the inventory yet.
+	res, err := p.trackExternalProvider(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.RequeueAfter).To(Equal(externalProviderRequeueAfter))
+	g.Expect(conditions.GetReason(provider, operatorv1.ProviderInstalledCondition)).To(Equal(operatorv1.ExternalProviderNotFoundReason))
+	g.Expect(provider.Status.InstalledVersion).To(BeNil())
+
+	g.Expect(fakeclient.Create(ctx, &clusterctlv1.Provider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "infrastructure-docker",
+			Namespace: "capd-system",
+		},
+		ProviderName: "docker",
+		Type:         string(clusterctlv1.InfrastructureProviderType),
+		Version:      "v1.5.0",
+	})).To(Succeed())
+
+	res, err = p.trackExternalProvider(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.RequeueAfter).To(Equal(externalProviderRequeueAfter))
+	g.Expect(conditions.IsTrue(provider, operatorv1.ProviderInstalledCondition)).To(BeTrue())
+	g.Expect(provider.Status.InstalledVersion).To(HaveValue(Equal("v1.5.0")))
+}
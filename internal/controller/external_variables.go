@@ -0,0 +1,304 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const (
+	// appliedVariablesHashAnnotation is the hash of the external variables the provider components were installed with.
+	appliedVariablesHashAnnotation = "operator.cluster.x-k8s.io/applied-variables-hash"
+
+	defaultVaultAuthMountPath      = "kubernetes"
+	defaultVaultServiceAccountName = "default"
+
+	// vaultTokenExpirationSeconds is the expiration of the ServiceAccount tokens used to log in to Vault,
+	// the minimum allowed by the TokenRequest API.
+	vaultTokenExpirationSeconds = 600
+)
+
+// variablesSource is an external store providing configuration variables.
+type variablesSource interface {
+	Variables(ctx context.Context) (map[string]string, error)
+}
+
+// newVariablesSource returns the external store of the configuration variables of the provider, or nil if it's not set.
+func newVariablesSource(ctx context.Context, c client.Client, provider operatorv1.GenericProvider) (variablesSource, error) {
+	external := provider.GetSpec().ExternalVariables
+	if external == nil || external.Vault == nil {
+		return nil, nil
+	}
+
+	var (
+		caBundle []byte
+		err      error
+	)
+
+	if external.Vault.CABundleRef != nil {
+		caBundle, err = readCABundle(ctx, c, provider.GetNamespace(), external.Vault.CABundleRef)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	httpClient, err := newFetchHTTPClient(caBundle, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vaultSource{
+		ctrlClient: c,
+		httpClient: httpClient,
+		source:     external.Vault,
+		namespace:  provider.GetNamespace(),
+	}, nil
+}
+
+// externalVariables returns the configuration variables of the external store of the provider, or nil if it's not set.
+func externalVariables(ctx context.Context, c client.Client, provider operatorv1.GenericProvider) (map[string]string, error) {
+	source, err := newVariablesSource(ctx, c, provider)
+	if err != nil || source == nil {
+		return nil, err
+	}
+
+	variables, err := source.Variables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read external variables of provider %q: %w", provider.GetName(), err)
+	}
+
+	return variables, nil
+}
+
+// externalVariablesRefreshInterval returns how often the external variables of the provider are read again,
+// zero if they aren't refreshed.
+func externalVariablesRefreshInterval(provider operatorv1.GenericProvider) time.Duration {
+	external := provider.GetSpec().ExternalVariables
+	if external == nil || external.Vault == nil || external.RefreshInterval == nil {
+		return 0
+	}
+
+	return external.RefreshInterval.Duration
+}
+
+// externalVariablesChanged returns true if the refreshed external variables of the provider differ from the ones
+// its components were installed with.
+func externalVariablesChanged(ctx context.Context, c client.Client, provider operatorv1.GenericProvider) (bool, error) {
+	if externalVariablesRefreshInterval(provider) == 0 {
+		return false, nil
+	}
+
+	variables, err := externalVariables(ctx, c, provider)
+	if err != nil {
+		return false, err
+	}
+
+	hash, err := calculateHash(variables)
+	if err != nil {
+		return false, err
+	}
+
+	return provider.GetAnnotations()[appliedVariablesHashAnnotation] != hash, nil
+}
+
+// setAppliedVariablesHash stores the hash of the external variables the provider components are installed with,
+// so their changes can be detected without storing the values.
+func setAppliedVariablesHash(provider operatorv1.GenericProvider, variables map[string]string) error {
+	annotations := provider.GetAnnotations()
+
+	if variables == nil {
+		if _, ok := annotations[appliedVariablesHashAnnotation]; ok {
+			delete(annotations, appliedVariablesHashAnnotation)
+			provider.SetAnnotations(annotations)
+		}
+
+		return nil
+	}
+
+	hash, err := calculateHash(variables)
+	if err != nil {
+		return err
+	}
+
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[appliedVariablesHashAnnotation] = hash
+	provider.SetAnnotations(annotations)
+
+	return nil
+}
+
+// vaultSource reads the configuration variables from a KV version 2 secret of HashiCorp Vault. It logs in with
+// the Kubernetes auth method, using a short-lived token of a ServiceAccount of the provider namespace.
+type vaultSource struct {
+	ctrlClient client.Client
+	httpClient *http.Client
+	source     *operatorv1.VaultSource
+	namespace  string
+}
+
+// vaultResponse is the part of the Vault API responses used to log in and read the secrets.
+type vaultResponse struct {
+	Auth *struct {
+		ClientToken string `json:"client_token"`
+	} `json:"auth"`
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []string                   `json:"errors"`
+}
+
+func (v *vaultSource) Variables(ctx context.Context) (map[string]string, error) {
+	jwt, err := v.serviceAccountToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := v.login(ctx, jwt)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.readSecret(ctx, token)
+}
+
+// serviceAccountToken requests a short-lived token of the ServiceAccount.
+func (v *vaultSource) serviceAccountToken(ctx context.Context) (string, error) {
+	name := v.source.ServiceAccountName
+	if name == "" {
+		name = defaultVaultServiceAccountName
+	}
+
+	serviceAccount := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: v.namespace}}
+
+	tokenRequest := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			ExpirationSeconds: pointer.Int64(vaultTokenExpirationSeconds),
+		},
+	}
+
+	if v.source.Audience != "" {
+		tokenRequest.Spec.Audiences = []string{v.source.Audience}
+	}
+
+	if err := v.ctrlClient.SubResource("token").Create(ctx, serviceAccount, tokenRequest); err != nil {
+		return "", fmt.Errorf("failed to request a token of ServiceAccount %s/%s: %w", v.namespace, name, err)
+	}
+
+	return tokenRequest.Status.Token, nil
+}
+
+// login logs in to Vault with the Kubernetes auth method and returns the Vault token.
+func (v *vaultSource) login(ctx context.Context, jwt string) (string, error) {
+	mountPath := v.source.AuthMountPath
+	if mountPath == "" {
+		mountPath = defaultVaultAuthMountPath
+	}
+
+	body, err := json.Marshal(map[string]string{"role": v.source.Role, "jwt": jwt})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := v.do(ctx, http.MethodPost, "auth/"+strings.Trim(mountPath, "/")+"/login", "", body)
+	if err != nil {
+		return "", fmt.Errorf("failed to log in to Vault with role %q: %w", v.source.Role, err)
+	}
+
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return "", fmt.Errorf("failed to log in to Vault with role %q: no token returned", v.source.Role)
+	}
+
+	return resp.Auth.ClientToken, nil
+}
+
+// readSecret returns the keys of the KV version 2 secret. Values that aren't strings are returned as JSON.
+func (v *vaultSource) readSecret(ctx context.Context, token string) (map[string]string, error) {
+	resp, err := v.do(ctx, http.MethodGet, strings.Trim(v.source.Path, "/"), token, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Vault secret %q: %w", v.source.Path, err)
+	}
+
+	data := map[string]json.RawMessage{}
+	if raw, ok := resp.Data["data"]; !ok || json.Unmarshal(raw, &data) != nil {
+		return nil, fmt.Errorf("Vault secret %q is not a KV version 2 secret", v.source.Path)
+	}
+
+	variables := make(map[string]string, len(data))
+
+	for k, raw := range data {
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			value = string(raw)
+		}
+
+		variables[k] = value
+	}
+
+	return variables, nil
+}
+
+// do sends a request to the Vault API and decodes the response.
+func (v *vaultSource) do(ctx context.Context, method, path, token string, body []byte) (*vaultResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(v.source.Address, "/")+"/v1/"+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	httpResp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &vaultResponse{}
+	if len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, resp); err != nil && httpResp.StatusCode == http.StatusOK {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d: %s", httpResp.StatusCode, strings.Join(resp.Errors, ", "))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const (
+	testVaultJWT   = "service-account-token"
+	testVaultToken = "vault-token"
+)
+
+// newFakeVaultServer returns a Vault server with the Kubernetes auth method mounted at the given path, accepting
+// the test ServiceAccount token for the given role, and serving the given secrets.
+func newFakeVaultServer(g *WithT, mountPath, role string, secrets map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/v1/auth/"+mountPath+"/login" {
+			login := map[string]string{}
+			g.Expect(json.NewDecoder(r.Body).Decode(&login)).To(Succeed())
+
+			if login["role"] != role || login["jwt"] != testVaultJWT {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, `{"errors": ["permission denied"]}`)
+
+				return
+			}
+
+			fmt.Fprintf(w, `{"auth": {"client_token": %q}}`, testVaultToken)
+
+			return
+		}
+
+		if r.Header.Get("X-Vault-Token") != testVaultToken {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors": ["permission denied"]}`)
+
+			return
+		}
+
+		secret, ok := secrets[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors": []}`)
+
+			return
+		}
+
+		fmt.Fprint(w, secret)
+	}))
+}
+
+func TestVaultSource(t *testing.T) {
+	secrets := map[string]string{
+		"/v1/secret/data/capa":   `{"data": {"data": {"AWS_REGION": "eu-west-1", "EXP_MACHINE_POOL": true}, "metadata": {"version": 2}}}`,
+		"/v1/kv1/capa":           `{"data": {"AWS_REGION": "eu-west-1"}}`,
+		"/v1/secret/data/broken": `{"data": "broken"}`,
+	}
+
+	testCases := []struct {
+		name          string
+		source        operatorv1.VaultSource
+		wantVariables map[string]string
+		wantErr       bool
+	}{
+		{
+			name:          "should read a KV version 2 secret",
+			source:        operatorv1.VaultSource{Path: "secret/data/capa", Role: "capi-operator"},
+			wantVariables: map[string]string{"AWS_REGION": "eu-west-1", "EXP_MACHINE_POOL": "true"},
+		},
+		{
+			name:          "should log in with a custom auth mount path",
+			source:        operatorv1.VaultSource{Path: "/secret/data/capa", Role: "capi-operator", AuthMountPath: "/kubernetes-mgmt/"},
+			wantVariables: map[string]string{"AWS_REGION": "eu-west-1", "EXP_MACHINE_POOL": "true"},
+		},
+		{
+			name:    "should fail when the role is denied",
+			source:  operatorv1.VaultSource{Path: "secret/data/capa", Role: "other"},
+			wantErr: true,
+		},
+		{
+			name:    "should fail when the secret doesn't exist",
+			source:  operatorv1.VaultSource{Path: "secret/data/capz", Role: "capi-operator"},
+			wantErr: true,
+		},
+		{
+			name:    "should fail on a KV version 1 secret",
+			source:  operatorv1.VaultSource{Path: "kv1/capa", Role: "capi-operator"},
+			wantErr: true,
+		},
+		{
+			name:    "should fail on an unexpected response",
+			source:  operatorv1.VaultSource{Path: "secret/data/broken", Role: "capi-operator"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mountPath := "kubernetes"
+			if tc.source.AuthMountPath != "" {
+				mountPath = "kubernetes-mgmt"
+			}
+
+			server := newFakeVaultServer(g, mountPath, "capi-operator", secrets)
+			defer server.Close()
+
+			tc.source.Address = server.URL + "/"
+			v := &vaultSource{httpClient: server.Client(), source: &tc.source}
+
+			token, err := v.login(context.Background(), testVaultJWT)
+			if err == nil {
+				var variables map[string]string
+
+				variables, err = v.readSecret(context.Background(), token)
+				g.Expect(variables).To(Equal(tc.wantVariables))
+			}
+
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
+
+func TestSetAppliedVariablesHash(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "cluster-api",
+			Annotations: map[string]string{"foo": "bar"},
+		},
+		Spec: operatorv1.CoreProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				ExternalVariables: &operatorv1.ExternalVariablesSource{
+					Vault:           &operatorv1.VaultSource{Address: "https://vault.example.com", Path: "secret/data/capi", Role: "capi-operator"},
+					RefreshInterval: &metav1.Duration{Duration: 10 * time.Minute},
+				},
+			},
+		},
+	}
+
+	g.Expect(externalVariablesRefreshInterval(provider)).To(Equal(10 * time.Minute))
+
+	g.Expect(setAppliedVariablesHash(provider, map[string]string{"KEY": "value"})).To(Succeed())
+	hash := provider.GetAnnotations()[appliedVariablesHashAnnotation]
+	g.Expect(hash).NotTo(BeEmpty())
+
+	g.Expect(setAppliedVariablesHash(provider, map[string]string{"KEY": "changed"})).To(Succeed())
+	g.Expect(provider.GetAnnotations()[appliedVariablesHashAnnotation]).NotTo(Equal(hash))
+
+	g.Expect(setAppliedVariablesHash(provider, nil)).To(Succeed())
+	g.Expect(provider.GetAnnotations()).To(Equal(map[string]string{"foo": "bar"}))
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cliflag "k8s.io/component-base/cli/flag"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const (
+	caBundleSecretKind = "Secret"
+	caBundleDefaultKey = "ca.crt"
+)
+
+var (
+	// fetchTransport is the HTTP transport of the connections fetching provider manifests and resolving image digests,
+	// with the TLS options set with SetFetchTLSOptions. It's separate from the default transport, so the TLS options
+	// don't apply to the other connections of the process.
+	fetchTransport = newFetchTransport()
+
+	// fetchClient is the HTTP client used when neither a CA bundle nor a proxy is configured.
+	fetchClient = &http.Client{Transport: fetchTransport}
+)
+
+// newFetchTransport returns a copy of the default HTTP transport with a minimum TLS version of 1.2.
+func newFetchTransport() *http.Transport {
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		transport = &http.Transport{Proxy: http.ProxyFromEnvironment, ForceAttemptHTTP2: true}
+	}
+
+	transport = transport.Clone()
+	transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+
+	return transport
+}
+
+// fetchCABundle returns the PEM encoded CA bundle referenced by the fetch config, or nil if it's not set.
+func (p *phaseReconciler) fetchCABundle(ctx context.Context) ([]byte, error) {
+	fetchConfig := p.provider.GetSpec().FetchConfig
+	if fetchConfig == nil || fetchConfig.CABundleRef == nil {
+		return nil, nil
+	}
+
+	return readCABundle(ctx, p.ctrlClient, p.provider.GetNamespace(), fetchConfig.CABundleRef)
+}
+
+// readCABundle returns the PEM encoded CA bundle of the reference, defaulting its namespace to the given one.
+func readCABundle(ctx context.Context, c client.Client, namespace string, ref *operatorv1.CABundleReference) ([]byte, error) {
+	key := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+	if key.Namespace == "" {
+		key.Namespace = namespace
+	}
+
+	dataKey := ref.Key
+	if dataKey == "" {
+		dataKey = caBundleDefaultKey
+	}
+
+	var (
+		caBundle []byte
+		ok       bool
+	)
+
+	if ref.Kind == caBundleSecretKind {
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, key, secret); err != nil {
+			return nil, fmt.Errorf("failed to get CA bundle Secret %s: %w", key, err)
+		}
+
+		caBundle, ok = secret.Data[dataKey]
+	} else {
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, key, cm); err != nil {
+			return nil, fmt.Errorf("failed to get CA bundle ConfigMap %s: %w", key, err)
+		}
+
+		var data string
+
+		data, ok = cm.Data[dataKey]
+		caBundle = []byte(data)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("CA bundle %s has no %q key", key, dataKey)
+	}
+
+	return caBundle, nil
+}
+
+// fetchHTTPClient returns the HTTP client used to fetch the provider manifests.
+func (p *phaseReconciler) fetchHTTPClient(ctx context.Context) (*http.Client, error) {
+	caBundle, err := p.fetchCABundle(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var proxy *operatorv1.ProxyConfiguration
+	if fetchConfig := p.provider.GetSpec().FetchConfig; fetchConfig != nil {
+		proxy = fetchConfig.Proxy
+	}
+
+	return newFetchHTTPClient(caBundle, proxy)
+}
+
+// newFetchHTTPClient returns an HTTP client trusting the CA bundle in addition to the system CAs and
+// using the proxy configuration, or the shared fetch client if neither is set.
+func newFetchHTTPClient(caBundle []byte, proxy *operatorv1.ProxyConfiguration) (*http.Client, error) {
+	if caBundle == nil && proxy == nil {
+		return fetchClient, nil
+	}
+
+	transport := fetchTransport.Clone()
+
+	if caBundle != nil {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no PEM encoded certificates found in CA bundle")
+		}
+
+		// The cloned TLS config keeps the options set with SetFetchTLSOptions.
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	if proxy != nil {
+		proxyFunc := fetchProxyFunc(proxy)
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFunc(req.URL)
+		}
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// fetchProxyFunc returns the function selecting the proxy of a request URL from the proxy configuration.
+// A nil proxy URL means the request is not proxied.
+func fetchProxyFunc(proxy *operatorv1.ProxyConfiguration) func(*url.URL) (*url.URL, error) {
+	config := &httpproxy.Config{
+		HTTPProxy:  proxy.HTTPProxy,
+		HTTPSProxy: proxy.HTTPSProxy,
+		NoProxy:    proxy.NoProxy,
+	}
+
+	return config.ProxyFunc()
+}
+
+// SetFetchTLSOptions sets the minimum TLS version and the cipher suites, by their Go names, of the fetch transport.
+// The HTTP clients fetching provider manifests and resolving image digests are built on it, including the clients of
+// Git, so it must be called before they are used. The default cipher suites of Go are kept if none are set.
+func SetFetchTLSOptions(minVersion string, cipherSuites []string) error {
+	version, err := cliflag.TLSVersion(minVersion)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig := &tls.Config{MinVersion: version}
+
+	if len(cipherSuites) > 0 {
+		if tlsConfig.CipherSuites, err = cliflag.TLSCipherSuites(cipherSuites); err != nil {
+			return err
+		}
+	}
+
+	fetchTransport.TLSClientConfig = tlsConfig
+
+	return nil
+}
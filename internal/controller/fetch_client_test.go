@@ -0,0 +1,228 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestFetchCABundle(t *testing.T) {
+	objects := []client.Object{
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "internal-ca", Namespace: "capv-system"},
+			Data:       map[string]string{"ca.crt": "configmap bundle"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "internal-ca", Namespace: "certs"},
+			Data:       map[string][]byte{"bundle.pem": []byte("secret bundle")},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		caBundleRef  *operatorv1.CABundleReference
+		wantCABundle []byte
+		wantErr      bool
+	}{
+		{
+			name: "no CA bundle",
+		},
+		{
+			name:         "configmap in the provider namespace",
+			caBundleRef:  &operatorv1.CABundleReference{Name: "internal-ca"},
+			wantCABundle: []byte("configmap bundle"),
+		},
+		{
+			name:         "secret with a custom key in another namespace",
+			caBundleRef:  &operatorv1.CABundleReference{Kind: "Secret", Name: "internal-ca", Namespace: "certs", Key: "bundle.pem"},
+			wantCABundle: []byte("secret bundle"),
+		},
+		{
+			name:        "missing key",
+			caBundleRef: &operatorv1.CABundleReference{Name: "internal-ca", Key: "bundle.pem"},
+			wantErr:     true,
+		},
+		{
+			name:        "missing configmap",
+			caBundleRef: &operatorv1.CABundleReference{Name: "other-ca"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			p := &phaseReconciler{
+				ctrlClient: fake.NewClientBuilder().WithObjects(objects...).Build(),
+				provider: &operatorv1.InfrastructureProvider{
+					ObjectMeta: metav1.ObjectMeta{Name: "vsphere", Namespace: "capv-system"},
+					Spec: operatorv1.InfrastructureProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							FetchConfig: &operatorv1.FetchConfiguration{
+								URL:         "https://gitlab.example.com/infra/provider/-/releases/latest/infrastructure-components.yaml",
+								CABundleRef: tt.caBundleRef,
+							},
+						},
+					},
+				},
+			}
+
+			caBundle, err := p.fetchCABundle(context.Background())
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(caBundle).To(Equal(tt.wantCABundle))
+		})
+	}
+}
+
+func TestNewFetchHTTPClient(t *testing.T) {
+	g := NewWithT(t)
+
+	httpClient, err := newFetchHTTPClient(nil, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(httpClient).To(BeIdenticalTo(fetchClient))
+
+	_, err = newFetchHTTPClient([]byte("not a certificate"), nil)
+	g.Expect(err).To(MatchError(ContainSubstring("no PEM encoded certificates")))
+}
+
+func TestFetchHTTPClientProxy(t *testing.T) {
+	proxy := &operatorv1.ProxyConfiguration{
+		HTTPSProxy: "http://proxy.example.com:3128",
+		NoProxy:    "internal.example.com",
+	}
+
+	tests := []struct {
+		name      string
+		url       string
+		wantProxy string
+	}{
+		{
+			name:      "https request",
+			url:       "https://github.com/kubernetes-sigs/cluster-api/releases",
+			wantProxy: "http://proxy.example.com:3128",
+		},
+		{
+			name: "http request without http proxy",
+			url:  "http://mirror.example.com/providers/index.yaml",
+		},
+		{
+			name: "excluded host",
+			url:  "https://internal.example.com/providers/index.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			httpClient, err := newFetchHTTPClient(nil, proxy)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			transport, ok := httpClient.Transport.(*http.Transport)
+			g.Expect(ok).To(BeTrue())
+
+			req, err := http.NewRequest(http.MethodGet, tt.url, http.NoBody)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			proxyURL, err := transport.Proxy(req)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			if tt.wantProxy == "" {
+				g.Expect(proxyURL).To(BeNil())
+
+				return
+			}
+
+			g.Expect(proxyURL.String()).To(Equal(tt.wantProxy))
+
+			options, err := gitProxyOptions(tt.url, proxy)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(options.URL).To(Equal(tt.wantProxy))
+		})
+	}
+}
+
+func TestSetFetchTLSOptions(t *testing.T) {
+	g := NewWithT(t)
+
+	defaultTransport, ok := http.DefaultTransport.(*http.Transport)
+	g.Expect(ok).To(BeTrue())
+
+	defaultTLSConfig := defaultTransport.TLSClientConfig
+
+	fetchTLSConfig := fetchTransport.TLSClientConfig
+	defer func() { fetchTransport.TLSClientConfig = fetchTLSConfig }()
+
+	g.Expect(SetFetchTLSOptions("VersionTLS14", nil)).ToNot(Succeed())
+	g.Expect(SetFetchTLSOptions("VersionTLS12", []string{"TLS_RSA_WITH_RC4_256_SHA"})).ToNot(Succeed())
+
+	g.Expect(SetFetchTLSOptions("VersionTLS12", []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"})).To(Succeed())
+	g.Expect(fetchTransport.TLSClientConfig.MinVersion).To(BeEquivalentTo(tls.VersionTLS12))
+	g.Expect(fetchTransport.TLSClientConfig.CipherSuites).To(Equal([]uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}))
+
+	// The default transport of the process is left untouched.
+	g.Expect(defaultTransport.TLSClientConfig).To(BeIdenticalTo(defaultTLSConfig))
+
+	g.Expect(SetFetchTLSOptions("VersionTLS13", nil)).To(Succeed())
+
+	// A server that only supports TLS 1.2 is trusted with a CA bundle, the TLS options of the fetch transport are kept.
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+
+	defer server.Close()
+
+	httpClient, err := newFetchHTTPClient(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, http.NoBody)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	_, err = httpClient.Do(req)
+	g.Expect(err).To(MatchError(ContainSubstring("protocol version")))
+
+	g.Expect(SetFetchTLSOptions("VersionTLS12", nil)).To(Succeed())
+
+	httpClient, err = newFetchHTTPClient(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	resp, err := httpClient.Do(req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(resp.Body.Close()).To(Succeed())
+}
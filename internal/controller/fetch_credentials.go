@@ -0,0 +1,158 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	"golang.org/x/oauth2"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const (
+	// fetchCredentialsExpirationWarning is how long before their expiration the fetch credentials are reported as expiring.
+	fetchCredentialsExpirationWarning = 7 * 24 * time.Hour
+
+	githubHost = "github.com"
+)
+
+// newGitHubClient returns a GitHub client authenticated with the token.
+var newGitHubClient = func(ctx context.Context, token string) *github.Client {
+	return github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)))
+}
+
+// validateGitHubToken checks that the GitHub token is valid, not about to expire and grants access to the provider
+// repository. The result is reported in the FetchCredentialsValid condition and the invalid fetch credentials metric.
+// Only authentication and authorization failures mark the token invalid, other errors, e.g. timeouts or server
+// errors, are transient and leave the condition unknown.
+func validateGitHubToken(ctx context.Context, provider operatorv1.GenericProvider, token string) error {
+	client := newGitHubClient(ctx, token)
+
+	_, resp, err := client.Organizations.List(ctx, "kubernetes-sigs", nil)
+	if err != nil {
+		switch githubErrorStatus(err) {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			setInvalidFetchCredentials(provider, "GitHub token is invalid or expired: %v", err)
+		default:
+			setFetchCredentialsCheckFailed(provider, "Failed to validate GitHub token: %v", err)
+		}
+
+		return err
+	}
+
+	if owner, repo, ok := githubRepository(provider); ok {
+		if _, _, err := client.Repositories.Get(ctx, owner, repo); err != nil {
+			switch githubErrorStatus(err) {
+			case http.StatusUnauthorized:
+				setInvalidFetchCredentials(provider, "GitHub token is invalid or expired: %v", err)
+			case http.StatusNotFound, http.StatusForbidden:
+				setInvalidFetchCredentials(provider, "GitHub token lacks the scopes to access repository %s/%s: %v", owner, repo, err)
+			default:
+				setFetchCredentialsCheckFailed(provider, "Failed to access repository %s/%s with GitHub token: %v", owner, repo, err)
+			}
+
+			return err
+		}
+	}
+
+	invalidFetchCredentials.WithLabelValues(providerMetricLabels(provider)...).Set(0)
+
+	if expiration := resp.TokenExpiration; !expiration.IsZero() && time.Until(expiration.Time) < fetchCredentialsExpirationWarning {
+		conditions.Set(provider, conditions.FalseCondition(
+			operatorv1.FetchCredentialsValidCondition,
+			operatorv1.FetchCredentialsExpiringReason,
+			clusterv1.ConditionSeverityWarning,
+			"GitHub token expires at %s", expiration.Time.UTC().Format(time.RFC3339),
+		))
+
+		return nil
+	}
+
+	conditions.MarkTrue(provider, operatorv1.FetchCredentialsValidCondition)
+
+	return nil
+}
+
+// setInvalidFetchCredentials reports invalid fetch credentials in the provider conditions and metrics.
+func setInvalidFetchCredentials(provider operatorv1.GenericProvider, messageFormat string, messageArgs ...interface{}) {
+	conditions.Set(provider, conditions.FalseCondition(
+		operatorv1.FetchCredentialsValidCondition,
+		operatorv1.InvalidFetchCredentialsReason,
+		clusterv1.ConditionSeverityError,
+		messageFormat, messageArgs...,
+	))
+
+	invalidFetchCredentials.WithLabelValues(providerMetricLabels(provider)...).Set(1)
+}
+
+// setFetchCredentialsCheckFailed reports that the fetch credentials couldn't be checked in the provider conditions.
+// The invalid fetch credentials metric keeps its last value.
+func setFetchCredentialsCheckFailed(provider operatorv1.GenericProvider, messageFormat string, messageArgs ...interface{}) {
+	conditions.Set(provider, conditions.UnknownCondition(
+		operatorv1.FetchCredentialsValidCondition,
+		operatorv1.FetchCredentialsCheckFailedReason,
+		messageFormat, messageArgs...,
+	))
+}
+
+// invalidFetchCredentialsReported returns true if the provider conditions report invalid fetch credentials.
+func invalidFetchCredentialsReported(provider operatorv1.GenericProvider) bool {
+	return conditions.IsFalse(provider, operatorv1.FetchCredentialsValidCondition) &&
+		conditions.GetReason(provider, operatorv1.FetchCredentialsValidCondition) == operatorv1.InvalidFetchCredentialsReason
+}
+
+// githubErrorStatus returns the HTTP status code of a GitHub API error, or 0 if the request failed without an error
+// response, e.g. on a timeout or a DNS failure. Rate limit errors have no status code either, as they are transient.
+func githubErrorStatus(err error) int {
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil {
+		return errResp.Response.StatusCode
+	}
+
+	return 0
+}
+
+// githubRepository returns the owner and name of the GitHub repository from the provider fetch URL.
+func githubRepository(provider operatorv1.GenericProvider) (string, string, bool) {
+	spec := provider.GetSpec()
+	if spec.FetchConfig == nil || spec.FetchConfig.URL == "" {
+		return "", "", false
+	}
+
+	u, err := url.Parse(spec.FetchConfig.URL)
+	if err != nil || u.Host != githubHost {
+		return "", "", false
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 {
+		return "", "", false
+	}
+
+	return segments[0], segments[1], true
+}
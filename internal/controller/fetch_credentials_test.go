@@ -0,0 +1,213 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestValidateGitHubToken(t *testing.T) {
+	tests := []struct {
+		name            string
+		fetchURL        string
+		orgsStatus      int
+		repoStatus      int
+		tokenExpiration time.Time
+		expectedErr     bool
+		expectedStatus  corev1.ConditionStatus
+		expectedReason  string
+		expectedMetric  float64
+	}{
+		{
+			name:           "valid token",
+			orgsStatus:     http.StatusOK,
+			expectedStatus: corev1.ConditionTrue,
+		},
+		{
+			name:           "invalid token",
+			orgsStatus:     http.StatusUnauthorized,
+			expectedErr:    true,
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: operatorv1.InvalidFetchCredentialsReason,
+			expectedMetric: 1,
+		},
+		{
+			name:           "forbidden token",
+			orgsStatus:     http.StatusForbidden,
+			expectedErr:    true,
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: operatorv1.InvalidFetchCredentialsReason,
+			expectedMetric: 1,
+		},
+		{
+			name:           "server error",
+			orgsStatus:     http.StatusInternalServerError,
+			expectedErr:    true,
+			expectedStatus: corev1.ConditionUnknown,
+			expectedReason: operatorv1.FetchCredentialsCheckFailedReason,
+		},
+		{
+			name:           "server error on the repository",
+			fetchURL:       "https://github.com/owner/repo/releases",
+			orgsStatus:     http.StatusOK,
+			repoStatus:     http.StatusBadGateway,
+			expectedErr:    true,
+			expectedStatus: corev1.ConditionUnknown,
+			expectedReason: operatorv1.FetchCredentialsCheckFailedReason,
+		},
+		{
+			name:           "token without access to the repository",
+			fetchURL:       "https://github.com/owner/repo/releases",
+			orgsStatus:     http.StatusOK,
+			repoStatus:     http.StatusNotFound,
+			expectedErr:    true,
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: operatorv1.InvalidFetchCredentialsReason,
+			expectedMetric: 1,
+		},
+		{
+			name:           "token with access to the repository",
+			fetchURL:       "https://github.com/owner/repo/releases",
+			orgsStatus:     http.StatusOK,
+			repoStatus:     http.StatusOK,
+			expectedStatus: corev1.ConditionTrue,
+		},
+		{
+			name:            "token expiring soon",
+			orgsStatus:      http.StatusOK,
+			tokenExpiration: time.Now().Add(24 * time.Hour),
+			expectedStatus:  corev1.ConditionFalse,
+			expectedReason:  operatorv1.FetchCredentialsExpiringReason,
+		},
+		{
+			name:            "token expiring later",
+			orgsStatus:      http.StatusOK,
+			tokenExpiration: time.Now().Add(30 * 24 * time.Hour),
+			expectedStatus:  corev1.ConditionTrue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/users/kubernetes-sigs/orgs", func(w http.ResponseWriter, r *http.Request) {
+				if !tt.tokenExpiration.IsZero() {
+					w.Header().Set("Github-Authentication-Token-Expiration", tt.tokenExpiration.UTC().Format("2006-01-02 15:04:05 MST"))
+				}
+
+				w.WriteHeader(tt.orgsStatus)
+				_, _ = w.Write([]byte("[]"))
+			})
+			mux.HandleFunc("/repos/owner/repo", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.repoStatus)
+				_, _ = w.Write([]byte("{}"))
+			})
+
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			origNewGitHubClient := newGitHubClient
+			defer func() { newGitHubClient = origNewGitHubClient }()
+
+			newGitHubClient = func(_ context.Context, _ string) *github.Client {
+				client := github.NewClient(nil)
+				client.BaseURL, _ = url.Parse(server.URL + "/")
+
+				return client
+			}
+
+			provider := &operatorv1.CoreProvider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "cluster-api",
+					Namespace: "capi-system",
+				},
+			}
+
+			if tt.fetchURL != "" {
+				provider.Spec.FetchConfig = &operatorv1.FetchConfiguration{URL: tt.fetchURL}
+			}
+
+			err := validateGitHubToken(context.TODO(), provider, "token")
+			if tt.expectedErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+
+			condition := conditions.Get(provider, operatorv1.FetchCredentialsValidCondition)
+			g.Expect(condition).ToNot(BeNil())
+			g.Expect(condition.Status).To(Equal(tt.expectedStatus))
+			g.Expect(condition.Reason).To(Equal(tt.expectedReason))
+
+			g.Expect(testutil.ToFloat64(invalidFetchCredentials.WithLabelValues(providerMetricLabels(provider)...))).To(Equal(tt.expectedMetric))
+
+			deleteProviderMetrics(provider)
+		})
+	}
+}
+
+func TestValidateGitHubTokenUnreachable(t *testing.T) {
+	g := NewWithT(t)
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	origNewGitHubClient := newGitHubClient
+	defer func() { newGitHubClient = origNewGitHubClient }()
+
+	newGitHubClient = func(_ context.Context, _ string) *github.Client {
+		client := github.NewClient(nil)
+		client.BaseURL, _ = url.Parse(server.URL + "/")
+
+		return client
+	}
+
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-api",
+			Namespace: "capi-system",
+		},
+	}
+
+	g.Expect(validateGitHubToken(context.TODO(), provider, "token")).ToNot(Succeed())
+
+	condition := conditions.Get(provider, operatorv1.FetchCredentialsValidCondition)
+	g.Expect(condition).ToNot(BeNil())
+	g.Expect(condition.Status).To(Equal(corev1.ConditionUnknown))
+	g.Expect(condition.Reason).To(Equal(operatorv1.FetchCredentialsCheckFailedReason))
+	g.Expect(invalidFetchCredentialsReported(provider)).To(BeFalse())
+
+	g.Expect(testutil.ToFloat64(invalidFetchCredentials.WithLabelValues(providerMetricLabels(provider)...))).To(BeZero())
+
+	deleteProviderMetrics(provider)
+}
@@ -22,8 +22,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/rest"
 	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
@@ -32,9 +35,13 @@ import (
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -43,6 +50,36 @@ type GenericProviderReconciler struct {
 	ProviderList genericprovider.GenericProviderList
 	Client       client.Client
 	Config       *rest.Config
+
+	// ImageRewriteRules are applied to the container images of all provider components.
+	ImageRewriteRules []ImageRewriteRule
+
+	// IPFamilyMode adjusts the provider components to the IP families of the management cluster.
+	IPFamilyMode IPFamilyMode
+
+	// FetchConfigMapNamespaces are the namespaces, other than the provider namespace, from which
+	// provider components and metadata can be fetched with a fetch config selector.
+	FetchConfigMapNamespaces []string
+
+	// RequireNamespaceGrants requires the namespaces of the configuration secrets and fetch config ConfigMaps
+	// and Secrets of other namespaces than the provider one to grant access to the provider namespace.
+	RequireNamespaceGrants bool
+
+	// FetchConfigMapRequiredLabel must be matched by the ConfigMaps selected with a fetch config selector,
+	// in addition to the selector itself.
+	FetchConfigMapRequiredLabel *labels.Requirement
+
+	// FetchLocalPaths are the directories of the operator pod under which provider components and metadata
+	// can be read with a fetch config local path. No local path can be read if it's empty.
+	FetchLocalPaths []string
+
+	// ComponentsCache keeps the downloaded provider manifests in memory across reconciles, it's shared by
+	// the reconcilers of all provider types. Manifests aren't cached if it's nil.
+	ComponentsCache *ComponentsCache
+
+	// NewVersionCheckInterval is how often the provider repositories are checked for versions newer than the
+	// installed ones, which are reported without upgrading the providers. They're never checked if it's zero.
+	NewVersionCheckInterval time.Duration
 }
 
 const (
@@ -50,10 +87,75 @@ const (
 )
 
 func (r *GenericProviderReconciler) SetupWithManager(mgr ctrl.Manager, options controller.Options) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	if err := r.setupReferencedObjectsIndexes(context.Background(), mgr); err != nil {
+		return err
+	}
+
+	// Changes of the referenced Secrets and ConfigMaps, e.g. rotated credentials or updated manifests, are
+	// applied without waiting for the resync period.
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(r.Provider).
-		WithOptions(options).
-		Complete(r)
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.secretToProviders)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.configMapToProviders)).
+		WithOptions(options)
+
+	// All other providers depend on the core provider, so reconcile the ones waiting for it as soon as
+	// it becomes ready instead of waiting for the preflight check requeue.
+	if _, ok := r.Provider.(*operatorv1.CoreProvider); !ok {
+		b = b.Watches(
+			&operatorv1.CoreProvider{},
+			handler.EnqueueRequestsFromMapFunc(r.coreProviderToProviders),
+			builder.WithPredicates(coreProviderReadyPredicate()),
+		)
+	}
+
+	return b.Complete(r)
+}
+
+// coreProviderToProviders returns requests for all providers that are waiting for the core provider to become ready.
+func (r *GenericProviderReconciler) coreProviderToProviders(ctx context.Context, _ client.Object) []reconcile.Request {
+	log := ctrl.LoggerFrom(ctx)
+
+	providerList, ok := r.ProviderList.DeepCopyObject().(genericprovider.GenericProviderList)
+	if !ok {
+		return nil
+	}
+
+	if err := r.Client.List(ctx, providerList); err != nil {
+		log.Error(err, "failed to list providers waiting for the core provider")
+
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+
+	for _, provider := range providerList.GetItems() {
+		condition := conditions.Get(provider, operatorv1.PreflightCheckCondition)
+		if condition == nil || condition.Reason != operatorv1.WaitingForCoreProviderReadyReason {
+			continue
+		}
+
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKey{Namespace: provider.GetNamespace(), Name: provider.GetName()},
+		})
+	}
+
+	return requests
+}
+
+// coreProviderReadyPredicate filters core provider events down to its Ready condition becoming true.
+func coreProviderReadyPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldProvider, okOld := e.ObjectOld.(*operatorv1.CoreProvider)
+			newProvider, okNew := e.ObjectNew.(*operatorv1.CoreProvider)
+
+			return okOld && okNew && !conditions.IsTrue(oldProvider, clusterv1.ReadyCondition) && conditions.IsTrue(newProvider, clusterv1.ReadyCondition)
+		},
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+	}
 }
 
 func (r *GenericProviderReconciler) Reconcile(ctx context.Context, req reconcile.Request) (_ reconcile.Result, reterr error) {
@@ -71,6 +173,11 @@ func (r *GenericProviderReconciler) Reconcile(ctx context.Context, req reconcile
 		return ctrl.Result{}, err
 	}
 
+	// Registered first, so failures to patch the provider are counted as well.
+	defer func() {
+		reconcileTotal.WithLabelValues(r.Provider.GetType(), reconcileResult(reterr)).Inc()
+	}()
+
 	// Initialize the patch helper
 	patchHelper, err := patch.NewHelper(r.Provider, r.Client)
 	if err != nil {
@@ -101,16 +208,62 @@ func (r *GenericProviderReconciler) Reconcile(ctx context.Context, req reconcile
 		return r.reconcileDelete(ctx, r.Provider)
 	}
 
+	setPausedCondition(r.Provider)
+
+	// Components of paused providers are left untouched until they are unpaused. The applied hashes are
+	// kept, so changes made while paused are applied once unpaused.
+	if isPaused(r.Provider) {
+		return r.reconcilePaused(ctx, r.Provider, r.ProviderList)
+	}
+
 	// Check if spec hash stays the same and don't go further in this case.
-	specHash, err := calculateHash(r.Provider.GetSpec())
+	specHash, err := providerHash(r.Provider)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	if r.Provider.GetAnnotations()[appliedSpecHashAnnotation] == specHash {
-		log.Info("No changes detected, skipping further steps")
+	referencesHash, err := referencedObjectsHash(ctx, r.Client, r.Provider)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
-		return ctrl.Result{}, nil
+	if r.Provider.GetAnnotations()[appliedSpecHashAnnotation] == specHash &&
+		r.Provider.GetAnnotations()[appliedReferencesHashAnnotation] == referencesHash {
+		// Refreshed external variables are compared with the ones the components were installed with.
+		changed, err := externalVariablesChanged(ctx, r.Client, r.Provider)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		checkVersion, versionCheckAfter := versionCheckDue(r.Provider)
+		checkNewVersion, newVersionCheckAfter := newVersionCheckDue(r.Provider, r.NewVersionCheckInterval)
+
+		switch {
+		case changed:
+			log.Info("External variables changed, re-installing the provider")
+		case checkVersion:
+			log.Info("Checking for new versions of the provider")
+		case checkNewVersion:
+			log.Info("Checking for newer versions than the installed one")
+		case readinessPending(r.Provider):
+			// The Deployments and Jobs are checked without reconciling the provider again, until the wait can be completed.
+			wait, err := newPhaseReconciler(*r, r.Provider, r.ProviderList).readinessWait(ctx)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+
+			if wait > 0 {
+				log.Info("Waiting for the provider deployments or jobs, skipping further steps")
+
+				return ctrl.Result{RequeueAfter: wait}, nil
+			}
+
+			log.Info("Completing the wait for the provider deployments or jobs")
+		default:
+			log.Info("No changes detected, skipping further steps")
+
+			return ctrl.Result{RequeueAfter: earliestRequeueAfter(externalVariablesRefreshInterval(r.Provider), versionCheckAfter, newVersionCheckAfter)}, nil
+		}
 	}
 
 	res, err := r.reconcile(ctx, r.Provider, r.ProviderList)
@@ -120,15 +273,20 @@ func (r *GenericProviderReconciler) Reconcile(ctx context.Context, req reconcile
 		annotations = map[string]string{}
 	}
 
-	// Set the spec hash annotation if reconciliation was successful or reset it otherwise.
-	if res.IsZero() && err == nil {
+	// Set the spec hash annotation if reconciliation was successful or reset it otherwise. It's also set while the
+	// Deployments or Jobs are waited for, so the wait is polled without reconciling the provider again.
+	if err == nil && (res.IsZero() || readinessPending(r.Provider)) {
 		// Recalculate spec hash in case it was changed during reconciliation process.
-		specHash, err = calculateHash(r.Provider.GetSpec())
+		specHash, err = providerHash(r.Provider)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
 
 		annotations[appliedSpecHashAnnotation] = specHash
+		annotations[appliedReferencesHashAnnotation] = referencesHash
+		versionCheckAfter := setVersionChecked(annotations, r.Provider)
+		newVersionCheckAfter := setNewVersionChecked(annotations, r.NewVersionCheckInterval)
+		res.RequeueAfter = earliestRequeueAfter(res.RequeueAfter, externalVariablesRefreshInterval(r.Provider), versionCheckAfter, newVersionCheckAfter)
 	} else {
 		annotations[appliedSpecHashAnnotation] = ""
 	}
@@ -142,6 +300,16 @@ func patchProvider(ctx context.Context, provider operatorv1.GenericProvider, pat
 	conds := []clusterv1.ConditionType{
 		operatorv1.PreflightCheckCondition,
 		operatorv1.ProviderInstalledCondition,
+		operatorv1.PreDeleteHooksSucceededCondition,
+		operatorv1.PreInstallHooksSucceededCondition,
+		operatorv1.PostInstallHooksSucceededCondition,
+		operatorv1.FetchCredentialsValidCondition,
+		operatorv1.ComponentsVerifiedCondition,
+		operatorv1.ProvenanceVerifiedCondition,
+		operatorv1.SuspendedCondition,
+		operatorv1.PausedCondition,
+		operatorv1.NewVersionAvailableCondition,
+		operatorv1.RollbackCompletedCondition,
 	}
 
 	options = append(options, patch.WithOwnedConditions{Conditions: conds})
@@ -151,17 +319,104 @@ func patchProvider(ctx context.Context, provider operatorv1.GenericProvider, pat
 
 func (r *GenericProviderReconciler) reconcile(ctx context.Context, provider genericprovider.GenericProvider, genericProviderList genericprovider.GenericProviderList) (ctrl.Result, error) {
 	reconciler := newPhaseReconciler(*r, provider, genericProviderList)
+
+	// The phases waiting for hook Jobs or Deployments record the wait again, so it is polled without this reconcile.
+	clearWaits(provider)
+
 	phases := []reconcilePhaseFn{
 		reconciler.preflightChecks,
 		reconciler.initializePhaseReconciler,
+		reconciler.migrateNamespace,
+		reconciler.adoptClusterctlProvider,
+		reconciler.applyVersionPolicy,
+		reconciler.resolveLatestVersion,
+		reconciler.deferVersionChange,
+		reconciler.nextUpgradeStep,
+		reconciler.runPreInstallHooks,
 		reconciler.downloadManifests,
 		reconciler.load,
+		reconciler.planUpgradeSteps,
 		reconciler.fetch,
+		reconciler.planUpgrade,
 		reconciler.upgrade,
 		reconciler.install,
+		reconciler.pruneRemovedObjects,
+		reconciler.updateInventory,
 		reconciler.reportStatus,
+		reconciler.syncClusterctlInventory,
+		reconciler.checkNewVersion,
+		reconciler.verifyUpgrade,
+		reconciler.waitForInstall,
+		reconciler.reportCRDsReady,
+		reconciler.runSmokeTest,
+		reconciler.runPostInstallHooks,
+	}
+
+	// The upgrade to a version the provider was rolled back from isn't retried until the version changes.
+	if checkRolledBack(provider) {
+		phases = []reconcilePhaseFn{
+			reconciler.preflightChecks,
+			reconciler.skipRolledBackVersion,
+		}
+	}
+
+	// Components of externally managed providers are installed by other tooling, only track their state.
+	if isExternallyManaged(provider) {
+		phases = []reconcilePhaseFn{
+			reconciler.preflightChecks,
+			reconciler.trackExternalProvider,
+		}
+	}
+
+	res, err := runPhases(ctx, reconciler, provider, phases)
+
+	res = reconciler.restoreDeferredVersion(res)
+	reconciler.restoreSpecVersion()
+
+	return res, err
+}
+
+// reconcilePaused publishes the plan of a pending upgrade of a paused provider, without changing its components,
+// so the upgrade can be reviewed before the provider is unpaused.
+func (r *GenericProviderReconciler) reconcilePaused(ctx context.Context, provider genericprovider.GenericProvider, genericProviderList genericprovider.GenericProviderList) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	reconciler := newPhaseReconciler(*r, provider, genericProviderList)
+	defer reconciler.restoreSpecVersion()
+
+	// The version policy can change the version, so it's applied before checking for a pending upgrade.
+	res, err := runPhases(ctx, reconciler, provider, []reconcilePhaseFn{
+		reconciler.preflightChecks,
+		reconciler.initializePhaseReconciler,
+		reconciler.applyVersionPolicy,
+		reconciler.resolveLatestVersion,
+	})
+	if !res.IsZero() || err != nil {
+		return res, err
 	}
 
+	if !upgradePending(provider) {
+		log.Info("Provider is paused, skipping further steps")
+
+		status := provider.GetStatus()
+		status.UpgradePlan = nil
+		provider.SetStatus(status)
+
+		return ctrl.Result{}, nil
+	}
+
+	log.Info("Provider is paused, planning the pending upgrade without applying it")
+
+	return runPhases(ctx, reconciler, provider, []reconcilePhaseFn{
+		reconciler.downloadManifests,
+		reconciler.load,
+		reconciler.fetch,
+		reconciler.planUpgrade,
+	})
+}
+
+// runPhases runs the reconciliation phases in order, stopping at the first one returning an error or a result.
+func runPhases(ctx context.Context, reconciler *phaseReconciler, provider genericprovider.GenericProvider, phases []reconcilePhaseFn) (ctrl.Result, error) {
 	res := reconcile.Result{}
 
 	var err error
@@ -169,9 +424,13 @@ func (r *GenericProviderReconciler) reconcile(ctx context.Context, provider gene
 	for _, phase := range phases {
 		res, err = phase(ctx)
 		if err != nil {
+			// Values of the configuration secrets must not leak into the logs and conditions.
+			err = reconciler.sensitiveValues.redactError(err)
+
 			var pe *PhaseError
 			if errors.As(err, &pe) {
 				conditions.Set(provider, conditions.FalseCondition(pe.Type, pe.Reason, pe.Severity, err.Error()))
+				observePhaseError(provider, pe)
 			}
 		}
 
@@ -191,7 +450,25 @@ func (r *GenericProviderReconciler) reconcileDelete(ctx context.Context, provide
 
 	reconciler := newPhaseReconciler(*r, provider, nil)
 	phases := []reconcilePhaseFn{
+		reconciler.runPreDeleteHooks,
 		reconciler.delete,
+		reconciler.deleteClusterctlInventory,
+	}
+
+	// Orphaned components are left running, so there's nothing for the pre-delete hooks to guard.
+	if provider.GetSpec().DeletionPolicy == operatorv1.DeletionPolicyOrphan {
+		log.Info("Provider deletion policy is Orphan, leaving the components running")
+
+		phases = []reconcilePhaseFn{
+			reconciler.orphanComponents,
+		}
+	}
+
+	// Components of a provider moved to another namespace were already removed by the migration.
+	if isMigrated(provider) {
+		log.Info("Provider was moved to another namespace, skipping components deletion", "namespace", provider.GetAnnotations()[operatorv1.MigratedToNamespaceAnnotation])
+
+		phases = nil
 	}
 
 	res := reconcile.Result{}
@@ -214,10 +491,28 @@ func (r *GenericProviderReconciler) reconcileDelete(ctx context.Context, provide
 	}
 
 	controllerutil.RemoveFinalizer(provider, operatorv1.ProviderFinalizer)
+	deleteProviderMetrics(provider)
 
 	return res, nil
 }
 
+// providerHash returns the hash of the provider spec. It also covers the restartedAt annotation when it is set,
+// so that changing the annotation forces a re-install.
+func providerHash(provider operatorv1.GenericProvider) (string, error) {
+	restartedAt, ok := provider.GetAnnotations()[operatorv1.RestartedAtAnnotation]
+	if !ok {
+		return calculateHash(provider.GetSpec())
+	}
+
+	return calculateHash(struct {
+		Spec        operatorv1.ProviderSpec `json:"spec"`
+		RestartedAt string                  `json:"restartedAt"`
+	}{
+		Spec:        provider.GetSpec(),
+		RestartedAt: restartedAt,
+	})
+}
+
 func calculateHash(object interface{}) (string, error) {
 	jsonData, err := json.Marshal(object)
 	if err != nil {
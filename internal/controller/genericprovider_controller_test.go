@@ -30,6 +30,9 @@ import (
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
 	"sigs.k8s.io/cluster-api-operator/internal/controller/genericprovider"
@@ -341,6 +344,10 @@ releaseSeries:
 					return false
 				}
 
+				if provider.GetStatus().TargetVersion == nil || *provider.GetStatus().TargetVersion != tc.newVersion {
+					return false
+				}
+
 				if provider.GetLabels()["provider-version"] != tc.newVersion {
 					return false
 				}
@@ -604,3 +611,92 @@ func setupScheme() *runtime.Scheme {
 
 	return scheme
 }
+
+func TestCoreProviderToProviders(t *testing.T) {
+	g := NewWithT(t)
+
+	waitingProvider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "docker", Namespace: "capd-system"},
+		Status: operatorv1.InfrastructureProviderStatus{
+			ProviderStatus: operatorv1.ProviderStatus{
+				Conditions: clusterv1.Conditions{
+					{
+						Type:   operatorv1.PreflightCheckCondition,
+						Status: corev1.ConditionFalse,
+						Reason: operatorv1.WaitingForCoreProviderReadyReason,
+					},
+				},
+			},
+		},
+	}
+
+	installedProvider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "capa-system"},
+		Status: operatorv1.InfrastructureProviderStatus{
+			ProviderStatus: operatorv1.ProviderStatus{
+				Conditions: clusterv1.Conditions{
+					{
+						Type:   operatorv1.PreflightCheckCondition,
+						Status: corev1.ConditionTrue,
+					},
+				},
+			},
+		},
+	}
+
+	r := &GenericProviderReconciler{
+		Provider:     &operatorv1.InfrastructureProvider{},
+		ProviderList: &operatorv1.InfrastructureProviderList{},
+		Client:       fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(waitingProvider, installedProvider).Build(),
+	}
+
+	g.Expect(r.coreProviderToProviders(ctx, &operatorv1.CoreProvider{})).To(Equal([]reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "capd-system", Name: "docker"}},
+	}))
+
+	notReady := &operatorv1.CoreProvider{}
+	ready := &operatorv1.CoreProvider{
+		Status: operatorv1.CoreProviderStatus{
+			ProviderStatus: operatorv1.ProviderStatus{
+				Conditions: clusterv1.Conditions{{Type: clusterv1.ReadyCondition, Status: corev1.ConditionTrue}},
+			},
+		},
+	}
+
+	predicate := coreProviderReadyPredicate()
+	g.Expect(predicate.Update(event.UpdateEvent{ObjectOld: notReady, ObjectNew: ready})).To(BeTrue())
+	g.Expect(predicate.Update(event.UpdateEvent{ObjectOld: ready, ObjectNew: ready})).To(BeFalse())
+	g.Expect(predicate.Update(event.UpdateEvent{ObjectOld: ready, ObjectNew: notReady})).To(BeFalse())
+}
+
+func TestProviderHash(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.CoreProvider{
+		Spec: operatorv1.CoreProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				Version: "v1.4.3",
+			},
+		},
+	}
+
+	specHash, err := calculateHash(provider.GetSpec())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Without the restartedAt annotation the hash of the spec is kept, so existing providers are not re-installed.
+	hash, err := providerHash(provider)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(hash).To(Equal(specHash))
+
+	provider.SetAnnotations(map[string]string{operatorv1.RestartedAtAnnotation: "2024-01-01T00:00:00Z"})
+
+	firstRestartHash, err := providerHash(provider)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(firstRestartHash).ToNot(Equal(specHash))
+
+	provider.SetAnnotations(map[string]string{operatorv1.RestartedAtAnnotation: "2024-01-02T00:00:00Z"})
+
+	secondRestartHash, err := providerHash(provider)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(secondRestartHash).ToNot(Equal(firstRestartHash))
+}
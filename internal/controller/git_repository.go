@@ -0,0 +1,389 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitclient "github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	versionutil "k8s.io/apimachinery/pkg/util/version"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const (
+	// gitUsernameKey and gitPasswordKey are the config secret variables with the Git credentials for HTTP(S) repositories.
+	// The password can be an access token.
+	gitUsernameKey = "GIT_USERNAME"
+	gitPasswordKey = "GIT_PASSWORD"
+
+	// gitSSHPrivateKeyKey and gitSSHKnownHostsKey are the config secret variables with the private key and the known
+	// hosts for SSH repositories.
+	gitSSHPrivateKeyKey = "GIT_SSH_PRIVATE_KEY"
+	gitSSHKnownHostsKey = "GIT_SSH_KNOWN_HOSTS"
+
+	// gitDefaultUsername is used for token authentication if no username is set, Git servers ignore it then.
+	gitDefaultUsername = "git"
+)
+
+func init() {
+	// Git repositories are fetched over HTTP(S) with the fetch transport, so they use its TLS options.
+	gitHTTPClient := githttp.NewClient(&http.Client{Transport: fetchTransport})
+	gitclient.InstallProtocol("http", gitHTTPClient)
+	gitclient.InstallProtocol("https", gitHTTPClient)
+}
+
+// gitConnection holds the options to connect to a remote Git repository with.
+type gitConnection struct {
+	auth     transport.AuthMethod
+	caBundle []byte
+	proxy    transport.ProxyOptions
+}
+
+// downloadGitManifests downloads the provider metadata and components from a Git repository and stores
+// them in a ConfigMap like the manifests downloaded from a GitHub release.
+func (p *phaseReconciler) downloadGitManifests(ctx context.Context) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	spec := p.provider.GetSpec()
+	source := spec.FetchConfig.Git
+
+	log.Info("Downloading provider manifests from Git repository", "url", source.URL, "ref", source.Ref, "path", source.Path)
+
+	auth, err := gitAuth(source.URL, p.configClient.Variables())
+	if err != nil {
+		err = fmt.Errorf("failed to configure authentication to Git repository %s for provider %q: %w", source.URL, p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	caBundle, err := p.fetchCABundle(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to get CA bundle for provider %q: %w", p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	proxy, err := gitProxyOptions(source.URL, spec.FetchConfig.Proxy)
+	if err != nil {
+		err = fmt.Errorf("failed to configure proxy to Git repository %s for provider %q: %w", source.URL, p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	conn := gitConnection{auth: auth, caBundle: caBundle, proxy: proxy}
+
+	refs, err := listGitRefs(ctx, source.URL, conn)
+	if err != nil {
+		err = fmt.Errorf("failed to list references of Git repository %s for provider %q: %w", source.URL, p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	version, refName, err := resolveGitRef(refs, source.Ref, spec.Version)
+	if err != nil {
+		err = fmt.Errorf("failed to resolve reference of Git repository %s for provider %q: %w", source.URL, p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if spec.Version == "" {
+		// User didn't set the version, use the one resolved from the repository tags.
+		spec.Version = version
+
+		// Add version to the provider spec.
+		p.provider.SetSpec(spec)
+	}
+
+	files, err := fetchGitFiles(ctx, source.URL, conn, refName, source.Path)
+	if err != nil {
+		err = fmt.Errorf("failed to fetch %s of Git repository %s for provider %q: %w", refName, source.URL, p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	// Metadata set in the provider spec replaces the one from the repository, which may not have it at all, when loaded.
+	metadata := files[metadataFile]
+	components := files[componentsFileName]
+
+	if (metadata == nil && !hasMetadataOverride(spec)) || components == nil {
+		err = fmt.Errorf("%s of Git repository %s for provider %q must contain %q and %q files", refName, source.URL, p.provider.GetName(),
+			path.Join(gitPath(source.Path), metadataFile), path.Join(gitPath(source.Path), componentsFileName))
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if err := p.createManifestsConfigMap(ctx, metadata, components, needToCompress(metadata, components)); err != nil {
+		err = fmt.Errorf("failed to create config map for provider %q: %w", p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// gitAuth returns the authentication method for the Git repository from the variables, or nil if no credentials are set.
+func gitAuth(url string, variables configclient.VariablesClient) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, err
+	}
+
+	// A missing variable is returned as an error, the credential is left empty then.
+	username, _ := variables.Get(gitUsernameKey)
+	password, _ := variables.Get(gitPasswordKey)
+	privateKey, _ := variables.Get(gitSSHPrivateKeyKey)
+	knownHosts, _ := variables.Get(gitSSHKnownHostsKey)
+
+	switch endpoint.Protocol {
+	case "ssh":
+		if privateKey == "" {
+			return nil, fmt.Errorf("%s variable must be set for SSH repositories", gitSSHPrivateKeyKey)
+		}
+
+		user := endpoint.User
+		if user == "" {
+			user = gitDefaultUsername
+		}
+
+		auth, err := gitssh.NewPublicKeys(user, []byte(privateKey), "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", gitSSHPrivateKeyKey, err)
+		}
+
+		// Without known hosts, the SSH_KNOWN_HOSTS files or ~/.ssh/known_hosts of the operator are used.
+		if knownHosts != "" {
+			auth.HostKeyCallback, err = knownHostsCallback(knownHosts)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", gitSSHKnownHostsKey, err)
+			}
+		}
+
+		return auth, nil
+	case "http", "https":
+		if password == "" {
+			return nil, nil
+		}
+
+		if username == "" {
+			username = gitDefaultUsername
+		}
+
+		return &githttp.BasicAuth{Username: username, Password: password}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// knownHostsCallback returns a host key callback accepting the hosts in the known_hosts data.
+func knownHostsCallback(knownHosts string) (ssh.HostKeyCallback, error) {
+	// knownhosts only reads files, the data is read from the file right away so it can be removed after.
+	file, err := os.CreateTemp("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(knownHosts); err != nil {
+		file.Close()
+
+		return nil, err
+	}
+
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	return knownhosts.New(file.Name())
+}
+
+// gitProxyOptions returns the proxy to connect to the Git repository url with, if the proxy configuration
+// is set and the url uses HTTP or HTTPS. Otherwise, the proxy environment variables apply.
+func gitProxyOptions(rawURL string, proxy *operatorv1.ProxyConfiguration) (transport.ProxyOptions, error) {
+	if proxy == nil {
+		return transport.ProxyOptions{}, nil
+	}
+
+	rURL, err := url.Parse(rawURL)
+	if err != nil || (rURL.Scheme != "http" && rURL.Scheme != "https") {
+		// Scp-like and SSH urls can't be proxied with the HTTP proxy configuration.
+		return transport.ProxyOptions{}, nil //nolint:nilerr
+	}
+
+	proxyURL, err := fetchProxyFunc(proxy)(rURL)
+	if err != nil || proxyURL == nil {
+		return transport.ProxyOptions{}, err
+	}
+
+	return transport.ProxyOptions{URL: proxyURL.String()}, nil
+}
+
+// listGitRefs returns the references of the remote Git repository.
+func listGitRefs(ctx context.Context, url string, conn gitConnection) ([]*plumbing.Reference, error) {
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{url},
+	})
+
+	return remote.ListContext(ctx, &git.ListOptions{Auth: conn.auth, CABundle: conn.caBundle, ProxyOptions: conn.proxy})
+}
+
+// resolveGitRef returns the provider version and the full name of the Git reference to check out. The reference
+// is the given branch or tag, the tag of the provider version if no reference is given, or the latest semver
+// tag if no version is given either.
+func resolveGitRef(refs []*plumbing.Reference, ref, version string) (string, plumbing.ReferenceName, error) {
+	if ref == "" && version == "" {
+		latestTag, err := latestGitTag(refs)
+		if err != nil {
+			return "", "", err
+		}
+
+		ref = latestTag
+	}
+
+	if ref == "" {
+		ref = version
+	}
+
+	for _, name := range []plumbing.ReferenceName{plumbing.NewTagReferenceName(ref), plumbing.NewBranchReferenceName(ref)} {
+		for _, r := range refs {
+			if r.Name() != name {
+				continue
+			}
+
+			if version != "" {
+				return version, name, nil
+			}
+
+			// Only version tags can be used as the provider version.
+			if _, err := versionutil.ParseSemantic(ref); !name.IsTag() || err != nil {
+				return "", "", fmt.Errorf("provider version must be set to install %q", ref)
+			}
+
+			return ref, name, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("branch or tag %q not found", ref)
+}
+
+// latestGitTag returns the latest semver tag among the Git references.
+func latestGitTag(refs []*plumbing.Reference) (string, error) {
+	var (
+		latestTag     string
+		latestVersion *versionutil.Version
+	)
+
+	for _, r := range refs {
+		if !r.Name().IsTag() {
+			continue
+		}
+
+		tag := r.Name().Short()
+
+		// Tags that are not versions are ignored.
+		version, err := versionutil.ParseSemantic(tag)
+		if err != nil {
+			continue
+		}
+
+		if latestVersion == nil || latestVersion.LessThan(version) {
+			latestTag, latestVersion = tag, version
+		}
+	}
+
+	if latestVersion == nil {
+		return "", errors.New("no versions available")
+	}
+
+	return latestTag, nil
+}
+
+// fetchGitFiles returns the metadata and components files in the directory of the Git reference, keyed by their names.
+// Only the referenced commit is fetched, without a worktree.
+func fetchGitFiles(ctx context.Context, url string, conn gitConnection, refName plumbing.ReferenceName, dir string) (map[string][]byte, error) {
+	repo, err := git.CloneContext(ctx, memory.NewStorage(), nil, &git.CloneOptions{
+		URL:           url,
+		Auth:          conn.auth,
+		CABundle:      conn.caBundle,
+		ProxyOptions:  conn.proxy,
+		ReferenceName: refName,
+		SingleBranch:  true,
+		Depth:         1,
+		Tags:          git.NoTags,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{}
+
+	for _, name := range []string{metadataFile, componentsFileName} {
+		file, err := commit.File(path.Join(gitPath(dir), name))
+		if errors.Is(err, object.ErrFileNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		contents, err := file.Contents()
+		if err != nil {
+			return nil, err
+		}
+
+		files[name] = []byte(contents)
+	}
+
+	return files, nil
+}
+
+// gitPath returns the directory path relative to the repository root.
+func gitPath(dir string) string {
+	return path.Clean("/" + dir)[1:]
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/gomega"
+)
+
+// newGitRepository creates a Git repository with a commit for each version. The versions are tagged, alternating
+// lightweight and annotated tags, and the last commit is on the dev branch with the files in the config directory.
+func newGitRepository(g *WithT, dir string, versions []string) {
+	repo, err := git.PlainInit(dir, false)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	worktree, err := repo.Worktree()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	signature := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+
+	commit := func(filesDir, version string) plumbing.Hash {
+		g.Expect(os.MkdirAll(filepath.Join(dir, filesDir), 0o755)).To(Succeed())
+
+		for _, name := range []string{metadataFile, componentsFileName} {
+			g.Expect(os.WriteFile(filepath.Join(dir, filesDir, name), []byte(name+" "+version), 0o600)).To(Succeed())
+		}
+
+		g.Expect(worktree.AddGlob(".")).To(Succeed())
+
+		hash, err := worktree.Commit(version, &git.CommitOptions{Author: signature})
+		g.Expect(err).ToNot(HaveOccurred())
+
+		return hash
+	}
+
+	for i, version := range versions {
+		hash := commit("", version)
+
+		opts := &git.CreateTagOptions{Tagger: signature, Message: version}
+		if i%2 == 0 {
+			opts = nil
+		}
+
+		_, err := repo.CreateTag(version, hash, opts)
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+
+	g.Expect(worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("dev"), Create: true})).To(Succeed())
+	commit("config", "dev")
+}
+
+func TestGitRepository(t *testing.T) {
+	testCases := []struct {
+		name        string
+		ref         string
+		version     string
+		path        string
+		wantVersion string
+		wantRef     plumbing.ReferenceName
+		wantFiles   map[string][]byte
+		wantErr     bool
+	}{
+		{
+			name:        "latest version",
+			wantVersion: "v1.10.0",
+			wantRef:     "refs/tags/v1.10.0",
+			wantFiles:   map[string][]byte{metadataFile: []byte("metadata.yaml v1.10.0"), componentsFileName: []byte("components.yaml v1.10.0")},
+		},
+		{
+			name:        "tag of the provider version",
+			version:     "v1.9.0",
+			wantVersion: "v1.9.0",
+			wantRef:     "refs/tags/v1.9.0",
+			wantFiles:   map[string][]byte{metadataFile: []byte("metadata.yaml v1.9.0"), componentsFileName: []byte("components.yaml v1.9.0")},
+		},
+		{
+			name:        "version tag without provider version",
+			ref:         "v1.0.0",
+			wantVersion: "v1.0.0",
+			wantRef:     "refs/tags/v1.0.0",
+			wantFiles:   map[string][]byte{metadataFile: []byte("metadata.yaml v1.0.0"), componentsFileName: []byte("components.yaml v1.0.0")},
+		},
+		{
+			name:        "branch with path",
+			ref:         "dev",
+			version:     "v1.11.0-dev",
+			path:        "/config/",
+			wantVersion: "v1.11.0-dev",
+			wantRef:     "refs/heads/dev",
+			wantFiles:   map[string][]byte{metadataFile: []byte("metadata.yaml dev"), componentsFileName: []byte("components.yaml dev")},
+		},
+		{
+			name:        "branch without files in path",
+			ref:         "dev",
+			version:     "v1.11.0-dev",
+			path:        "missing",
+			wantVersion: "v1.11.0-dev",
+			wantRef:     "refs/heads/dev",
+			wantFiles:   map[string][]byte{},
+		},
+		{
+			name:    "branch without provider version",
+			ref:     "dev",
+			wantErr: true,
+		},
+		{
+			name:    "missing tag",
+			version: "v2.0.0",
+			wantErr: true,
+		},
+	}
+
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	newGitRepository(g, dir, []string{"v1.0.0", "v1.9.0", "v1.10.0"})
+
+	url := "file://" + dir
+
+	refs, err := listGitRefs(context.TODO(), url, gitConnection{})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			version, refName, err := resolveGitRef(refs, tc.ref, tc.version)
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(version).To(Equal(tc.wantVersion))
+			g.Expect(refName).To(Equal(tc.wantRef))
+
+			files, err := fetchGitFiles(context.TODO(), url, gitConnection{}, refName, tc.path)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(files).To(Equal(tc.wantFiles))
+		})
+	}
+}
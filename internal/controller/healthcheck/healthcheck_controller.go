@@ -18,10 +18,13 @@ package healthcheck
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 
@@ -29,7 +32,9 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-operator/util"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -126,12 +131,22 @@ func (r *GenericProviderHealthCheckReconciler) Reconcile(ctx context.Context, re
 		return result, err
 	}
 
-	// There should be one owner pointing to the Provider resource.
-	if err := r.Client.Get(ctx, r.getProviderKey(deployment), r.Provider); err != nil {
+	// There should be one owner pointing to the Provider resource. Deployments of externally managed
+	// providers are not owned by the Provider resource, so they are matched by the provider label instead.
+	providerKey, owned := r.getProviderKey(deployment)
+	if err := r.Client.Get(ctx, providerKey, r.Provider); err != nil {
+		if !owned && apierrors.IsNotFound(err) {
+			return result, nil
+		}
+
 		// Error reading the object - requeue the request.
 		return result, err
 	}
 
+	if !owned && r.Provider.GetSpec().ManagementMode != operatorv1.ManagementModeExternal {
+		return result, nil
+	}
+
 	deploymentAvailableCondition := getDeploymentCondition(deployment.Status, appsv1.DeploymentAvailable)
 
 	typedProvider := r.Provider
@@ -141,9 +156,43 @@ func (r *GenericProviderHealthCheckReconciler) Reconcile(ctx context.Context, re
 		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
 	}
 
-	// Compare provider's Ready condition with the deployment's Available condition and stop if they already match.
+	readyCondition := &clusterv1.Condition{
+		Type:   clusterv1.ReadyCondition,
+		Status: corev1.ConditionFalse,
+		Reason: operatorv1.NoDeploymentAvailableConditionReason,
+	}
+
+	if deploymentAvailableCondition != nil {
+		readyCondition.Status = deploymentAvailableCondition.Status
+		readyCondition.Reason = deploymentAvailableCondition.Reason
+	}
+
+	// If a smoke test is configured, the provider is only ready once the smoke test Job has succeeded.
+	var smokeTestCondition *clusterv1.Condition
+
+	if typedProvider.GetSpec().SmokeTest != nil {
+		var err error
+
+		smokeTestCondition, err = r.getSmokeTestCondition(ctx, typedProvider)
+		if err != nil {
+			return result, err
+		}
+
+		if readyCondition.Status == corev1.ConditionTrue && smokeTestCondition.Status != corev1.ConditionTrue {
+			readyCondition.Status = corev1.ConditionFalse
+			readyCondition.Reason = smokeTestCondition.Reason
+		}
+	}
+
+	// Compare provider's Ready condition with the computed one and stop if they already match.
 	currentReadyCondition := conditions.Get(typedProvider, clusterv1.ReadyCondition)
-	if currentReadyCondition != nil && deploymentAvailableCondition != nil && currentReadyCondition.Status == deploymentAvailableCondition.Status {
+	if currentReadyCondition != nil && deploymentAvailableCondition != nil && currentReadyCondition.Status == readyCondition.Status &&
+		conditionUnchanged(typedProvider, smokeTestCondition) {
+		// Keep polling the smoke test Job until it finishes, Job updates don't trigger this controller.
+		if smokeTestCondition != nil && smokeTestCondition.Reason == operatorv1.SmokeTestRunningReason {
+			result = ctrl.Result{RequeueAfter: 5 * time.Second}
+		}
+
 		return result, nil
 	}
 
@@ -153,18 +202,14 @@ func (r *GenericProviderHealthCheckReconciler) Reconcile(ctx context.Context, re
 		return result, err
 	}
 
-	if deploymentAvailableCondition != nil {
-		conditions.Set(typedProvider, &clusterv1.Condition{
-			Type:   clusterv1.ReadyCondition,
-			Status: deploymentAvailableCondition.Status,
-			Reason: deploymentAvailableCondition.Reason,
-		})
-	} else {
-		conditions.Set(typedProvider, &clusterv1.Condition{
-			Type:   clusterv1.ReadyCondition,
-			Status: corev1.ConditionFalse,
-			Reason: operatorv1.NoDeploymentAvailableConditionReason,
-		})
+	conditions.Set(typedProvider, readyCondition)
+
+	ownedConditions := []clusterv1.ConditionType{clusterv1.ReadyCondition}
+
+	if smokeTestCondition != nil {
+		conditions.Set(typedProvider, smokeTestCondition)
+
+		ownedConditions = append(ownedConditions, operatorv1.SmokeTestPassedCondition)
 	}
 
 	// Don't requeue immediately if the deployment is not ready, but rather wait 5 seconds.
@@ -172,7 +217,7 @@ func (r *GenericProviderHealthCheckReconciler) Reconcile(ctx context.Context, re
 		result = ctrl.Result{RequeueAfter: 5 * time.Second}
 	}
 
-	options := patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{clusterv1.ReadyCondition}}
+	options := patch.WithOwnedConditions{Conditions: ownedConditions}
 
 	return result, patchHelper.Patch(ctx, typedProvider, options)
 }
@@ -187,11 +232,86 @@ func (r *GenericProviderHealthCheckReconciler) getProviderName(deploy client.Obj
 	return ""
 }
 
-func (r *GenericProviderHealthCheckReconciler) getProviderKey(deploy client.Object) types.NamespacedName {
-	return types.NamespacedName{
-		Namespace: deploy.GetNamespace(),
-		Name:      r.getProviderName(deploy),
+// getProviderKey returns the key of the provider owning the deployment. Deployments installed into a target
+// namespace other than the provider namespace are annotated with the provider namespace instead. If there is no
+// owner, the key is computed from the provider label set by clusterctl, and the second return value is false.
+func (r *GenericProviderHealthCheckReconciler) getProviderKey(deploy client.Object) (types.NamespacedName, bool) {
+	if name := r.getProviderName(deploy); name != "" {
+		return types.NamespacedName{Namespace: deploy.GetNamespace(), Name: name}, true
+	}
+
+	if namespace, ok := deploy.GetAnnotations()[operatorv1.ProviderNamespaceAnnotation]; ok {
+		if name := r.getProviderNameFromLabel(deploy); name != "" {
+			return types.NamespacedName{Namespace: namespace, Name: name}, true
+		}
 	}
+
+	return types.NamespacedName{Namespace: deploy.GetNamespace(), Name: r.getProviderNameFromLabel(deploy)}, false
+}
+
+// getProviderNameFromLabel returns the provider name from the provider label, e.g. "docker" for "infrastructure-docker",
+// or an empty string if the label doesn't belong to a provider of the reconciled kind.
+func (r *GenericProviderHealthCheckReconciler) getProviderNameFromLabel(deploy client.Object) string {
+	providerType := util.ClusterctlProviderType(r.Provider)
+	label := deploy.GetLabels()[providerLabelKey]
+
+	name := strings.TrimPrefix(label, clusterctlv1.ManifestLabel("", providerType))
+	if name == "" || clusterctlv1.ManifestLabel(name, providerType) != label {
+		return ""
+	}
+
+	return name
+}
+
+// getSmokeTestCondition computes the SmokeTestPassed condition from the most recent smoke test Job of the provider.
+func (r *GenericProviderHealthCheckReconciler) getSmokeTestCondition(ctx context.Context, provider operatorv1.GenericProvider) (*clusterv1.Condition, error) {
+	jobList := &batchv1.JobList{}
+	if err := r.Client.List(ctx, jobList, client.InNamespace(provider.GetNamespace()), client.MatchingLabels{
+		operatorv1.SmokeTestJobLabelName: util.SmokeTestJobLabelValue(provider),
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(jobList.Items) == 0 {
+		return conditions.FalseCondition(operatorv1.SmokeTestPassedCondition, operatorv1.SmokeTestNotFoundReason, clusterv1.ConditionSeverityInfo,
+			"Smoke test job not found"), nil
+	}
+
+	// Older jobs are removed when a new one is created, but they might still be terminating.
+	job := &jobList.Items[0]
+	for i := range jobList.Items {
+		if job.CreationTimestamp.Before(&jobList.Items[i].CreationTimestamp) {
+			job = &jobList.Items[i]
+		}
+	}
+
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch c.Type {
+		case batchv1.JobComplete:
+			return conditions.TrueCondition(operatorv1.SmokeTestPassedCondition), nil
+		case batchv1.JobFailed:
+			return conditions.FalseCondition(operatorv1.SmokeTestPassedCondition, operatorv1.SmokeTestFailedReason, clusterv1.ConditionSeverityError,
+				"Smoke test job %s failed: %s", job.Name, c.Message), nil
+		}
+	}
+
+	return conditions.FalseCondition(operatorv1.SmokeTestPassedCondition, operatorv1.SmokeTestRunningReason, clusterv1.ConditionSeverityInfo,
+		"Smoke test job %s is running", job.Name), nil
+}
+
+// conditionUnchanged returns true if the provider already has a condition with the same status and reason as the given one.
+func conditionUnchanged(provider operatorv1.GenericProvider, condition *clusterv1.Condition) bool {
+	if condition == nil {
+		return true
+	}
+
+	current := conditions.Get(provider, condition.Type)
+
+	return current != nil && current.Status == condition.Status && current.Reason == condition.Reason
 }
 
 // getDeploymentCondition returns the deployment condition with the provided type.
@@ -213,7 +333,7 @@ func (r *GenericProviderHealthCheckReconciler) providerDeploymentPredicates() pr
 			panic("expected to get an of object of type appsv1.Deployment")
 		}
 
-		return r.getProviderName(deployment) != ""
+		return r.getProviderName(deployment) != "" || r.getProviderNameFromLabel(deployment) != ""
 	}
 
 	return predicate.Funcs{
@@ -195,3 +195,112 @@ func TestReconcilerReadyConditions(t *testing.T) {
 		})
 	}
 }
+
+func TestGetProviderNameFromLabel(t *testing.T) {
+	testCases := []struct {
+		name         string
+		provider     operatorv1.GenericProvider
+		label        string
+		expectedName string
+	}{
+		{
+			name:         "core provider",
+			provider:     &operatorv1.CoreProvider{},
+			label:        "cluster-api",
+			expectedName: "cluster-api",
+		},
+		{
+			name:         "infrastructure provider",
+			provider:     &operatorv1.InfrastructureProvider{},
+			label:        "infrastructure-docker",
+			expectedName: "docker",
+		},
+		{
+			name:         "control plane provider",
+			provider:     &operatorv1.ControlPlaneProvider{},
+			label:        "control-plane-kubeadm",
+			expectedName: "kubeadm",
+		},
+		{
+			name:         "label of another provider kind",
+			provider:     &operatorv1.BootstrapProvider{},
+			label:        "control-plane-kubeadm",
+			expectedName: "",
+		},
+		{
+			name:         "no label",
+			provider:     &operatorv1.InfrastructureProvider{},
+			expectedName: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := &GenericProviderHealthCheckReconciler{Provider: tc.provider}
+
+			deployment := &appsv1.Deployment{}
+			if tc.label != "" {
+				deployment.SetLabels(map[string]string{providerLabelKey: tc.label})
+			}
+
+			g.Expect(r.getProviderNameFromLabel(deployment)).To(Equal(tc.expectedName))
+		})
+	}
+}
+
+func TestGetProviderKey(t *testing.T) {
+	testCases := []struct {
+		name          string
+		ownerRefs     []metav1.OwnerReference
+		labels        map[string]string
+		annotations   map[string]string
+		expectedKey   types.NamespacedName
+		expectedOwned bool
+	}{
+		{
+			name:          "owned deployment",
+			ownerRefs:     []metav1.OwnerReference{{Kind: "InfrastructureProvider", Name: "docker"}},
+			expectedKey:   types.NamespacedName{Namespace: "capd-system", Name: "docker"},
+			expectedOwned: true,
+		},
+		{
+			name:          "deployment in a target namespace",
+			labels:        map[string]string{providerLabelKey: "infrastructure-docker"},
+			annotations:   map[string]string{operatorv1.ProviderNamespaceAnnotation: "providers"},
+			expectedKey:   types.NamespacedName{Namespace: "providers", Name: "docker"},
+			expectedOwned: true,
+		},
+		{
+			name:        "externally managed deployment",
+			labels:      map[string]string{providerLabelKey: "infrastructure-docker"},
+			expectedKey: types.NamespacedName{Namespace: "capd-system", Name: "docker"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := &GenericProviderHealthCheckReconciler{
+				Provider:    &operatorv1.InfrastructureProvider{},
+				providerGVK: operatorv1.GroupVersion.WithKind("InfrastructureProvider"),
+			}
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "capd-controller-manager",
+					Namespace:       "capd-system",
+					OwnerReferences: tc.ownerRefs,
+					Labels:          tc.labels,
+					Annotations:     tc.annotations,
+				},
+			}
+
+			key, owned := r.getProviderKey(deployment)
+			g.Expect(key).To(Equal(tc.expectedKey))
+			g.Expect(owned).To(Equal(tc.expectedOwned))
+		})
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const (
+	preInstallHookType  = "pre-install"
+	postInstallHookType = "post-install"
+	preDeleteHookType   = "pre-delete"
+
+	// installHookJobHashLength is the number of spec hash characters used in the install hook Job names.
+	installHookJobHashLength = 8
+)
+
+// hookRun describes how the hook Jobs of a type are run and reported in the provider conditions.
+type hookRun struct {
+	hookType      string
+	hooks         []operatorv1.HookSpec
+	condition     clusterv1.ConditionType
+	runningReason string
+	failedReason  string
+
+	// jobSuffix is appended to the Job names, so the hooks are run again when it changes.
+	jobSuffix string
+}
+
+// runPreInstallHooks runs the pre-install hook Jobs one after another and blocks the installation of the provider
+// spec until all of them have completed successfully.
+func (p *phaseReconciler) runPreInstallHooks(ctx context.Context) (reconcile.Result, error) {
+	hooks := p.provider.GetSpec().Hooks
+	if hooks == nil || len(hooks.PreInstall) == 0 {
+		conditions.Delete(p.provider, operatorv1.PreInstallHooksSucceededCondition)
+
+		return reconcile.Result{}, nil
+	}
+
+	return p.runInstallHooks(ctx, hookRun{
+		hookType:      preInstallHookType,
+		hooks:         hooks.PreInstall,
+		condition:     operatorv1.PreInstallHooksSucceededCondition,
+		runningReason: operatorv1.PreInstallHookRunningReason,
+		failedReason:  operatorv1.PreInstallHookFailedReason,
+	})
+}
+
+// runPostInstallHooks runs the post-install hook Jobs one after another once the provider spec is installed,
+// the installation isn't completed until all of them have completed successfully.
+func (p *phaseReconciler) runPostInstallHooks(ctx context.Context) (reconcile.Result, error) {
+	hooks := p.provider.GetSpec().Hooks
+	if hooks == nil || len(hooks.PostInstall) == 0 {
+		conditions.Delete(p.provider, operatorv1.PostInstallHooksSucceededCondition)
+
+		return reconcile.Result{}, nil
+	}
+
+	// The Deployments of a suspended provider are scaled down, so they can't become ready.
+	if !isSuspended(p.provider) {
+		ready, err := deploymentsReady(ctx, p.ctrlClient, p.components.Objs())
+		if err != nil {
+			return reconcile.Result{}, wrapPhaseError(err, operatorv1.PostInstallHookFailedReason, operatorv1.PostInstallHooksSucceededCondition)
+		}
+
+		if !ready {
+			conditions.Set(p.provider, conditions.FalseCondition(operatorv1.PostInstallHooksSucceededCondition, operatorv1.PostInstallHookRunningReason,
+				clusterv1.ConditionSeverityInfo, "Waiting for the provider deployments to become ready"))
+			setWaitingForDeployments(p.provider)
+
+			return reconcile.Result{RequeueAfter: hookRunningRequeueAfter}, nil
+		}
+	}
+
+	return p.runInstallHooks(ctx, hookRun{
+		hookType:      postInstallHookType,
+		hooks:         hooks.PostInstall,
+		condition:     operatorv1.PostInstallHooksSucceededCondition,
+		runningReason: operatorv1.PostInstallHookRunningReason,
+		failedReason:  operatorv1.PostInstallHookFailedReason,
+	})
+}
+
+// runInstallHooks runs install hook Jobs named after the spec hash, so they are run again for every applied spec,
+// and removes the Jobs of the previously applied specs once they have completed.
+func (p *phaseReconciler) runInstallHooks(ctx context.Context, run hookRun) (reconcile.Result, error) {
+	specHash, err := calculateHash(p.provider.GetSpec())
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	run.jobSuffix = specHash[:installHookJobHashLength]
+
+	res, err := p.runHooks(ctx, run)
+	if err != nil || !res.IsZero() {
+		return res, err
+	}
+
+	return reconcile.Result{}, p.deletePreviousHookJobs(ctx, run)
+}
+
+// runPreDeleteHooks runs the pre-delete hook Jobs one after another and blocks the provider deletion
+// until all of them have completed successfully.
+func (p *phaseReconciler) runPreDeleteHooks(ctx context.Context) (reconcile.Result, error) {
+	hooks := p.provider.GetSpec().Hooks
+	if hooks == nil || len(hooks.PreDelete) == 0 {
+		return reconcile.Result{}, nil
+	}
+
+	return p.runHooks(ctx, hookRun{
+		hookType:      preDeleteHookType,
+		hooks:         hooks.PreDelete,
+		condition:     operatorv1.PreDeleteHooksSucceededCondition,
+		runningReason: operatorv1.PreDeleteHookRunningReason,
+		failedReason:  operatorv1.PreDeleteHookFailedReason,
+	})
+}
+
+// runHooks runs the hook Jobs one after another and requeues the provider until all of them have completed successfully.
+func (p *phaseReconciler) runHooks(ctx context.Context, run hookRun) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	for _, hook := range run.hooks {
+		job, err := p.jobFromTemplate(ctx, hook.JobTemplateRef, run.jobName(p.provider, hook.Name), map[string]string{
+			operatorv1.HookJobLabelName: run.hookType,
+		})
+		if err != nil {
+			return reconcile.Result{}, wrapPhaseError(err, run.failedReason, run.condition)
+		}
+
+		current := &batchv1.Job{}
+		if err := p.ctrlClient.Get(ctx, client.ObjectKeyFromObject(job), current); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return reconcile.Result{}, fmt.Errorf("failed to get %s hook job %s: %w", run.hookType, job.Name, err)
+			}
+
+			log.Info("Creating hook job", "type", run.hookType, "hook", hook.Name)
+
+			if err := p.ctrlClient.Create(ctx, job); err != nil {
+				return reconcile.Result{}, wrapPhaseError(err, run.failedReason, run.condition)
+			}
+
+			current = job
+		}
+
+		if failed := jobFailedCondition(current); failed != nil {
+			log.Info("Hook job failed, delete the job to run it again", "type", run.hookType, "hook", hook.Name, "job", current.Name)
+			conditions.Set(p.provider, conditions.FalseCondition(run.condition, run.failedReason,
+				clusterv1.ConditionSeverityError, "Hook %s failed: %s", hook.Name, failed.Message))
+			setWaitingForJob(p.provider, current.Name)
+
+			return reconcile.Result{RequeueAfter: hookFailedRequeueAfter}, nil
+		}
+
+		if !jobCompleted(current) {
+			conditions.Set(p.provider, conditions.FalseCondition(run.condition, run.runningReason,
+				clusterv1.ConditionSeverityInfo, "Waiting for hook %s to complete", hook.Name))
+			setWaitingForJob(p.provider, current.Name)
+
+			return reconcile.Result{RequeueAfter: hookRunningRequeueAfter}, nil
+		}
+	}
+
+	conditions.MarkTrue(p.provider, run.condition)
+
+	return reconcile.Result{}, nil
+}
+
+// deletePreviousHookJobs deletes the hook Jobs of the provider that were created for other Job names.
+func (p *phaseReconciler) deletePreviousHookJobs(ctx context.Context, run hookRun) error {
+	current := map[string]bool{}
+	for _, hook := range run.hooks {
+		current[run.jobName(p.provider, hook.Name)] = true
+	}
+
+	jobList := &batchv1.JobList{}
+	if err := p.ctrlClient.List(ctx, jobList, client.InNamespace(p.provider.GetNamespace()), client.MatchingLabels{
+		operatorv1.HookJobLabelName: run.hookType,
+	}); err != nil {
+		return fmt.Errorf("failed to list %s hook jobs: %w", run.hookType, err)
+	}
+
+	for i := range jobList.Items {
+		job := &jobList.Items[i]
+		if current[job.Name] || !hookJobOwnedBy(job, p.provider) {
+			continue
+		}
+
+		if err := p.ctrlClient.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete %s hook job %s/%s: %w", run.hookType, job.Namespace, job.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// hookJobOwnedBy returns true if the Job was created for a hook of the provider.
+func hookJobOwnedBy(job *batchv1.Job, provider operatorv1.GenericProvider) bool {
+	for _, ref := range job.GetOwnerReferences() {
+		if ref.UID == provider.GetUID() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// jobName returns the name of the Job created for a hook of the run.
+func (run hookRun) jobName(provider operatorv1.GenericProvider, hookName string) string {
+	return boundedJobName(fmt.Sprintf("%s-%s-%s-%s", provider.GetType(), provider.GetName(), run.hookType, hookName), run.jobSuffix)
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestRunPreDeleteHooks(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := "capi-system"
+
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-api",
+			Namespace: namespace,
+		},
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "CoreProvider",
+			APIVersion: "operator.cluster.x-k8s.io/v1alpha2",
+		},
+		Spec: operatorv1.CoreProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				Hooks: &operatorv1.ProviderHooks{
+					PreDelete: []operatorv1.HookSpec{
+						{Name: "first", JobTemplateRef: operatorv1.ConfigmapReference{Name: "hook"}},
+						{Name: "second", JobTemplateRef: operatorv1.ConfigmapReference{Name: "hook"}},
+					},
+				},
+			},
+		},
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "hook",
+			Namespace: namespace,
+		},
+		Data: map[string]string{
+			"job": `apiVersion: batch/v1
+kind: Job
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+      - name: hook
+        image: busybox
+`,
+		},
+	}
+
+	fakeclient := fake.NewClientBuilder().WithObjects(configMap).Build()
+
+	p := &phaseReconciler{
+		ctrlClient: fakeclient,
+		provider:   provider,
+	}
+
+	setJobCondition := func(name string, conditionType batchv1.JobConditionType) {
+		job := &batchv1.Job{}
+		g.Expect(fakeclient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, job)).To(Succeed())
+
+		job.Status.Conditions = []batchv1.JobCondition{{Type: conditionType, Status: corev1.ConditionTrue, Message: "test"}}
+		g.Expect(fakeclient.Status().Update(ctx, job)).To(Succeed())
+	}
+
+	// The first hook is created and the deletion waits for it.
+	res, err := p.runPreDeleteHooks(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.RequeueAfter).To(Equal(hookRunningRequeueAfter))
+	g.Expect(conditions.GetReason(provider, operatorv1.PreDeleteHooksSucceededCondition)).To(Equal(operatorv1.PreDeleteHookRunningReason))
+
+	jobs := &batchv1.JobList{}
+	g.Expect(fakeclient.List(ctx, jobs, client.InNamespace(namespace))).To(Succeed())
+	g.Expect(jobs.Items).To(HaveLen(1))
+	g.Expect(jobs.Items[0].Name).To(Equal("core-cluster-api-pre-delete-first"))
+	g.Expect(jobs.Items[0].Labels).To(HaveKeyWithValue(operatorv1.HookJobLabelName, "pre-delete"))
+
+	// A failed hook blocks the deletion.
+	setJobCondition("core-cluster-api-pre-delete-first", batchv1.JobFailed)
+
+	res, err = p.runPreDeleteHooks(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.RequeueAfter).To(Equal(hookFailedRequeueAfter))
+	g.Expect(conditions.GetReason(provider, operatorv1.PreDeleteHooksSucceededCondition)).To(Equal(operatorv1.PreDeleteHookFailedReason))
+
+	// Once the first hook completes, the second one is started.
+	setJobCondition("core-cluster-api-pre-delete-first", batchv1.JobComplete)
+
+	res, err = p.runPreDeleteHooks(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.RequeueAfter).To(Equal(hookRunningRequeueAfter))
+	g.Expect(fakeclient.List(ctx, jobs, client.InNamespace(namespace))).To(Succeed())
+	g.Expect(jobs.Items).To(HaveLen(2))
+
+	setJobCondition("core-cluster-api-pre-delete-second", batchv1.JobComplete)
+
+	res, err = p.runPreDeleteHooks(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.IsZero()).To(BeTrue())
+	g.Expect(conditions.IsTrue(provider, operatorv1.PreDeleteHooksSucceededCondition)).To(BeTrue())
+}
+
+func TestRunPreInstallHooks(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := "capi-system"
+
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-api",
+			Namespace: namespace,
+			UID:       "provider-uid",
+		},
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "CoreProvider",
+			APIVersion: "operator.cluster.x-k8s.io/v1alpha2",
+		},
+		Spec: operatorv1.CoreProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				Version: "v1.6.0",
+				Hooks: &operatorv1.ProviderHooks{
+					PreInstall: []operatorv1.HookSpec{
+						{Name: "backup", JobTemplateRef: operatorv1.ConfigmapReference{Name: "hook"}},
+					},
+				},
+			},
+		},
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "hook",
+			Namespace: namespace,
+		},
+		Data: map[string]string{
+			"job": `apiVersion: batch/v1
+kind: Job
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+      - name: hook
+        image: busybox
+`,
+		},
+	}
+
+	fakeclient := fake.NewClientBuilder().WithObjects(configMap).Build()
+
+	p := &phaseReconciler{
+		ctrlClient: fakeclient,
+		provider:   provider,
+	}
+
+	completeJobs := func() {
+		jobs := &batchv1.JobList{}
+		g.Expect(fakeclient.List(ctx, jobs, client.InNamespace(namespace))).To(Succeed())
+
+		for i := range jobs.Items {
+			jobs.Items[i].Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+			g.Expect(fakeclient.Status().Update(ctx, &jobs.Items[i])).To(Succeed())
+		}
+	}
+
+	// The installation waits for the hook.
+	res, err := p.runPreInstallHooks(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.RequeueAfter).To(Equal(hookRunningRequeueAfter))
+	g.Expect(conditions.GetReason(provider, operatorv1.PreInstallHooksSucceededCondition)).To(Equal(operatorv1.PreInstallHookRunningReason))
+
+	jobs := &batchv1.JobList{}
+	g.Expect(fakeclient.List(ctx, jobs, client.InNamespace(namespace))).To(Succeed())
+	g.Expect(jobs.Items).To(HaveLen(1))
+	g.Expect(jobs.Items[0].Name).To(HavePrefix("core-cluster-api-pre-install-backup-"))
+	g.Expect(jobs.Items[0].Labels).To(HaveKeyWithValue(operatorv1.HookJobLabelName, "pre-install"))
+
+	// The Job is polled without reconciling the provider again.
+	g.Expect(provider.GetAnnotations()).To(HaveKeyWithValue(waitingForJobAnnotation, jobs.Items[0].Name))
+
+	firstJob := jobs.Items[0].Name
+
+	completeJobs()
+
+	res, err = p.runPreInstallHooks(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.IsZero()).To(BeTrue())
+	g.Expect(conditions.IsTrue(provider, operatorv1.PreInstallHooksSucceededCondition)).To(BeTrue())
+
+	// The hook is run again for a new spec, and the Job of the previous spec is removed once it completes.
+	provider.Spec.Version = "v1.7.0"
+
+	res, err = p.runPreInstallHooks(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.RequeueAfter).To(Equal(hookRunningRequeueAfter))
+
+	completeJobs()
+
+	res, err = p.runPreInstallHooks(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.IsZero()).To(BeTrue())
+
+	g.Expect(fakeclient.List(ctx, jobs, client.InNamespace(namespace))).To(Succeed())
+	g.Expect(jobs.Items).To(HaveLen(1))
+	g.Expect(jobs.Items[0].Name).ToNot(Equal(firstJob))
+
+	// The condition is removed with the hooks.
+	provider.Spec.Hooks = nil
+
+	_, err = p.runPreInstallHooks(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(conditions.Has(provider, operatorv1.PreInstallHooksSucceededCondition)).To(BeFalse())
+}
+
+func TestHookJobName(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "vsphere", Namespace: "capv-system"},
+	}
+
+	preDelete := hookRun{hookType: preDeleteHookType}
+	postInstall := hookRun{hookType: postInstallHookType, jobSuffix: "0123abcd"}
+
+	g.Expect(preDelete.jobName(provider, "cleanup")).To(Equal("infrastructure-vsphere-pre-delete-cleanup"))
+	g.Expect(postInstall.jobName(provider, "check")).To(Equal("infrastructure-vsphere-post-install-check-0123abcd"))
+
+	// Long names are truncated to a valid label value, keeping the spec hash suffix, and stay unique per hook.
+	name := postInstall.jobName(provider, "validate-credentials")
+	g.Expect(validation.IsValidLabelValue(name)).To(BeEmpty())
+	g.Expect(name).To(HavePrefix("infrastructure-vsphere-post-install-validate"))
+	g.Expect(name).To(HaveSuffix("-0123abcd"))
+	g.Expect(postInstall.jobName(provider, "validate-credentialz")).ToNot(Equal(name))
+
+	name = preDelete.jobName(provider, strings.Repeat("cleanup", 10))
+	g.Expect(validation.IsValidLabelValue(name)).To(BeEmpty())
+	g.Expect(name).To(HavePrefix("infrastructure-vsphere-pre-delete-cleanup"))
+}
@@ -0,0 +1,331 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/retry"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const (
+	// dockerHubRegistry is the registry of images without one, its API is served by dockerHubRegistryHost.
+	dockerHubRegistry     = "docker.io"
+	dockerHubRegistryHost = "registry-1.docker.io"
+)
+
+// dockerConfig is the content of a kubernetes.io/dockerconfigjson Secret.
+type dockerConfig struct {
+	Auths map[string]dockerConfigAuth `json:"auths"`
+}
+
+// dockerConfigAuth holds the credentials of a registry in a docker config.
+type dockerConfigAuth struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	Auth          string `json:"auth,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// pinImageDigests replaces the tags of the container images in the provider components with the digests they
+// resolve to, and records the digests in the provider status. Digests already recorded for an image are reused,
+// so the components don't change if its tag is moved.
+func (p *phaseReconciler) pinImageDigests(ctx context.Context) error {
+	status := p.provider.GetStatus()
+
+	if !p.provider.GetSpec().PinImageDigests {
+		if status.ImageDigests != nil {
+			status.ImageDigests = nil
+			p.provider.SetStatus(status)
+		}
+
+		return nil
+	}
+
+	recorded := map[string]string{}
+	for _, imageDigest := range status.ImageDigests {
+		recorded[imageDigest.Image] = imageDigest.Digest
+	}
+
+	digests := map[string]string{}
+	unresolved := []string{}
+
+	objs := p.components.Objs()
+	for i := range objs {
+		if err := forEachContainer(&objs[i], func(container map[string]interface{}) {
+			image, ok := container["image"].(string)
+			if !ok {
+				return
+			}
+
+			if _, found := digests[image]; found {
+				return
+			}
+
+			if _, digest, found := strings.Cut(image, "@"); found {
+				digests[image] = digest
+			} else if digest, found := recorded[image]; found {
+				digests[image] = digest
+			} else {
+				digests[image] = ""
+				unresolved = append(unresolved, image)
+			}
+		}); err != nil {
+			return err
+		}
+	}
+
+	if len(unresolved) > 0 {
+		resolved, err := p.resolveImageDigests(ctx, unresolved)
+		if err != nil {
+			return err
+		}
+
+		for image, digest := range resolved {
+			digests[image] = digest
+		}
+	}
+
+	if err := repository.AlterComponents(p.components, pinImageDigestsFn(digests)); err != nil {
+		return err
+	}
+
+	status.ImageDigests = make([]operatorv1.ImageDigest, 0, len(digests))
+	for image, digest := range digests {
+		status.ImageDigests = append(status.ImageDigests, operatorv1.ImageDigest{Image: image, Digest: digest})
+	}
+
+	sort.Slice(status.ImageDigests, func(i, j int) bool {
+		return status.ImageDigests[i].Image < status.ImageDigests[j].Image
+	})
+
+	p.provider.SetStatus(status)
+
+	return nil
+}
+
+// pinImageDigestsFn appends the digests to the container images of the workloads in the provider components.
+// Images that already have a digest are not changed.
+func pinImageDigestsFn(digests map[string]string) func(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+	return func(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+		for i := range objs {
+			if err := forEachContainer(&objs[i], func(container map[string]interface{}) {
+				image, ok := container["image"].(string)
+				if !ok || strings.Contains(image, "@") {
+					return
+				}
+
+				if digest := digests[image]; digest != "" {
+					container["image"] = image + "@" + digest
+				}
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		return objs, nil
+	}
+}
+
+// resolveImageDigests returns the digests of the manifests or indexes the image tags point to. Registries are
+// accessed with the HTTP client of the fetch config and the credentials of the deployment image pull secrets.
+func (p *phaseReconciler) resolveImageDigests(ctx context.Context, images []string) (map[string]string, error) {
+	httpClient, err := p.fetchHTTPClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client for provider %q: %w", p.provider.GetName(), err)
+	}
+
+	credentials, err := p.imagePullCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	digests := make(map[string]string, len(images))
+
+	for _, image := range images {
+		digest, err := resolveImageDigest(ctx, image, credentials, httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the digest of image %q of provider %q: %w", image, p.provider.GetName(), err)
+		}
+
+		digests[image] = digest
+	}
+
+	return digests, nil
+}
+
+// imagePullCredentials returns the registry credentials of the image pull secrets of the provider deployment,
+// by registry host. The secrets are read from the target namespace, like the kubelet does for the provider pods.
+func (p *phaseReconciler) imagePullCredentials(ctx context.Context) (map[string]auth.Credential, error) {
+	credentials := map[string]auth.Credential{}
+
+	deployment := p.provider.GetSpec().Deployment
+	if deployment == nil {
+		return credentials, nil
+	}
+
+	for _, ref := range deployment.ImagePullSecrets {
+		secret := &corev1.Secret{}
+
+		key := types.NamespacedName{Namespace: targetNamespace(p.provider), Name: ref.Name}
+		if err := p.ctrlClient.Get(ctx, key, secret); err != nil {
+			return nil, fmt.Errorf("failed to get image pull secret %s: %w", key, err)
+		}
+
+		secretCredentials, err := dockerConfigCredentials(secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse image pull secret %s: %w", key, err)
+		}
+
+		// The first secret with credentials for a registry is used, like the kubelet does.
+		for registry, credential := range secretCredentials {
+			if _, found := credentials[registry]; !found {
+				credentials[registry] = credential
+			}
+		}
+	}
+
+	return credentials, nil
+}
+
+// dockerConfigCredentials returns the registry credentials of a kubernetes.io/dockerconfigjson Secret, by registry
+// host. Secrets of other types have no credentials.
+func dockerConfigCredentials(secret *corev1.Secret) (map[string]auth.Credential, error) {
+	credentials := map[string]auth.Credential{}
+
+	data, ok := secret.Data[corev1.DockerConfigJsonKey]
+	if !ok {
+		return credentials, nil
+	}
+
+	config := dockerConfig{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	for registry, registryAuth := range config.Auths {
+		credential, err := registryAuth.credential()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse credentials of registry %q: %w", registry, err)
+		}
+
+		credentials[normalizeDockerConfigRegistry(registry)] = credential
+	}
+
+	return credentials, nil
+}
+
+// credential returns the registry credential, the username and password are read from the auth field if set.
+func (a dockerConfigAuth) credential() (auth.Credential, error) {
+	credential := auth.Credential{Username: a.Username, Password: a.Password, RefreshToken: a.IdentityToken}
+
+	if a.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+		if err != nil {
+			return auth.Credential{}, err
+		}
+
+		username, password, found := strings.Cut(string(decoded), ":")
+		if !found {
+			return auth.Credential{}, fmt.Errorf("auth must be in the username:password format")
+		}
+
+		credential.Username, credential.Password = username, password
+	}
+
+	return credential, nil
+}
+
+// normalizeDockerConfigRegistry returns the registry host of a docker config key, which can be a URL, e.g.
+// "https://index.docker.io/v1/".
+func normalizeDockerConfigRegistry(registry string) string {
+	registry = strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://")
+	registry, _, _ = strings.Cut(registry, "/")
+
+	if registry == "index.docker.io" {
+		return dockerHubRegistry
+	}
+
+	return registry
+}
+
+// resolveImageDigest returns the digest of the manifest or index the tag of the image points to.
+func resolveImageDigest(ctx context.Context, image string, credentials map[string]auth.Credential, httpClient *http.Client) (string, error) {
+	name, tag := splitImageTag(image)
+
+	repo, err := remote.NewRepository(normalizeImageName(name))
+	if err != nil {
+		return "", err
+	}
+
+	credential := credentials[repo.Reference.Registry]
+
+	if repo.Reference.Registry == dockerHubRegistry {
+		repo.Reference.Registry = dockerHubRegistryHost
+	}
+
+	repo.Client = &auth.Client{
+		Client:     &http.Client{Transport: retry.NewTransport(httpClient.Transport)},
+		Cache:      auth.NewCache(),
+		Credential: auth.StaticCredential(repo.Reference.Registry, credential),
+	}
+
+	descriptor, err := repo.Resolve(ctx, tag)
+	if err != nil {
+		return "", err
+	}
+
+	return descriptor.Digest.String(), nil
+}
+
+// splitImageTag returns the name and the tag of the image, which is "latest" if the image has none.
+func splitImageTag(image string) (string, string) {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+
+	return image, "latest"
+}
+
+// normalizeImageName returns the fully qualified name of the image, adding the Docker Hub registry and
+// library namespace to short names like the container runtimes do, e.g. "busybox" is "docker.io/library/busybox".
+func normalizeImageName(name string) string {
+	first, _, found := strings.Cut(name, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return name
+	}
+
+	if !found {
+		name = "library/" + name
+	}
+
+	return dockerHubRegistry + "/" + name
+}
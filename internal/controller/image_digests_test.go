@@ -0,0 +1,232 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/yamlprocessor"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestNormalizeImageName(t *testing.T) {
+	tests := []struct {
+		image    string
+		wantName string
+		wantTag  string
+	}{
+		{image: "registry.k8s.io/cluster-api/cluster-api-controller:v1.5.3", wantName: "registry.k8s.io/cluster-api/cluster-api-controller", wantTag: "v1.5.3"},
+		{image: "localhost:5000/capa-manager", wantName: "localhost:5000/capa-manager", wantTag: "latest"},
+		{image: "localhost/capa-manager:dev", wantName: "localhost/capa-manager", wantTag: "dev"},
+		{image: "kindest/node:v1.27.3", wantName: "docker.io/kindest/node", wantTag: "v1.27.3"},
+		{image: "busybox", wantName: "docker.io/library/busybox", wantTag: "latest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			g := NewWithT(t)
+
+			name, tag := splitImageTag(tt.image)
+			g.Expect(normalizeImageName(name)).To(Equal(tt.wantName))
+			g.Expect(tag).To(Equal(tt.wantTag))
+		})
+	}
+}
+
+func TestPinImageDigests(t *testing.T) {
+	g := NewWithT(t)
+
+	registry := newFakeOCIRegistry(g, []string{"v1.0.0"}, map[string]string{}, "user", "password")
+	defer registry.Close()
+
+	host := strings.TrimPrefix(registry.URL, "https://")
+	image := host + "/org/provider:v1.0.0"
+	pinnedImage := "registry.k8s.io/kube-rbac-proxy@sha256:" + strings.Repeat("a", 64)
+
+	components := fmt.Sprintf(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: capa-controller-manager
+  namespace: capa-system
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: %[1]s
+      containers:
+      - name: manager
+        image: %[1]s
+      - name: kube-rbac-proxy
+        image: %[2]s
+`, image, pinnedImage)
+
+	// The fake registry certificate is trusted with a CA bundle, like a registry using a private CA.
+	caBundle := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-ca", Namespace: "capa-system"},
+		Data: map[string]string{
+			caBundleDefaultKey: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: registry.Certificate().Raw})),
+		},
+	}
+
+	pullSecret := func(password string) *corev1.Secret {
+		auth := base64.StdEncoding.EncodeToString([]byte("user:" + password))
+
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry-credentials", Namespace: "capa-system"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{
+				corev1.DockerConfigJsonKey: []byte(fmt.Sprintf(`{"auths": {"https://%s/v1/": {"auth": %q}}}`, host, auth)),
+			},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		pinImageDigests bool
+		secret          *corev1.Secret
+		recorded        []operatorv1.ImageDigest
+		wantImage       string
+		wantErr         bool
+	}{
+		{
+			name:     "disabled",
+			recorded: []operatorv1.ImageDigest{{Image: image, Digest: "sha256:" + strings.Repeat("b", 64)}},
+		},
+		{
+			name:            "resolved with pull secret credentials",
+			pinImageDigests: true,
+			secret:          pullSecret("password"),
+		},
+		{
+			name:            "recorded digest reused",
+			pinImageDigests: true,
+			recorded:        []operatorv1.ImageDigest{{Image: image, Digest: "sha256:" + strings.Repeat("b", 64)}},
+			wantImage:       image + "@sha256:" + strings.Repeat("b", 64),
+		},
+		{
+			name:            "invalid credentials",
+			pinImageDigests: true,
+			secret:          pullSecret("wrong"),
+			wantErr:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			objs := []client.Object{caBundle}
+			deployment := &operatorv1.DeploymentSpec{}
+
+			if tt.secret != nil {
+				objs = append(objs, tt.secret)
+				deployment.ImagePullSecrets = []corev1.LocalObjectReference{{Name: tt.secret.Name}}
+			}
+
+			provider := &operatorv1.InfrastructureProvider{
+				ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "capa-system"},
+				Spec: operatorv1.InfrastructureProviderSpec{
+					ProviderSpec: operatorv1.ProviderSpec{
+						Version:    "v2.3.0",
+						Deployment: deployment,
+						FetchConfig: &operatorv1.FetchConfiguration{
+							OCI:         host + "/org/provider",
+							CABundleRef: &operatorv1.CABundleReference{Name: caBundle.Name},
+						},
+						PinImageDigests: tt.pinImageDigests,
+					},
+				},
+				Status: operatorv1.InfrastructureProviderStatus{
+					ProviderStatus: operatorv1.ProviderStatus{ImageDigests: tt.recorded},
+				},
+			}
+
+			configClient, err := configclient.New(context.TODO(), "", configclient.InjectReader(configclient.NewMemoryReader()))
+			g.Expect(err).ToNot(HaveOccurred())
+
+			providerConfig := configclient.NewProvider("aws", "", "InfrastructureProvider")
+
+			p := &phaseReconciler{
+				provider:   provider,
+				ctrlClient: fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(objs...).Build(),
+			}
+
+			p.components, err = repository.NewComponents(repository.ComponentsInput{
+				Provider:     providerConfig,
+				ConfigClient: configClient,
+				Processor:    yamlprocessor.NewSimpleProcessor(),
+				RawYaml:      []byte(components),
+				Options:      repository.ComponentsOptions{TargetNamespace: "capa-system", Version: "v2.3.0"},
+			})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			err = p.pinImageDigests(context.TODO())
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+
+			images := []string{}
+			g.Expect(forEachContainer(&p.components.Objs()[0], func(container map[string]interface{}) {
+				containerImage, _ := container["image"].(string)
+				images = append(images, containerImage)
+			})).To(Succeed())
+
+			g.Expect(images).To(HaveLen(3))
+			g.Expect(images[2]).To(Equal(pinnedImage))
+
+			if !tt.pinImageDigests {
+				g.Expect(images[:2]).To(HaveEach(Equal(image)))
+				g.Expect(provider.Status.ImageDigests).To(BeNil())
+
+				return
+			}
+
+			g.Expect(images[0]).To(Equal(images[1]))
+
+			if tt.wantImage != "" {
+				g.Expect(images[0]).To(Equal(tt.wantImage))
+			} else {
+				g.Expect(images[0]).To(MatchRegexp(`^` + regexp.QuoteMeta(image) + `@sha256:[a-f0-9]{64}$`))
+			}
+
+			_, digest, _ := strings.Cut(images[0], "@")
+			g.Expect(provider.Status.ImageDigests).To(Equal([]operatorv1.ImageDigest{
+				{Image: image, Digest: digest},
+				{Image: pinnedImage, Digest: "sha256:" + strings.Repeat("a", 64)},
+			}))
+		})
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ImageRewriteRule replaces the registry prefix of container images in the provider components.
+type ImageRewriteRule struct {
+	// From is the image prefix to replace, e.g. "registry.k8s.io".
+	From string
+	// To is the prefix used instead, e.g. "registry.corp.local/k8s".
+	To string
+}
+
+// ParseImageRewriteRules parses image rewrite rules in the "<from>=<to>" format.
+func ParseImageRewriteRules(rules []string) ([]ImageRewriteRule, error) {
+	result := make([]ImageRewriteRule, 0, len(rules))
+
+	for _, rule := range rules {
+		from, to, found := strings.Cut(rule, "=")
+
+		from = strings.TrimSuffix(strings.TrimSpace(from), "/")
+		to = strings.TrimSuffix(strings.TrimSpace(to), "/")
+
+		if !found || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid image rewrite rule %q, expected format is <from>=<to>", rule)
+		}
+
+		result = append(result, ImageRewriteRule{From: from, To: to})
+	}
+
+	return result, nil
+}
+
+// rewriteImage applies the first matching rule to the image. A rule matches if the image
+// is the rule prefix itself or starts with the prefix followed by a "/".
+func rewriteImage(rules []ImageRewriteRule, image string) string {
+	for _, rule := range rules {
+		if image == rule.From || strings.HasPrefix(image, rule.From+"/") {
+			return rule.To + strings.TrimPrefix(image, rule.From)
+		}
+	}
+
+	return image
+}
+
+// podSpecPaths contains the path to the pod spec for the workload kinds that have one.
+var podSpecPaths = map[string][]string{
+	"Pod":         {"spec"},
+	"Deployment":  {"spec", "template", "spec"},
+	"DaemonSet":   {"spec", "template", "spec"},
+	"StatefulSet": {"spec", "template", "spec"},
+	"ReplicaSet":  {"spec", "template", "spec"},
+	"Job":         {"spec", "template", "spec"},
+	"CronJob":     {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// forEachContainer calls fn for every init container and container of the workload, objects without a pod spec are ignored.
+func forEachContainer(obj *unstructured.Unstructured, fn func(container map[string]interface{})) error {
+	path, ok := podSpecPaths[obj.GetKind()]
+	if !ok {
+		return nil
+	}
+
+	for _, field := range []string{"initContainers", "containers"} {
+		containersPath := append(append([]string{}, path...), field)
+
+		containers, found, err := unstructured.NestedSlice(obj.Object, containersPath...)
+		if err != nil {
+			return fmt.Errorf("failed to get %s of %s %s: %w", field, obj.GetKind(), obj.GetName(), err)
+		}
+
+		if !found {
+			continue
+		}
+
+		for i := range containers {
+			if container, ok := containers[i].(map[string]interface{}); ok {
+				fn(container)
+			}
+		}
+
+		if err := unstructured.SetNestedSlice(obj.Object, containers, containersPath...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rewriteImagesFn applies image rewrite rules to all containers of the workloads in the provider components.
+func rewriteImagesFn(rules []ImageRewriteRule) func(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+	return func(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+		for i := range objs {
+			if err := forEachContainer(&objs[i], func(container map[string]interface{}) {
+				if image, ok := container["image"].(string); ok {
+					container["image"] = rewriteImage(rules, image)
+				}
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		return objs, nil
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseImageRewriteRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []string
+		want    []ImageRewriteRule
+		wantErr bool
+	}{
+		{
+			name:  "no rules",
+			rules: []string{},
+			want:  []ImageRewriteRule{},
+		},
+		{
+			name:  "valid rules",
+			rules: []string{"registry.k8s.io=registry.corp.local/k8s", " gcr.io/ = registry.corp.local/gcr/ "},
+			want: []ImageRewriteRule{
+				{From: "registry.k8s.io", To: "registry.corp.local/k8s"},
+				{From: "gcr.io", To: "registry.corp.local/gcr"},
+			},
+		},
+		{
+			name:    "missing separator",
+			rules:   []string{"registry.k8s.io"},
+			wantErr: true,
+		},
+		{
+			name:    "empty target",
+			rules:   []string{"registry.k8s.io="},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, err := ParseImageRewriteRules(tt.rules)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestRewriteImages(t *testing.T) {
+	g := NewWithT(t)
+
+	rules := []ImageRewriteRule{
+		{From: "registry.k8s.io", To: "registry.corp.local/k8s"},
+		{From: "gcr.io/k8s-staging", To: "registry.corp.local/staging"},
+	}
+
+	container := func(name, image string) interface{} {
+		return map[string]interface{}{"name": name, "image": image}
+	}
+
+	objs := []unstructured.Unstructured{
+		{
+			Object: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "manager"},
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"initContainers": []interface{}{container("init", "gcr.io/k8s-staging/init:v1")},
+							"containers": []interface{}{
+								container("manager", "registry.k8s.io/cluster-api/cluster-api-controller:v1.5.0"),
+								container("other", "registry.k8s.io.example.com/other:v1"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Object: map[string]interface{}{
+				"apiVersion": "batch/v1",
+				"kind":       "CronJob",
+				"metadata":   map[string]interface{}{"name": "cleanup"},
+				"spec": map[string]interface{}{
+					"jobTemplate": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"template": map[string]interface{}{
+								"spec": map[string]interface{}{
+									"containers": []interface{}{container("cleanup", "registry.k8s.io/kubectl:v1.28.0")},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "config"},
+				"data":       map[string]interface{}{"image": "registry.k8s.io/untouched:v1"},
+			},
+		},
+	}
+
+	got, err := rewriteImagesFn(rules)(objs)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	initContainers, _, _ := unstructured.NestedSlice(got[0].Object, "spec", "template", "spec", "initContainers")
+	g.Expect(initContainers).To(Equal([]interface{}{container("init", "registry.corp.local/staging/init:v1")}))
+
+	containers, _, _ := unstructured.NestedSlice(got[0].Object, "spec", "template", "spec", "containers")
+	g.Expect(containers).To(Equal([]interface{}{
+		container("manager", "registry.corp.local/k8s/cluster-api/cluster-api-controller:v1.5.0"),
+		container("other", "registry.k8s.io.example.com/other:v1"),
+	}))
+
+	cronJobContainers, _, _ := unstructured.NestedSlice(got[1].Object, "spec", "jobTemplate", "spec", "template", "spec", "containers")
+	g.Expect(cronJobContainers).To(Equal([]interface{}{container("cleanup", "registry.corp.local/k8s/kubectl:v1.28.0")}))
+
+	data, _, _ := unstructured.NestedString(got[2].Object, "data", "image")
+	g.Expect(data).To(Equal("registry.k8s.io/untouched:v1"))
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const certManagerGroup = "cert-manager.io"
+
+var conversionServicePath = []string{"spec", "conversion", "webhook", "clientConfig", "service"}
+
+// isCRDsOnly returns true if only the CRDs of the provider are installed.
+func isCRDsOnly(provider operatorv1.GenericProvider) bool {
+	return provider.GetSpec().InstallMode == operatorv1.InstallModeCRDsOnly
+}
+
+// crdsOnlyFn drops all provider components except for the namespaces, the CRDs, the Services of the CRD
+// conversion webhooks and the cert-manager resources providing their certificates.
+func crdsOnlyFn() func(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+	return func(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+		conversionServices := map[client.ObjectKey]bool{}
+
+		for i := range objs {
+			if objs[i].GetKind() != customResourceDefinitionKind {
+				continue
+			}
+
+			name, found, err := unstructured.NestedString(objs[i].Object, append(conversionServicePath, "name")...)
+			if err != nil {
+				return nil, err
+			}
+
+			if !found {
+				continue
+			}
+
+			namespace, _, err := unstructured.NestedString(objs[i].Object, append(conversionServicePath, "namespace")...)
+			if err != nil {
+				return nil, err
+			}
+
+			conversionServices[client.ObjectKey{Namespace: namespace, Name: name}] = true
+		}
+
+		crdObjs := []unstructured.Unstructured{}
+
+		for i := range objs {
+			if installedWithCRDs(&objs[i], conversionServices) {
+				crdObjs = append(crdObjs, objs[i])
+			}
+		}
+
+		return crdObjs, nil
+	}
+}
+
+// installedWithCRDs returns true if the object is needed to serve the provider CRDs.
+func installedWithCRDs(obj *unstructured.Unstructured, conversionServices map[client.ObjectKey]bool) bool {
+	switch obj.GetKind() {
+	case customResourceDefinitionKind, namespaceKind:
+		return true
+	case serviceKind:
+		return conversionServices[client.ObjectKeyFromObject(obj)]
+	default:
+		return obj.GroupVersionKind().Group == certManagerGroup
+	}
+}
+
+// crdsPending returns true while the CRDs of a provider installing only its CRDs are waited for.
+func crdsPending(provider operatorv1.GenericProvider) bool {
+	return isCRDsOnly(provider) && !conditions.IsTrue(provider, clusterv1.ReadyCondition)
+}
+
+// reportCRDsReady reports a provider installing only its CRDs as ready once the CRDs are established. Such a
+// provider has no Deployments, so its Ready condition isn't reported by the health check.
+func (p *phaseReconciler) reportCRDsReady(ctx context.Context) (reconcile.Result, error) {
+	if !isCRDsOnly(p.provider) {
+		return reconcile.Result{}, nil
+	}
+
+	established, err := crdsEstablished(ctx, p.ctrlClient, p.components.Objs())
+	if err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.WaitingForCRDsReason, clusterv1.ReadyCondition)
+	}
+
+	if !established {
+		conditions.MarkFalse(p.provider, clusterv1.ReadyCondition, operatorv1.WaitingForCRDsReason, clusterv1.ConditionSeverityInfo,
+			"Waiting for the CRDs to be established")
+
+		return reconcile.Result{RequeueAfter: crdsEstablishedRequeueAfter}, nil
+	}
+
+	conditions.MarkTrue(p.provider, clusterv1.ReadyCondition)
+
+	return reconcile.Result{}, nil
+}
+
+// crdsWait returns how long to wait before checking the CRDs of a provider installing only its CRDs again, without
+// reconciling the provider. The CRDs are read from the inventory, and zero is returned once they are established.
+func (p *phaseReconciler) crdsWait(ctx context.Context) (time.Duration, error) {
+	entries, err := p.readInventory(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	crds := []unstructured.Unstructured{}
+
+	for _, entry := range entries {
+		if entry.Group != crdGVK.Group || entry.Kind != customResourceDefinitionKind {
+			continue
+		}
+
+		crd := unstructured.Unstructured{}
+		crd.SetGroupVersionKind(crdGVK)
+		crd.SetName(entry.Name)
+		crds = append(crds, crd)
+	}
+
+	established, err := crdsEstablished(ctx, p.ctrlClient, crds)
+	if err != nil || established {
+		return 0, err
+	}
+
+	return crdsEstablishedRequeueAfter, nil
+}
+
+// crdsEstablished returns true if all the CRDs of the components are established.
+func crdsEstablished(ctx context.Context, c client.Client, objs []unstructured.Unstructured) (bool, error) {
+	for i := range objs {
+		if objs[i].GetKind() != customResourceDefinitionKind {
+			continue
+		}
+
+		crd := &unstructured.Unstructured{}
+		crd.SetGroupVersionKind(crdGVK)
+
+		if err := c.Get(ctx, client.ObjectKey{Name: objs[i].GetName()}, crd); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+
+			return false, fmt.Errorf("failed to get CustomResourceDefinition %s: %w", objs[i].GetName(), err)
+		}
+
+		if !crdEstablished(crd) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func crdEstablished(crd *unstructured.Unstructured) bool {
+	crdConditions, _, _ := unstructured.NestedSlice(crd.Object, "status", "conditions")
+
+	for _, c := range crdConditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == "Established" {
+			return condition["status"] == string(corev1.ConditionTrue)
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestCRDsOnly(t *testing.T) {
+	newObj := func(apiVersion, kind, namespace, name string) unstructured.Unstructured {
+		obj := unstructured.Unstructured{}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+
+		return obj
+	}
+
+	crd := newObj("apiextensions.k8s.io/v1", customResourceDefinitionKind, "", "clusters.cluster.x-k8s.io")
+	crd.Object["spec"] = map[string]interface{}{
+		"conversion": map[string]interface{}{
+			"strategy": "Webhook",
+			"webhook": map[string]interface{}{
+				"clientConfig": map[string]interface{}{
+					"service": map[string]interface{}{
+						"namespace": "capi-system",
+						"name":      "capi-webhook-service",
+						"path":      "/convert",
+						"port":      int64(443),
+					},
+				},
+			},
+		},
+	}
+
+	objs := []unstructured.Unstructured{
+		newObj("v1", namespaceKind, "", "capi-system"),
+		crd,
+		newObj("apiextensions.k8s.io/v1", customResourceDefinitionKind, "", "machines.cluster.x-k8s.io"),
+		newObj("v1", serviceKind, "capi-system", "capi-webhook-service"),
+		newObj("v1", serviceKind, "capi-system", "capi-metrics-service"),
+		newObj("cert-manager.io/v1", "Certificate", "capi-system", "capi-serving-cert"),
+		newObj("cert-manager.io/v1", "Issuer", "capi-system", "capi-selfsigned-issuer"),
+		newObj("apps/v1", deploymentKind, "capi-system", "capi-controller-manager"),
+		newObj("v1", "ServiceAccount", "capi-system", "capi-manager"),
+		newObj("rbac.authorization.k8s.io/v1", "ClusterRole", "", "capi-manager-role"),
+		newObj("admissionregistration.k8s.io/v1", "ValidatingWebhookConfiguration", "", "capi-validating-webhook-configuration"),
+	}
+
+	g := NewWithT(t)
+
+	got, err := crdsOnlyFn()(objs)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	names := []string{}
+	for _, obj := range got {
+		names = append(names, obj.GetKind()+"/"+obj.GetName())
+	}
+
+	g.Expect(names).To(Equal([]string{
+		"Namespace/capi-system",
+		"CustomResourceDefinition/clusters.cluster.x-k8s.io",
+		"CustomResourceDefinition/machines.cluster.x-k8s.io",
+		"Service/capi-webhook-service",
+		"Certificate/capi-serving-cert",
+		"Issuer/capi-selfsigned-issuer",
+	}))
+}
+
+func TestReportCRDsReady(t *testing.T) {
+	newCRD := func(name string, established apiextensionsv1.ConditionStatus) *apiextensionsv1.CustomResourceDefinition {
+		return &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: apiextensionsv1.CustomResourceDefinitionStatus{
+				Conditions: []apiextensionsv1.CustomResourceDefinitionCondition{
+					{Type: apiextensionsv1.Established, Status: established},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		installMode operatorv1.InstallMode
+		crds        []client.Object
+		wantReady   *bool
+		wantRequeue bool
+	}{
+		{
+			name:        "established CRDs",
+			installMode: operatorv1.InstallModeCRDsOnly,
+			crds: []client.Object{
+				newCRD("clusters.cluster.x-k8s.io", apiextensionsv1.ConditionTrue),
+				newCRD("machines.cluster.x-k8s.io", apiextensionsv1.ConditionTrue),
+			},
+			wantReady: pointer.Bool(true),
+		},
+		{
+			name:        "CRD not established",
+			installMode: operatorv1.InstallModeCRDsOnly,
+			crds: []client.Object{
+				newCRD("clusters.cluster.x-k8s.io", apiextensionsv1.ConditionTrue),
+				newCRD("machines.cluster.x-k8s.io", apiextensionsv1.ConditionFalse),
+			},
+			wantReady:   pointer.Bool(false),
+			wantRequeue: true,
+		},
+		{
+			name:        "CRD not found",
+			installMode: operatorv1.InstallModeCRDsOnly,
+			crds: []client.Object{
+				newCRD("clusters.cluster.x-k8s.io", apiextensionsv1.ConditionTrue),
+			},
+			wantReady:   pointer.Bool(false),
+			wantRequeue: true,
+		},
+		{
+			name:        "full install mode",
+			installMode: operatorv1.InstallModeFull,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			scheme := setupScheme()
+			utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+
+			provider := &operatorv1.CoreProvider{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "capi-system"},
+				Spec: operatorv1.CoreProviderSpec{
+					ProviderSpec: operatorv1.ProviderSpec{Version: "v1.6.0", InstallMode: tc.installMode},
+				},
+			}
+
+			crds := []unstructured.Unstructured{}
+
+			for _, name := range []string{"clusters.cluster.x-k8s.io", "machines.cluster.x-k8s.io"} {
+				crd := unstructured.Unstructured{}
+				crd.SetGroupVersionKind(crdGVK)
+				crd.SetName(name)
+				crds = append(crds, crd)
+			}
+
+			p := &phaseReconciler{
+				ctrlClient: fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.crds...).Build(),
+				provider:   provider,
+				components: objsComponents{objs: crds},
+			}
+
+			res, err := p.reportCRDsReady(context.TODO())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(res.RequeueAfter > 0).To(Equal(tc.wantRequeue))
+
+			if tc.wantReady == nil {
+				g.Expect(conditions.Has(provider, clusterv1.ReadyCondition)).To(BeFalse())
+
+				return
+			}
+
+			g.Expect(conditions.IsTrue(provider, clusterv1.ReadyCondition)).To(Equal(*tc.wantReady))
+			g.Expect(crdsPending(provider)).To(Equal(!*tc.wantReady))
+
+			// The CRDs are checked from the inventory while the provider isn't reconciled again.
+			g.Expect(p.writeInventory(context.TODO(), crds)).To(Succeed())
+
+			wait, err := p.readinessWait(context.TODO())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(wait > 0).To(Equal(tc.wantRequeue))
+		})
+	}
+}
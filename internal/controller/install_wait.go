@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// installedAtAnnotation is the time a provider was installed, until its Deployments are ready.
+const installedAtAnnotation = "operator.cluster.x-k8s.io/installed-at"
+
+// installWaitEnabled returns true if the Deployments of a newly installed provider are waited for.
+func installWaitEnabled(provider operatorv1.GenericProvider) bool {
+	spec := provider.GetSpec()
+
+	return spec.InstallWaitTimeout != nil && spec.InstallWaitTimeout.Duration > 0 && !spec.SkipWaitForReadiness
+}
+
+// waitingForInstall returns true while the Deployments of a newly installed provider are waited for.
+func waitingForInstall(provider operatorv1.GenericProvider) bool {
+	_, ok := provider.GetAnnotations()[installedAtAnnotation]
+
+	return ok
+}
+
+// setInstalledAt records the time the provider was installed, so its Deployments are waited for.
+func setInstalledAt(provider operatorv1.GenericProvider) {
+	annotations := provider.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[installedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	provider.SetAnnotations(annotations)
+}
+
+// clearInstalledAt stops waiting for the Deployments of a newly installed provider.
+func clearInstalledAt(provider operatorv1.GenericProvider) {
+	annotations := provider.GetAnnotations()
+	delete(annotations, installedAtAnnotation)
+	provider.SetAnnotations(annotations)
+}
+
+// waitForInstall reports a newly installed provider as installed once its Deployments are ready, and the install
+// as timed out if they don't within the install wait timeout. The reconciliation isn't failed in either case,
+// the provider is only requeued until the Deployments are ready or the timeout expires.
+func (p *phaseReconciler) waitForInstall(ctx context.Context) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if !waitingForInstall(p.provider) {
+		return reconcile.Result{}, nil
+	}
+
+	// The Deployments of a suspended provider are scaled down, so they can't become ready.
+	if !installWaitEnabled(p.provider) || isSuspended(p.provider) {
+		clearInstalledAt(p.provider)
+		conditions.MarkTrue(p.provider, operatorv1.ProviderInstalledCondition)
+
+		return reconcile.Result{}, nil
+	}
+
+	ready, err := deploymentsReady(ctx, p.ctrlClient, p.components.Objs())
+	if err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.WaitingForDeploymentsReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if ready {
+		log.Info("Installed provider deployments are ready")
+		clearInstalledAt(p.provider)
+		conditions.MarkTrue(p.provider, operatorv1.ProviderInstalledCondition)
+
+		return reconcile.Result{}, nil
+	}
+
+	timeout := p.provider.GetSpec().InstallWaitTimeout.Duration
+
+	// The deadline is restarted if the install time can't be parsed.
+	installedAt, err := time.Parse(time.RFC3339, p.provider.GetAnnotations()[installedAtAnnotation])
+	if err != nil {
+		setInstalledAt(p.provider)
+
+		return reconcile.Result{RequeueAfter: upgradeReadyCheckInterval}, nil
+	}
+
+	if remaining := timeout - time.Since(installedAt); remaining > 0 {
+		log.Info("Waiting for the installed provider deployments to become ready", "timeout", remaining.Round(time.Second))
+
+		return reconcile.Result{RequeueAfter: earliestRequeueAfter(remaining, upgradeReadyCheckInterval)}, nil
+	}
+
+	log.Info("Installed provider deployments did not become ready within the install wait timeout", "timeout", timeout)
+	clearInstalledAt(p.provider)
+	conditions.MarkFalse(p.provider, operatorv1.ProviderInstalledCondition, operatorv1.InstallTimedOutReason, clusterv1.ConditionSeverityWarning,
+		"Deployments did not become ready within %s after the installation, see the Ready condition", timeout)
+
+	return reconcile.Result{}, nil
+}
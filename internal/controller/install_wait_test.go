@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// objsComponents are provider components only listing their objects.
+type objsComponents struct {
+	repository.Components
+
+	objs []unstructured.Unstructured
+}
+
+func (c objsComponents) Objs() []unstructured.Unstructured {
+	return c.objs
+}
+
+func TestWaitForInstall(t *testing.T) {
+	g := NewWithT(t)
+
+	deployment := unstructured.Unstructured{}
+	deployment.SetKind(deploymentKind)
+	deployment.SetNamespace("capi-system")
+	deployment.SetName("capi-controller-manager")
+
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "capi-system"},
+		Spec: operatorv1.CoreProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				Version:            "v1.6.0",
+				InstallWaitTimeout: &metav1.Duration{Duration: 5 * time.Minute},
+			},
+		},
+	}
+
+	fakeclient := fake.NewClientBuilder().WithObjects(
+		newTestDeployment("capi-controller-manager", 1, 1, 0, corev1.ConditionFalse),
+	).Build()
+
+	p := &phaseReconciler{
+		ctrlClient: fakeclient,
+		provider:   provider,
+		components: objsComponents{objs: []unstructured.Unstructured{deployment}},
+	}
+
+	// Providers are reported as installed right away unless they were installed with a wait timeout.
+	res, err := p.waitForInstall(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res.IsZero()).To(BeTrue())
+
+	// The provider is requeued until its deployments are ready.
+	setInstalledAt(provider)
+
+	res, err = p.waitForInstall(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res.RequeueAfter).To(Equal(upgradeReadyCheckInterval))
+	g.Expect(waitingForInstall(provider)).To(BeTrue())
+
+	// The install is reported as timed out once the timeout expires.
+	provider.Annotations[installedAtAnnotation] = time.Now().Add(-10 * time.Minute).UTC().Format(time.RFC3339)
+
+	res, err = p.waitForInstall(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res.IsZero()).To(BeTrue())
+	g.Expect(waitingForInstall(provider)).To(BeFalse())
+	g.Expect(conditions.IsFalse(provider, operatorv1.ProviderInstalledCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(provider, operatorv1.ProviderInstalledCondition)).To(Equal(operatorv1.InstallTimedOutReason))
+
+	// The provider is reported as installed once its deployments are ready.
+	setInstalledAt(provider)
+	p.ctrlClient = fake.NewClientBuilder().WithObjects(
+		newTestDeployment("capi-controller-manager", 1, 1, 1, corev1.ConditionTrue),
+	).Build()
+
+	res, err = p.waitForInstall(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res.IsZero()).To(BeTrue())
+	g.Expect(waitingForInstall(provider)).To(BeFalse())
+	g.Expect(conditions.IsTrue(provider, operatorv1.ProviderInstalledCondition)).To(BeTrue())
+
+	// The wait is dropped once readiness is no longer waited for.
+	setInstalledAt(provider)
+	p.ctrlClient = fakeclient
+
+	provider.Spec.SkipWaitForReadiness = true
+
+	res, err = p.waitForInstall(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res.IsZero()).To(BeTrue())
+	g.Expect(waitingForInstall(provider)).To(BeFalse())
+	g.Expect(conditions.IsTrue(provider, operatorv1.ProviderInstalledCondition)).To(BeTrue())
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const (
+	// inventoryLabel marks the ConfigMaps listing the objects applied for a provider.
+	inventoryLabel = "operator.cluster.x-k8s.io/inventory"
+
+	inventoryConfigMapKey = "inventory"
+)
+
+// inventoryEntry identifies an object applied for a provider, the hash changes with the applied content.
+type inventoryEntry struct {
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Hash      string `json:"hash"`
+}
+
+// updateInventory records the objects applied for the provider in the inventory ConfigMap.
+func (p *phaseReconciler) updateInventory(ctx context.Context) (reconcile.Result, error) {
+	return reconcile.Result{}, wrapPhaseError(p.writeInventory(ctx, p.components.Objs()), operatorv1.InventoryUpdateErrorReason, operatorv1.ProviderInstalledCondition)
+}
+
+// writeInventory creates or updates the inventory ConfigMap of the provider with the given objects.
+func (p *phaseReconciler) writeInventory(ctx context.Context, objs []unstructured.Unstructured) error {
+	entries, err := inventoryEntries(objs)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal inventory: %w", err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      inventoryConfigMapName(p.provider),
+			Namespace: p.provider.GetNamespace(),
+		},
+	}
+
+	result, err := controllerutil.CreateOrUpdate(ctx, p.ctrlClient, cm, func() error {
+		labels := cm.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+
+		labels[configMapTypeLabel] = p.provider.GetType()
+		labels[configMapNameLabel] = p.provider.GetName()
+		labels[operatorManagedLabel] = "true"
+		labels[inventoryLabel] = "true"
+		cm.SetLabels(labels)
+
+		cm.Data = map[string]string{inventoryConfigMapKey: string(data)}
+
+		gvk := p.provider.GetObjectKind().GroupVersionKind()
+
+		cm.SetOwnerReferences([]metav1.OwnerReference{
+			{
+				APIVersion: gvk.GroupVersion().String(),
+				Kind:       gvk.Kind,
+				Name:       p.provider.GetName(),
+				UID:        p.provider.GetUID(),
+			},
+		})
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update inventory ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
+	}
+
+	ctrl.LoggerFrom(ctx).V(5).Info("Provider inventory updated", "configMap", cm.Name, "result", result)
+
+	return nil
+}
+
+// readInventory returns the objects recorded in the inventory ConfigMap of the provider, none if it doesn't exist.
+func (p *phaseReconciler) readInventory(ctx context.Context) ([]inventoryEntry, error) {
+	cm := &corev1.ConfigMap{}
+
+	key := client.ObjectKey{Name: inventoryConfigMapName(p.provider), Namespace: p.provider.GetNamespace()}
+	if err := p.ctrlClient.Get(ctx, key, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to get inventory ConfigMap %s: %w", key, err)
+	}
+
+	entries := []inventoryEntry{}
+	if err := json.Unmarshal([]byte(cm.Data[inventoryConfigMapKey]), &entries); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal inventory ConfigMap %s: %w", key, err)
+	}
+
+	return entries, nil
+}
+
+// inventoryEntries returns the inventory entries of the objects, sorted by group, kind, namespace and name.
+func inventoryEntries(objs []unstructured.Unstructured) ([]inventoryEntry, error) {
+	entries := make([]inventoryEntry, 0, len(objs))
+
+	for _, obj := range objs {
+		// Maps are marshaled with sorted keys, so the hash only depends on the object content.
+		data, err := json.Marshal(obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+
+		sum := sha256.Sum256(data)
+		gvk := obj.GroupVersionKind()
+
+		entries = append(entries, inventoryEntry{
+			Group:     gvk.Group,
+			Version:   gvk.Version,
+			Kind:      gvk.Kind,
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+			Hash:      "sha256:" + hex.EncodeToString(sum[:]),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+
+		return a.Name < b.Name
+	})
+
+	return entries, nil
+}
+
+// inventoryConfigMapName returns the name of the inventory ConfigMap of the provider.
+func inventoryConfigMapName(provider operatorv1.GenericProvider) string {
+	return fmt.Sprintf("%s-%s-inventory", provider.GetType(), provider.GetName())
+}
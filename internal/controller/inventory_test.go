@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestWriteInventory(t *testing.T) {
+	g := NewWithT(t)
+
+	newObj := func(apiVersion, kind, namespace, name string) unstructured.Unstructured {
+		obj := unstructured.Unstructured{}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+
+		return obj
+	}
+
+	provider := &operatorv1.CoreProvider{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "CoreProvider",
+			APIVersion: "operator.cluster.x-k8s.io/v1alpha2",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-api",
+			Namespace: "capi-system",
+		},
+	}
+
+	p := &phaseReconciler{
+		ctrlClient: fake.NewClientBuilder().Build(),
+		provider:   provider,
+	}
+
+	deployment := newObj("apps/v1", "Deployment", "capi-system", "capi-controller-manager")
+	objs := []unstructured.Unstructured{
+		deployment,
+		newObj("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "clusters.cluster.x-k8s.io"),
+		newObj("v1", "ServiceAccount", "capi-system", "capi-manager"),
+	}
+
+	getInventory := func() []inventoryEntry {
+		cm := &corev1.ConfigMap{}
+		g.Expect(p.ctrlClient.Get(context.TODO(), types.NamespacedName{Namespace: "capi-system", Name: "core-cluster-api-inventory"}, cm)).To(Succeed())
+		g.Expect(cm.Labels).To(HaveKeyWithValue(inventoryLabel, "true"))
+		g.Expect(cm.OwnerReferences).To(HaveLen(1))
+
+		entries := []inventoryEntry{}
+		g.Expect(json.Unmarshal([]byte(cm.Data[inventoryConfigMapKey]), &entries)).To(Succeed())
+
+		return entries
+	}
+
+	g.Expect(p.writeInventory(context.TODO(), objs)).To(Succeed())
+
+	entries := getInventory()
+	g.Expect(entries).To(HaveLen(3))
+	g.Expect(entries[0].Kind).To(Equal("ServiceAccount"))
+	g.Expect(entries[1].Group).To(Equal("apiextensions.k8s.io"))
+	g.Expect(entries[1].Version).To(Equal("v1"))
+	g.Expect(entries[1].Kind).To(Equal("CustomResourceDefinition"))
+	g.Expect(entries[1].Name).To(Equal("clusters.cluster.x-k8s.io"))
+	g.Expect(entries[2].Group).To(Equal("apps"))
+	g.Expect(entries[2].Kind).To(Equal("Deployment"))
+	g.Expect(entries[2].Namespace).To(Equal("capi-system"))
+	g.Expect(entries[2].Name).To(Equal("capi-controller-manager"))
+	g.Expect(entries[2].Hash).To(HavePrefix("sha256:"))
+
+	// Changing an object changes only its hash.
+	deploymentHash := entries[2].Hash
+	objs[0].SetLabels(map[string]string{"foo": "bar"})
+
+	g.Expect(p.writeInventory(context.TODO(), objs)).To(Succeed())
+
+	updated := getInventory()
+	g.Expect(updated[2].Hash).ToNot(Equal(deploymentHash))
+	g.Expect(updated[:2]).To(Equal(entries[:2]))
+}
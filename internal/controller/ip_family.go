@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// IPFamilyMode defines how the provider components are adjusted to the IP families of the management cluster.
+type IPFamilyMode string
+
+const (
+	// IPFamilyModeIPv4 keeps the provider components as they are.
+	IPFamilyModeIPv4 IPFamilyMode = "IPv4"
+
+	// IPFamilyModeIPv6 makes Services single stack IPv6 and replaces IPv4 bind addresses with their IPv6 equivalents.
+	IPFamilyModeIPv6 IPFamilyMode = "IPv6"
+
+	// IPFamilyModeDualStack makes Services prefer dual stack and binds wildcard addresses on both IP families.
+	IPFamilyModeDualStack IPFamilyMode = "DualStack"
+
+	serviceKind = "Service"
+)
+
+// ParseIPFamilyMode validates the IP family mode, an empty value defaults to IPv4.
+func ParseIPFamilyMode(mode string) (IPFamilyMode, error) {
+	switch IPFamilyMode(mode) {
+	case "", IPFamilyModeIPv4:
+		return IPFamilyModeIPv4, nil
+	case IPFamilyModeIPv6, IPFamilyModeDualStack:
+		return IPFamilyMode(mode), nil
+	default:
+		return "", fmt.Errorf("invalid IP family mode %q, must be one of %s, %s, %s", mode, IPFamilyModeIPv4, IPFamilyModeIPv6, IPFamilyModeDualStack)
+	}
+}
+
+// customizeIPFamilyFn adjusts Services and container bind addresses in the provider components to the IP family mode.
+func customizeIPFamilyFn(mode IPFamilyMode) func(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+	return func(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+		if mode == IPFamilyModeIPv4 {
+			return objs, nil
+		}
+
+		for i := range objs {
+			if objs[i].GetKind() == serviceKind {
+				if err := customizeServiceIPFamily(&objs[i], mode); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			if err := forEachContainer(&objs[i], func(container map[string]interface{}) {
+				for _, field := range []string{"command", "args"} {
+					args, ok := container[field].([]interface{})
+					if !ok {
+						continue
+					}
+
+					for j := range args {
+						if arg, ok := args[j].(string); ok {
+							args[j] = customizeBindAddressArg(arg, mode)
+						}
+					}
+				}
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		return objs, nil
+	}
+}
+
+// customizeServiceIPFamily sets the IP family policy of the Service, ExternalName services are left untouched.
+func customizeServiceIPFamily(svc *unstructured.Unstructured, mode IPFamilyMode) error {
+	serviceType, _, err := unstructured.NestedString(svc.Object, "spec", "type")
+	if err != nil {
+		return err
+	}
+
+	if serviceType == string(corev1.ServiceTypeExternalName) {
+		return nil
+	}
+
+	if mode == IPFamilyModeDualStack {
+		return unstructured.SetNestedField(svc.Object, string(corev1.IPFamilyPolicyPreferDualStack), "spec", "ipFamilyPolicy")
+	}
+
+	if err := unstructured.SetNestedField(svc.Object, string(corev1.IPFamilyPolicySingleStack), "spec", "ipFamilyPolicy"); err != nil {
+		return err
+	}
+
+	return unstructured.SetNestedStringSlice(svc.Object, []string{string(corev1.IPv6Protocol)}, "spec", "ipFamilies")
+}
+
+// customizeBindAddressArg replaces IPv4 addresses in "--flag=host:port" arguments. The wildcard address
+// is replaced in both modes, as binding "[::]" accepts IPv4 connections too; the loopback address only in IPv6 mode.
+func customizeBindAddressArg(arg string, mode IPFamilyMode) string {
+	name, value, found := strings.Cut(arg, "=")
+	if !found || !strings.HasPrefix(name, "-") {
+		return arg
+	}
+
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return arg
+	}
+
+	switch {
+	case host == "0.0.0.0":
+		host = "::"
+	case host == "127.0.0.1" && mode == IPFamilyModeIPv6:
+		host = "::1"
+	default:
+		return arg
+	}
+
+	return name + "=" + net.JoinHostPort(host, port)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestCustomizeIPFamily(t *testing.T) {
+	newObjs := func() []unstructured.Unstructured {
+		return []unstructured.Unstructured{
+			{
+				Object: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Service",
+					"metadata":   map[string]interface{}{"name": "capi-webhook-service"},
+					"spec":       map[string]interface{}{"ports": []interface{}{}},
+				},
+			},
+			{
+				Object: map[string]interface{}{
+					"apiVersion": "apps/v1",
+					"kind":       "Deployment",
+					"metadata":   map[string]interface{}{"name": "capi-controller-manager"},
+					"spec": map[string]interface{}{
+						"template": map[string]interface{}{
+							"spec": map[string]interface{}{
+								"containers": []interface{}{
+									map[string]interface{}{
+										"name": "manager",
+										"args": []interface{}{
+											"--leader-elect",
+											"--metrics-bind-addr=127.0.0.1:8080",
+											"--health-addr=0.0.0.0:9440",
+											"--webhook-port=9443",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name               string
+		mode               IPFamilyMode
+		wantIPFamilyPolicy string
+		wantIPFamilies     []string
+		wantArgs           []interface{}
+	}{
+		{
+			name:     "IPv4 doesn't change components",
+			mode:     IPFamilyModeIPv4,
+			wantArgs: []interface{}{"--leader-elect", "--metrics-bind-addr=127.0.0.1:8080", "--health-addr=0.0.0.0:9440", "--webhook-port=9443"},
+		},
+		{
+			name:               "IPv6 uses single stack services and IPv6 bind addresses",
+			mode:               IPFamilyModeIPv6,
+			wantIPFamilyPolicy: "SingleStack",
+			wantIPFamilies:     []string{"IPv6"},
+			wantArgs:           []interface{}{"--leader-elect", "--metrics-bind-addr=[::1]:8080", "--health-addr=[::]:9440", "--webhook-port=9443"},
+		},
+		{
+			name:               "DualStack prefers dual stack services and keeps loopback address",
+			mode:               IPFamilyModeDualStack,
+			wantIPFamilyPolicy: "PreferDualStack",
+			wantArgs:           []interface{}{"--leader-elect", "--metrics-bind-addr=127.0.0.1:8080", "--health-addr=[::]:9440", "--webhook-port=9443"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			objs, err := customizeIPFamilyFn(tt.mode)(newObjs())
+			g.Expect(err).ToNot(HaveOccurred())
+
+			policy, _, _ := unstructured.NestedString(objs[0].Object, "spec", "ipFamilyPolicy")
+			g.Expect(policy).To(Equal(tt.wantIPFamilyPolicy))
+
+			families, _, _ := unstructured.NestedStringSlice(objs[0].Object, "spec", "ipFamilies")
+			g.Expect(families).To(Equal(tt.wantIPFamilies))
+
+			containers, _, _ := unstructured.NestedSlice(objs[1].Object, "spec", "template", "spec", "containers")
+			g.Expect(containers[0].(map[string]interface{})["args"]).To(Equal(tt.wantArgs))
+		})
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const (
+	jobTemplateConfigMapKey = "job"
+
+	// jobNameHashLength is the number of hash characters replacing the truncated part of long Job names.
+	jobNameHashLength = 8
+)
+
+// boundedJobName returns the Job name "<prefix>-<suffix>", or the prefix alone if the suffix is empty. The name is
+// also the value of the job-name label of the Job pods, so the prefix of names longer than a label value can be is
+// truncated and suffixed with a hash of the full prefix, so they stay unique.
+func boundedJobName(prefix, suffix string) string {
+	if suffix != "" {
+		suffix = "-" + suffix
+	}
+
+	if len(prefix)+len(suffix) <= validation.LabelValueMaxLength {
+		return prefix + suffix
+	}
+
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(prefix)))[:jobNameHashLength]
+
+	return prefix[:validation.LabelValueMaxLength-len(suffix)-jobNameHashLength-1] + "-" + hash + suffix
+}
+
+// jobFromTemplate builds a Job from the manifest stored under the `job` key of the referenced ConfigMap.
+// The Job gets the given name and labels, is created in the provider namespace and is owned by the provider.
+func (p *phaseReconciler) jobFromTemplate(ctx context.Context, ref operatorv1.ConfigmapReference, name string, labels map[string]string) (*batchv1.Job, error) {
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = p.provider.GetNamespace()
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := p.ctrlClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, cm); err != nil {
+		return nil, fmt.Errorf("failed to get ConfigMap %s/%s: %w", namespace, ref.Name, err)
+	}
+
+	data, ok := cm.Data[jobTemplateConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap %s/%s has no %q key", namespace, ref.Name, jobTemplateConfigMapKey)
+	}
+
+	job := &batchv1.Job{}
+	if err := yaml.Unmarshal([]byte(data), job); err != nil {
+		return nil, fmt.Errorf("failed to parse job from ConfigMap %s/%s: %w", namespace, ref.Name, err)
+	}
+
+	job.Name = name
+	job.Namespace = p.provider.GetNamespace()
+
+	jobLabels := job.GetLabels()
+	if jobLabels == nil {
+		jobLabels = map[string]string{}
+	}
+
+	for k, v := range labels {
+		jobLabels[k] = v
+	}
+
+	job.SetLabels(jobLabels)
+
+	gvk := p.provider.GetObjectKind().GroupVersionKind()
+
+	job.SetOwnerReferences([]metav1.OwnerReference{
+		{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+			Name:       p.provider.GetName(),
+			UID:        p.provider.GetUID(),
+		},
+	})
+
+	return job, nil
+}
+
+// jobFailedCondition returns the Failed condition of the Job if it is set to true.
+func jobFailedCondition(job *batchv1.Job) *batchv1.JobCondition {
+	for i := range job.Status.Conditions {
+		c := job.Status.Conditions[i]
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			return &c
+		}
+	}
+
+	return nil
+}
+
+// jobCompleted returns true if the Job has completed successfully.
+func jobCompleted(job *batchv1.Job) bool {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobComplete && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
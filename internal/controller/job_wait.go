@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const (
+	// waitingForJobAnnotation is the name of the hook Job a provider waits for. The Job is polled without
+	// reconciling the provider again until it has completed, or was deleted after it failed.
+	waitingForJobAnnotation = "operator.cluster.x-k8s.io/waiting-for-job"
+
+	// waitingForDeploymentsAnnotation is set while a provider waits for its Deployments to become ready before
+	// running its smoke test or post-install hooks. The Deployments are polled without reconciling the provider again.
+	waitingForDeploymentsAnnotation = "operator.cluster.x-k8s.io/waiting-for-deployments"
+)
+
+// setWaitingForJob records the hook Job the provider waits for.
+func setWaitingForJob(provider operatorv1.GenericProvider, name string) {
+	setAnnotation(provider, waitingForJobAnnotation, name)
+}
+
+// setWaitingForDeployments records that the provider waits for its Deployments before running the next phase.
+func setWaitingForDeployments(provider operatorv1.GenericProvider) {
+	setAnnotation(provider, waitingForDeploymentsAnnotation, "")
+}
+
+// clearWaits removes the Job and Deployments waits of the provider, the phases set them again if they still wait.
+func clearWaits(provider operatorv1.GenericProvider) {
+	annotations := provider.GetAnnotations()
+	delete(annotations, waitingForJobAnnotation)
+	delete(annotations, waitingForDeploymentsAnnotation)
+	provider.SetAnnotations(annotations)
+}
+
+func setAnnotation(provider operatorv1.GenericProvider, key, value string) {
+	annotations := provider.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[key] = value
+	provider.SetAnnotations(annotations)
+}
+
+// waitingForJobOrDeployments returns true while the provider waits for a hook Job or for its Deployments.
+func waitingForJobOrDeployments(provider operatorv1.GenericProvider) bool {
+	annotations := provider.GetAnnotations()
+	_, job := annotations[waitingForJobAnnotation]
+	_, deployments := annotations[waitingForDeploymentsAnnotation]
+
+	return job || deployments
+}
+
+// jobWait returns how long to wait before checking the hook Job the provider waits for again. It returns zero once
+// the provider has to be reconciled, i.e. when the Job has completed or doesn't exist.
+func (p *phaseReconciler) jobWait(ctx context.Context, name string) (time.Duration, error) {
+	job := &batchv1.Job{}
+	if err := p.ctrlClient.Get(ctx, client.ObjectKey{Namespace: p.provider.GetNamespace(), Name: name}, job); err != nil {
+		if apierrors.IsNotFound(err) {
+			return 0, nil
+		}
+
+		return 0, fmt.Errorf("failed to get hook job %s: %w", name, err)
+	}
+
+	switch {
+	case jobCompleted(job):
+		return 0, nil
+	case jobFailedCondition(job) != nil:
+		// The failed Job is deleted to run the hook again.
+		return hookFailedRequeueAfter, nil
+	default:
+		return hookRunningRequeueAfter, nil
+	}
+}
+
+// deploymentsWait returns how long to wait before checking the Deployments of the provider again, or zero once
+// they are ready.
+func (p *phaseReconciler) deploymentsWait(ctx context.Context) (time.Duration, error) {
+	deployments, err := p.inventoryDeployments(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	ready, err := deploymentsReady(ctx, p.ctrlClient, deployments)
+	if err != nil || ready {
+		return 0, err
+	}
+
+	return upgradeReadyCheckInterval, nil
+}
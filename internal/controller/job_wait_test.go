@@ -0,0 +1,132 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestJobWait(t *testing.T) {
+	newJob := func(conditionType batchv1.JobConditionType) *batchv1.Job {
+		job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "core-cluster-api-post-install-check-0123abcd", Namespace: "capi-system"}}
+		if conditionType != "" {
+			job.Status.Conditions = []batchv1.JobCondition{{Type: conditionType, Status: corev1.ConditionTrue}}
+		}
+
+		return job
+	}
+
+	testCases := []struct {
+		name     string
+		objs     []client.Object
+		wantWait time.Duration
+	}{
+		{
+			name:     "running job",
+			objs:     []client.Object{newJob("")},
+			wantWait: hookRunningRequeueAfter,
+		},
+		{
+			name:     "failed job",
+			objs:     []client.Object{newJob(batchv1.JobFailed)},
+			wantWait: hookFailedRequeueAfter,
+		},
+		{
+			name: "completed job",
+			objs: []client.Object{newJob(batchv1.JobComplete)},
+		},
+		{
+			name: "deleted job",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			provider := &operatorv1.CoreProvider{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "capi-system"},
+			}
+
+			p := &phaseReconciler{
+				ctrlClient: fake.NewClientBuilder().WithObjects(tc.objs...).Build(),
+				provider:   provider,
+			}
+
+			g.Expect(readinessPending(provider)).To(BeFalse())
+
+			setWaitingForJob(provider, "core-cluster-api-post-install-check-0123abcd")
+			g.Expect(readinessPending(provider)).To(BeTrue())
+
+			wait, err := p.readinessWait(context.TODO())
+			g.Expect(err).ToNot(HaveOccurred())
+
+			g.Expect(wait).To(Equal(tc.wantWait))
+
+			// The phases record the wait again when the provider is reconciled.
+			clearWaits(provider)
+			g.Expect(readinessPending(provider)).To(BeFalse())
+		})
+	}
+}
+
+func TestDeploymentsWait(t *testing.T) {
+	g := NewWithT(t)
+
+	deployment := unstructured.Unstructured{}
+	deployment.SetAPIVersion("apps/v1")
+	deployment.SetKind(deploymentKind)
+	deployment.SetNamespace("capi-system")
+	deployment.SetName("capi-controller-manager")
+
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "capi-system"},
+	}
+
+	newReconciler := func(deployments ...client.Object) *phaseReconciler {
+		p := &phaseReconciler{
+			ctrlClient: fake.NewClientBuilder().WithObjects(deployments...).Build(),
+			provider:   provider,
+		}
+
+		g.Expect(p.writeInventory(context.TODO(), []unstructured.Unstructured{deployment})).To(Succeed())
+
+		return p
+	}
+
+	setWaitingForDeployments(provider)
+	g.Expect(readinessPending(provider)).To(BeTrue())
+
+	// The Deployments are checked again until they are ready, then the provider is reconciled.
+	notReady := newReconciler(newTestDeployment("capi-controller-manager", 1, 1, 0, corev1.ConditionFalse))
+	g.Expect(notReady.readinessWait(context.TODO())).To(Equal(upgradeReadyCheckInterval))
+
+	ready := newReconciler(newTestDeployment("capi-controller-manager", 1, 1, 1, corev1.ConditionTrue))
+	g.Expect(ready.readinessWait(context.TODO())).To(BeZero())
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	versionutil "k8s.io/apimachinery/pkg/util/version"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// downloadLocalManifests reads the provider metadata and components from a directory mounted in the operator pod
+// and stores them in a ConfigMap like the manifests downloaded from a GitHub release.
+func (p *phaseReconciler) downloadLocalManifests(ctx context.Context) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	spec := p.provider.GetSpec()
+	root := filepath.Clean(spec.FetchConfig.LocalPath)
+
+	log.Info("Reading provider manifests from local path", "path", root)
+
+	// Symbolic links are resolved first, so they can't point out of the allowed paths.
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		err = fmt.Errorf("failed to find local path of provider %q: %w", p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if !p.fetchLocalPathAllowed(root) {
+		err = fmt.Errorf("fetching provider manifests from local path %s is not allowed, the path must be under one of the paths of the operator --fetch-local-paths flag", root)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.FetchLocalPathNotAllowedReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if spec.Version == "" {
+		// User didn't set the version, use the latest version directory of the path.
+		spec.Version, err = latestLocalVersion(root)
+		if err != nil {
+			err = fmt.Errorf("failed to get the latest version of provider %q from local path %s: %w", p.provider.GetName(), root, err)
+
+			return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+		}
+
+		// Add version to the provider spec.
+		p.provider.SetSpec(spec)
+	}
+
+	// The version must name a directory of the path, not a path of its own.
+	if spec.Version != filepath.Base(spec.Version) || spec.Version == ".." {
+		err = fmt.Errorf("version %q of provider %q is not a valid directory name", spec.Version, p.provider.GetName())
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	// The version directory may be a symbolic link too.
+	dir, err := filepath.EvalSymlinks(filepath.Join(root, spec.Version))
+	if err != nil {
+		err = fmt.Errorf("failed to find version %s of provider %q in local path %s: %w", spec.Version, p.provider.GetName(), root, err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if !p.fetchLocalPathAllowed(dir) {
+		err = fmt.Errorf("fetching provider manifests from local path %s is not allowed, the path must be under one of the paths of the operator --fetch-local-paths flag", dir)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.FetchLocalPathNotAllowedReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	files, err := readLocalFiles(dir, p.fetchLocalPathAllowed)
+	if err != nil {
+		err = fmt.Errorf("failed to read version %s of provider %q from local path %s: %w", spec.Version, p.provider.GetName(), root, err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	// Metadata set in the provider spec replaces the one from the path, which may not have it at all, when loaded.
+	metadata := files[metadataFile]
+	components := files[componentsFileName]
+	signedFiles := map[string][]byte{componentsFileName: components}
+
+	if metadata != nil {
+		signedFiles[metadataFile] = metadata
+	}
+
+	if (metadata == nil && !hasMetadataOverride(spec)) || components == nil {
+		err = fmt.Errorf("local path %s for provider %q must contain %q and %q files", dir, p.provider.GetName(), metadataFile, componentsFileName)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if err := p.verifyManifests(ctx, signedFiles, func(_ context.Context, name string) ([]byte, error) {
+		return readLocalFile(dir, name, p.fetchLocalPathAllowed)
+	}); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err := p.createManifestsConfigMap(ctx, metadata, components, needToCompress(metadata, components)); err != nil {
+		err = fmt.Errorf("failed to create config map for provider %q: %w", p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// fetchLocalPathAllowed returns true if the directory is one of the paths allowed on the operator, or is under one.
+func (p *phaseReconciler) fetchLocalPathAllowed(dir string) bool {
+	for _, allowed := range p.fetchLocalPaths {
+		allowed, err := filepath.EvalSymlinks(filepath.Clean(allowed))
+		if err != nil {
+			continue
+		}
+
+		rel, err := filepath.Rel(allowed, dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// latestLocalVersion returns the latest semver version directory of the path.
+func latestLocalVersion(root string) (string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		latestTag     string
+		latestVersion *versionutil.Version
+	)
+
+	for _, entry := range entries {
+		// Only directories are releases, other files are ignored.
+		if !entry.IsDir() {
+			continue
+		}
+
+		version, err := versionutil.ParseSemantic(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		if latestVersion == nil || latestVersion.LessThan(version) {
+			latestTag, latestVersion = entry.Name(), version
+		}
+	}
+
+	if latestVersion == nil {
+		return "", errors.New("no versions available")
+	}
+
+	return latestTag, nil
+}
+
+// readLocalFiles returns the metadata and components files of the directory, keyed by their names.
+func readLocalFiles(dir string, allowed func(string) bool) (map[string][]byte, error) {
+	files := map[string][]byte{}
+
+	for _, name := range []string{metadataFile, componentsFileName} {
+		data, err := readLocalFile(dir, name, allowed)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		files[name] = data
+	}
+
+	return files, nil
+}
+
+// readLocalFile returns the file of the directory with the name. Files that are symbolic links, e.g. in mounted
+// ConfigMap volumes, must point to a path passing the allowed check.
+func readLocalFile(dir, name string, allowed func(string) bool) ([]byte, error) {
+	file, err := filepath.EvalSymlinks(filepath.Join(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %q: %w", name, err)
+	}
+
+	if !allowed(file) {
+		return nil, fmt.Errorf("%q links to %s, which is not an allowed path", name, file)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", name, err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestDownloadLocalManifests(t *testing.T) {
+	g := NewWithT(t)
+
+	mirror := t.TempDir()
+	outside := t.TempDir()
+
+	files := map[string]string{
+		"aws/v2.9.0/components.yaml":  "components v2.9.0",
+		"aws/v2.10.0/metadata.yaml":   "metadata v2.10.0",
+		"aws/v2.10.0/components.yaml": "components v2.10.0",
+		"aws/latest/components.yaml":  "components latest",
+	}
+
+	for name, data := range files {
+		g.Expect(os.MkdirAll(filepath.Join(mirror, filepath.Dir(name)), 0o755)).To(Succeed())
+		g.Expect(os.WriteFile(filepath.Join(mirror, name), []byte(data), 0o600)).To(Succeed())
+	}
+
+	g.Expect(os.WriteFile(filepath.Join(outside, "token"), []byte("secret"), 0o600)).To(Succeed())
+	g.Expect(os.MkdirAll(filepath.Join(mirror, "linked", "v1.0.0"), 0o755)).To(Succeed())
+	g.Expect(os.Symlink(filepath.Join(outside, "token"), filepath.Join(mirror, "linked", "v1.0.0", componentsFileName))).To(Succeed())
+	g.Expect(os.Symlink(outside, filepath.Join(mirror, "escape"))).To(Succeed())
+
+	tests := []struct {
+		name            string
+		localPath       string
+		version         string
+		allowedPaths    []string
+		metadata        *operatorv1.ProviderMetadata
+		wantVersion     string
+		wantComponents  string
+		wantMetadata    string
+		wantErrReason   string
+		wantErrContains string
+	}{
+		{
+			name:           "latest version",
+			localPath:      filepath.Join(mirror, "aws"),
+			allowedPaths:   []string{mirror},
+			wantVersion:    "v2.10.0",
+			wantComponents: "components v2.10.0",
+			wantMetadata:   "metadata v2.10.0",
+		},
+		{
+			// The upstream metadata is stored, the metadata of the spec replaces it when loaded.
+			name:           "metadata override",
+			localPath:      filepath.Join(mirror, "aws"),
+			version:        "v2.10.0",
+			allowedPaths:   []string{mirror},
+			metadata:       &operatorv1.ProviderMetadata{ReleaseSeries: []operatorv1.ReleaseSeries{{Major: 2, Minor: 10, Contract: "v1beta1"}}},
+			wantVersion:    "v2.10.0",
+			wantComponents: "components v2.10.0",
+			wantMetadata:   "metadata v2.10.0",
+		},
+		{
+			name:           "given version without metadata and metadata override",
+			localPath:      filepath.Join(mirror, "aws"),
+			version:        "v2.9.0",
+			allowedPaths:   []string{mirror},
+			metadata:       &operatorv1.ProviderMetadata{ReleaseSeries: []operatorv1.ReleaseSeries{{Major: 2, Minor: 9, Contract: "v1beta1"}}},
+			wantVersion:    "v2.9.0",
+			wantComponents: "components v2.9.0",
+		},
+		{
+			name:            "given version without metadata",
+			localPath:       filepath.Join(mirror, "aws"),
+			version:         "v2.9.0",
+			allowedPaths:    []string{mirror},
+			wantErrReason:   operatorv1.ComponentsFetchErrorReason,
+			wantErrContains: "must contain",
+		},
+		{
+			name:          "path not allowed",
+			localPath:     filepath.Join(mirror, "aws"),
+			wantErrReason: operatorv1.FetchLocalPathNotAllowedReason,
+		},
+		{
+			name:          "version out of the path",
+			localPath:     filepath.Join(mirror, "aws"),
+			version:       "..",
+			allowedPaths:  []string{filepath.Join(mirror, "aws")},
+			wantErrReason: operatorv1.ComponentsFetchErrorReason,
+		},
+		{
+			name:          "path linked out of the allowed paths",
+			localPath:     filepath.Join(mirror, "escape"),
+			allowedPaths:  []string{mirror},
+			wantErrReason: operatorv1.FetchLocalPathNotAllowedReason,
+		},
+		{
+			name:            "file linked out of the allowed paths",
+			localPath:       filepath.Join(mirror, "linked"),
+			allowedPaths:    []string{mirror},
+			wantErrReason:   operatorv1.ComponentsFetchErrorReason,
+			wantErrContains: "not an allowed path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			provider := &operatorv1.InfrastructureProvider{
+				ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "capa-system"},
+				Spec: operatorv1.InfrastructureProviderSpec{
+					ProviderSpec: operatorv1.ProviderSpec{
+						Version:     tt.version,
+						FetchConfig: &operatorv1.FetchConfiguration{LocalPath: tt.localPath, Metadata: tt.metadata},
+					},
+				},
+			}
+
+			ctrlClient := fake.NewClientBuilder().WithScheme(setupScheme()).Build()
+
+			p := &phaseReconciler{
+				ctrlClient:      ctrlClient,
+				provider:        provider,
+				fetchLocalPaths: tt.allowedPaths,
+			}
+
+			_, err := p.downloadLocalManifests(context.Background())
+			if tt.wantErrReason != "" {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErrContains)))
+
+				phaseErr := &PhaseError{}
+				g.Expect(errors.As(err, &phaseErr)).To(BeTrue())
+				g.Expect(phaseErr.Reason).To(Equal(tt.wantErrReason))
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(provider.Spec.Version).To(Equal(tt.wantVersion))
+
+			configMaps := &corev1.ConfigMapList{}
+			g.Expect(ctrlClient.List(context.Background(), configMaps, client.InNamespace("capa-system"))).To(Succeed())
+			g.Expect(configMaps.Items).To(HaveLen(1))
+			g.Expect(configMaps.Items[0].Data[componentsConfigMapKey]).To(Equal(tt.wantComponents))
+
+			if tt.wantMetadata == "" {
+				g.Expect(configMaps.Items[0].Data).ToNot(HaveKey(metadataConfigMapKey))
+			} else {
+				g.Expect(configMaps.Items[0].Data).To(HaveKeyWithValue(metadataConfigMapKey, tt.wantMetadata))
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// maintenanceWindowSchedule parses the schedule of the maintenance window in its time zone.
+func maintenanceWindowSchedule(window *operatorv1.MaintenanceWindow) (cron.Schedule, *time.Location, error) {
+	if window.Duration.Duration <= 0 {
+		return nil, nil, fmt.Errorf("maintenance window duration must be positive")
+	}
+
+	location := time.UTC
+
+	if window.TimeZone != "" {
+		var err error
+
+		location, err = time.LoadLocation(window.TimeZone)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid maintenance window time zone %q: %w", window.TimeZone, err)
+		}
+	}
+
+	schedule, err := cron.ParseStandard(window.Schedule)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid maintenance window schedule %q: %w", window.Schedule, err)
+	}
+
+	return schedule, location, nil
+}
+
+// maintenanceWindowOpen returns true if the maintenance window is open at the given time, otherwise how long
+// until it opens.
+func maintenanceWindowOpen(window *operatorv1.MaintenanceWindow, now time.Time) (bool, time.Duration, error) {
+	schedule, location, err := maintenanceWindowSchedule(window)
+	if err != nil {
+		return false, 0, err
+	}
+
+	now = now.In(location)
+
+	// The window is open if it was opened less than its duration ago.
+	if opened := schedule.Next(now.Add(-window.Duration.Duration)); !opened.After(now) {
+		return true, 0, nil
+	}
+
+	return false, schedule.Next(now).Sub(now), nil
+}
+
+// deferVersionChange keeps the installed version of a provider outside of its maintenance window, so the other
+// changes of the spec are still applied. The version of the spec is restored once the reconciliation completes.
+func (p *phaseReconciler) deferVersionChange(ctx context.Context) (reconcile.Result, error) {
+	spec := p.provider.GetSpec()
+
+	if spec.MaintenanceWindow == nil || !upgradePending(p.provider) {
+		// The version change is no longer deferred, e.g. it was reverted.
+		if conditions.GetReason(p.provider, operatorv1.ProviderUpgradedCondition) == operatorv1.OutsideMaintenanceWindowReason {
+			conditions.Delete(p.provider, operatorv1.ProviderUpgradedCondition)
+		}
+
+		return reconcile.Result{}, nil
+	}
+
+	open, opensIn, err := maintenanceWindowOpen(spec.MaintenanceWindow, time.Now())
+	if err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.InvalidMaintenanceWindowReason, operatorv1.ProviderUpgradedCondition)
+	}
+
+	if open {
+		return reconcile.Result{}, nil
+	}
+
+	installedVersion := *p.provider.GetStatus().InstalledVersion
+	opensAt := time.Now().Add(opensIn).UTC().Format(time.RFC3339)
+
+	ctrl.LoggerFrom(ctx).Info("Deferring the version change until the maintenance window opens", "version", spec.Version,
+		"installedVersion", installedVersion, "opensAt", opensAt)
+	conditions.MarkFalse(p.provider, operatorv1.ProviderUpgradedCondition, operatorv1.OutsideMaintenanceWindowReason, clusterv1.ConditionSeverityInfo,
+		"Upgrade to version %s is deferred until the maintenance window opens at %s", spec.Version, opensAt)
+
+	p.deferredVersion = spec.Version
+	p.deferredVersionRequeueAfter = opensIn
+
+	spec.Version = installedVersion
+	p.provider.SetSpec(spec)
+
+	return reconcile.Result{}, nil
+}
+
+// restoreDeferredVersion restores the version of the spec deferred until the maintenance window opens or an
+// intermediate version of a multi-step upgrade is installed, and requeues the provider to install it.
+func (p *phaseReconciler) restoreDeferredVersion(res reconcile.Result) reconcile.Result {
+	if p.deferredVersion == "" {
+		return res
+	}
+
+	spec := p.provider.GetSpec()
+	spec.Version = p.deferredVersion
+	p.provider.SetSpec(spec)
+
+	// Only lower the requeue delay of the result, so an immediate requeue asked by a phase is kept.
+	res.RequeueAfter = earliestRequeueAfter(res.RequeueAfter, p.deferredVersionRequeueAfter)
+
+	return res
+}
+
+// desiredVersion returns the version of the spec, including a version deferred during the reconciliation.
+func (p *phaseReconciler) desiredVersion() string {
+	if p.deferredVersion != "" {
+		return p.deferredVersion
+	}
+
+	return p.provider.GetSpec().Version
+}
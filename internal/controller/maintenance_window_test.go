@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestMaintenanceWindowOpen(t *testing.T) {
+	// Saturday.
+	saturday := time.Date(2024, time.March, 16, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		window      operatorv1.MaintenanceWindow
+		now         time.Time
+		wantOpen    bool
+		wantOpensIn time.Duration
+		wantErr     bool
+	}{
+		{
+			name:     "window is open",
+			window:   operatorv1.MaintenanceWindow{Schedule: "0 2 * * SAT", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+			now:      saturday.Add(3 * time.Hour),
+			wantOpen: true,
+		},
+		{
+			name:        "window is not open yet",
+			window:      operatorv1.MaintenanceWindow{Schedule: "0 2 * * SAT", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+			now:         saturday.Add(time.Hour),
+			wantOpensIn: time.Hour,
+		},
+		{
+			name:        "window is closed",
+			window:      operatorv1.MaintenanceWindow{Schedule: "0 2 * * SAT", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+			now:         saturday.Add(6 * time.Hour),
+			wantOpensIn: 7*24*time.Hour - 4*time.Hour,
+		},
+		{
+			name: "window in another time zone",
+			window: operatorv1.MaintenanceWindow{
+				Schedule: "0 2 * * SAT", Duration: metav1.Duration{Duration: time.Hour}, TimeZone: "Europe/Berlin",
+			},
+			now:      saturday.Add(time.Hour + 30*time.Minute),
+			wantOpen: true,
+		},
+		{
+			name:    "invalid schedule",
+			window:  operatorv1.MaintenanceWindow{Schedule: "every saturday", Duration: metav1.Duration{Duration: time.Hour}},
+			now:     saturday,
+			wantErr: true,
+		},
+		{
+			name:    "invalid time zone",
+			window:  operatorv1.MaintenanceWindow{Schedule: "0 2 * * SAT", Duration: metav1.Duration{Duration: time.Hour}, TimeZone: "Mars/Olympus"},
+			now:     saturday,
+			wantErr: true,
+		},
+		{
+			name:    "no duration",
+			window:  operatorv1.MaintenanceWindow{Schedule: "0 2 * * SAT"},
+			now:     saturday,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			open, opensIn, err := maintenanceWindowOpen(&tc.window, tc.now)
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(open).To(Equal(tc.wantOpen))
+			g.Expect(opensIn).To(Equal(tc.wantOpensIn))
+		})
+	}
+}
+
+func TestDeferVersionChange(t *testing.T) {
+	g := NewWithT(t)
+
+	// A window opening one minute before now every year is closed for most of the year.
+	opened := time.Now().UTC().Add(-time.Minute)
+	schedule := opened.Format("4 15 2 1") + " *"
+
+	provider := &operatorv1.InfrastructureProvider{
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				Version: "v2.4.0",
+				MaintenanceWindow: &operatorv1.MaintenanceWindow{
+					Schedule: schedule,
+					Duration: metav1.Duration{Duration: time.Hour},
+				},
+			},
+		},
+		Status: operatorv1.InfrastructureProviderStatus{
+			ProviderStatus: operatorv1.ProviderStatus{InstalledVersion: pointer.String("v2.3.0")},
+		},
+	}
+
+	// The version is changed in the open window.
+	p := &phaseReconciler{provider: provider}
+	_, err := p.deferVersionChange(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider.Spec.Version).To(Equal("v2.4.0"))
+
+	// The installed version is reconciled outside of the window.
+	provider.Spec.MaintenanceWindow.Duration = metav1.Duration{Duration: 30 * time.Second}
+
+	p = &phaseReconciler{provider: provider}
+	_, err = p.deferVersionChange(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider.Spec.Version).To(Equal("v2.3.0"))
+	g.Expect(conditions.GetReason(provider, operatorv1.ProviderUpgradedCondition)).To(Equal(operatorv1.OutsideMaintenanceWindowReason))
+
+	res := p.restoreDeferredVersion(reconcile.Result{})
+	g.Expect(provider.Spec.Version).To(Equal("v2.4.0"))
+	g.Expect(res.RequeueAfter).To(BeNumerically(">", 300*24*time.Hour))
+
+	// The result of the phases is kept, only its requeue delay is lowered.
+	res = p.restoreDeferredVersion(reconcile.Result{Requeue: true, RequeueAfter: time.Minute})
+	g.Expect(res.Requeue).To(BeTrue())
+	g.Expect(res.RequeueAfter).To(Equal(time.Minute))
+
+	// The condition is removed once the version change is reverted.
+	provider.Spec.Version = "v2.3.0"
+
+	p = &phaseReconciler{provider: provider}
+	_, err = p.deferVersionChange(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(conditions.Has(provider, operatorv1.ProviderUpgradedCondition)).To(BeFalse())
+}
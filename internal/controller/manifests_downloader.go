@@ -21,11 +21,14 @@ import (
 	"compress/gzip"
 	"context"
 	"fmt"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -43,20 +46,33 @@ const (
 
 	compressedAnnotation = "provider.cluster.x-k8s.io/compressed"
 
+	// verificationHashAnnotation is the hash of the signature and provenance verification configuration the
+	// manifests of a ConfigMap were verified with.
+	verificationHashAnnotation = "operator.cluster.x-k8s.io/verification-hash"
+
 	metadataConfigMapKey            = "metadata"
 	componentsConfigMapKey          = "components"
 	additionalManifestsConfigMapKey = "manifests"
 
+	// compressedComponentsConfigMapKey is the binary data key of gzip compressed components, which don't need the compressed annotation.
+	compressedComponentsConfigMapKey = "components-gzip"
+
 	maxConfigMapSize = 1 * 1024 * 1024
+
+	// defaultRateLimitRetryAfter is when a rate limited download is retried if the rate limit reset time is unknown.
+	defaultRateLimitRetryAfter = 10 * time.Minute
+
+	// minRateLimitRetryAfter avoids retrying a rate limited download immediately if the rate limit reset time has passed.
+	minRateLimitRetryAfter = 10 * time.Second
 )
 
 // downloadManifests downloads CAPI manifests from a url.
 func (p *phaseReconciler) downloadManifests(ctx context.Context) (reconcile.Result, error) {
 	log := ctrl.LoggerFrom(ctx)
 
-	// Return immediately if a custom config map is used instead of a url.
-	if p.provider.GetSpec().FetchConfig != nil && p.provider.GetSpec().FetchConfig.Selector != nil {
-		log.V(5).Info("Custom config map is used, skip downloading provider manifests")
+	// Return immediately if a custom config map or secret is used instead of a url.
+	if fetchConfig := p.provider.GetSpec().FetchConfig; fetchConfig != nil && (fetchConfig.Selector != nil || fetchConfig.Secret != nil) {
+		log.V(5).Info("Custom config map or secret is used, skip downloading provider manifests")
 
 		return reconcile.Result{}, nil
 	}
@@ -66,27 +82,76 @@ func (p *phaseReconciler) downloadManifests(ctx context.Context) (reconcile.Resu
 		MatchLabels: p.prepareConfigMapLabels(),
 	}
 
-	exists, err := p.checkConfigMapExists(ctx, labelSelector)
+	reuse, err := p.reuseDownloadedManifests(ctx, labelSelector)
 	if err != nil {
 		return reconcile.Result{}, wrapPhaseError(err, "failed to check that config map with manifests exists", operatorv1.ProviderInstalledCondition)
 	}
 
-	if exists {
+	if reuse {
 		log.V(5).Info("Config map with downloaded manifests already exists, skip downloading provider manifests")
 
 		return reconcile.Result{}, nil
 	}
 
+	if p.provider.GetSpec().FetchConfig != nil && p.provider.GetSpec().FetchConfig.OCI != "" {
+		return p.downloadOCIManifests(ctx)
+	}
+
+	if p.provider.GetSpec().FetchConfig != nil && p.provider.GetSpec().FetchConfig.Git != nil {
+		return p.downloadGitManifests(ctx)
+	}
+
+	if p.provider.GetSpec().FetchConfig != nil && p.provider.GetSpec().FetchConfig.Chart != nil {
+		return p.downloadChartManifests(ctx)
+	}
+
+	if p.provider.GetSpec().FetchConfig != nil && p.provider.GetSpec().FetchConfig.S3 != nil {
+		return p.downloadS3Manifests(ctx)
+	}
+
+	if p.provider.GetSpec().FetchConfig != nil && p.provider.GetSpec().FetchConfig.LocalPath != "" {
+		return p.downloadLocalManifests(ctx)
+	}
+
+	spec := p.provider.GetSpec()
+
+	restartedAt := p.provider.GetAnnotations()[operatorv1.RestartedAtAnnotation]
+
+	// Reuse the manifests downloaded by a previous reconcile, which requires the version to be known. Manifests
+	// with signatures or provenance are always downloaded, so they are verified with the current configuration of the provider.
+	verify := spec.FetchConfig != nil && (spec.FetchConfig.Verification != nil || spec.FetchConfig.Provenance != nil)
+
+	cached, ok := p.componentsCache.get(componentsCacheKey(p.providerConfig.URL(), spec.Version, restartedAt, p.credentials))
+	if ok && !verify && spec.Version != "" && (cached.metadata != nil || hasMetadataOverride(spec)) {
+		log.Info("Using cached provider manifests", "version", spec.Version)
+
+		return p.storeDownloadedManifests(ctx, cached.metadata, cached.components)
+	}
+
 	log.Info("Downloading provider manifests")
 
-	repo, err := util.RepositoryFactory(ctx, p.providerConfig, p.configClient.Variables())
+	var forge operatorv1.ForgeType
+	if spec.FetchConfig != nil {
+		forge = spec.FetchConfig.Forge
+	}
+
+	httpClient, err := p.fetchHTTPClient(ctx)
 	if err != nil {
-		err = fmt.Errorf("failed to create repo from provider url for provider %q: %w", p.provider.GetName(), err)
+		err = fmt.Errorf("failed to create HTTP client for provider %q: %w", p.provider.GetName(), err)
 
 		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
 	}
 
-	spec := p.provider.GetSpec()
+	repo, err := util.RepositoryFactory(ctx, p.providerConfig, forge, httpClient, p.configClient.Variables())
+	if err != nil {
+		if res, ok := p.rateLimited(ctx, err); ok {
+			return res, nil
+		}
+
+		err = fmt.Errorf("failed to create repo from provider url for provider %q: %w", p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
 
 	if spec.Version == "" {
 		// User didn't set the version, try to get repository default.
@@ -96,24 +161,90 @@ func (p *phaseReconciler) downloadManifests(ctx context.Context) (reconcile.Resu
 		p.provider.SetSpec(spec)
 	}
 
-	// Fetch the provider metadata and components yaml files from the provided repository GitHub/GitLab.
-	metadataFile, err := repo.GetFile(ctx, spec.Version, metadataFile)
+	// Fetch the provider metadata and components yaml files from the provided repository GitHub/GitLab. Metadata set
+	// in the provider spec replaces the one from the repository when loaded, the repository may not have it at all then.
+	metadata, err := repo.GetFile(ctx, spec.Version, metadataFile)
 	if err != nil {
-		err = fmt.Errorf("failed to read %q from the repository for provider %q: %w", metadataFile, p.provider.GetName(), err)
+		if res, ok := p.rateLimited(ctx, err); ok {
+			return res, nil
+		}
 
-		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+		if !hasMetadataOverride(spec) {
+			err = fmt.Errorf("failed to read %q from the repository for provider %q: %w", metadataFile, p.provider.GetName(), err)
+
+			return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+		}
+
+		log.V(5).Info("Provider metadata not found in the repository, using the metadata of the provider spec", "error", p.sensitiveValues.redact(err.Error()))
+
+		metadata = nil
 	}
 
 	componentsFile, err := repo.GetFile(ctx, spec.Version, repo.ComponentsPath())
 	if err != nil {
-		err = fmt.Errorf("failed to read %q from the repository for provider %q: %w", componentsFile, p.provider.GetName(), err)
+		if res, ok := p.rateLimited(ctx, err); ok {
+			return res, nil
+		}
+
+		err = fmt.Errorf("failed to read %q from the repository for provider %q: %w", repo.ComponentsPath(), p.provider.GetName(), err)
 
 		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
 	}
 
-	withCompression := needToCompress(metadataFile, componentsFile)
+	signedFiles := map[string][]byte{repo.ComponentsPath(): componentsFile}
+	if metadata != nil {
+		signedFiles[metadataFile] = metadata
+	}
+
+	if err := p.verifyManifests(ctx, signedFiles, func(ctx context.Context, name string) ([]byte, error) {
+		return repo.GetFile(ctx, spec.Version, name)
+	}); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	p.componentsCache.add(componentsCacheKey(p.providerConfig.URL(), spec.Version, restartedAt, p.credentials), cachedManifests{
+		metadata:   metadata,
+		components: componentsFile,
+	})
+
+	return p.storeDownloadedManifests(ctx, metadata, componentsFile)
+}
+
+// rateLimited reports a rate limited download of the provider manifests in the ProviderInstalled condition and
+// returns when to retry it, which is when the rate limit resets if known. It returns false for other errors.
+func (p *phaseReconciler) rateLimited(ctx context.Context, err error) (reconcile.Result, bool) {
+	rateLimitErr, ok := util.AsRateLimitError(err)
+	if !ok {
+		return reconcile.Result{}, false
+	}
+
+	retryAfter := defaultRateLimitRetryAfter
+	if !rateLimitErr.Reset.IsZero() {
+		retryAfter = time.Until(rateLimitErr.Reset)
+	}
+
+	if retryAfter < minRateLimitRetryAfter {
+		retryAfter = minRateLimitRetryAfter
+	}
+
+	ctrl.LoggerFrom(ctx).Info("Rate limited while downloading provider manifests, retrying later", "retryAfter", retryAfter, "error", p.sensitiveValues.redact(err.Error()))
+
+	fetchFailures.WithLabelValues(p.provider.GetType(), operatorv1.RateLimitedReason).Inc()
+	conditions.Set(p.provider, conditions.FalseCondition(
+		operatorv1.ProviderInstalledCondition,
+		operatorv1.RateLimitedReason,
+		clusterv1.ConditionSeverityWarning,
+		"Rate limited while downloading the manifests of provider %q, retrying in %s: %s", p.provider.GetName(), retryAfter.Round(time.Second), p.sensitiveValues.redact(err.Error()),
+	))
+
+	return reconcile.Result{RequeueAfter: retryAfter}, true
+}
+
+// storeDownloadedManifests stores the downloaded provider manifests in a config map.
+func (p *phaseReconciler) storeDownloadedManifests(ctx context.Context, metadata, componentsFile []byte) (reconcile.Result, error) {
+	withCompression := needToCompress(metadata, componentsFile)
 
-	if err := p.createManifestsConfigMap(ctx, metadataFile, componentsFile, withCompression); err != nil {
+	if err := p.createManifestsConfigMap(ctx, metadata, componentsFile, withCompression); err != nil {
 		err = fmt.Errorf("failed to create config map for provider %q: %w", p.provider.GetName(), err)
 
 		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
@@ -122,8 +253,46 @@ func (p *phaseReconciler) downloadManifests(ctx context.Context) (reconcile.Resu
 	return reconcile.Result{}, nil
 }
 
+// reuseDownloadedManifests returns true if the manifests were already downloaded for the current restart of the
+// provider and verified with its current verification configuration. Other downloaded manifests are deleted.
+func (p *phaseReconciler) reuseDownloadedManifests(ctx context.Context, labelSelector metav1.LabelSelector) (bool, error) {
+	cm, err := p.getConfigMap(ctx, labelSelector)
+	if err != nil || cm == nil {
+		return false, err
+	}
+
+	verificationHash, err := manifestsVerificationHash(p.provider.GetSpec())
+	if err != nil {
+		return false, err
+	}
+
+	restartedAt := p.provider.GetAnnotations()[operatorv1.RestartedAtAnnotation]
+
+	switch {
+	case cm.GetAnnotations()[operatorv1.RestartedAtAnnotation] != restartedAt:
+		ctrl.LoggerFrom(ctx).Info("Provider restart requested, deleting previously downloaded manifests", "restartedAt", restartedAt)
+	case cm.GetAnnotations()[verificationHashAnnotation] != verificationHash:
+		ctrl.LoggerFrom(ctx).Info("Provider verification configuration changed, deleting previously downloaded manifests")
+	default:
+		return true, nil
+	}
+
+	if err := p.ctrlClient.Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("failed to delete ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
+	}
+
+	return false, nil
+}
+
 // checkConfigMapExists checks if a config map exists in Kubernetes with the given LabelSelector.
 func (p *phaseReconciler) checkConfigMapExists(ctx context.Context, labelSelector metav1.LabelSelector) (bool, error) {
+	cm, err := p.getConfigMap(ctx, labelSelector)
+
+	return cm != nil, err
+}
+
+// getConfigMap returns the config map matching the given LabelSelector, or nil if there is none.
+func (p *phaseReconciler) getConfigMap(ctx context.Context, labelSelector metav1.LabelSelector) (*corev1.ConfigMap, error) {
 	labelSet := labels.Set(labelSelector.MatchLabels)
 	listOpts := []client.ListOption{
 		client.MatchingLabelsSelector{Selector: labels.SelectorFromSet(labelSet)},
@@ -132,14 +301,18 @@ func (p *phaseReconciler) checkConfigMapExists(ctx context.Context, labelSelecto
 	var configMapList corev1.ConfigMapList
 
 	if err := p.ctrlClient.List(ctx, &configMapList, listOpts...); err != nil {
-		return false, fmt.Errorf("failed to list ConfigMaps: %w", err)
+		return nil, fmt.Errorf("failed to list ConfigMaps: %w", err)
 	}
 
 	if len(configMapList.Items) > 1 {
-		return false, fmt.Errorf("more than one config maps were found for given selector: %v", labelSelector.String())
+		return nil, fmt.Errorf("more than one config maps were found for given selector: %v", labelSelector.String())
+	}
+
+	if len(configMapList.Items) == 0 {
+		return nil, nil
 	}
 
-	return len(configMapList.Items) == 1, nil
+	return &configMapList.Items[0], nil
 }
 
 // prepareConfigMapLabels returns labels that identify a config map with downloaded manifests.
@@ -152,6 +325,19 @@ func (p *phaseReconciler) prepareConfigMapLabels() map[string]string {
 	}
 }
 
+// manifestsVerificationHash returns the hash of the signature and provenance verification configuration of the
+// provider, or an empty string if the manifests aren't verified.
+func manifestsVerificationHash(spec operatorv1.ProviderSpec) (string, error) {
+	if spec.FetchConfig == nil || (spec.FetchConfig.Verification == nil && spec.FetchConfig.Provenance == nil) {
+		return "", nil
+	}
+
+	return calculateHash(struct {
+		Verification *operatorv1.VerificationConfiguration
+		Provenance   *operatorv1.ProvenanceVerification
+	}{spec.FetchConfig.Verification, spec.FetchConfig.Provenance})
+}
+
 // createManifestsConfigMap creates a config map with downloaded manifests.
 func (p *phaseReconciler) createManifestsConfigMap(ctx context.Context, metadata, components []byte, compress bool) error {
 	configMapName := fmt.Sprintf("%s-%s-%s", p.provider.GetType(), p.provider.GetName(), p.provider.GetSpec().Version)
@@ -162,9 +348,12 @@ func (p *phaseReconciler) createManifestsConfigMap(ctx context.Context, metadata
 			Namespace: p.provider.GetNamespace(),
 			Labels:    p.prepareConfigMapLabels(),
 		},
-		Data: map[string]string{
-			metadataConfigMapKey: string(metadata),
-		},
+		Data: map[string]string{},
+	}
+
+	// Manifests without metadata rely on the metadata set in the provider spec.
+	if metadata != nil {
+		configMap.Data[metadataConfigMapKey] = string(metadata)
 	}
 
 	// Components manifests data can exceed the configmap size limit. In this case we have to compress it.
@@ -191,6 +380,30 @@ func (p *phaseReconciler) createManifestsConfigMap(ctx context.Context, metadata
 		configMap.SetAnnotations(map[string]string{compressedAnnotation: "true"})
 	}
 
+	annotations := configMap.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	// Record the restart the manifests were downloaded for, so that they are downloaded again on the next one.
+	if restartedAt, ok := p.provider.GetAnnotations()[operatorv1.RestartedAtAnnotation]; ok {
+		annotations[operatorv1.RestartedAtAnnotation] = restartedAt
+	}
+
+	// Record the verification configuration, so that the manifests are downloaded and verified again when it changes.
+	verificationHash, err := manifestsVerificationHash(p.provider.GetSpec())
+	if err != nil {
+		return err
+	}
+
+	if verificationHash != "" {
+		annotations[verificationHashAnnotation] = verificationHash
+	}
+
+	if len(annotations) > 0 {
+		configMap.SetAnnotations(annotations)
+	}
+
 	gvk := p.provider.GetObjectKind().GroupVersionKind()
 
 	configMap.SetOwnerReferences([]metav1.OwnerReference{
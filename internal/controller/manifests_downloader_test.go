@@ -18,13 +18,23 @@ package controller
 
 import (
 	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-operator/util"
 )
 
 func TestManifestsDownloader(t *testing.T) {
@@ -67,3 +77,304 @@ func TestManifestsDownloader(t *testing.T) {
 
 	g.Expect(exists).To(BeTrue())
 }
+
+func TestReuseDownloadedManifests(t *testing.T) {
+	tests := []struct {
+		name                   string
+		providerRestartedAt    string
+		configMapRestartedAt   string
+		configMapExists        bool
+		verification           *operatorv1.VerificationConfiguration
+		configMapVerification  *operatorv1.VerificationConfiguration
+		expectedReuse          bool
+		expectedConfigMapExist bool
+	}{
+		{
+			name: "no downloaded manifests",
+		},
+		{
+			name:                   "downloaded manifests without restart",
+			configMapExists:        true,
+			expectedReuse:          true,
+			expectedConfigMapExist: true,
+		},
+		{
+			name:                   "downloaded manifests for the current restart",
+			providerRestartedAt:    "2024-01-02T00:00:00Z",
+			configMapRestartedAt:   "2024-01-02T00:00:00Z",
+			configMapExists:        true,
+			expectedReuse:          true,
+			expectedConfigMapExist: true,
+		},
+		{
+			name:                 "downloaded manifests before a restart",
+			providerRestartedAt:  "2024-01-02T00:00:00Z",
+			configMapRestartedAt: "2024-01-01T00:00:00Z",
+			configMapExists:      true,
+		},
+		{
+			name:                   "downloaded manifests verified with the current configuration",
+			configMapExists:        true,
+			verification:           &operatorv1.VerificationConfiguration{PublicKey: "key"},
+			configMapVerification:  &operatorv1.VerificationConfiguration{PublicKey: "key"},
+			expectedReuse:          true,
+			expectedConfigMapExist: true,
+		},
+		{
+			name:            "unverified manifests after enabling verification",
+			configMapExists: true,
+			verification:    &operatorv1.VerificationConfiguration{PublicKey: "key"},
+		},
+		{
+			name:                  "downloaded manifests verified with another key",
+			configMapExists:       true,
+			verification:          &operatorv1.VerificationConfiguration{PublicKey: "key"},
+			configMapVerification: &operatorv1.VerificationConfiguration{PublicKey: "old-key"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			provider := &operatorv1.CoreProvider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "cluster-api",
+					Namespace: "capi-system",
+				},
+				Spec: operatorv1.CoreProviderSpec{
+					ProviderSpec: operatorv1.ProviderSpec{
+						Version: "v1.4.3",
+					},
+				},
+			}
+
+			if tt.providerRestartedAt != "" {
+				provider.SetAnnotations(map[string]string{operatorv1.RestartedAtAnnotation: tt.providerRestartedAt})
+			}
+
+			if tt.verification != nil {
+				provider.Spec.FetchConfig = &operatorv1.FetchConfiguration{Verification: tt.verification}
+			}
+
+			p := &phaseReconciler{
+				ctrlClient: fake.NewClientBuilder().Build(),
+				provider:   provider,
+			}
+
+			labelSelector := metav1.LabelSelector{
+				MatchLabels: p.prepareConfigMapLabels(),
+			}
+
+			if tt.configMapExists {
+				cm := &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "core-cluster-api-v1.4.3",
+						Namespace: "capi-system",
+						Labels:    p.prepareConfigMapLabels(),
+					},
+				}
+
+				if tt.configMapRestartedAt != "" {
+					cm.SetAnnotations(map[string]string{operatorv1.RestartedAtAnnotation: tt.configMapRestartedAt})
+				}
+
+				if tt.configMapVerification != nil {
+					hash, err := manifestsVerificationHash(operatorv1.ProviderSpec{
+						FetchConfig: &operatorv1.FetchConfiguration{Verification: tt.configMapVerification},
+					})
+					g.Expect(err).ToNot(HaveOccurred())
+
+					cm.SetAnnotations(map[string]string{verificationHashAnnotation: hash})
+				}
+
+				g.Expect(p.ctrlClient.Create(context.TODO(), cm)).To(Succeed())
+			}
+
+			reuse, err := p.reuseDownloadedManifests(context.TODO(), labelSelector)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(reuse).To(Equal(tt.expectedReuse))
+
+			exists, err := p.checkConfigMapExists(context.TODO(), labelSelector)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(exists).To(Equal(tt.expectedConfigMapExist))
+		})
+	}
+}
+
+func TestDownloadManifestsCache(t *testing.T) {
+	g := NewWithT(t)
+
+	metadata := `apiVersion: clusterctl.cluster.x-k8s.io/v1alpha3
+releaseSeries:
+- major: 1
+  minor: 4
+  contract: v1beta1
+`
+
+	files := map[string]string{
+		"/providers/cluster-api/index.yaml":                  "versions:\n- v1.4.3\n",
+		"/providers/cluster-api/v1.4.3/metadata.yaml":        metadata,
+		"/providers/cluster-api/v1.4.3/core-components.yaml": "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: capi-system\n",
+	}
+
+	var requests atomic.Int32
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		data, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(data))
+	}))
+	defer server.Close()
+
+	caBundle := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "server-ca", Namespace: "capi-system"},
+		Data: map[string]string{
+			"ca.crt": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})),
+		},
+	}
+
+	tenantSecret := func(name, token string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "capi-system"},
+			Data:       map[string][]byte{"GITHUB_TOKEN": []byte(token)},
+		}
+	}
+
+	ctrlClient := fake.NewClientBuilder().WithObjects(caBundle, tenantSecret("tenant-a", "token-a-123456"), tenantSecret("tenant-b", "token-b-123456")).Build()
+	componentsCache := NewComponentsCache(DefaultComponentsCacheSize, time.Hour)
+
+	download := func(restartedAt, configSecret string) {
+		provider := &operatorv1.CoreProvider{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "cluster-api",
+				Namespace:   "capi-system",
+				Annotations: map[string]string{operatorv1.RestartedAtAnnotation: restartedAt},
+			},
+			Spec: operatorv1.CoreProviderSpec{
+				ProviderSpec: operatorv1.ProviderSpec{
+					Version: "v1.4.3",
+					FetchConfig: &operatorv1.FetchConfiguration{
+						URL:         server.URL + "/providers/cluster-api/latest/core-components.yaml",
+						Forge:       operatorv1.ForgeStatic,
+						CABundleRef: &operatorv1.CABundleReference{Name: "server-ca"},
+					},
+				},
+			},
+		}
+
+		if configSecret != "" {
+			provider.Spec.ConfigSecret = &operatorv1.SecretReference{Name: configSecret, Namespace: "capi-system"}
+		}
+
+		p := &phaseReconciler{
+			ctrlClient:      ctrlClient,
+			provider:        provider,
+			componentsCache: componentsCache,
+		}
+
+		_, err := p.initializePhaseReconciler(context.TODO())
+		g.Expect(err).ToNot(HaveOccurred())
+
+		_, err = p.downloadManifests(context.TODO())
+		g.Expect(err).ToNot(HaveOccurred())
+
+		cm, err := p.getConfigMap(context.TODO(), metav1.LabelSelector{MatchLabels: p.prepareConfigMapLabels()})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(cm).ToNot(BeNil())
+		g.Expect(cm.Data).To(HaveKeyWithValue(metadataConfigMapKey, metadata))
+
+		// Delete the config map, so the next download can't reuse it.
+		g.Expect(ctrlClient.Delete(context.TODO(), cm)).To(Succeed())
+	}
+
+	download("", "")
+	downloaded := requests.Load()
+	g.Expect(downloaded).ToNot(BeZero())
+
+	download("", "")
+	g.Expect(requests.Load()).To(Equal(downloaded), "manifests should be reused from the cache")
+
+	download("2024-01-02T00:00:00Z", "")
+	g.Expect(requests.Load()).To(BeNumerically(">", downloaded), "a restart should download the manifests again")
+
+	// Manifests fetched with credentials are only reused by providers with the same credentials.
+	downloaded = requests.Load()
+
+	download("", "tenant-a")
+	g.Expect(requests.Load()).To(BeNumerically(">", downloaded), "manifests should be downloaded with the credentials of the provider")
+
+	downloaded = requests.Load()
+
+	download("", "tenant-a")
+	g.Expect(requests.Load()).To(Equal(downloaded), "manifests should be reused with the same credentials")
+
+	download("", "tenant-b")
+	g.Expect(requests.Load()).To(BeNumerically(">", downloaded), "manifests fetched with other credentials should not be reused")
+}
+
+func TestRateLimited(t *testing.T) {
+	tests := []struct {
+		name            string
+		err             error
+		wantRateLimited bool
+		wantRequeue     time.Duration
+	}{
+		{
+			name:            "rate limit with reset time",
+			err:             fmt.Errorf("failed to get release: %w", &util.RateLimitError{Message: "rate limit reached", Reset: time.Now().Add(time.Hour)}),
+			wantRateLimited: true,
+			wantRequeue:     time.Hour,
+		},
+		{
+			name:            "clusterctl GitHub rate limit",
+			err:             errors.New("error creating the GitHub repository client: failed to get latest release: rate limit for github api has been reached"),
+			wantRateLimited: true,
+			wantRequeue:     defaultRateLimitRetryAfter,
+		},
+		{
+			name:            "rate limit reset in the past",
+			err:             &util.RateLimitError{Message: "rate limit reached", Reset: time.Now().Add(-time.Minute)},
+			wantRateLimited: true,
+			wantRequeue:     minRateLimitRetryAfter,
+		},
+		{
+			name: "other error",
+			err:  errors.New("failed to get release: not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			p := &phaseReconciler{
+				provider: &operatorv1.CoreProvider{
+					ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "capi-system"},
+				},
+			}
+
+			res, ok := p.rateLimited(context.TODO(), tt.err)
+			g.Expect(ok).To(Equal(tt.wantRateLimited))
+
+			if !tt.wantRateLimited {
+				g.Expect(res.IsZero()).To(BeTrue())
+
+				return
+			}
+
+			g.Expect(res.RequeueAfter).To(BeNumerically("~", tt.wantRequeue, time.Second))
+
+			condition := conditions.Get(p.provider, operatorv1.ProviderInstalledCondition)
+			g.Expect(condition).ToNot(BeNil())
+			g.Expect(condition.Reason).To(Equal(operatorv1.RateLimitedReason))
+		})
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// invalidFetchCredentials reports providers whose fetch credentials are invalid.
+var invalidFetchCredentials = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "capi_operator_provider_invalid_fetch_credentials",
+	Help: "Whether the credentials used to fetch the provider components are invalid (1) or not (0).",
+}, []string{"type", "namespace", "name"})
+
+// newVersionAvailable reports providers for which a newer version than the installed one is available.
+var newVersionAvailable = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "capi_operator_provider_new_version_available",
+	Help: "Whether a newer version than the installed one is available in the provider repository (1) or not (0).",
+}, []string{"type", "namespace", "name"})
+
+// reconcileTotal counts the provider reconciliations by outcome.
+var reconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "capi_operator_provider_reconcile_total",
+	Help: "Total number of provider reconciliations, by provider type and result (success or error).",
+}, []string{"type", "result"})
+
+// installDuration observes how long the successful installs of provider components take.
+var installDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "capi_operator_provider_install_duration_seconds",
+	Help:    "Duration in seconds of the successful installs of provider components, by provider type.",
+	Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+}, []string{"type"})
+
+// upgradeDuration observes how long the successful upgrades of provider components take.
+var upgradeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "capi_operator_provider_upgrade_duration_seconds",
+	Help:    "Duration in seconds of the successful upgrades of provider components, by provider type.",
+	Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+}, []string{"type"})
+
+// fetchFailures counts the failures to fetch provider components by condition reason.
+var fetchFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "capi_operator_provider_fetch_failures_total",
+	Help: "Total number of failures to fetch provider components, by provider type and reason.",
+}, []string{"type", "reason"})
+
+// preflightFailures counts the failed preflight checks by condition reason.
+var preflightFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "capi_operator_provider_preflight_failures_total",
+	Help: "Total number of failed provider preflight checks, by provider type and reason.",
+}, []string{"type", "reason"})
+
+// fetchFailureReasons are the reasons of the phase errors counted as fetch failures.
+var fetchFailureReasons = map[string]bool{
+	operatorv1.ComponentsFetchErrorReason:         true,
+	operatorv1.SignatureVerificationFailedReason:  true,
+	operatorv1.ProvenanceVerificationFailedReason: true,
+	operatorv1.ChecksumMismatchReason:             true,
+	operatorv1.ImageDigestResolutionFailedReason:  true,
+}
+
+func init() {
+	metrics.Registry.MustRegister(invalidFetchCredentials, newVersionAvailable, reconcileTotal, installDuration, upgradeDuration,
+		fetchFailures, preflightFailures)
+}
+
+// reconcileResult returns the result label of a provider reconciliation. Successful reconciliations are often
+// requeued to refresh the provider periodically, so requeues aren't reported separately.
+func reconcileResult(err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	return "success"
+}
+
+// observePhaseError counts the phase errors caused by failures to fetch the provider components.
+func observePhaseError(provider operatorv1.GenericProvider, pe *PhaseError) {
+	if fetchFailureReasons[pe.Reason] {
+		fetchFailures.WithLabelValues(provider.GetType(), pe.Reason).Inc()
+	}
+}
+
+// providerMetricLabels returns the label values identifying a provider in the operator metrics.
+func providerMetricLabels(provider operatorv1.GenericProvider) []string {
+	return []string{provider.GetType(), provider.GetNamespace(), provider.GetName()}
+}
+
+// deleteProviderMetrics removes the metrics of a deleted provider.
+func deleteProviderMetrics(provider operatorv1.GenericProvider) {
+	invalidFetchCredentials.DeleteLabelValues(providerMetricLabels(provider)...)
+	newVersionAvailable.DeleteLabelValues(providerMetricLabels(provider)...)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestObservePhaseError(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.BootstrapProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubeadm", Namespace: "capi-kubeadm-bootstrap-system"},
+	}
+
+	fetchFailed := fetchFailures.WithLabelValues("bootstrap", operatorv1.ChecksumMismatchReason)
+	before := testutil.ToFloat64(fetchFailed)
+
+	observePhaseError(provider, &PhaseError{Reason: operatorv1.ChecksumMismatchReason, Err: errors.New("checksum mismatch")})
+	g.Expect(testutil.ToFloat64(fetchFailed)).To(Equal(before + 1))
+
+	// Other phase errors aren't fetch failures.
+	observePhaseError(provider, &PhaseError{Reason: operatorv1.ComponentsUpgradeErrorReason, Err: errors.New("upgrade failed")})
+	g.Expect(testutil.ToFloat64(fetchFailures.WithLabelValues("bootstrap", operatorv1.ComponentsUpgradeErrorReason))).To(BeZero())
+}
+
+func TestPreflightFailuresMetric(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.ControlPlaneProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubeadm", Namespace: "capi-kubeadm-control-plane-system"},
+		Spec: operatorv1.ControlPlaneProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{Version: "one"},
+		},
+	}
+
+	p := &phaseReconciler{
+		ctrlClient:   fake.NewClientBuilder().WithScheme(setupScheme()).Build(),
+		provider:     provider,
+		providerList: &operatorv1.ControlPlaneProviderList{},
+	}
+
+	preflightFailed := preflightFailures.WithLabelValues("controlplane", operatorv1.IncorrectVersionFormatReason)
+	before := testutil.ToFloat64(preflightFailed)
+
+	_, err := p.preflightChecks(context.TODO())
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(testutil.ToFloat64(preflightFailed)).To(Equal(before + 1))
+}
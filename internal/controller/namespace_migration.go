@@ -0,0 +1,156 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-operator/internal/controller/genericprovider"
+)
+
+// migrationSourceNamespace returns the namespace the provider is moved from, or an empty string if it isn't moved.
+func migrationSourceNamespace(provider operatorv1.GenericProvider) string {
+	namespace := provider.GetAnnotations()[operatorv1.MigrateFromNamespaceAnnotation]
+	if namespace == provider.GetNamespace() {
+		return ""
+	}
+
+	return namespace
+}
+
+// isMigrated returns true if the provider was moved to another namespace.
+func isMigrated(provider operatorv1.GenericProvider) bool {
+	_, ok := provider.GetAnnotations()[operatorv1.MigratedToNamespaceAnnotation]
+
+	return ok
+}
+
+// migrateNamespace moves the provider of the same kind and name from the namespace in the migrate-from-namespace
+// annotation. The previous provider must allow the move with the migrate-to-namespace annotation, so that a provider
+// can't take over the components of another namespace it has no access to. The previous provider is marked as migrated, so deleting it leaves the components alone, then its
+// components and clusterctl inventory entry are deleted along with the provider itself. The components are
+// installed in the namespace of the provider by the following phases.
+func (p *phaseReconciler) migrateNamespace(ctx context.Context) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	namespace := migrationSourceNamespace(p.provider)
+	if namespace == "" {
+		return reconcile.Result{}, nil
+	}
+
+	previous, found, err := p.getProviderInNamespace(ctx, namespace)
+	if err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.NamespaceMigrationErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	// The provider was already moved, or there is nothing to move.
+	if !found {
+		return reconcile.Result{}, nil
+	}
+
+	if previous.GetAnnotations()[operatorv1.MigrateToNamespaceAnnotation] != p.provider.GetNamespace() {
+		err := fmt.Errorf("provider %s/%s must allow the move with the %s annotation set to %q",
+			namespace, previous.GetName(), operatorv1.MigrateToNamespaceAnnotation, p.provider.GetNamespace())
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.NamespaceMigrationErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	log.Info("Moving provider from another namespace", "namespace", namespace)
+
+	if !isMigrated(previous) {
+		base, ok := previous.DeepCopyObject().(client.Object)
+		if !ok {
+			return reconcile.Result{}, fmt.Errorf("unexpected provider type %T", previous)
+		}
+
+		annotations := previous.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+
+		annotations[operatorv1.MigratedToNamespaceAnnotation] = p.provider.GetNamespace()
+		previous.SetAnnotations(annotations)
+
+		if err := p.ctrlClient.Patch(ctx, previous, client.MergeFrom(base)); err != nil {
+			err = fmt.Errorf("failed to mark provider %s/%s as migrated: %w", namespace, previous.GetName(), err)
+
+			return reconcile.Result{}, wrapPhaseError(err, operatorv1.NamespaceMigrationErrorReason, operatorv1.ProviderInstalledCondition)
+		}
+	}
+
+	// Components of externally managed providers are left to the tooling that installed them.
+	if !isExternallyManaged(previous) {
+		if err := p.newClusterClient().ProviderComponents().Delete(ctx, cluster.DeleteOptions{
+			Provider:         getProvider(previous, p.provider.GetSpec().Version),
+			IncludeNamespace: false,
+			IncludeCRDs:      false,
+		}); err != nil {
+			err = fmt.Errorf("failed to delete components of provider %s/%s: %w", namespace, previous.GetName(), err)
+
+			return reconcile.Result{}, wrapPhaseError(err, operatorv1.NamespaceMigrationErrorReason, operatorv1.ProviderInstalledCondition)
+		}
+	}
+
+	// The clusterctl inventory entry is not among the components listed for deletion, remove it separately.
+	inventoryKey := clusterctlProviderName(previous)
+	inventoryProvider := &clusterctlv1.Provider{ObjectMeta: metav1.ObjectMeta{Namespace: inventoryKey.Namespace, Name: inventoryKey.Name}}
+
+	if err := p.ctrlClient.Delete(ctx, inventoryProvider); client.IgnoreNotFound(err) != nil {
+		err = fmt.Errorf("failed to delete clusterctl inventory provider %s: %w", inventoryKey, err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.NamespaceMigrationErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if err := p.ctrlClient.Delete(ctx, previous); client.IgnoreNotFound(err) != nil {
+		err = fmt.Errorf("failed to delete provider %s/%s: %w", namespace, previous.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.NamespaceMigrationErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	log.Info("Provider components were removed from the previous namespace", "namespace", namespace)
+
+	return reconcile.Result{}, nil
+}
+
+// getProviderInNamespace returns the provider of the same kind and name in the given namespace, if it exists.
+func (p *phaseReconciler) getProviderInNamespace(ctx context.Context, namespace string) (operatorv1.GenericProvider, bool, error) {
+	providerList, ok := p.providerList.DeepCopyObject().(genericprovider.GenericProviderList)
+	if !ok {
+		return nil, false, fmt.Errorf("unexpected provider list type %T", p.providerList)
+	}
+
+	if err := p.ctrlClient.List(ctx, providerList, client.InNamespace(namespace)); err != nil {
+		return nil, false, fmt.Errorf("failed to list providers in namespace %s: %w", namespace, err)
+	}
+
+	for _, provider := range providerList.GetItems() {
+		if provider.GetName() == p.provider.GetName() {
+			return provider, true, nil
+		}
+	}
+
+	return nil, false, nil
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestMigrateNamespace(t *testing.T) {
+	g := NewWithT(t)
+
+	previous := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "docker",
+			Namespace:   "capd-old",
+			Finalizers:  []string{operatorv1.ProviderFinalizer},
+			Annotations: map[string]string{operatorv1.MigrateToNamespaceAnnotation: "capd-system"},
+		},
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{Version: "v1.5.0"},
+		},
+		Status: operatorv1.InfrastructureProviderStatus{
+			ProviderStatus: operatorv1.ProviderStatus{InstalledVersion: pointer.String("v1.5.0")},
+		},
+	}
+
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "docker",
+			Namespace:   "capd-system",
+			Annotations: map[string]string{operatorv1.MigrateFromNamespaceAnnotation: "capd-old"},
+		},
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{Version: "v1.5.0"},
+		},
+	}
+
+	providerLabels := map[string]string{clusterctlv1.ClusterctlLabel: "", clusterv1.ProviderNameLabel: "infrastructure-docker"}
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "capd-controller-manager", Namespace: "capd-old", Labels: providerLabels},
+	}
+
+	inventoryProvider := &clusterctlv1.Provider{
+		ObjectMeta:   metav1.ObjectMeta{Name: "infrastructure-docker", Namespace: "capd-old", Labels: providerLabels},
+		ProviderName: "docker",
+		Type:         string(clusterctlv1.InfrastructureProviderType),
+		Version:      "v1.5.0",
+	}
+
+	scheme := setupScheme()
+	utilruntime.Must(appsv1.AddToScheme(scheme))
+
+	fakeclient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(previous, provider, deployment, inventoryProvider).Build()
+
+	p := &phaseReconciler{
+		ctrlClient:   fakeclient,
+		provider:     provider,
+		providerList: &operatorv1.InfrastructureProviderList{},
+	}
+
+	_, err := p.migrateNamespace(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// The previous provider is marked as migrated and deleted, the finalizer is removed by its own reconciler.
+	g.Expect(fakeclient.Get(ctx, client.ObjectKeyFromObject(previous), previous)).To(Succeed())
+	g.Expect(previous.GetAnnotations()).To(HaveKeyWithValue(operatorv1.MigratedToNamespaceAnnotation, "capd-system"))
+	g.Expect(previous.GetDeletionTimestamp()).ToNot(BeNil())
+
+	// The components and the clusterctl inventory entry are removed from the previous namespace.
+	g.Expect(apierrors.IsNotFound(fakeclient.Get(ctx, client.ObjectKeyFromObject(deployment), &appsv1.Deployment{}))).To(BeTrue())
+	g.Expect(apierrors.IsNotFound(fakeclient.Get(ctx, client.ObjectKeyFromObject(inventoryProvider), &clusterctlv1.Provider{}))).To(BeTrue())
+
+	// Nothing is left to move once the previous provider is gone.
+	previous.SetFinalizers(nil)
+	g.Expect(fakeclient.Update(ctx, previous)).To(Succeed())
+
+	_, err = p.migrateNamespace(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestMigrateNamespaceWithoutConsent(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+	}{
+		{
+			name: "move not allowed",
+		},
+		{
+			name:        "move allowed to another namespace",
+			annotations: map[string]string{operatorv1.MigrateToNamespaceAnnotation: "capd-other"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			previous := &operatorv1.InfrastructureProvider{
+				ObjectMeta: metav1.ObjectMeta{Name: "docker", Namespace: "capd-old", Annotations: tc.annotations},
+				Spec: operatorv1.InfrastructureProviderSpec{
+					ProviderSpec: operatorv1.ProviderSpec{Version: "v1.5.0"},
+				},
+			}
+
+			provider := &operatorv1.InfrastructureProvider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "docker",
+					Namespace:   "capd-system",
+					Annotations: map[string]string{operatorv1.MigrateFromNamespaceAnnotation: "capd-old"},
+				},
+				Spec: operatorv1.InfrastructureProviderSpec{
+					ProviderSpec: operatorv1.ProviderSpec{Version: "v1.5.0"},
+				},
+			}
+
+			inventoryProvider := &clusterctlv1.Provider{
+				ObjectMeta:   metav1.ObjectMeta{Name: "infrastructure-docker", Namespace: "capd-old"},
+				ProviderName: "docker",
+				Type:         string(clusterctlv1.InfrastructureProviderType),
+				Version:      "v1.5.0",
+			}
+
+			fakeclient := fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(previous, provider, inventoryProvider).Build()
+
+			p := &phaseReconciler{
+				ctrlClient:   fakeclient,
+				provider:     provider,
+				providerList: &operatorv1.InfrastructureProviderList{},
+			}
+
+			_, err := p.migrateNamespace(context.TODO())
+			g.Expect(err).To(HaveOccurred())
+
+			// Nothing is touched in the previous namespace.
+			g.Expect(fakeclient.Get(ctx, client.ObjectKeyFromObject(previous), previous)).To(Succeed())
+			g.Expect(previous.GetAnnotations()).ToNot(HaveKey(operatorv1.MigratedToNamespaceAnnotation))
+			g.Expect(previous.GetDeletionTimestamp()).To(BeNil())
+			g.Expect(fakeclient.Get(ctx, client.ObjectKeyFromObject(inventoryProvider), &clusterctlv1.Provider{})).To(Succeed())
+		})
+	}
+}
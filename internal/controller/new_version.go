@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	versionutil "k8s.io/apimachinery/pkg/util/version"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// newVersionCheckedAtAnnotation is the time the provider repository was last checked for newer versions.
+const newVersionCheckedAtAnnotation = "operator.cluster.x-k8s.io/new-version-checked-at"
+
+// newVersionCheckDue returns true if the provider repository must be checked for newer versions, otherwise how long
+// to wait before checking it. The repository is never checked if the interval isn't positive.
+func newVersionCheckDue(provider operatorv1.GenericProvider, interval time.Duration) (bool, time.Duration) {
+	if interval <= 0 {
+		return false, 0
+	}
+
+	checkedAt, err := time.Parse(time.RFC3339, provider.GetAnnotations()[newVersionCheckedAtAnnotation])
+	if err != nil {
+		return true, 0
+	}
+
+	if elapsed := time.Since(checkedAt); elapsed < interval {
+		return false, interval - elapsed
+	}
+
+	return true, 0
+}
+
+// setNewVersionChecked records the time the provider repository was checked for newer versions, and returns how
+// long to wait before checking it again, zero if it's never checked.
+func setNewVersionChecked(annotations map[string]string, interval time.Duration) time.Duration {
+	if interval <= 0 {
+		delete(annotations, newVersionCheckedAtAnnotation)
+
+		return 0
+	}
+
+	annotations[newVersionCheckedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+
+	return interval
+}
+
+// checkNewVersion reports whether a newer version than the installed one is available in the provider repository
+// in the NewVersionAvailable condition and metric, without upgrading the provider. Failures to list the versions
+// are only reported in the condition, so they don't block the reconciliation.
+func (p *phaseReconciler) checkNewVersion(ctx context.Context) (reconcile.Result, error) {
+	installedVersion := p.provider.GetStatus().InstalledVersion
+
+	if p.newVersionCheckInterval <= 0 || installedVersion == nil || !versionPolicySupported(p.provider.GetSpec().FetchConfig) {
+		conditions.Delete(p.provider, operatorv1.NewVersionAvailableCondition)
+		newVersionAvailable.DeleteLabelValues(providerMetricLabels(p.provider)...)
+
+		return reconcile.Result{}, nil
+	}
+
+	available, res, err := p.availableVersions(ctx)
+	if err != nil || !res.IsZero() {
+		message := "the provider repository is rate limited"
+		if err != nil {
+			message = p.sensitiveValues.redact(err.Error())
+		}
+
+		conditions.MarkUnknown(p.provider, operatorv1.NewVersionAvailableCondition, operatorv1.NewVersionCheckFailedReason,
+			"Failed to list the available versions: %s", message)
+
+		return reconcile.Result{}, nil
+	}
+
+	newer := newerVersion(*installedVersion, available)
+	if newer == "" {
+		conditions.MarkFalse(p.provider, operatorv1.NewVersionAvailableCondition, operatorv1.NoNewVersionReason, clusterv1.ConditionSeverityInfo,
+			"Version %s is the newest available version", *installedVersion)
+		newVersionAvailable.WithLabelValues(providerMetricLabels(p.provider)...).Set(0)
+
+		return reconcile.Result{}, nil
+	}
+
+	ctrl.LoggerFrom(ctx).Info("A newer version of the provider is available", "version", newer, "installedVersion", *installedVersion)
+
+	condition := conditions.TrueCondition(operatorv1.NewVersionAvailableCondition)
+	condition.Message = fmt.Sprintf("Version %s is available, version %s is installed", newer, *installedVersion)
+	conditions.Set(p.provider, condition)
+	newVersionAvailable.WithLabelValues(providerMetricLabels(p.provider)...).Set(1)
+
+	return reconcile.Result{}, nil
+}
+
+// newerVersion returns the newest of the available versions if it's newer than the installed version, otherwise an
+// empty string. Pre-releases and versions that can't be parsed are ignored.
+func newerVersion(installedVersion string, available []string) string {
+	newest, err := versionutil.ParseSemantic(installedVersion)
+	if err != nil {
+		return ""
+	}
+
+	newestString := ""
+
+	for _, v := range available {
+		parsed, err := versionutil.ParseSemantic(v)
+		if err != nil || parsed.PreRelease() != "" {
+			continue
+		}
+
+		if newest.LessThan(parsed) {
+			newest, newestString = parsed, v
+		}
+	}
+
+	return newestString
+}
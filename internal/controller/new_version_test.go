@@ -0,0 +1,167 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// versionsRepository is a repository only listing versions.
+type versionsRepository struct {
+	repository.Repository
+
+	versions []string
+	err      error
+}
+
+func (r versionsRepository) GetVersions(_ context.Context) ([]string, error) {
+	return r.versions, r.err
+}
+
+func TestNewerVersion(t *testing.T) {
+	testCases := []struct {
+		name      string
+		installed string
+		available []string
+		want      string
+	}{
+		{
+			name:      "newer version available",
+			installed: "v1.5.0",
+			available: []string{"v1.4.0", "v1.5.0", "v1.6.1", "v1.6.0"},
+			want:      "v1.6.1",
+		},
+		{
+			name:      "newest version installed",
+			installed: "v1.6.1",
+			available: []string{"v1.5.0", "v1.6.1"},
+		},
+		{
+			name:      "pre-releases and invalid versions are ignored",
+			installed: "v1.5.0",
+			available: []string{"v1.6.0-rc.0", "latest", "v1.5.1"},
+			want:      "v1.5.1",
+		},
+		{
+			name:      "invalid installed version",
+			installed: "main",
+			available: []string{"v1.6.0"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(newerVersion(tc.installed, tc.available)).To(Equal(tc.want))
+		})
+	}
+}
+
+func TestNewVersionCheckDue(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "capi-system"},
+	}
+
+	// The repository is never checked without an interval.
+	due, after := newVersionCheckDue(provider, 0)
+	g.Expect(due).To(BeFalse())
+	g.Expect(after).To(BeZero())
+
+	annotations := map[string]string{newVersionCheckedAtAnnotation: "2024-01-01T00:00:00Z"}
+	g.Expect(setNewVersionChecked(annotations, 0)).To(BeZero())
+	g.Expect(annotations).To(BeEmpty())
+
+	// The repository is checked first, then on every interval.
+	due, _ = newVersionCheckDue(provider, 24*time.Hour)
+	g.Expect(due).To(BeTrue())
+
+	g.Expect(setNewVersionChecked(annotations, 24*time.Hour)).To(Equal(24 * time.Hour))
+	provider.SetAnnotations(annotations)
+
+	due, after = newVersionCheckDue(provider, 24*time.Hour)
+	g.Expect(due).To(BeFalse())
+	g.Expect(after).To(BeNumerically("~", 24*time.Hour, time.Minute))
+}
+
+func TestCheckNewVersion(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "capi-system"},
+		Spec: operatorv1.CoreProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				Version: "v1.5.0",
+				FetchConfig: &operatorv1.FetchConfiguration{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"provider-components": "cluster-api"}},
+				},
+			},
+		},
+		Status: operatorv1.CoreProviderStatus{
+			ProviderStatus: operatorv1.ProviderStatus{InstalledVersion: pointer.String("v1.5.0")},
+		},
+	}
+
+	p := &phaseReconciler{
+		provider:                provider,
+		newVersionCheckInterval: 24 * time.Hour,
+		repo:                    versionsRepository{versions: []string{"v1.5.0", "v1.6.0"}},
+	}
+
+	// A newer version is reported, the provider isn't upgraded.
+	_, err := p.checkNewVersion(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(conditions.IsTrue(provider, operatorv1.NewVersionAvailableCondition)).To(BeTrue())
+	g.Expect(conditions.GetMessage(provider, operatorv1.NewVersionAvailableCondition)).To(ContainSubstring("v1.6.0"))
+	g.Expect(provider.Spec.Version).To(Equal("v1.5.0"))
+
+	// The newest version is installed.
+	provider.Status.InstalledVersion = pointer.String("v1.6.0")
+
+	_, err = p.checkNewVersion(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(conditions.GetReason(provider, operatorv1.NewVersionAvailableCondition)).To(Equal(operatorv1.NoNewVersionReason))
+
+	// Failures to list the versions don't block the reconciliation.
+	p.repo = versionsRepository{err: errors.New("connection refused")}
+
+	_, err = p.checkNewVersion(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(conditions.Get(provider, operatorv1.NewVersionAvailableCondition).Status).To(Equal(corev1.ConditionUnknown))
+	g.Expect(conditions.GetReason(provider, operatorv1.NewVersionAvailableCondition)).To(Equal(operatorv1.NewVersionCheckFailedReason))
+
+	// The condition is removed once the check is disabled.
+	p.newVersionCheckInterval = 0
+
+	_, err = p.checkNewVersion(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(conditions.Has(provider, operatorv1.NewVersionAvailableCondition)).To(BeFalse())
+}
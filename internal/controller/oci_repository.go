@@ -0,0 +1,252 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	versionutil "k8s.io/apimachinery/pkg/util/version"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/retry"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const (
+	// ociUsernameKey, ociPasswordKey and ociAccessTokenKey are the config secret variables with the OCI registry credentials.
+	ociUsernameKey    = "OCI_USERNAME"
+	ociPasswordKey    = "OCI_PASSWORD"
+	ociAccessTokenKey = "OCI_ACCESS_TOKEN"
+)
+
+// downloadOCIManifests downloads the provider metadata and components from an OCI artifact and stores
+// them in a ConfigMap like the manifests downloaded from a GitHub release.
+func (p *phaseReconciler) downloadOCIManifests(ctx context.Context) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	spec := p.provider.GetSpec()
+
+	log.Info("Downloading provider manifests from OCI artifact", "repository", spec.FetchConfig.OCI)
+
+	httpClient, err := p.fetchHTTPClient(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to create HTTP client for provider %q: %w", p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	credentials, err := p.ociPullCredentials(ctx)
+	if err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	repo, err := newOCIRepository(spec.FetchConfig.OCI, p.configClient.Variables(), credentials, httpClient)
+	if err != nil {
+		err = fmt.Errorf("failed to create OCI repository for provider %q: %w", p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if spec.Version == "" {
+		// User didn't set the version, use the latest semver tag of the repository.
+		spec.Version, err = latestOCITag(ctx, repo)
+		if err != nil {
+			err = fmt.Errorf("failed to get the latest version of provider %q from OCI repository %s: %w", p.provider.GetName(), spec.FetchConfig.OCI, err)
+
+			return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+		}
+
+		// Add version to the provider spec.
+		p.provider.SetSpec(spec)
+	}
+
+	files, err := fetchOCIFiles(ctx, repo, spec.Version)
+	if err != nil {
+		err = fmt.Errorf("failed to fetch OCI artifact %s:%s for provider %q: %w", spec.FetchConfig.OCI, spec.Version, p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	// Metadata set in the provider spec replaces the one from the artifact, which may not have it at all, when loaded.
+	metadata := files[metadataFile]
+	components := files[componentsFileName]
+
+	if (metadata == nil && !hasMetadataOverride(spec)) || components == nil {
+		err = fmt.Errorf("OCI artifact %s:%s for provider %q must contain %q and %q files", spec.FetchConfig.OCI, spec.Version, p.provider.GetName(), metadataFile, componentsFileName)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if err := p.createManifestsConfigMap(ctx, metadata, components, needToCompress(metadata, components)); err != nil {
+		err = fmt.Errorf("failed to create config map for provider %q: %w", p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// ociPullCredentials returns the registry credentials of the OCI pull secret of the fetch config, by registry host.
+// The secret is read from the provider namespace.
+func (p *phaseReconciler) ociPullCredentials(ctx context.Context) (map[string]auth.Credential, error) {
+	fetchConfig := p.provider.GetSpec().FetchConfig
+	if fetchConfig == nil || fetchConfig.OCIPullSecret == nil {
+		return nil, nil
+	}
+
+	secret := &corev1.Secret{}
+
+	key := types.NamespacedName{Namespace: p.provider.GetNamespace(), Name: fetchConfig.OCIPullSecret.Name}
+	if err := p.ctrlClient.Get(ctx, key, secret); err != nil {
+		return nil, fmt.Errorf("failed to get OCI pull secret %s: %w", key, err)
+	}
+
+	if secret.Type != corev1.SecretTypeDockerConfigJson {
+		return nil, fmt.Errorf("OCI pull secret %s must be of type %s", key, corev1.SecretTypeDockerConfigJson)
+	}
+
+	credentials, err := dockerConfigCredentials(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OCI pull secret %s: %w", key, err)
+	}
+
+	return credentials, nil
+}
+
+// newOCIRepository returns a client for the OCI repository, authenticated with the credentials of its registry if
+// set, or with the credentials from the variables otherwise. Requests sent with the HTTP client are retried on
+// transient errors.
+func newOCIRepository(reference string, variables configclient.VariablesClient, credentials map[string]auth.Credential,
+	httpClient *http.Client,
+) (*remote.Repository, error) {
+	repo, err := remote.NewRepository(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, ok := credentials[repo.Reference.Registry]
+	if !ok {
+		for key, value := range map[string]*string{
+			ociUsernameKey:    &credential.Username,
+			ociPasswordKey:    &credential.Password,
+			ociAccessTokenKey: &credential.AccessToken,
+		} {
+			// A missing variable is returned as an error, the credential is left empty then.
+			*value, _ = variables.Get(key)
+		}
+	}
+
+	repo.Client = &auth.Client{
+		Client:     &http.Client{Transport: retry.NewTransport(httpClient.Transport)},
+		Cache:      auth.NewCache(),
+		Credential: auth.StaticCredential(repo.Reference.Registry, credential),
+	}
+
+	return repo, nil
+}
+
+// latestOCITag returns the latest semver tag of the OCI repository. Tags are returned as is, with or without
+// the "v" prefix, since they are used to reference the artifact.
+func latestOCITag(ctx context.Context, repo *remote.Repository) (string, error) {
+	var (
+		latestTag     string
+		latestVersion *versionutil.Version
+	)
+
+	if err := repo.Tags(ctx, "", func(tags []string) error {
+		for _, tag := range tags {
+			// Tags that are not versions, e.g. "latest", are ignored.
+			version, err := versionutil.ParseSemantic(tag)
+			if err != nil {
+				continue
+			}
+
+			if latestVersion == nil || latestVersion.LessThan(version) {
+				latestTag, latestVersion = tag, version
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return "", err
+	}
+
+	if latestVersion == nil {
+		return "", fmt.Errorf("no versions available")
+	}
+
+	return latestTag, nil
+}
+
+// fetchOCIFiles returns the files of the OCI artifact with the given tag, keyed by their layer titles.
+func fetchOCIFiles(ctx context.Context, repo *remote.Repository, tag string) (map[string][]byte, error) {
+	manifest, err := fetchOCIManifest(ctx, repo, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{}
+
+	for _, layer := range manifest.Layers {
+		title := layer.Annotations[ocispec.AnnotationTitle]
+		if title != metadataFile && title != componentsFileName {
+			continue
+		}
+
+		data, err := content.FetchAll(ctx, repo, layer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %q: %w", title, err)
+		}
+
+		files[title] = data
+	}
+
+	return files, nil
+}
+
+// fetchOCIManifest returns the manifest of the OCI artifact with the given tag.
+func fetchOCIManifest(ctx context.Context, repo *remote.Repository, tag string) (ocispec.Manifest, error) {
+	manifest := ocispec.Manifest{}
+
+	desc, rc, err := repo.FetchReference(ctx, tag)
+	if err != nil {
+		return manifest, err
+	}
+	defer rc.Close()
+
+	manifestData, err := content.ReadAll(rc, desc)
+	if err != nil {
+		return manifest, err
+	}
+
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return manifest, fmt.Errorf("failed to decode manifest %s: %w", desc.Digest, err)
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// newFakeOCIRegistry returns a registry serving a single repository with the given tags, all pointing to an
+// artifact with the given files. Requests must use the given basic auth credentials.
+func newFakeOCIRegistry(g *WithT, tags []string, files map[string]string, username, password string) *httptest.Server {
+	blobs := map[digest.Digest][]byte{}
+	manifest := ocispec.Manifest{MediaType: ocispec.MediaTypeImageManifest}
+	manifest.SchemaVersion = 2
+
+	config := []byte("{}")
+	manifest.Config = ocispec.Descriptor{MediaType: ocispec.MediaTypeImageConfig, Digest: digest.FromBytes(config), Size: int64(len(config))}
+	blobs[manifest.Config.Digest] = config
+
+	for name, data := range files {
+		layer := ocispec.Descriptor{
+			MediaType:   "application/yaml",
+			Digest:      digest.FromString(data),
+			Size:        int64(len(data)),
+			Annotations: map[string]string{ocispec.AnnotationTitle: name},
+		}
+		manifest.Layers = append(manifest.Layers, layer)
+		blobs[layer.Digest] = []byte(data)
+	}
+
+	manifestData, err := json.Marshal(manifest)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != username || pass != password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		switch path := strings.TrimPrefix(r.URL.Path, "/v2/org/provider/"); {
+		case path == "tags/list":
+			w.Header().Set("Content-Type", "application/json")
+			g.Expect(json.NewEncoder(w).Encode(map[string]interface{}{"name": "org/provider", "tags": tags})).To(Succeed())
+		case strings.HasPrefix(path, "manifests/"):
+			for _, tag := range tags {
+				if strings.TrimPrefix(path, "manifests/") == tag {
+					w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+					w.Header().Set("Docker-Content-Digest", digest.FromBytes(manifestData).String())
+					_, _ = w.Write(manifestData)
+
+					return
+				}
+			}
+
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasPrefix(path, "blobs/"):
+			blob, ok := blobs[digest.Digest(strings.TrimPrefix(path, "blobs/"))]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			_, _ = w.Write(blob)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestOCIRepository(t *testing.T) {
+	testCases := []struct {
+		name        string
+		tags        []string
+		files       map[string]string
+		variables   map[string]string
+		pullSecret  *auth.Credential
+		wantVersion string
+		wantFiles   map[string][]byte
+		wantErr     bool
+	}{
+		{
+			name:        "latest version with metadata and components",
+			tags:        []string{"latest", "v1.1.0", "v1.10.0", "v1.9.1"},
+			files:       map[string]string{metadataFile: "metadata", componentsFileName: "components", "README.md": "readme"},
+			variables:   map[string]string{ociUsernameKey: "user", ociPasswordKey: "password"},
+			wantVersion: "v1.10.0",
+			wantFiles:   map[string][]byte{metadataFile: []byte("metadata"), componentsFileName: []byte("components")},
+		},
+		{
+			name:        "versions without prefix",
+			tags:        []string{"1.0.0", "1.1.0"},
+			files:       map[string]string{componentsFileName: "components"},
+			variables:   map[string]string{ociUsernameKey: "user", ociPasswordKey: "password"},
+			wantVersion: "1.1.0",
+			wantFiles:   map[string][]byte{componentsFileName: []byte("components")},
+		},
+		{
+			name:      "no versions",
+			tags:      []string{"latest"},
+			variables: map[string]string{ociUsernameKey: "user", ociPasswordKey: "password"},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid credentials",
+			tags:      []string{"v1.0.0"},
+			variables: map[string]string{ociUsernameKey: "user", ociPasswordKey: "wrong"},
+			wantErr:   true,
+		},
+		{
+			name:        "pull secret credentials over variables",
+			tags:        []string{"v1.0.0"},
+			files:       map[string]string{componentsFileName: "components"},
+			variables:   map[string]string{ociUsernameKey: "user", ociPasswordKey: "wrong"},
+			pullSecret:  &auth.Credential{Username: "user", Password: "password"},
+			wantVersion: "v1.0.0",
+			wantFiles:   map[string][]byte{componentsFileName: []byte("components")},
+		},
+		{
+			name:       "invalid pull secret credentials",
+			tags:       []string{"v1.0.0"},
+			variables:  map[string]string{ociUsernameKey: "user", ociPasswordKey: "password"},
+			pullSecret: &auth.Credential{Username: "user", Password: "wrong"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			registry := newFakeOCIRegistry(g, tc.tags, tc.files, "user", "password")
+			defer registry.Close()
+
+			// The fake registry certificate is trusted with a CA bundle, like a registry using a private CA.
+			httpClient, err := newFetchHTTPClient(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: registry.Certificate().Raw}), nil)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			mr := configclient.NewMemoryReader()
+			for key, value := range tc.variables {
+				mr.Set(key, value)
+			}
+
+			configClient, err := configclient.New(context.TODO(), "", configclient.InjectReader(mr))
+			g.Expect(err).ToNot(HaveOccurred())
+
+			host := strings.TrimPrefix(registry.URL, "https://")
+
+			var credentials map[string]auth.Credential
+			if tc.pullSecret != nil {
+				credentials = map[string]auth.Credential{host: *tc.pullSecret}
+			}
+
+			repo, err := newOCIRepository(host+"/org/provider", configClient.Variables(), credentials, httpClient)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			version, err := latestOCITag(context.TODO(), repo)
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(version).To(Equal(tc.wantVersion))
+
+			files, err := fetchOCIFiles(context.TODO(), repo, version)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(files).To(Equal(tc.wantFiles))
+		})
+	}
+}
+
+func TestOCIPullCredentials(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "capa-system"},
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				FetchConfig: &operatorv1.FetchConfiguration{OCI: "registry.example.com/org/cluster-api-provider-aws"},
+			},
+		},
+	}
+
+	p := &phaseReconciler{
+		ctrlClient: fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry-credentials", Namespace: "capa-system"},
+				Type:       corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{
+					corev1.DockerConfigJsonKey: []byte(`{"auths":{"https://registry.example.com/v1/":{"auth":"dXNlcjpwYXNzd29yZA=="}}}`),
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "aws-variables", Namespace: "capa-system"},
+				Data:       map[string][]byte{ociUsernameKey: []byte("user")},
+			},
+		).Build(),
+		provider: provider,
+	}
+
+	// No credentials are read without a pull secret.
+	credentials, err := p.ociPullCredentials(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(credentials).To(BeEmpty())
+
+	// The credentials of the pull secret are keyed by registry host.
+	provider.Spec.FetchConfig.OCIPullSecret = &corev1.LocalObjectReference{Name: "registry-credentials"}
+
+	credentials, err = p.ociPullCredentials(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(credentials).To(Equal(map[string]auth.Credential{"registry.example.com": {Username: "user", Password: "password"}}))
+
+	// The pull secret must be a docker config.
+	provider.Spec.FetchConfig.OCIPullSecret = &corev1.LocalObjectReference{Name: "aws-variables"}
+
+	_, err = p.ociPullCredentials(context.TODO())
+	g.Expect(err).To(HaveOccurred())
+}
@@ -23,14 +23,24 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/types"
 	versionutil "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
@@ -46,9 +56,21 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
 )
 
-const metadataFile = "metadata.yaml"
+const (
+	metadataFile = "metadata.yaml"
+
+	// componentsFileName is the name of the provider components file in the artifacts fetched from OCI and Git repositories.
+	componentsFileName = "components.yaml"
+
+	// Built-in variables available to all provider manifests.
+	providerNameVariable                       = "PROVIDER_NAME"
+	providerTypeVariable                       = "PROVIDER_TYPE"
+	providerNamespaceVariable                  = "PROVIDER_NAMESPACE"
+	managementClusterKubernetesVersionVariable = "MANAGEMENT_CLUSTER_KUBERNETES_VERSION"
+)
 
 // phaseReconciler holds all required information for interacting with clusterctl code and
 // helps to iterate through provider reconciliation phases.
@@ -56,15 +78,45 @@ type phaseReconciler struct {
 	provider     genericprovider.GenericProvider
 	providerList genericprovider.GenericProviderList
 
-	ctrlClient         client.Client
-	ctrlConfig         *rest.Config
-	repo               repository.Repository
-	contract           string
-	options            repository.ComponentsOptions
-	providerConfig     configclient.Provider
-	configClient       configclient.Client
-	components         repository.Components
-	clusterctlProvider *clusterctlv1.Provider
+	ctrlClient                  client.Client
+	ctrlConfig                  *rest.Config
+	repo                        repository.Repository
+	contract                    string
+	options                     repository.ComponentsOptions
+	providerConfig              configclient.Provider
+	configClient                configclient.Client
+	components                  repository.Components
+	clusterctlProvider          *clusterctlv1.Provider
+	imageRewriteRules           []ImageRewriteRule
+	ipFamilyMode                IPFamilyMode
+	fetchConfigMapNamespaces    []string
+	requireNamespaceGrants      bool
+	fetchConfigMapRequiredLabel *labels.Requirement
+	fetchLocalPaths             []string
+	componentsCache             *ComponentsCache
+	newVersionCheckInterval     time.Duration
+	manifestDigests             map[string]manifestDigests
+	sensitiveValues             redactor
+
+	// credentials are the variables read from the configuration secrets and the external variables store,
+	// as "name=value" pairs. Manifests fetched with them are only shared with providers having the same ones.
+	credentials []string
+
+	// rollingBack is set while the previously installed version is reinstalled after a failed upgrade.
+	rollingBack bool
+
+	// deferredVersion is the version of the spec deferred until the maintenance window opens, or while an
+	// intermediate version of a multi-step upgrade is installed. The provider is requeued after
+	// deferredVersionRequeueAfter to install it.
+	deferredVersion             string
+	deferredVersionRequeueAfter time.Duration
+
+	// specVersion is the version of the spec replaced with the version resolved for the reconciliation, e.g. from
+	// the version policy or the newest release, and restored once the reconciliation completes.
+	specVersion *string
+
+	// metadata is the metadata of the version being installed.
+	metadata *clusterctlv1.Metadata
 }
 
 // reconcilePhaseFn is a function that represent a phase of the reconciliation.
@@ -98,17 +150,37 @@ func wrapPhaseError(err error, reason string, condition clusterv1.ConditionType)
 // newPhaseReconciler returns phase reconciler for the given provider.
 func newPhaseReconciler(r GenericProviderReconciler, provider genericprovider.GenericProvider, providerList genericprovider.GenericProviderList) *phaseReconciler {
 	return &phaseReconciler{
-		ctrlClient:         r.Client,
-		ctrlConfig:         r.Config,
-		clusterctlProvider: &clusterctlv1.Provider{},
-		provider:           provider,
-		providerList:       providerList,
+		ctrlClient:                  r.Client,
+		ctrlConfig:                  r.Config,
+		clusterctlProvider:          &clusterctlv1.Provider{},
+		provider:                    provider,
+		providerList:                providerList,
+		imageRewriteRules:           r.ImageRewriteRules,
+		ipFamilyMode:                r.IPFamilyMode,
+		fetchConfigMapNamespaces:    r.FetchConfigMapNamespaces,
+		requireNamespaceGrants:      r.RequireNamespaceGrants,
+		fetchConfigMapRequiredLabel: r.FetchConfigMapRequiredLabel,
+		fetchLocalPaths:             r.FetchLocalPaths,
+		componentsCache:             r.ComponentsCache,
+		newVersionCheckInterval:     r.NewVersionCheckInterval,
 	}
 }
 
 // preflightChecks a wrapper around the preflight checks.
 func (p *phaseReconciler) preflightChecks(ctx context.Context) (reconcile.Result, error) {
-	return preflightChecks(ctx, p.ctrlClient, p.provider, p.providerList)
+	var res reconcile.Result
+
+	// Other namespaces are checked first, so their secrets aren't read without a grant.
+	err := p.checkNamespaceGrants(ctx)
+	if err == nil {
+		res, err = preflightChecks(ctx, p.ctrlClient, p.provider, p.providerList)
+	}
+
+	if err != nil && conditions.IsFalse(p.provider, operatorv1.PreflightCheckCondition) {
+		preflightFailures.WithLabelValues(p.provider.GetType(), conditions.GetReason(p.provider, operatorv1.PreflightCheckCondition)).Inc()
+	}
+
+	return res, err
 }
 
 // initializePhaseReconciler initializes phase reconciler.
@@ -166,9 +238,24 @@ func (p *phaseReconciler) load(ctx context.Context) (reconcile.Result, error) {
 		MatchLabels: p.prepareConfigMapLabels(),
 	}
 
-	// Replace label selector if user wants to use custom config map
-	if p.provider.GetSpec().FetchConfig != nil && p.provider.GetSpec().FetchConfig.Selector != nil {
-		labelSelector = p.provider.GetSpec().FetchConfig.Selector
+	namespace := p.provider.GetNamespace()
+
+	// Replace label selector if user wants to use custom config map or secret
+	if spec.FetchConfig != nil && (spec.FetchConfig.Selector != nil || spec.FetchConfig.Secret != nil) {
+		labelSelector = spec.FetchConfig.Selector
+		if spec.FetchConfig.Secret != nil {
+			labelSelector = spec.FetchConfig.Secret
+		}
+
+		if spec.FetchConfig.Namespace != "" {
+			namespace = spec.FetchConfig.Namespace
+		}
+	}
+
+	if !p.fetchConfigMapNamespaceAllowed(namespace) {
+		err := fmt.Errorf("fetching ConfigMaps from namespace %q is not allowed, the namespace must be added to the operator --fetch-configmap-namespaces flag", namespace)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.FetchConfigMapNamespaceNotAllowedReason, operatorv1.ProviderInstalledCondition)
 	}
 
 	additionalManifests, err := p.fetchAddionalManifests(ctx)
@@ -176,43 +263,74 @@ func (p *phaseReconciler) load(ctx context.Context) (reconcile.Result, error) {
 		return reconcile.Result{}, wrapPhaseError(err, "failed to load additional manifests", operatorv1.ProviderInstalledCondition)
 	}
 
-	p.repo, err = p.configmapRepository(ctx, labelSelector, additionalManifests)
+	if spec.FetchConfig != nil && spec.FetchConfig.Secret != nil {
+		p.repo, err = p.secretRepository(ctx, labelSelector, namespace, additionalManifests)
+	} else {
+		p.repo, err = p.configmapRepository(ctx, labelSelector, namespace, additionalManifests)
+	}
+
 	if err != nil {
 		return reconcile.Result{}, wrapPhaseError(err, "failed to load the repository", operatorv1.ProviderInstalledCondition)
 	}
 
-	if spec.Version == "" {
-		// User didn't set the version, so we need to find the latest one from the matching config maps.
-		repoVersions, err := p.repo.GetVersions(ctx)
-		if err != nil {
-			return reconcile.Result{}, wrapPhaseError(err, fmt.Sprintf("failed to get a list of available versions for provider %q", p.provider.GetName()), operatorv1.ProviderInstalledCondition)
+	repoVersions, err := p.repo.GetVersions(ctx)
+	if err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, fmt.Sprintf("failed to get a list of available versions for provider %q", p.provider.GetName()), operatorv1.ProviderInstalledCondition)
+	}
+
+	if spec.Version != "" {
+		// The selected config maps or secrets may hold newer versions allowed by the version policy.
+		if spec.FetchConfig != nil && (spec.FetchConfig.Selector != nil || spec.FetchConfig.Secret != nil) {
+			p.setPolicyVersion(ctx, repoVersions)
+			spec = p.provider.GetSpec()
 		}
 
-		spec.Version, err = getLatestVersion(repoVersions)
+		// The matching config maps can hold many versions, so the provider can be upgraded by only changing its version.
+		if err := checkVersionAvailable(repoVersions, spec.Version); err != nil {
+			return reconcile.Result{}, wrapPhaseError(err, fmt.Sprintf("failed to load version %q for provider %q", spec.Version, p.provider.GetName()), operatorv1.ProviderInstalledCondition)
+		}
+	} else {
+		// User didn't set the version, so we need to find the latest one from the matching config maps.
+		latestVersion, err := getLatestVersion(repoVersions)
 		if err != nil {
 			return reconcile.Result{}, wrapPhaseError(err, fmt.Sprintf("failed to get the latest version for provider %q", p.provider.GetName()), operatorv1.ProviderInstalledCondition)
 		}
 
-		// Add latest version to the provider spec.
-		p.provider.SetSpec(spec)
+		p.setResolvedVersion(latestVersion)
+		spec = p.provider.GetSpec()
 	}
 
 	// Store some provider specific inputs for passing it to clusterctl library
 	p.options = repository.ComponentsOptions{
-		TargetNamespace:     p.provider.GetNamespace(),
+		TargetNamespace:     targetNamespace(p.provider),
 		SkipTemplateProcess: false,
 		Version:             spec.Version,
 	}
 
+	// The version of providers created without one is only known now.
+	violation, err := util.ProviderPolicyViolation(ctx, p.ctrlClient, p.provider, spec.Version)
+	if err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, "failed to check provider policies", operatorv1.ProviderInstalledCondition)
+	}
+
+	if violation != "" {
+		return reconcile.Result{}, wrapPhaseError(fmt.Errorf("%s", violation), operatorv1.ProviderNotAllowedReason, operatorv1.ProviderInstalledCondition)
+	}
+
 	if err := p.validateRepoCAPIVersion(ctx); err != nil {
 		return reconcile.Result{}, wrapPhaseError(err, operatorv1.CAPIVersionIncompatibilityReason, operatorv1.ProviderInstalledCondition)
 	}
 
+	status := p.provider.GetStatus()
+	targetVersion := spec.Version
+	status.TargetVersion = &targetVersion
+	p.provider.SetStatus(status)
+
 	return reconcile.Result{}, nil
 }
 
 // secretReader use clusterctl MemoryReader structure to store the configuration variables
-// that are obtained from a secret and try to set fetch url config.
+// that are obtained from a configmap and a secret and try to set fetch url config.
 func (p *phaseReconciler) secretReader(ctx context.Context, providers ...configclient.Provider) (configclient.Reader, error) {
 	log := ctrl.LoggerFrom(ctx)
 
@@ -222,10 +340,44 @@ func (p *phaseReconciler) secretReader(ctx context.Context, providers ...configc
 		return nil, err
 	}
 
-	// Fetch configuration variables from the secret. See API field docs for more info.
-	if p.provider.GetSpec().ConfigSecret != nil {
+	// Built-in variables have the lowest precedence, so they can be overridden by the user.
+	builtins, err := p.builtinVariables()
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range builtins {
+		mr.Set(k, v)
+	}
+
+	// Fetch non-sensitive configuration variables from the configmap and the provider spec, they are overridden
+	// by the secret ones.
+	if p.provider.GetSpec().ConfigMapRef != nil {
+		configMap := &corev1.ConfigMap{}
+		key := types.NamespacedName{Namespace: p.provider.GetSpec().ConfigMapRef.Namespace, Name: p.provider.GetSpec().ConfigMapRef.Name}
+
+		if err := p.ctrlClient.Get(ctx, key, configMap); err != nil {
+			return nil, err
+		}
+
+		for k, v := range configMap.Data {
+			mr.Set(k, v)
+		}
+	}
+
+	for k, v := range p.provider.GetSpec().Variables {
+		mr.Set(k, v)
+	}
+
+	// Fetch configuration variables from the secrets. See API field docs for more info.
+	secretRefs := configSecretRefs(p.provider.GetSpec())
+	if len(secretRefs) == 0 {
+		log.Info("No configuration secret was specified")
+	}
+
+	for _, ref := range secretRefs {
 		secret := &corev1.Secret{}
-		key := types.NamespacedName{Namespace: p.provider.GetSpec().ConfigSecret.Namespace, Name: p.provider.GetSpec().ConfigSecret.Name}
+		key := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
 
 		if err := p.ctrlClient.Get(ctx, key, secret); err != nil {
 			return nil, err
@@ -233,9 +385,25 @@ func (p *phaseReconciler) secretReader(ctx context.Context, providers ...configc
 
 		for k, v := range secret.Data {
 			mr.Set(k, string(v))
+			p.sensitiveValues.add(string(v))
+			p.credentials = append(p.credentials, k+"="+string(v))
 		}
-	} else {
-		log.Info("No configuration secret was specified")
+	}
+
+	// Variables of the external store take precedence over the ones of the secrets.
+	variables, err := externalVariables(ctx, p.ctrlClient, p.provider)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range variables {
+		mr.Set(k, v)
+		p.sensitiveValues.add(v)
+		p.credentials = append(p.credentials, k+"="+v)
+	}
+
+	if err := setAppliedVariablesHash(p.provider, variables); err != nil {
+		return nil, err
 	}
 
 	for _, provider := range providers {
@@ -251,12 +419,13 @@ func (p *phaseReconciler) secretReader(ctx context.Context, providers ...configc
 			return mr.AddProvider(p.provider.GetName(), util.ClusterctlProviderType(p.provider), p.provider.GetSpec().FetchConfig.URL)
 		}
 
-		if p.provider.GetSpec().FetchConfig.Selector != nil {
-			log.Info("Custom fetch configuration config map was provided")
+		if fetchConfig := p.provider.GetSpec().FetchConfig; fetchConfig.Selector != nil || fetchConfig.Secret != nil || fetchConfig.OCI != "" || fetchConfig.Git != nil || fetchConfig.Chart != nil || fetchConfig.S3 != nil || fetchConfig.LocalPath != "" {
+			log.Info("Custom fetch configuration config map, secret, OCI or Git repository, Helm chart, S3 bucket or local path was provided")
 
 			// To register a new provider from the config map, we need to specify a URL with a valid
-			// format. However, since we're using data from a local config map, URLs are not needed.
-			// As a workaround, we add a fake but well-formatted URL.
+			// format. However, since we're using data from a local config map or secret, URLs are not needed.
+			// As a workaround, we add a fake but well-formatted URL. Manifests pulled from OCI and
+			// Git repositories, S3 buckets or local paths, or rendered from Helm charts are stored in a config map too.
 
 			fakeURL := "https://example.com/my-provider"
 
@@ -267,28 +436,255 @@ func (p *phaseReconciler) secretReader(ctx context.Context, providers ...configc
 	return mr, nil
 }
 
+// missingVariables returns the variables of the components without a default value, e.g. ${AWS_B64ENCODED_CREDENTIALS},
+// that are not set by the configuration variables of the provider or the environment of the operator.
+func (p *phaseReconciler) missingVariables(componentsFile []byte) ([]string, error) {
+	variables, err := yamlprocessor.NewSimpleProcessor().GetVariableMap(componentsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := []string{}
+
+	for name, defaultValue := range variables {
+		if defaultValue != nil {
+			continue
+		}
+
+		if _, err := p.configClient.Variables().Get(name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+
+	sort.Strings(missing)
+
+	return missing, nil
+}
+
+// builtinVariables returns the variables derived from the provider and the management cluster, so the
+// provider manifests and additional manifests can reference them without user configuration.
+func (p *phaseReconciler) builtinVariables() (map[string]string, error) {
+	variables := map[string]string{
+		providerNameVariable:      p.provider.GetName(),
+		providerTypeVariable:      string(util.ClusterctlProviderType(p.provider)),
+		providerNamespaceVariable: targetNamespace(p.provider),
+	}
+
+	if p.ctrlConfig != nil {
+		discoveryClient, err := discovery.NewDiscoveryClientForConfig(p.ctrlConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		serverVersion, err := discoveryClient.ServerVersion()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the management cluster version: %w", err)
+		}
+
+		variables[managementClusterKubernetesVersionVariable] = serverVersion.GitVersion
+	}
+
+	return variables, nil
+}
+
+// configSecretRefs returns the references of the configuration secrets of the provider, in the order
+// their variables are merged.
+func configSecretRefs(spec operatorv1.ProviderSpec) []operatorv1.SecretReference {
+	refs := []operatorv1.SecretReference{}
+
+	if spec.ConfigSecret != nil {
+		refs = append(refs, *spec.ConfigSecret)
+	}
+
+	return append(refs, spec.ConfigSecrets...)
+}
+
+// checkNamespaceGranted returns an error if the namespace is another namespace than the provider one, and it
+// doesn't grant access to the provider namespace with the namespace grant annotation.
+func checkNamespaceGranted(ctx context.Context, c client.Client, providerNamespace, namespace string) error {
+	if namespace == "" || namespace == providerNamespace {
+		return nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return fmt.Errorf("failed to get namespace %q: %w", namespace, err)
+	}
+
+	for _, granted := range strings.Split(ns.GetAnnotations()[operatorv1.ConfigSecretGrantAnnotation], ",") {
+		if granted = strings.TrimSpace(granted); granted == "*" || granted == providerNamespace {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("namespace %q doesn't grant access to namespace %q, it must be added to its %s annotation",
+		namespace, providerNamespace, operatorv1.ConfigSecretGrantAnnotation)
+}
+
+// checkNamespaceGrants fails the preflight checks if the configuration secrets or the fetch config ConfigMaps and
+// Secrets of the provider are in other namespaces that don't grant access to the provider namespace. Grants are
+// only required if enabled on the operator, other namespaces can be referenced freely otherwise.
+func (p *phaseReconciler) checkNamespaceGrants(ctx context.Context) error {
+	if !p.requireNamespaceGrants {
+		return nil
+	}
+
+	spec := p.provider.GetSpec()
+
+	notGranted := func(reason string, err error) error {
+		conditions.Set(p.provider, conditions.FalseCondition(
+			operatorv1.PreflightCheckCondition,
+			reason,
+			clusterv1.ConditionSeverityError,
+			err.Error(),
+		))
+
+		return err
+	}
+
+	for _, ref := range configSecretRefs(spec) {
+		if err := checkNamespaceGranted(ctx, p.ctrlClient, p.provider.GetNamespace(), ref.Namespace); err != nil {
+			return notGranted(operatorv1.ConfigSecretNamespaceNotGrantedReason, fmt.Errorf("configuration secret %q: %w", ref.Name, err))
+		}
+	}
+
+	if spec.FetchConfig != nil && (spec.FetchConfig.Selector != nil || spec.FetchConfig.Secret != nil) {
+		if err := checkNamespaceGranted(ctx, p.ctrlClient, p.provider.GetNamespace(), spec.FetchConfig.Namespace); err != nil {
+			return notGranted(operatorv1.FetchConfigNamespaceNotGrantedReason, fmt.Errorf("fetch config: %w", err))
+		}
+	}
+
+	return nil
+}
+
+// fetchConfigMapNamespaceAllowed returns true if provider ConfigMaps can be fetched from the namespace.
+// The provider namespace is always allowed, other namespaces must be allowed on the operator.
+func (p *phaseReconciler) fetchConfigMapNamespaceAllowed(namespace string) bool {
+	if namespace == p.provider.GetNamespace() {
+		return true
+	}
+
+	for _, ns := range p.fetchConfigMapNamespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseLabelRequirement parses a "<key>" or "<key>=<value>" label requirement.
+func ParseLabelRequirement(requirement string) (*labels.Requirement, error) {
+	key, value, found := strings.Cut(requirement, "=")
+	if !found {
+		return labels.NewRequirement(key, selection.Exists, nil)
+	}
+
+	return labels.NewRequirement(key, selection.Equals, []string{value})
+}
+
 // configmapRepository use clusterctl NewMemoryRepository structure to store the manifests
-// and metadata from a given configmap.
-func (p *phaseReconciler) configmapRepository(ctx context.Context, labelSelector *metav1.LabelSelector, additionalManifests string) (repository.Repository, error) {
-	mr := repository.NewMemoryRepository()
-	mr.WithPaths("", "components.yaml")
+// and metadata from the configmaps matching the selector in the given namespace.
+func (p *phaseReconciler) configmapRepository(ctx context.Context, labelSelector *metav1.LabelSelector, namespace, additionalManifests string) (repository.Repository, error) {
+	selector, err := p.fetchConfigSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
 
 	cml := &corev1.ConfigMapList{}
 
-	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err = p.ctrlClient.List(ctx, cml, &client.ListOptions{LabelSelector: selector, Namespace: namespace}); err != nil {
+		return nil, err
+	}
+
+	if len(cml.Items) == 0 {
+		return nil, fmt.Errorf("no ConfigMaps found with selector %s in namespace %s", labelSelector.String(), namespace)
+	}
+
+	return p.memoryRepository(cml.Items, additionalManifests)
+}
+
+// secretRepository stores the manifests and metadata from the secrets matching the selector in the given
+// namespace, like configmapRepository does for configmaps. The secrets have the same keys, labels and
+// annotations as the configmaps.
+func (p *phaseReconciler) secretRepository(ctx context.Context, labelSelector *metav1.LabelSelector, namespace, additionalManifests string) (repository.Repository, error) {
+	selector, err := p.fetchConfigSelector(labelSelector)
 	if err != nil {
 		return nil, err
 	}
 
-	if err = p.ctrlClient.List(ctx, cml, &client.ListOptions{LabelSelector: selector}); err != nil {
+	sl := &corev1.SecretList{}
+
+	if err = p.ctrlClient.List(ctx, sl, &client.ListOptions{LabelSelector: selector, Namespace: namespace}); err != nil {
 		return nil, err
 	}
 
-	if len(cml.Items) == 0 {
-		return nil, fmt.Errorf("no ConfigMaps found with selector %s", labelSelector.String())
+	if len(sl.Items) == 0 {
+		return nil, fmt.Errorf("no Secrets found with selector %s in namespace %s", labelSelector.String(), namespace)
+	}
+
+	configMaps := make([]corev1.ConfigMap, 0, len(sl.Items))
+	for _, secret := range sl.Items {
+		configMaps = append(configMaps, secretAsConfigMap(secret))
+	}
+
+	return p.memoryRepository(configMaps, additionalManifests)
+}
+
+// fetchConfigSelector returns the selector of the configmaps or secrets with the manifests.
+func (p *phaseReconciler) fetchConfigSelector(labelSelector *metav1.LabelSelector) (labels.Selector, error) {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, cm := range cml.Items {
+	// ConfigMaps and Secrets matched by a user provided selector must also have the label required by the operator.
+	if spec := p.provider.GetSpec(); spec.FetchConfig != nil && (spec.FetchConfig.Selector != nil || spec.FetchConfig.Secret != nil) && p.fetchConfigMapRequiredLabel != nil {
+		selector = selector.Add(*p.fetchConfigMapRequiredLabel)
+	}
+
+	return selector, nil
+}
+
+// secretAsConfigMap returns the configmap with the data of the secret, compressed components are stored
+// in the configmap binary data.
+func secretAsConfigMap(secret corev1.Secret) corev1.ConfigMap {
+	cm := corev1.ConfigMap{
+		ObjectMeta: secret.ObjectMeta,
+		Data:       map[string]string{},
+		BinaryData: map[string][]byte{},
+	}
+
+	compressed := secret.GetAnnotations()[compressedAnnotation] == "true"
+
+	for key, value := range secret.Data {
+		if key == compressedComponentsConfigMapKey || compressed && strings.HasPrefix(key, componentsConfigMapKey) {
+			cm.BinaryData[key] = value
+		} else {
+			cm.Data[key] = string(value)
+		}
+	}
+
+	return cm
+}
+
+// memoryRepository use clusterctl NewMemoryRepository structure to store the manifests
+// and metadata from the configmaps.
+func (p *phaseReconciler) memoryRepository(configMaps []corev1.ConfigMap, additionalManifests string) (repository.Repository, error) {
+	mr := repository.NewMemoryRepository()
+	mr.WithPaths("", "components.yaml")
+
+	// Metadata set in the provider spec takes precedence over the one stored in the ConfigMaps.
+	metadataOverride, err := providerMetadataOverride(p.provider.GetSpec())
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+	versionConfigMaps := map[string][]corev1.ConfigMap{}
+	p.manifestDigests = map[string]manifestDigests{}
+
+	for _, cm := range configMaps {
 		version := cm.Name
 		errMsg := "from the Name"
 
@@ -304,18 +700,40 @@ func (p *phaseReconciler) configmapRepository(ctx context.Context, labelSelector
 			return nil, fmt.Errorf("ConfigMap %s/%s has invalid version:%s (%s)", cm.Namespace, cm.Name, version, errMsg)
 		}
 
-		metadata, ok := cm.Data[metadataConfigMapKey]
-		if !ok {
-			return nil, fmt.Errorf("ConfigMap %s/%s has no metadata", cm.Namespace, cm.Name)
+		if _, ok := versionConfigMaps[version]; !ok {
+			versions = append(versions, version)
+		}
+
+		versionConfigMaps[version] = append(versionConfigMaps[version], cm)
+	}
+
+	for _, version := range versions {
+		cms, err := sortConfigMapChunks(version, versionConfigMaps[version])
+		if err != nil {
+			return nil, err
 		}
 
-		mr.WithFile(version, metadataFile, []byte(metadata))
+		metadata, ok := cms[0].Data[metadataConfigMapKey]
+		digests := manifestDigests{}
+
+		switch {
+		case metadataOverride != nil:
+			mr.WithFile(version, metadataFile, metadataOverride)
+		case ok:
+			mr.WithFile(version, metadataFile, []byte(metadata))
+			digests.metadata = sha256Digest([]byte(metadata))
+		default:
+			return nil, fmt.Errorf("ConfigMap %s/%s has no metadata", cms[0].Namespace, cms[0].Name)
+		}
 
-		components, err := getComponentsData(cm)
+		components, err := getComponentsData(cms...)
 		if err != nil {
 			return nil, err
 		}
 
+		digests.components = sha256Digest([]byte(components))
+		p.manifestDigests[version] = digests
+
 		if additionalManifests != "" {
 			components = components + "\n---\n" + additionalManifests
 		}
@@ -326,6 +744,32 @@ func (p *phaseReconciler) configmapRepository(ctx context.Context, labelSelector
 	return mr, nil
 }
 
+// sortConfigMapChunks orders the ConfigMaps of a version by their chunk label. A version stored in a single
+// ConfigMap doesn't need the label, otherwise the labels must number the ConfigMaps from 0 without gaps.
+func sortConfigMapChunks(version string, cms []corev1.ConfigMap) ([]corev1.ConfigMap, error) {
+	if len(cms) == 1 {
+		return cms, nil
+	}
+
+	sorted := make([]corev1.ConfigMap, len(cms))
+
+	for _, cm := range cms {
+		value, ok := cm.Labels[operatorv1.ConfigMapChunkLabelName]
+		if !ok {
+			return nil, fmt.Errorf("found %d ConfigMaps for version %s, ConfigMap %s/%s must have the %s label", len(cms), version, cm.Namespace, cm.Name, operatorv1.ConfigMapChunkLabelName)
+		}
+
+		index, err := strconv.Atoi(value)
+		if err != nil || index < 0 || index >= len(cms) || sorted[index].Name != "" {
+			return nil, fmt.Errorf("ConfigMap %s/%s has invalid chunk %q, chunks of version %s must be numbered from 0 to %d", cm.Namespace, cm.Name, value, version, len(cms)-1)
+		}
+
+		sorted[index] = cm
+	}
+
+	return sorted, nil
+}
+
 func (p *phaseReconciler) fetchAddionalManifests(ctx context.Context) (string, error) {
 	cm := &corev1.ConfigMap{}
 
@@ -341,31 +785,39 @@ func (p *phaseReconciler) fetchAddionalManifests(ctx context.Context) (string, e
 }
 
 // getComponentsData returns components data based on if it's compressed or not.
-func getComponentsData(cm corev1.ConfigMap) (string, error) {
-	// Data is not compressed, return it immediately.
-	if cm.GetAnnotations()[compressedAnnotation] != "true" {
-		components, ok := cm.Data[componentsConfigMapKey]
-		if !ok {
-			return "", fmt.Errorf("ConfigMap %s/%s Data has no components", cm.Namespace, cm.Name)
+// Components split across several ConfigMaps or keys are joined in order before decompression.
+func getComponentsData(cms ...corev1.ConfigMap) (string, error) {
+	compressed := isConfigMapCompressed(cms[0])
+
+	var data []byte
+
+	for _, cm := range cms {
+		if isConfigMapCompressed(cm) != compressed {
+			return "", fmt.Errorf("ConfigMap %s/%s must be compressed like ConfigMap %s/%s", cm.Namespace, cm.Name, cms[0].Namespace, cms[0].Name)
 		}
 
-		return components, nil
+		chunk, err := getConfigMapComponents(cm, compressed)
+		if err != nil {
+			return "", err
+		}
+
+		data = append(data, chunk...)
 	}
 
-	// Otherwise we have to decompress the data first.
-	compressedComponents, ok := cm.BinaryData[componentsConfigMapKey]
-	if !ok {
-		return "", fmt.Errorf("ConfigMap %s/%s BinaryData has no components", cm.Namespace, cm.Name)
+	// Data is not compressed, return it immediately.
+	if !compressed {
+		return string(data), nil
 	}
 
-	zr, err := gzip.NewReader(bytes.NewReader(compressedComponents))
+	// Otherwise we have to decompress the data first.
+	zr, err := gzip.NewReader(bytes.NewReader(data))
 	if err != nil {
 		return "", err
 	}
 
 	components, err := io.ReadAll(zr)
 	if err != nil {
-		return "", fmt.Errorf("cannot decompress data from ConfigMap %s/%s", cm.Namespace, cm.Name)
+		return "", fmt.Errorf("cannot decompress data from ConfigMap %s/%s", cms[0].Namespace, cms[0].Name)
 	}
 
 	if err := zr.Close(); err != nil {
@@ -375,6 +827,94 @@ func getComponentsData(cm corev1.ConfigMap) (string, error) {
 	return string(components), nil
 }
 
+// isConfigMapCompressed returns true if the ConfigMap has the compressed annotation or the components-gzip binary data key.
+func isConfigMapCompressed(cm corev1.ConfigMap) bool {
+	_, ok := cm.BinaryData[compressedComponentsConfigMapKey]
+
+	return ok || cm.GetAnnotations()[compressedAnnotation] == "true"
+}
+
+// getConfigMapComponents returns the components stored in the ConfigMap under the components key,
+// or split across the components-0, components-1, ... keys. Compressed components are read from BinaryData,
+// where they can also be stored under the components-gzip key.
+func getConfigMapComponents(cm corev1.ConfigMap, compressed bool) ([]byte, error) {
+	field := "Data"
+	get := func(key string) ([]byte, bool) {
+		value, ok := cm.Data[key]
+
+		return []byte(value), ok
+	}
+
+	if compressed {
+		field = "BinaryData"
+		get = func(key string) ([]byte, bool) {
+			value, ok := cm.BinaryData[key]
+
+			return value, ok
+		}
+	}
+
+	if components, ok := get(componentsConfigMapKey); ok {
+		return components, nil
+	}
+
+	if components, ok := cm.BinaryData[compressedComponentsConfigMapKey]; ok && compressed {
+		return components, nil
+	}
+
+	var components []byte
+
+	for i := 0; ; i++ {
+		chunk, ok := get(fmt.Sprintf("%s-%d", componentsConfigMapKey, i))
+		if !ok {
+			if i == 0 {
+				return nil, fmt.Errorf("ConfigMap %s/%s %s has no components", cm.Namespace, cm.Name, field)
+			}
+
+			return components, nil
+		}
+
+		components = append(components, chunk...)
+	}
+}
+
+// hasMetadataOverride returns true if the provider metadata is set in the fetch config. The downloaded manifests
+// don't need to contain the metadata then.
+func hasMetadataOverride(spec operatorv1.ProviderSpec) bool {
+	return spec.FetchConfig != nil && spec.FetchConfig.Metadata != nil
+}
+
+// providerMetadataOverride returns the provider metadata set in the fetch config as a clusterctl metadata file,
+// or nil if it's not set. It is applied when the downloaded manifests are loaded, so they always store the
+// upstream metadata and changes to the override don't require downloading them again.
+func providerMetadataOverride(spec operatorv1.ProviderSpec) ([]byte, error) {
+	if spec.FetchConfig == nil || spec.FetchConfig.Metadata == nil {
+		return nil, nil
+	}
+
+	metadata := clusterctlv1.Metadata{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clusterctlv1.GroupVersion.String(),
+			Kind:       "Metadata",
+		},
+	}
+
+	for _, series := range spec.FetchConfig.Metadata.ReleaseSeries {
+		metadata.ReleaseSeries = append(metadata.ReleaseSeries, clusterctlv1.ReleaseSeries{
+			Major:    uint(series.Major),
+			Minor:    uint(series.Minor),
+			Contract: series.Contract,
+		})
+	}
+
+	file, err := yaml.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the provider metadata from the fetch config: %w", err)
+	}
+
+	return file, nil
+}
+
 // validateRepoCAPIVersion checks that the repo is using the correct version.
 func (p *phaseReconciler) validateRepoCAPIVersion(ctx context.Context) error {
 	name := p.provider.GetName()
@@ -408,6 +948,7 @@ func (p *phaseReconciler) validateRepoCAPIVersion(ctx context.Context) error {
 	}
 
 	p.contract = releaseSeries.Contract
+	p.metadata = latestMetadata
 
 	return nil
 }
@@ -417,6 +958,11 @@ func (p *phaseReconciler) fetch(ctx context.Context) (reconcile.Result, error) {
 	log := ctrl.LoggerFrom(ctx)
 	log.Info("Fetching provider")
 
+	// Pinned checksums are validated before the components are processed and installed.
+	if err := p.validateChecksums(p.options.Version); err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ChecksumMismatchReason, operatorv1.ProviderInstalledCondition)
+	}
+
 	// Fetch the provider components yaml file from the provided repository GitHub/GitLab/ConfigMap.
 	componentsFile, err := p.repo.GetFile(ctx, p.options.Version, p.repo.ComponentsPath())
 	if err != nil {
@@ -425,6 +971,18 @@ func (p *phaseReconciler) fetch(ctx context.Context) (reconcile.Result, error) {
 		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
 	}
 
+	// List all the missing variables at once, instead of the clusterctl processing error.
+	missing, err := p.missingVariables(componentsFile)
+	if err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if len(missing) > 0 {
+		err := fmt.Errorf("variables %s of the provider components are not set, set them in the config secrets, config map or variables of the provider", strings.Join(missing, ", "))
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.MissingVariablesReason, operatorv1.ProviderInstalledCondition)
+	}
+
 	// Generate a set of new objects using the clusterctl library. NewComponents() will do the yaml processing,
 	// like ensure all the provider components are in proper namespace, replace variables, etc. See the clusterctl
 	// documentation for more details.
@@ -450,6 +1008,46 @@ func (p *phaseReconciler) fetch(ctx context.Context) (reconcile.Result, error) {
 		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
 	}
 
+	if err := repository.AlterComponents(p.components, applyTargetedPatches(ctx, p.provider)); err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	// Scale down the provider Deployments after the patches are applied, so they can't override it.
+	if isSuspended(p.provider) {
+		if err := repository.AlterComponents(p.components, suspendDeploymentsFn()); err != nil {
+			return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+		}
+	}
+
+	// Adjust Services and bind addresses for IPv6-only and dual-stack management clusters.
+	if err := repository.AlterComponents(p.components, customizeIPFamilyFn(p.ipFamilyMode)); err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	// Drop everything but the CRDs and their conversion webhook resources if only the CRDs are installed.
+	if isCRDsOnly(p.provider) {
+		if err := repository.AlterComponents(p.components, crdsOnlyFn()); err != nil {
+			return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+		}
+	}
+
+	// Keep annotations and CA bundles added to the existing CRDs by other controllers.
+	if err := repository.AlterComponents(p.components, preserveCRDFieldsFn(ctx, p.ctrlClient)); err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	// Rewrite image registries last, so the rules also apply to images set by the provider spec or patches.
+	if len(p.imageRewriteRules) > 0 {
+		if err := repository.AlterComponents(p.components, rewriteImagesFn(p.imageRewriteRules)); err != nil {
+			return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+		}
+	}
+
+	// Pin the images after their registries are rewritten, so the digests are resolved from the rewritten registries.
+	if err := p.pinImageDigests(ctx); err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ImageDigestResolutionFailedReason, operatorv1.ProviderInstalledCondition)
+	}
+
 	conditions.Set(p.provider, conditions.TrueCondition(operatorv1.ProviderInstalledCondition))
 
 	return reconcile.Result{}, nil
@@ -472,16 +1070,30 @@ func (p *phaseReconciler) upgrade(ctx context.Context) (reconcile.Result, error)
 
 	log.Info("Version changes detected, updating existing components")
 
+	previousVersion := *p.provider.GetStatus().InstalledVersion
+	start := time.Now()
+
 	if err := p.newClusterClient().ProviderUpgrader().ApplyCustomPlan(ctx, cluster.UpgradeOptions{}, cluster.UpgradeItem{
 		NextVersion: p.provider.GetSpec().Version,
 		Provider:    getProvider(p.provider, p.options.Version),
 	}); err != nil {
-		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsUpgradeErrorReason, operatorv1.ProviderUpgradedCondition)
+		err = wrapPhaseError(err, operatorv1.ComponentsUpgradeErrorReason, operatorv1.ProviderUpgradedCondition)
+
+		// A failed upgrade can leave the provider partially deleted, reinstall the previous version.
+		if !p.rollingBack {
+			err = p.rollback(ctx, previousVersion, err)
+		}
+
+		return reconcile.Result{}, err
 	}
 
 	log.Info("Provider successfully upgraded")
+	upgradeDuration.WithLabelValues(p.provider.GetType()).Observe(time.Since(start).Seconds())
 	conditions.Set(p.provider, conditions.TrueCondition(operatorv1.ProviderUpgradedCondition))
 
+	// The previous version is reinstalled if the Deployments of the new one don't become ready.
+	setUpgradedFrom(p.provider, previousVersion)
+
 	return reconcile.Result{}, nil
 }
 
@@ -498,6 +1110,8 @@ func (p *phaseReconciler) install(ctx context.Context) (reconcile.Result, error)
 
 	log.Info("Installing provider")
 
+	start := time.Now()
+
 	if err := clusterClient.ProviderComponents().Create(ctx, p.components.Objs()); err != nil {
 		reason := "Install failed"
 		if wait.Interrupted(err) {
@@ -508,7 +1122,17 @@ func (p *phaseReconciler) install(ctx context.Context) (reconcile.Result, error)
 	}
 
 	log.Info("Provider successfully installed")
-	conditions.Set(p.provider, conditions.TrueCondition(operatorv1.ProviderInstalledCondition))
+	installDuration.WithLabelValues(p.provider.GetType()).Observe(time.Since(start).Seconds())
+
+	// The Deployments of a newly installed provider are waited for without blocking the reconciliation.
+	switch {
+	case p.provider.GetStatus().InstalledVersion == nil && installWaitEnabled(p.provider):
+		setInstalledAt(p.provider)
+		conditions.MarkFalse(p.provider, operatorv1.ProviderInstalledCondition, operatorv1.WaitingForDeploymentsReason, clusterv1.ConditionSeverityInfo,
+			"Waiting for the provider deployments to become ready")
+	case !waitingForInstall(p.provider):
+		conditions.Set(p.provider, conditions.TrueCondition(operatorv1.ProviderInstalledCondition))
+	}
 
 	return reconcile.Result{}, nil
 }
@@ -518,15 +1142,25 @@ func (p *phaseReconciler) reportStatus(ctx context.Context) (reconcile.Result, e
 	status.Contract = &p.contract
 	installedVersion := p.components.Version()
 	status.InstalledVersion = &installedVersion
+	status.UpgradePlan = nil
+
+	if installedVersion == p.desiredVersion() {
+		status.UpgradeSteps = nil
+	} else {
+		markCompletedUpgradeSteps(status.UpgradeSteps, installedVersion)
+	}
+
 	p.provider.SetStatus(status)
 
+	setSuspendedCondition(p.provider)
+
 	return reconcile.Result{}, nil
 }
 
 func getProvider(provider operatorv1.GenericProvider, defaultVersion string) clusterctlv1.Provider {
 	clusterctlProvider := &clusterctlv1.Provider{}
 	clusterctlProvider.Name = clusterctlProviderName(provider).Name
-	clusterctlProvider.Namespace = provider.GetNamespace()
+	clusterctlProvider.Namespace = targetNamespace(provider)
 	clusterctlProvider.Type = string(util.ClusterctlProviderType(provider))
 	clusterctlProvider.ProviderName = provider.GetName()
 
@@ -542,19 +1176,146 @@ func getProvider(provider operatorv1.GenericProvider, defaultVersion string) clu
 // delete deletes the provider components using clusterctl library.
 func (p *phaseReconciler) delete(ctx context.Context) (reconcile.Result, error) {
 	log := ctrl.LoggerFrom(ctx)
+	// Components of externally managed providers are left to the tooling that installed them.
+	if isExternallyManaged(p.provider) {
+		log.Info("Provider is externally managed, skipping components deletion")
+
+		return reconcile.Result{}, nil
+	}
+
+	options := clusterctlDeleteOptions(p.provider, p.options.Version)
+
+	// The CRD webhook denies the deletion of CRDs with instances, and clusterctl deletes the provider controllers
+	// before the CRDs, leaving no one to remove the finalizers of the instances. Nothing is deleted until they are gone.
+	if options.IncludeCRDs {
+		crds, err := p.crdsWithInstances(ctx)
+		if err != nil {
+			return reconcile.Result{}, wrapPhaseError(err, operatorv1.OldComponentsDeletionErrorReason, operatorv1.ProviderInstalledCondition)
+		}
+
+		if len(crds) > 0 {
+			log.Info("Waiting for the instances of the provider CRDs to be deleted", "crds", crds)
+			conditions.MarkFalse(p.provider, operatorv1.ProviderInstalledCondition, operatorv1.CRDInstancesExistReason, clusterv1.ConditionSeverityWarning,
+				"Instances of %s must be deleted before the provider CRDs", strings.Join(crds, ", "))
+
+			return reconcile.Result{RequeueAfter: crdInstancesRequeueAfter}, nil
+		}
+	}
+
 	log.Info("Deleting provider")
 
 	clusterClient := p.newClusterClient()
 
-	err := clusterClient.ProviderComponents().Delete(ctx, cluster.DeleteOptions{
-		Provider:         getProvider(p.provider, p.options.Version),
-		IncludeNamespace: false,
-		IncludeCRDs:      false,
-	})
+	err := clusterClient.ProviderComponents().Delete(ctx, options)
 
 	return reconcile.Result{}, wrapPhaseError(err, operatorv1.OldComponentsDeletionErrorReason, operatorv1.ProviderInstalledCondition)
 }
 
+// crdsWithInstances returns the names of the provider CRDs that still have instances.
+func (p *phaseReconciler) crdsWithInstances(ctx context.Context) ([]string, error) {
+	clusterctlProvider := getProvider(p.provider, "")
+
+	crdList := &unstructured.UnstructuredList{}
+	crdList.SetGroupVersionKind(apiextensionsv1.SchemeGroupVersion.WithKind("CustomResourceDefinitionList"))
+
+	if err := p.ctrlClient.List(ctx, crdList, client.MatchingLabels{clusterv1.ProviderNameLabel: clusterctlProvider.ManifestLabel()}); err != nil {
+		return nil, fmt.Errorf("failed to list CRDs of provider %q: %w", p.provider.GetName(), err)
+	}
+
+	crds := []string{}
+
+	for i := range crdList.Items {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(crdList.Items[i].Object, crd); err != nil {
+			return nil, fmt.Errorf("failed to decode CRD %s: %w", crdList.Items[i].GetName(), err)
+		}
+
+		for _, version := range crd.Spec.Versions {
+			if !version.Storage {
+				continue
+			}
+
+			// Unstructured objects aren't cached, so the instances are listed from the API server.
+			instances := &unstructured.UnstructuredList{}
+			instances.SetGroupVersionKind(schema.GroupVersionKind{Group: crd.Spec.Group, Version: version.Name, Kind: crd.Spec.Names.ListKind})
+
+			if err := p.ctrlClient.List(ctx, instances, client.Limit(1)); err != nil {
+				return nil, fmt.Errorf("failed to list %s: %w", crd.Name, err)
+			}
+
+			if len(instances.Items) > 0 {
+				crds = append(crds, crd.Name)
+			}
+		}
+	}
+
+	return crds, nil
+}
+
+// clusterctlDeleteOptions returns the options to delete the provider components with, CRDs and namespaces are only
+// deleted when requested in the provider spec.
+func clusterctlDeleteOptions(provider operatorv1.GenericProvider, defaultVersion string) cluster.DeleteOptions {
+	options := cluster.DeleteOptions{
+		Provider: getProvider(provider, defaultVersion),
+	}
+
+	if deleteOptions := provider.GetSpec().DeleteOptions; deleteOptions != nil {
+		options.IncludeCRDs = deleteOptions.IncludeCRDs
+		options.IncludeNamespace = deleteOptions.IncludeNamespace
+	}
+
+	return options
+}
+
+// orphanComponents removes the owner references to the provider from its components, so they aren't garbage
+// collected with the provider.
+func (p *phaseReconciler) orphanComponents(ctx context.Context) (reconcile.Result, error) {
+	clusterctlProvider := getProvider(p.provider, "")
+	proxy := &controllerProxy{ctrlClient: clientProxy{p.ctrlClient}, ctrlConfig: p.ctrlConfig}
+
+	objs, err := proxy.ListResources(ctx, map[string]string{clusterv1.ProviderNameLabel: clusterctlProvider.ManifestLabel()}, targetNamespace(p.provider))
+	if err != nil {
+		err = fmt.Errorf("failed to list components of provider %q: %w", p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.OldComponentsDeletionErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	for i := range objs {
+		obj := &objs[i]
+
+		ownerReferences := removeOwnerReference(obj.GetOwnerReferences(), p.provider.GetUID())
+		if len(ownerReferences) == len(obj.GetOwnerReferences()) {
+			continue
+		}
+
+		patchBase := client.MergeFrom(obj.DeepCopy())
+		obj.SetOwnerReferences(ownerReferences)
+
+		if err := p.ctrlClient.Patch(ctx, obj, patchBase); client.IgnoreNotFound(err) != nil {
+			err = fmt.Errorf("failed to orphan %s %s/%s: %w", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+
+			return reconcile.Result{}, wrapPhaseError(err, operatorv1.OldComponentsDeletionErrorReason, operatorv1.ProviderInstalledCondition)
+		}
+	}
+
+	ctrl.LoggerFrom(ctx).Info("Provider components orphaned")
+
+	return reconcile.Result{}, nil
+}
+
+// removeOwnerReference returns the owner references without the ones to the owner with the given UID.
+func removeOwnerReference(ownerReferences []metav1.OwnerReference, uid types.UID) []metav1.OwnerReference {
+	result := []metav1.OwnerReference{}
+
+	for _, ref := range ownerReferences {
+		if ref.UID != uid {
+			result = append(result, ref)
+		}
+	}
+
+	return result
+}
+
 func clusterctlProviderName(provider operatorv1.GenericProvider) client.ObjectKey {
 	prefix := ""
 	switch provider.(type) {
@@ -570,7 +1331,16 @@ func clusterctlProviderName(provider operatorv1.GenericProvider) client.ObjectKe
 		prefix = "ipam-"
 	}
 
-	return client.ObjectKey{Name: prefix + provider.GetName(), Namespace: provider.GetNamespace()}
+	return client.ObjectKey{Name: prefix + provider.GetName(), Namespace: targetNamespace(provider)}
+}
+
+// targetNamespace returns the namespace the provider components are installed into.
+func targetNamespace(provider operatorv1.GenericProvider) string {
+	if namespace := provider.GetSpec().TargetNamespace; namespace != "" {
+		return namespace
+	}
+
+	return provider.GetNamespace()
 }
 
 func (p *phaseReconciler) repositoryProxy(ctx context.Context, provider configclient.Provider, configClient configclient.Client, options ...repository.Option) (repository.Client, error) {
@@ -590,6 +1360,17 @@ func (p *phaseReconciler) newClusterClient() cluster.Client {
 	}), cluster.InjectRepositoryFactory(p.repositoryProxy))
 }
 
+// checkVersionAvailable returns an error listing the available versions if the version is not one of them.
+func checkVersionAvailable(repoVersions []string, version string) error {
+	for _, v := range repoVersions {
+		if v == version {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("version %s is not available, available versions are: %s", version, strings.Join(repoVersions, ", "))
+}
+
 func getLatestVersion(repoVersions []string) (string, error) {
 	if len(repoVersions) == 0 {
 		err := fmt.Errorf("no versions available")
@@ -17,15 +17,29 @@ limitations under the License.
 package controller
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"net/http"
 	"testing"
 
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
@@ -39,8 +53,20 @@ func TestSecretReader(t *testing.T) {
 
 	secretName := "test-secret"
 	secretNamespace := "test-secret-namespace"
+	configMapName := "test-configmap"
 	namespace := "test-namespace"
 
+	testKey1 := "test-key1"
+	testValue1 := "test-value1"
+	testKey2 := "test-key2"
+	testValue2 := "test-value2"
+	testKey3 := "test-key3"
+	testValue3 := "test-value3"
+	testKey4 := "test-key4"
+	testValue4 := "test-value4"
+	testKey5 := "test-key5"
+	testValue5 := "test-value5"
+
 	p := &phaseReconciler{
 		ctrlClient: fakeclient,
 		provider: &operatorv1.CoreProvider{
@@ -58,6 +84,17 @@ func TestSecretReader(t *testing.T) {
 						Name:      secretName,
 						Namespace: secretNamespace,
 					},
+					ConfigMapRef: &operatorv1.ConfigmapReference{
+						Name:      configMapName,
+						Namespace: namespace,
+					},
+					Variables: map[string]string{
+						testKey1: "overridden-by-secret",
+						testKey4: testValue4,
+						testKey5: testValue5,
+
+						providerNamespaceVariable: "overridden-namespace",
+					},
 					FetchConfig: &operatorv1.FetchConfiguration{
 						URL: "https://example.com",
 					},
@@ -66,13 +103,6 @@ func TestSecretReader(t *testing.T) {
 		},
 	}
 
-	testKey1 := "test-key1"
-	testValue1 := "test-value1"
-	testKey2 := "test-key2"
-	testValue2 := "test-value2"
-	testKey3 := "test-key3"
-	testValue3 := "test-value3"
-
 	g.Expect(fakeclient.Create(ctx, &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      secretName,
@@ -84,6 +114,17 @@ func TestSecretReader(t *testing.T) {
 		},
 	})).To(Succeed())
 
+	g.Expect(fakeclient.Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMapName,
+			Namespace: namespace,
+		},
+		Data: map[string]string{
+			testKey2: "overridden-by-secret",
+			testKey4: "overridden-by-variables",
+		},
+	})).To(Succeed())
+
 	configreader, err := p.secretReader(context.TODO(), configclient.NewProvider(testKey3, testValue3, clusterctlv1.CoreProviderType))
 	g.Expect(err).ToNot(HaveOccurred())
 
@@ -95,6 +136,30 @@ func TestSecretReader(t *testing.T) {
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(expectedValue2).To(Equal(testValue2))
 
+	expectedValue4, err := configreader.Get(testKey4)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(expectedValue4).To(Equal(testValue4))
+
+	expectedValue5, err := configreader.Get(testKey5)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(expectedValue5).To(Equal(testValue5))
+
+	// Only the values of the secrets are redacted.
+	g.Expect(p.sensitiveValues.redact(testValue1 + " " + testValue4)).To(Equal(redactedValue + " " + testValue4))
+
+	// Built-in variables are set and can be overridden.
+	providerName, err := configreader.Get(providerNameVariable)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(providerName).To(Equal("cluster-api"))
+
+	providerType, err := configreader.Get(providerTypeVariable)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(providerType).To(Equal("CoreProvider"))
+
+	providerNamespace, err := configreader.Get(providerNamespaceVariable)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(providerNamespace).To(Equal("overridden-namespace"))
+
 	exptectedProviderData, err := configreader.Get("providers")
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(exptectedProviderData).To(Equal(`- name: test-key3
@@ -106,6 +171,80 @@ func TestSecretReader(t *testing.T) {
 `))
 }
 
+func TestSecretReaderConfigSecrets(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := "test-namespace"
+	fakeclient := fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: namespace},
+			Data: map[string][]byte{
+				"AWS_B64ENCODED_CREDENTIALS": []byte("credentials"),
+				"EXP_MACHINE_POOL":           []byte("false"),
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "feature-toggles", Namespace: namespace},
+			Data: map[string][]byte{
+				"EXP_MACHINE_POOL": []byte("true"),
+			},
+		},
+	).Build()
+
+	p := &phaseReconciler{
+		ctrlClient: fakeclient,
+		provider: &operatorv1.InfrastructureProvider{
+			ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: namespace},
+			Spec: operatorv1.InfrastructureProviderSpec{
+				ProviderSpec: operatorv1.ProviderSpec{
+					ConfigSecret: &operatorv1.SecretReference{Name: "credentials", Namespace: namespace},
+					ConfigSecrets: []operatorv1.SecretReference{
+						{Name: "feature-toggles", Namespace: namespace},
+					},
+				},
+			},
+		},
+	}
+
+	configreader, err := p.secretReader(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	credentials, err := configreader.Get("AWS_B64ENCODED_CREDENTIALS")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(credentials).To(Equal("credentials"))
+
+	// The last secret setting a variable takes precedence.
+	machinePool, err := configreader.Get("EXP_MACHINE_POOL")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(machinePool).To(Equal("true"))
+}
+
+func TestMissingVariables(t *testing.T) {
+	g := NewWithT(t)
+
+	mr := configclient.NewMemoryReader()
+	g.Expect(mr.Init(ctx, "")).To(Succeed())
+	mr.Set("TEST_PROVIDER_CREDENTIALS", "credentials")
+
+	configClient, err := configclient.New(ctx, "", configclient.InjectReader(mr))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	p := &phaseReconciler{configClient: configClient}
+
+	missing, err := p.missingVariables([]byte(`apiVersion: v1
+kind: Secret
+metadata:
+  name: manager-bootstrap-credentials
+stringData:
+  credentials: ${TEST_PROVIDER_CREDENTIALS}
+  region: ${TEST_PROVIDER_REGION}
+  profile: ${TEST_PROVIDER_PROFILE:=default}
+  account: ${TEST_PROVIDER_ACCOUNT}
+`))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(missing).To(Equal([]string{"TEST_PROVIDER_ACCOUNT", "TEST_PROVIDER_REGION"}))
+}
+
 func TestConfigmapRepository(t *testing.T) {
 	provider := &operatorv1.InfrastructureProvider{
 		ObjectMeta: metav1.ObjectMeta{
@@ -136,6 +275,16 @@ releaseSeries:
 	minor: 3
 	contract: v1alpha3`
 
+	overriddenMetadata := `apiVersion: clusterctl.cluster.x-k8s.io/v1alpha3
+kind: Metadata
+metadata:
+  creationTimestamp: null
+releaseSeries:
+- contract: v1beta1
+  major: 1
+  minor: 2
+`
+
 	components := `
 apiVersion: v1
 kind: Namespace
@@ -168,10 +317,78 @@ metadata:
 		want                repository.Repository
 		wantErr             string
 		wantDefaultVersion  string
+		requiredLabel       string
+		metadataOverride    *operatorv1.ProviderMetadata
+		wantMetadata        string
 	}{
 		{
 			name:    "missing configmaps",
-			wantErr: "no ConfigMaps found with selector &LabelSelector{MatchLabels:map[string]string{provider-components: aws,},MatchExpressions:[]LabelSelectorRequirement{},}",
+			wantErr: "no ConfigMaps found with selector &LabelSelector{MatchLabels:map[string]string{provider-components: aws,},MatchExpressions:[]LabelSelectorRequirement{},} in namespace ns1",
+		},
+		{
+			name: "configmap without the required label",
+			configMaps: []corev1.ConfigMap{
+				{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "ConfigMap",
+						APIVersion: "v1",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "v1.2.3",
+						Namespace: "ns1",
+						Labels:    map[string]string{"provider-components": "aws"},
+					},
+					Data: map[string]string{
+						"metadata":   metadata,
+						"components": components,
+					},
+				},
+			},
+			requiredLabel: "trusted=true",
+			wantErr:       "no ConfigMaps found with selector &LabelSelector{MatchLabels:map[string]string{provider-components: aws,},MatchExpressions:[]LabelSelectorRequirement{},} in namespace ns1",
+		},
+		{
+			name: "configmap with the required label",
+			configMaps: []corev1.ConfigMap{
+				{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "ConfigMap",
+						APIVersion: "v1",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "v1.2.3",
+						Namespace: "ns1",
+						Labels:    map[string]string{"provider-components": "aws", "trusted": "true"},
+					},
+					Data: map[string]string{
+						"metadata":   metadata,
+						"components": components,
+					},
+				},
+			},
+			requiredLabel:      "trusted=true",
+			wantDefaultVersion: "v1.2.3",
+		},
+		{
+			name: "configmap in another namespace",
+			configMaps: []corev1.ConfigMap{
+				{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "ConfigMap",
+						APIVersion: "v1",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "v1.2.3",
+						Namespace: "ns2",
+						Labels:    map[string]string{"provider-components": "aws"},
+					},
+					Data: map[string]string{
+						"metadata":   metadata,
+						"components": components,
+					},
+				},
+			},
+			wantErr: "no ConfigMaps found with selector &LabelSelector{MatchLabels:map[string]string{provider-components: aws,},MatchExpressions:[]LabelSelectorRequirement{},} in namespace ns1",
 		},
 		{
 			name: "configmap with missing metadata",
@@ -275,6 +492,110 @@ metadata:
 			},
 			wantDefaultVersion: "v1.2.3",
 		},
+		{
+			name: "components split across keys",
+			configMaps: []corev1.ConfigMap{
+				{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "ConfigMap",
+						APIVersion: "v1",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "v1.2.3",
+						Namespace: "ns1",
+						Labels:    map[string]string{"provider-components": "aws"},
+					},
+					Data: map[string]string{
+						"metadata":     metadata,
+						"components-0": components[:100],
+						"components-1": components[100:],
+					},
+				},
+			},
+			wantDefaultVersion: "v1.2.3",
+		},
+		{
+			name: "components split across configmaps",
+			configMaps: []corev1.ConfigMap{
+				{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "ConfigMap",
+						APIVersion: "v1",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "v1.2.3-1",
+						Namespace: "ns1",
+						Labels: map[string]string{
+							"provider-components":                "aws",
+							operatorv1.ConfigMapVersionLabelName: "v1.2.3",
+							operatorv1.ConfigMapChunkLabelName:   "1",
+						},
+					},
+					Data: map[string]string{
+						"components": components[100:],
+					},
+				},
+				{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "ConfigMap",
+						APIVersion: "v1",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "v1.2.3-0",
+						Namespace: "ns1",
+						Labels: map[string]string{
+							"provider-components":                "aws",
+							operatorv1.ConfigMapVersionLabelName: "v1.2.3",
+							operatorv1.ConfigMapChunkLabelName:   "0",
+						},
+					},
+					Data: map[string]string{
+						"metadata":   metadata,
+						"components": components[:100],
+					},
+				},
+			},
+			wantDefaultVersion: "v1.2.3",
+		},
+		{
+			name: "configmaps of the same version without chunk label",
+			configMaps: []corev1.ConfigMap{
+				{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "ConfigMap",
+						APIVersion: "v1",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "v1.2.3",
+						Namespace: "ns1",
+						Labels:    map[string]string{"provider-components": "aws"},
+					},
+					Data: map[string]string{
+						"metadata":   metadata,
+						"components": components,
+					},
+				},
+				{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "ConfigMap",
+						APIVersion: "v1",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "v1.2.3-copy",
+						Namespace: "ns1",
+						Labels: map[string]string{
+							"provider-components":                "aws",
+							operatorv1.ConfigMapVersionLabelName: "v1.2.3",
+						},
+					},
+					Data: map[string]string{
+						"metadata":   metadata,
+						"components": components,
+					},
+				},
+			},
+			wantErr: "found 2 ConfigMaps for version v1.2.3, ConfigMap ns1/v1.2.3 must have the provider.cluster.x-k8s.io/chunk label",
+		},
 		{
 			name: "one correct configmap with label version",
 			configMaps: []corev1.ConfigMap{
@@ -372,51 +693,525 @@ metadata:
 			additionalManifests: additionalManifests,
 			wantDefaultVersion:  "v1.2.3",
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			g := NewWithT(t)
-
-			fakeclient := fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(provider).Build()
-			p := &phaseReconciler{
-				ctrlClient: fakeclient,
-				provider:   provider,
-			}
-
-			for i := range tt.configMaps {
-				g.Expect(fakeclient.Create(ctx, &tt.configMaps[i])).To(Succeed())
-			}
-
-			got, err := p.configmapRepository(context.TODO(), p.provider.GetSpec().FetchConfig.Selector, tt.additionalManifests)
-			if len(tt.wantErr) > 0 {
-				g.Expect(err).Should(MatchError(tt.wantErr))
-				return
-			}
-			g.Expect(err).To(Succeed())
-			gotComponents, err := got.GetFile(ctx, got.DefaultVersion(), got.ComponentsPath())
-			g.Expect(err).To(Succeed())
-
-			if tt.additionalManifests != "" {
-				g.Expect(string(gotComponents)).To(Equal(components + "\n---\n" + additionalManifests))
-			} else {
-				g.Expect(string(gotComponents)).To(Equal(components))
-			}
-
-			gotMetadata, err := got.GetFile(ctx, got.DefaultVersion(), "metadata.yaml")
-			g.Expect(err).To(Succeed())
-			g.Expect(string(gotMetadata)).To(Equal(metadata))
-
-			g.Expect(got.DefaultVersion()).To(Equal(tt.wantDefaultVersion))
-		})
-	}
-}
-
-func TestRepositoryFactory(t *testing.T) {
-	testCases := []struct {
-		name          string
-		fetchURL      string
-		expectedError bool
+		{
+			name: "configmap without metadata and metadata override",
+			configMaps: []corev1.ConfigMap{
+				{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "ConfigMap",
+						APIVersion: "v1",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "v1.2.3",
+						Namespace: "ns1",
+						Labels:    map[string]string{"provider-components": "aws"},
+					},
+					Data: map[string]string{
+						"components": components,
+					},
+				},
+			},
+			metadataOverride: &operatorv1.ProviderMetadata{
+				ReleaseSeries: []operatorv1.ReleaseSeries{
+					{Major: 1, Minor: 2, Contract: "v1beta1"},
+				},
+			},
+			wantMetadata:       overriddenMetadata,
+			wantDefaultVersion: "v1.2.3",
+		},
+		{
+			name: "configmap with gzip compressed components",
+			configMaps: []corev1.ConfigMap{
+				{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "ConfigMap",
+						APIVersion: "v1",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "v1.2.3",
+						Namespace: "ns1",
+						Labels:    map[string]string{"provider-components": "aws"},
+					},
+					Data: map[string]string{
+						"metadata": metadata,
+					},
+					BinaryData: map[string][]byte{
+						"components-gzip": gzipData(NewWithT(t), components),
+					},
+				},
+			},
+			wantDefaultVersion: "v1.2.3",
+		},
+		{
+			name: "configmap with metadata and metadata override",
+			configMaps: []corev1.ConfigMap{
+				{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "ConfigMap",
+						APIVersion: "v1",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "v1.2.3",
+						Namespace: "ns1",
+						Labels:    map[string]string{"provider-components": "aws"},
+					},
+					Data: map[string]string{
+						"metadata":   metadata,
+						"components": components,
+					},
+				},
+			},
+			metadataOverride: &operatorv1.ProviderMetadata{
+				ReleaseSeries: []operatorv1.ReleaseSeries{
+					{Major: 1, Minor: 2, Contract: "v1beta1"},
+				},
+			},
+			wantMetadata:       overriddenMetadata,
+			wantDefaultVersion: "v1.2.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			provider := provider.DeepCopy()
+			provider.Spec.FetchConfig.Metadata = tt.metadataOverride
+
+			fakeclient := fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(provider).Build()
+			p := &phaseReconciler{
+				ctrlClient: fakeclient,
+				provider:   provider,
+			}
+
+			if tt.requiredLabel != "" {
+				requirement, err := ParseLabelRequirement(tt.requiredLabel)
+				g.Expect(err).ToNot(HaveOccurred())
+
+				p.fetchConfigMapRequiredLabel = requirement
+			}
+
+			for i := range tt.configMaps {
+				g.Expect(fakeclient.Create(ctx, &tt.configMaps[i])).To(Succeed())
+			}
+
+			got, err := p.configmapRepository(context.TODO(), p.provider.GetSpec().FetchConfig.Selector, "ns1", tt.additionalManifests)
+			if len(tt.wantErr) > 0 {
+				g.Expect(err).Should(MatchError(tt.wantErr))
+				return
+			}
+			g.Expect(err).To(Succeed())
+			gotComponents, err := got.GetFile(ctx, got.DefaultVersion(), got.ComponentsPath())
+			g.Expect(err).To(Succeed())
+
+			if tt.additionalManifests != "" {
+				g.Expect(string(gotComponents)).To(Equal(components + "\n---\n" + additionalManifests))
+			} else {
+				g.Expect(string(gotComponents)).To(Equal(components))
+			}
+
+			gotMetadata, err := got.GetFile(ctx, got.DefaultVersion(), "metadata.yaml")
+			g.Expect(err).To(Succeed())
+
+			if tt.wantMetadata != "" {
+				g.Expect(string(gotMetadata)).To(Equal(tt.wantMetadata))
+			} else {
+				g.Expect(string(gotMetadata)).To(Equal(metadata))
+			}
+
+			g.Expect(got.DefaultVersion()).To(Equal(tt.wantDefaultVersion))
+
+			// Every version of the matching configmaps is loaded.
+			versions, err := got.GetVersions(ctx)
+			g.Expect(err).To(Succeed())
+
+			for _, version := range versions {
+				_, err := got.GetFile(ctx, version, got.ComponentsPath())
+				g.Expect(err).To(Succeed())
+			}
+		})
+	}
+}
+
+func TestSecretRepository(t *testing.T) {
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "aws",
+			Namespace: "ns1",
+		},
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				FetchConfig: &operatorv1.FetchConfiguration{
+					Secret: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"provider-components": "aws"},
+					},
+				},
+			},
+		},
+	}
+
+	metadata := `apiVersion: clusterctl.cluster.x-k8s.io/v1alpha3
+releaseSeries:
+- major: 1
+  minor: 2
+  contract: v1beta1
+`
+
+	components := `apiVersion: v1
+kind: Namespace
+metadata:
+  name: capa-system
+`
+
+	tests := []struct {
+		name          string
+		secrets       []corev1.Secret
+		requiredLabel string
+		wantErr       string
+	}{
+		{
+			name: "secret with components and metadata",
+			secrets: []corev1.Secret{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "v1.2.3",
+						Namespace: "ns1",
+						Labels:    map[string]string{"provider-components": "aws"},
+					},
+					Data: map[string][]byte{
+						"metadata":   []byte(metadata),
+						"components": []byte(components),
+					},
+				},
+			},
+		},
+		{
+			name: "secret with compressed components",
+			secrets: []corev1.Secret{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "aws-components",
+						Namespace:   "ns1",
+						Labels:      map[string]string{"provider-components": "aws", operatorv1.ConfigMapVersionLabelName: "v1.2.3"},
+						Annotations: map[string]string{compressedAnnotation: "true"},
+					},
+					Data: map[string][]byte{
+						"metadata":   []byte(metadata),
+						"components": gzipData(NewWithT(t), components),
+					},
+				},
+			},
+		},
+		{
+			name: "secret with gzip compressed components",
+			secrets: []corev1.Secret{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "v1.2.3",
+						Namespace: "ns1",
+						Labels:    map[string]string{"provider-components": "aws"},
+					},
+					Data: map[string][]byte{
+						"metadata":        []byte(metadata),
+						"components-gzip": gzipData(NewWithT(t), components),
+					},
+				},
+			},
+		},
+		{
+			name:          "secret without the required label",
+			requiredLabel: "provider-components-trusted",
+			secrets: []corev1.Secret{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "v1.2.3",
+						Namespace: "ns1",
+						Labels:    map[string]string{"provider-components": "aws"},
+					},
+					Data: map[string][]byte{
+						"metadata":   []byte(metadata),
+						"components": []byte(components),
+					},
+				},
+			},
+			wantErr: "no Secrets found with selector &LabelSelector{MatchLabels:map[string]string{provider-components: aws,},MatchExpressions:[]LabelSelectorRequirement{},} in namespace ns1",
+		},
+		{
+			name:    "no secrets",
+			wantErr: "no Secrets found with selector &LabelSelector{MatchLabels:map[string]string{provider-components: aws,},MatchExpressions:[]LabelSelectorRequirement{},} in namespace ns1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			fakeclient := fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(provider.DeepCopy()).Build()
+			p := &phaseReconciler{
+				ctrlClient: fakeclient,
+				provider:   provider.DeepCopy(),
+			}
+
+			if tt.requiredLabel != "" {
+				requirement, err := ParseLabelRequirement(tt.requiredLabel)
+				g.Expect(err).ToNot(HaveOccurred())
+
+				p.fetchConfigMapRequiredLabel = requirement
+			}
+
+			for i := range tt.secrets {
+				g.Expect(fakeclient.Create(ctx, &tt.secrets[i])).To(Succeed())
+			}
+
+			got, err := p.secretRepository(context.TODO(), p.provider.GetSpec().FetchConfig.Secret, "ns1", "")
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(tt.wantErr))
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got.DefaultVersion()).To(Equal("v1.2.3"))
+
+			gotComponents, err := got.GetFile(ctx, got.DefaultVersion(), got.ComponentsPath())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(gotComponents)).To(Equal(components))
+
+			gotMetadata, err := got.GetFile(ctx, got.DefaultVersion(), "metadata.yaml")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(gotMetadata)).To(Equal(metadata))
+		})
+	}
+}
+
+func TestFetchConfigMapNamespaceAllowed(t *testing.T) {
+	tests := []struct {
+		name                     string
+		namespace                string
+		fetchConfigMapNamespaces []string
+		want                     bool
+	}{
+		{
+			name:      "provider namespace",
+			namespace: "capi-system",
+			want:      true,
+		},
+		{
+			name:      "other namespace not allowed",
+			namespace: "capi-manifests",
+			want:      false,
+		},
+		{
+			name:                     "other namespace allowed",
+			namespace:                "capi-manifests",
+			fetchConfigMapNamespaces: []string{"capi-config", "capi-manifests"},
+			want:                     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			p := &phaseReconciler{
+				provider: &operatorv1.CoreProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "cluster-api",
+						Namespace: "capi-system",
+					},
+				},
+				fetchConfigMapNamespaces: tt.fetchConfigMapNamespaces,
+			}
+
+			g.Expect(p.fetchConfigMapNamespaceAllowed(tt.namespace)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestCheckNamespaceGranted(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		grant     string
+		wantErr   bool
+	}{
+		{
+			name:      "provider namespace",
+			namespace: "capi-system",
+		},
+		{
+			name:      "other namespace without grant",
+			namespace: "capi-secrets",
+			wantErr:   true,
+		},
+		{
+			name:      "other namespace granting other namespaces",
+			namespace: "capi-secrets",
+			grant:     "capa-system,capz-system",
+			wantErr:   true,
+		},
+		{
+			name:      "other namespace granting the provider namespace",
+			namespace: "capi-secrets",
+			grant:     "capa-system, capi-system",
+		},
+		{
+			name:      "other namespace granting all namespaces",
+			namespace: "capi-secrets",
+			grant:     "*",
+		},
+		{
+			name:      "missing namespace",
+			namespace: "missing",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "capi-secrets"}}
+			if tt.grant != "" {
+				namespace.Annotations = map[string]string{operatorv1.ConfigSecretGrantAnnotation: tt.grant}
+			}
+
+			fakeclient := fake.NewClientBuilder().WithObjects(namespace).Build()
+
+			err := checkNamespaceGranted(context.Background(), fakeclient, "capi-system", tt.namespace)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
+
+func TestCheckNamespaceGrants(t *testing.T) {
+	tests := []struct {
+		name                   string
+		spec                   operatorv1.ProviderSpec
+		requireNamespaceGrants bool
+		wantReason             string
+	}{
+		{
+			name: "configuration secret of another namespace without requiring grants",
+			spec: operatorv1.ProviderSpec{
+				ConfigSecret: &operatorv1.SecretReference{Name: "capi-variables", Namespace: "capi-secrets"},
+			},
+		},
+		{
+			name: "configuration secret of another namespace",
+			spec: operatorv1.ProviderSpec{
+				ConfigSecret: &operatorv1.SecretReference{Name: "capi-variables", Namespace: "capi-secrets"},
+			},
+			requireNamespaceGrants: true,
+			wantReason:             operatorv1.ConfigSecretNamespaceNotGrantedReason,
+		},
+		{
+			name: "configuration secret of the provider namespace",
+			spec: operatorv1.ProviderSpec{
+				ConfigSecret: &operatorv1.SecretReference{Name: "capi-variables"},
+			},
+			requireNamespaceGrants: true,
+		},
+		{
+			name: "fetch config of another namespace",
+			spec: operatorv1.ProviderSpec{
+				FetchConfig: &operatorv1.FetchConfiguration{
+					Selector:  &metav1.LabelSelector{MatchLabels: map[string]string{"provider-components": "cluster-api"}},
+					Namespace: "capi-secrets",
+				},
+			},
+			requireNamespaceGrants: true,
+			wantReason:             operatorv1.FetchConfigNamespaceNotGrantedReason,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			provider := &operatorv1.CoreProvider{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "capi-system"},
+				Spec:       operatorv1.CoreProviderSpec{ProviderSpec: tt.spec},
+			}
+
+			p := &phaseReconciler{
+				ctrlClient: fake.NewClientBuilder().WithObjects(
+					&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "capi-secrets"}},
+				).Build(),
+				provider:               provider,
+				requireNamespaceGrants: tt.requireNamespaceGrants,
+			}
+
+			err := p.checkNamespaceGrants(context.Background())
+			if tt.wantReason != "" {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(conditions.GetReason(provider, operatorv1.PreflightCheckCondition)).To(Equal(tt.wantReason))
+
+				return
+			}
+
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
+
+func TestParseLabelRequirement(t *testing.T) {
+	tests := []struct {
+		name        string
+		requirement string
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:        "label key",
+			requirement: "trusted",
+			want:        "trusted",
+		},
+		{
+			name:        "label key and value",
+			requirement: "provider-components=trusted",
+			want:        "provider-components=trusted",
+		},
+		{
+			name:        "invalid label key",
+			requirement: "not a label=value",
+			wantErr:     true,
+		},
+		{
+			name:        "invalid label value",
+			requirement: "key=not a value",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, err := ParseLabelRequirement(tt.requirement)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got.String()).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestRepositoryFactory(t *testing.T) {
+	testCases := []struct {
+		name          string
+		fetchURL      string
+		forge         operatorv1.ForgeType
+		expectedError bool
 	}{
 		{
 			name:     "github repo",
@@ -426,6 +1221,12 @@ func TestRepositoryFactory(t *testing.T) {
 			name:     "gitlab repo",
 			fetchURL: "https://gitlab.example.org/api/v4/projects/group%2Fproject/packages/generic/cluster-api-proviver-aws/v1.4.1/path",
 		},
+		{
+			name:          "github repo with gitea forge",
+			fetchURL:      "https://github.com/kubernetes-sigs/cluster-api-provider-aws/infrastructure-components.yaml",
+			forge:         operatorv1.ForgeGitea,
+			expectedError: true,
+		},
 		{
 			name:          "unsupported url",
 			fetchURL:      "https://unsupported.xyz/kubernetes-sigs/cluster-api-provider-aws/releases/v1.4.1/infrastructure-components.yaml",
@@ -476,7 +1277,7 @@ func TestRepositoryFactory(t *testing.T) {
 			providerConfig, err := configClient.Providers().Get(providerName, providerType)
 			g.Expect(err).ToNot(HaveOccurred())
 
-			repo, err := util.RepositoryFactory(ctx, providerConfig, configClient.Variables())
+			repo, err := util.RepositoryFactory(ctx, providerConfig, tc.forge, http.DefaultClient, configClient.Variables())
 			if tc.expectedError {
 				g.Expect(err).To(HaveOccurred())
 
@@ -490,6 +1291,26 @@ func TestRepositoryFactory(t *testing.T) {
 	}
 }
 
+// gzipData returns the gzip compressed data.
+func gzipData(g *WithT, data string) []byte {
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(data))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(zw.Close()).To(Succeed())
+
+	return buf.Bytes()
+}
+
+func TestCheckVersionAvailable(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(checkVersionAvailable([]string{"v1.2.3", "v1.2.4"}, "v1.2.4")).To(Succeed())
+	g.Expect(checkVersionAvailable([]string{"v1.2.3", "v1.2.4"}, "v1.2.5")).To(MatchError("version v1.2.5 is not available, available versions are: v1.2.3, v1.2.4"))
+	g.Expect(checkVersionAvailable([]string{}, "v1.2.5")).To(HaveOccurred())
+}
+
 func TestGetLatestVersion(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -540,3 +1361,153 @@ func TestGetLatestVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestOrphanComponents(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "docker",
+			Namespace: "capd-system",
+			UID:       "provider-uid",
+		},
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				DeletionPolicy: operatorv1.DeletionPolicyOrphan,
+			},
+		},
+	}
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "capd-controller-manager",
+			Namespace: "capd-system",
+			Labels:    map[string]string{clusterv1.ProviderNameLabel: "infrastructure-docker"},
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: operatorv1.GroupVersion.String(), Kind: "InfrastructureProvider", Name: "docker", UID: "provider-uid"},
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "other", UID: "other-uid"},
+			},
+		},
+	}
+
+	fakeclient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(deployment).Build()
+
+	p := &phaseReconciler{
+		ctrlClient: fakeclient,
+		provider:   provider,
+	}
+
+	_, err := p.orphanComponents(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(fakeclient.Get(context.TODO(), client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+	g.Expect(deployment.OwnerReferences).To(HaveLen(1))
+	g.Expect(deployment.OwnerReferences[0].UID).To(BeEquivalentTo("other-uid"))
+}
+
+func TestClusterctlDeleteOptions(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "docker",
+			Namespace: "capd-system",
+		},
+	}
+
+	// CRDs and namespaces are kept by default.
+	options := clusterctlDeleteOptions(provider, "v1.6.0")
+	g.Expect(options.Provider.Name).To(Equal("infrastructure-docker"))
+	g.Expect(options.IncludeCRDs).To(BeFalse())
+	g.Expect(options.IncludeNamespace).To(BeFalse())
+
+	provider.Spec.DeleteOptions = &operatorv1.DeleteOptions{IncludeCRDs: true, IncludeNamespace: true}
+
+	options = clusterctlDeleteOptions(provider, "v1.6.0")
+	g.Expect(options.IncludeCRDs).To(BeTrue())
+	g.Expect(options.IncludeNamespace).To(BeTrue())
+}
+
+func TestDeleteWaitsForCRDInstances(t *testing.T) {
+	g := NewWithT(t)
+
+	crdGVK := apiextensionsv1.SchemeGroupVersion.WithKind("CustomResourceDefinition")
+	gvk := schema.GroupVersionKind{Group: "infrastructure.cluster.x-k8s.io", Version: "v1beta1", Kind: "AWSCluster"}
+
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "awsclusters.infrastructure.cluster.x-k8s.io",
+			Labels: map[string]string{clusterv1.ProviderNameLabel: "infrastructure-aws"},
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: gvk.Group,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Kind:     gvk.Kind,
+				ListKind: gvk.Kind + "List",
+				Plural:   "awsclusters",
+			},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1beta2", Storage: false},
+				{Name: gvk.Version, Storage: true},
+			},
+		},
+	}
+
+	crdObject, err := runtime.DefaultUnstructuredConverter.ToUnstructured(crd)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	crdUnstructured := &unstructured.Unstructured{Object: crdObject}
+	crdUnstructured.SetGroupVersionKind(crdGVK)
+
+	instance := &unstructured.Unstructured{}
+	instance.SetGroupVersionKind(gvk)
+	instance.SetName("my-cluster")
+	instance.SetNamespace("default")
+
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{crdGVK.GroupVersion(), gvk.GroupVersion()})
+	mapper.Add(crdGVK, meta.RESTScopeRoot)
+	mapper.Add(gvk, meta.RESTScopeNamespace)
+
+	fakeclient := fake.NewClientBuilder().
+		WithScheme(runtime.NewScheme()).
+		WithRESTMapper(mapper).
+		WithObjects(crdUnstructured, instance).
+		Build()
+
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "aws",
+			Namespace: "capa-system",
+		},
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				DeleteOptions: &operatorv1.DeleteOptions{IncludeCRDs: true},
+			},
+		},
+	}
+
+	p := &phaseReconciler{
+		ctrlClient: fakeclient,
+		provider:   provider,
+	}
+
+	crds, err := p.crdsWithInstances(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(crds).To(ConsistOf(crd.Name))
+
+	// Nothing is deleted while the instances exist.
+	res, err := p.delete(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.RequeueAfter).To(Equal(crdInstancesRequeueAfter))
+	g.Expect(conditions.GetReason(provider, operatorv1.ProviderInstalledCondition)).To(Equal(operatorv1.CRDInstancesExistReason))
+
+	g.Expect(fakeclient.Delete(context.TODO(), instance)).To(Succeed())
+
+	crds, err = p.crdsWithInstances(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(crds).To(BeEmpty())
+}
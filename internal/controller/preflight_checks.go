@@ -21,8 +21,6 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/google/go-github/v52/github"
-	"golang.org/x/oauth2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/version"
@@ -58,8 +56,8 @@ func preflightChecks(ctx context.Context, c client.Client, provider genericprovi
 
 	spec := provider.GetSpec()
 
-	// Check that provider version contains a valid value if it's not empty.
-	if spec.Version != "" {
+	// Check that provider version contains a valid value if it doesn't track the newest release.
+	if !tracksLatestVersion(spec) {
 		if _, err := version.ParseSemantic(spec.Version); err != nil {
 			log.Info("Version contains invalid value")
 			conditions.Set(provider, conditions.FalseCondition(
@@ -73,6 +71,23 @@ func preflightChecks(ctx context.Context, c client.Client, provider genericprovi
 		}
 	}
 
+	// Check that the provider is allowed by the provider policies, its version is checked again once it's resolved.
+	violation, err := util.ProviderPolicyViolation(ctx, c, provider, spec.Version)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if violation != "" {
+		conditions.Set(provider, conditions.FalseCondition(
+			operatorv1.PreflightCheckCondition,
+			operatorv1.ProviderNotAllowedReason,
+			clusterv1.ConditionSeverityError,
+			violation,
+		))
+
+		return ctrl.Result{}, fmt.Errorf("provider %q is not allowed: %s", provider.GetName(), violation)
+	}
+
 	// Ensure that the CoreProvider is called "cluster-api".
 	if util.IsCoreProvider(provider) {
 		if provider.GetName() != configclient.ClusterAPIProviderName {
@@ -94,53 +109,121 @@ func preflightChecks(ctx context.Context, c client.Client, provider genericprovi
 	}
 
 	if !isPredefinedProvider {
-		if spec.FetchConfig == nil || spec.FetchConfig.Selector == nil && spec.FetchConfig.URL == "" {
+		if fetchConfigSources(spec.FetchConfig) == 0 {
 			conditions.Set(provider, conditions.FalseCondition(
 				operatorv1.PreflightCheckCondition,
 				operatorv1.FetchConfigValidationErrorReason,
 				clusterv1.ConditionSeverityError,
-				"Either Selector or URL must be provided for a not predefined provider",
+				"Either Selector, Secret, URL, OCI, Git, Chart, S3 or LocalPath must be provided for a not predefined provider",
+			))
+
+			return ctrl.Result{}, fmt.Errorf("either selector, secret, URL, OCI, Git, Chart, S3 or LocalPath must be provided for a not predefined provider %s", provider.GetName())
+		}
+	}
+
+	if fetchConfigSources(spec.FetchConfig) > 1 {
+		// If FetchConfiguration is not nil, exactly one of `URL`, `Selector`, `Secret`, `OCI`, `Git`, `Chart`, `S3` or `LocalPath` must be specified.
+		conditions.Set(provider, conditions.FalseCondition(
+			operatorv1.PreflightCheckCondition,
+			operatorv1.FetchConfigValidationErrorReason,
+			clusterv1.ConditionSeverityError,
+			"Only one of Selector, Secret, URL, OCI, Git, Chart, S3 and LocalPath must be provided",
+		))
+
+		return ctrl.Result{}, fmt.Errorf("only one of Selector, Secret, URL, OCI, Git, Chart, S3 and LocalPath must be provided for provider %s", provider.GetName())
+	}
+
+	if hasVersionPolicy(spec) && !versionPolicySupported(spec.FetchConfig) {
+		conditions.Set(provider, conditions.FalseCondition(
+			operatorv1.PreflightCheckCondition,
+			operatorv1.FetchConfigValidationErrorReason,
+			clusterv1.ConditionSeverityError,
+			"VersionPolicy can only be provided with URL, Forge, Selector or Secret",
+		))
+
+		return ctrl.Result{}, fmt.Errorf("version policy can only be provided with URL, forge, selector or secret for provider %s", provider.GetName())
+	}
+
+	if spec.MaintenanceWindow != nil {
+		if _, _, err := maintenanceWindowSchedule(spec.MaintenanceWindow); err != nil {
+			conditions.Set(provider, conditions.FalseCondition(
+				operatorv1.PreflightCheckCondition,
+				operatorv1.InvalidMaintenanceWindowReason,
+				clusterv1.ConditionSeverityError,
+				err.Error(),
 			))
 
-			return ctrl.Result{}, fmt.Errorf("either selector or URL must be provided for a not predefined provider %s", provider.GetName())
+			return ctrl.Result{}, fmt.Errorf("invalid maintenance window for provider %s: %w", provider.GetName(), err)
 		}
 	}
 
-	if spec.FetchConfig != nil && spec.FetchConfig.Selector != nil && spec.FetchConfig.URL != "" {
-		// If FetchConfiguration is not nil, exactly one of `URL` or `Selector` must be specified.
+	if spec.FetchConfig != nil && spec.FetchConfig.Forge != "" && spec.FetchConfig.URL == "" {
+		// Forge is the type of the URL forge, it can't be used with other sources.
+		conditions.Set(provider, conditions.FalseCondition(
+			operatorv1.PreflightCheckCondition,
+			operatorv1.FetchConfigValidationErrorReason,
+			clusterv1.ConditionSeverityError,
+			"Forge can only be provided with URL",
+		))
+
+		return ctrl.Result{}, fmt.Errorf("forge can only be provided with URL for provider %s", provider.GetName())
+	}
+
+	if spec.FetchConfig != nil && spec.FetchConfig.OCIPullSecret != nil && spec.FetchConfig.OCI == "" && spec.FetchConfig.Chart == nil {
+		// The OCI pull secret only has the credentials of OCI registries.
+		conditions.Set(provider, conditions.FalseCondition(
+			operatorv1.PreflightCheckCondition,
+			operatorv1.FetchConfigValidationErrorReason,
+			clusterv1.ConditionSeverityError,
+			"OCIPullSecret can only be provided with OCI or Chart",
+		))
+
+		return ctrl.Result{}, fmt.Errorf("OCIPullSecret can only be provided with OCI or Chart for provider %s", provider.GetName())
+	}
+
+	if message := fetchConfigVerificationError(spec.FetchConfig); message != "" {
 		conditions.Set(provider, conditions.FalseCondition(
 			operatorv1.PreflightCheckCondition,
 			operatorv1.FetchConfigValidationErrorReason,
 			clusterv1.ConditionSeverityError,
-			"Only one of Selector and URL must be provided, not both",
+			message,
 		))
 
-		return ctrl.Result{}, fmt.Errorf("only one of Selector and URL must be provided for provider %s", provider.GetName())
+		return ctrl.Result{}, fmt.Errorf("invalid verification for provider %s: %s", provider.GetName(), message)
 	}
 
-	// Validate that provided github token works and has repository access.
-	if spec.ConfigSecret != nil {
+	// Validate that provided github token works and has repository access. The token of the last
+	// configuration secret setting it is used, like for the other variables.
+	var token []byte
+
+	for _, ref := range configSecretRefs(spec) {
 		secret := &corev1.Secret{}
-		key := types.NamespacedName{Namespace: provider.GetSpec().ConfigSecret.Namespace, Name: provider.GetSpec().ConfigSecret.Name}
+		key := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
 
 		if err := c.Get(ctx, key, secret); err != nil {
 			return ctrl.Result{}, fmt.Errorf("failed to get providers secret: %w", err)
 		}
 
-		if token, ok := secret.Data[configclient.GitHubTokenVariable]; ok {
-			client := github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(
-				&oauth2.Token{AccessToken: string(token)},
-			)))
-			if _, _, err := client.Organizations.List(ctx, "kubernetes-sigs", nil); err != nil {
-				conditions.Set(provider, conditions.FalseCondition(
-					operatorv1.PreflightCheckCondition,
-					operatorv1.InvalidGithubTokenReason,
-					clusterv1.ConditionSeverityError,
-					invalidGithubTokenMessage,
-				))
+		if t, ok := secret.Data[configclient.GitHubTokenVariable]; ok {
+			token = t
+		}
+	}
 
+	if token != nil {
+		if err := validateGitHubToken(ctx, provider, string(token)); err != nil {
+			// The preflight check only fails on invalid tokens, transient errors are retried.
+			if !invalidFetchCredentialsReported(provider) {
 				return ctrl.Result{}, fmt.Errorf("failed to validate provided github token: %w", err)
 			}
+
+			conditions.Set(provider, conditions.FalseCondition(
+				operatorv1.PreflightCheckCondition,
+				operatorv1.InvalidGithubTokenReason,
+				clusterv1.ConditionSeverityError,
+				invalidGithubTokenMessage,
+			))
+
+			return ctrl.Result{}, fmt.Errorf("failed to validate provided github token: %w", err)
 		}
 	}
 
@@ -155,6 +238,11 @@ func preflightChecks(ctx context.Context, c client.Client, provider genericprovi
 			continue
 		}
 
+		// Skip the instance that is being moved to the namespace of the provider.
+		if p.GetNamespace() == migrationSourceNamespace(provider) && p.GetName() == provider.GetName() {
+			continue
+		}
+
 		preflightFalseCondition := conditions.FalseCondition(
 			operatorv1.PreflightCheckCondition,
 			operatorv1.MoreThanOneProviderInstanceExistsReason,
@@ -249,3 +337,66 @@ func isPredefinedProvider(ctx context.Context, providerName string, providerType
 
 	return err == nil, nil
 }
+
+// fetchConfigVerificationError returns why the signature or provenance verification of the fetch configuration is
+// invalid, or an empty string if they are valid or not set.
+func fetchConfigVerificationError(fetchConfig *operatorv1.FetchConfiguration) string {
+	if fetchConfig == nil || (fetchConfig.Verification == nil && fetchConfig.Provenance == nil) {
+		return ""
+	}
+
+	// Signatures and attestations are only read next to the manifests downloaded from release repositories, buckets and local paths.
+	if fetchConfig.Selector != nil || fetchConfig.Secret != nil || fetchConfig.OCI != "" || fetchConfig.Git != nil || fetchConfig.Chart != nil {
+		if fetchConfig.Verification != nil {
+			return "Verification can only be provided with URL, S3 or LocalPath"
+		}
+
+		return "Provenance can only be provided with URL, S3 or LocalPath"
+	}
+
+	if verification := fetchConfig.Verification; verification != nil {
+		if message := verificationKeyError("Verification", verification.PublicKey); message != "" {
+			return message
+		}
+	}
+
+	if provenance := fetchConfig.Provenance; provenance != nil {
+		if provenance.BuilderID == "" {
+			return "BuilderID must be provided in Provenance"
+		}
+
+		return verificationKeyError("Provenance", provenance.PublicKey)
+	}
+
+	return ""
+}
+
+// verificationKeyError returns why the public key of the field is invalid, or an empty string if it's valid.
+func verificationKeyError(field, publicKey string) string {
+	if publicKey == "" {
+		return fmt.Sprintf("PublicKey must be provided in %s", field)
+	}
+
+	if _, err := parsePublicKey([]byte(publicKey)); err != nil {
+		return fmt.Sprintf("PublicKey of %s must be a PEM encoded public key", field)
+	}
+
+	return ""
+}
+
+// fetchConfigSources returns the number of sources set in the fetch configuration.
+func fetchConfigSources(fetchConfig *operatorv1.FetchConfiguration) int {
+	if fetchConfig == nil {
+		return 0
+	}
+
+	sources := 0
+
+	for _, set := range []bool{fetchConfig.URL != "", fetchConfig.Selector != nil, fetchConfig.Secret != nil, fetchConfig.OCI != "", fetchConfig.Git != nil, fetchConfig.Chart != nil, fetchConfig.S3 != nil, fetchConfig.LocalPath != ""} {
+		if set {
+			sources++
+		}
+	}
+
+	return sources
+}
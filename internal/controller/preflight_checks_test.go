@@ -39,6 +39,7 @@ func TestPreflightChecks(t *testing.T) {
 		name              string
 		providers         []operatorv1.GenericProvider
 		providerList      genericprovider.GenericProviderList
+		policies          []operatorv1.ProviderPolicy
 		expectedCondition clusterv1.Condition
 		expectedError     bool
 	}{
@@ -417,6 +418,73 @@ func TestPreflightChecks(t *testing.T) {
 			},
 			providerList: &operatorv1.InfrastructureProviderList{},
 		},
+		{
+			name: "similar infra provider is moved from another namespace, preflight check passed",
+			providers: []operatorv1.GenericProvider{
+				&operatorv1.InfrastructureProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "aws",
+						Namespace:   namespaceName1,
+						Annotations: map[string]string{operatorv1.MigrateFromNamespaceAnnotation: namespaceName2},
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "InfrastructureProvider",
+						APIVersion: "operator.cluster.x-k8s.io/v1alpha1",
+					},
+					Spec: operatorv1.InfrastructureProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							Version: "v1.0.0",
+						},
+					},
+				},
+				&operatorv1.InfrastructureProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "aws",
+						Namespace: namespaceName2,
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "InfrastructureProvider",
+						APIVersion: "operator.cluster.x-k8s.io/v1alpha1",
+					},
+					Spec: operatorv1.InfrastructureProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							Version: "v1.0.0",
+						},
+					},
+				},
+				&operatorv1.CoreProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "cluster-api",
+						Namespace: namespaceName2,
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "CoreProvider",
+						APIVersion: "operator.cluster.x-k8s.io/v1alpha4",
+					},
+					Spec: operatorv1.CoreProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							Version: "v1.0.0",
+						},
+					},
+					Status: operatorv1.CoreProviderStatus{
+						ProviderStatus: operatorv1.ProviderStatus{
+							Conditions: []clusterv1.Condition{
+								{
+									Type:               clusterv1.ReadyCondition,
+									Status:             corev1.ConditionTrue,
+									LastTransitionTime: metav1.Now(),
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedCondition: clusterv1.Condition{
+				Type:   operatorv1.PreflightCheckCondition,
+				Status: corev1.ConditionTrue,
+			},
+			providerList: &operatorv1.InfrastructureProviderList{},
+		},
 		{
 			name:          "wrong version, preflight check failed",
 			expectedError: true,
@@ -473,6 +541,105 @@ func TestPreflightChecks(t *testing.T) {
 			},
 			providerList: &operatorv1.CoreProviderList{},
 		},
+		{
+			name: "latest version, preflight check passed",
+			providers: []operatorv1.GenericProvider{
+				&operatorv1.CoreProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "cluster-api",
+						Namespace: namespaceName1,
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "CoreProvider",
+						APIVersion: "operator.cluster.x-k8s.io/v1alpha1",
+					},
+					Spec: operatorv1.CoreProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							Version: operatorv1.VersionLatest,
+						},
+					},
+				},
+			},
+			expectedCondition: clusterv1.Condition{
+				Type:   operatorv1.PreflightCheckCondition,
+				Status: corev1.ConditionTrue,
+			},
+			providerList: &operatorv1.CoreProviderList{},
+		},
+		{
+			name: "provider allowed by a provider policy, preflight check passed",
+			providers: []operatorv1.GenericProvider{
+				&operatorv1.CoreProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "cluster-api",
+						Namespace: namespaceName1,
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "CoreProvider",
+						APIVersion: "operator.cluster.x-k8s.io/v1alpha1",
+					},
+					Spec: operatorv1.CoreProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							Version: "v1.5.0",
+						},
+					},
+				},
+			},
+			policies: []operatorv1.ProviderPolicy{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "core"},
+					Spec: operatorv1.ProviderPolicySpec{
+						Rules: []operatorv1.ProviderPolicyRule{
+							{Type: "CoreProvider", Versions: ">= v1.4.0, < v2.0.0"},
+						},
+					},
+				},
+			},
+			expectedCondition: clusterv1.Condition{
+				Type:   operatorv1.PreflightCheckCondition,
+				Status: corev1.ConditionTrue,
+			},
+			providerList: &operatorv1.CoreProviderList{},
+		},
+		{
+			name:          "provider version not allowed by provider policies, preflight check failed",
+			expectedError: true,
+			providers: []operatorv1.GenericProvider{
+				&operatorv1.InfrastructureProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "aws",
+						Namespace: namespaceName1,
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "InfrastructureProvider",
+						APIVersion: "operator.cluster.x-k8s.io/v1alpha1",
+					},
+					Spec: operatorv1.InfrastructureProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							Version: "v1.5.0",
+						},
+					},
+				},
+			},
+			policies: []operatorv1.ProviderPolicy{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "infrastructure"},
+					Spec: operatorv1.ProviderPolicySpec{
+						Rules: []operatorv1.ProviderPolicyRule{
+							{Type: "InfrastructureProvider", Names: []string{"aws", "azure"}, Versions: ">= v2.0.0"},
+						},
+					},
+				},
+			},
+			expectedCondition: clusterv1.Condition{
+				Type:     operatorv1.PreflightCheckCondition,
+				Reason:   operatorv1.ProviderNotAllowedReason,
+				Severity: clusterv1.ConditionSeverityError,
+				Message:  "InfrastructureProvider \"aws\" with version v1.5.0 is not allowed by any ProviderPolicy",
+				Status:   corev1.ConditionFalse,
+			},
+			providerList: &operatorv1.InfrastructureProviderList{},
+		},
 		{
 			name:          "incorrect fetchConfig, preflight check failed",
 			expectedError: true,
@@ -503,7 +670,7 @@ func TestPreflightChecks(t *testing.T) {
 				Type:     operatorv1.PreflightCheckCondition,
 				Reason:   operatorv1.FetchConfigValidationErrorReason,
 				Severity: clusterv1.ConditionSeverityError,
-				Message:  "Only one of Selector and URL must be provided, not both",
+				Message:  "Only one of Selector, Secret, URL, OCI, Git, Chart, S3 and LocalPath must be provided",
 				Status:   corev1.ConditionFalse,
 			},
 			providerList: &operatorv1.InfrastructureProviderList{},
@@ -557,7 +724,7 @@ func TestPreflightChecks(t *testing.T) {
 				Type:     operatorv1.PreflightCheckCondition,
 				Reason:   operatorv1.FetchConfigValidationErrorReason,
 				Severity: clusterv1.ConditionSeverityError,
-				Message:  "Either Selector or URL must be provided for a not predefined provider",
+				Message:  "Either Selector, Secret, URL, OCI, Git, Chart, S3 or LocalPath must be provided for a not predefined provider",
 				Status:   corev1.ConditionFalse,
 			},
 			providerList: &operatorv1.CoreProviderList{},
@@ -590,11 +757,299 @@ func TestPreflightChecks(t *testing.T) {
 				Type:     operatorv1.PreflightCheckCondition,
 				Reason:   operatorv1.FetchConfigValidationErrorReason,
 				Severity: clusterv1.ConditionSeverityError,
-				Message:  "Either Selector or URL must be provided for a not predefined provider",
+				Message:  "Either Selector, Secret, URL, OCI, Git, Chart, S3 or LocalPath must be provided for a not predefined provider",
 				Status:   corev1.ConditionFalse,
 			},
 			providerList: &operatorv1.CoreProviderList{},
 		},
+		{
+			name: "custom Infrastructure Provider with OCI fetch config, preflight check passed",
+			providers: []operatorv1.GenericProvider{
+				&operatorv1.InfrastructureProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "my-custom-aws",
+						Namespace: namespaceName1,
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "InfrastructureProvider",
+						APIVersion: "operator.cluster.x-k8s.io/v1alpha1",
+					},
+					Spec: operatorv1.InfrastructureProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							Version: "v1.0.0",
+							FetchConfig: &operatorv1.FetchConfiguration{
+								OCI: "registry.example.com/my-custom-aws",
+							},
+						},
+					},
+				},
+				&operatorv1.CoreProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "cluster-api",
+						Namespace: namespaceName2,
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "CoreProvider",
+						APIVersion: "operator.cluster.x-k8s.io/v1alpha4",
+					},
+					Spec: operatorv1.CoreProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							Version: "v1.0.0",
+						},
+					},
+					Status: operatorv1.CoreProviderStatus{
+						ProviderStatus: operatorv1.ProviderStatus{
+							Conditions: []clusterv1.Condition{
+								{
+									Type:               clusterv1.ReadyCondition,
+									Status:             corev1.ConditionTrue,
+									LastTransitionTime: metav1.Now(),
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedCondition: clusterv1.Condition{
+				Type:   operatorv1.PreflightCheckCondition,
+				Status: corev1.ConditionTrue,
+			},
+			providerList: &operatorv1.InfrastructureProviderList{},
+		},
+		{
+			name:          "fetch config with URL and OCI, preflight check failed",
+			expectedError: true,
+			providers: []operatorv1.GenericProvider{
+				&operatorv1.InfrastructureProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "aws",
+						Namespace: namespaceName1,
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "InfrastructureProvider",
+						APIVersion: "operator.cluster.x-k8s.io/v1alpha1",
+					},
+					Spec: operatorv1.InfrastructureProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							Version: "v1.0.0",
+							FetchConfig: &operatorv1.FetchConfiguration{
+								URL: "https://example.com",
+								OCI: "registry.example.com/aws",
+							},
+						},
+					},
+				},
+			},
+			expectedCondition: clusterv1.Condition{
+				Type:     operatorv1.PreflightCheckCondition,
+				Reason:   operatorv1.FetchConfigValidationErrorReason,
+				Severity: clusterv1.ConditionSeverityError,
+				Message:  "Only one of Selector, Secret, URL, OCI, Git, Chart, S3 and LocalPath must be provided",
+				Status:   corev1.ConditionFalse,
+			},
+			providerList: &operatorv1.InfrastructureProviderList{},
+		},
+		{
+			name:          "version policy with OCI fetch config, preflight check failed",
+			expectedError: true,
+			providers: []operatorv1.GenericProvider{
+				&operatorv1.InfrastructureProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "aws",
+						Namespace: namespaceName1,
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "InfrastructureProvider",
+						APIVersion: "operator.cluster.x-k8s.io/v1alpha1",
+					},
+					Spec: operatorv1.InfrastructureProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							Version:       "v1.0.0",
+							VersionPolicy: operatorv1.VersionPolicyLatestPatch,
+							FetchConfig: &operatorv1.FetchConfiguration{
+								OCI: "registry.example.com/aws",
+							},
+						},
+					},
+				},
+			},
+			expectedCondition: clusterv1.Condition{
+				Type:     operatorv1.PreflightCheckCondition,
+				Reason:   operatorv1.FetchConfigValidationErrorReason,
+				Severity: clusterv1.ConditionSeverityError,
+				Message:  "VersionPolicy can only be provided with URL, Forge, Selector or Secret",
+				Status:   corev1.ConditionFalse,
+			},
+			providerList: &operatorv1.InfrastructureProviderList{},
+		},
+		{
+			name:          "fetch config with forge and OCI, preflight check failed",
+			expectedError: true,
+			providers: []operatorv1.GenericProvider{
+				&operatorv1.InfrastructureProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "aws",
+						Namespace: namespaceName1,
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "InfrastructureProvider",
+						APIVersion: "operator.cluster.x-k8s.io/v1alpha1",
+					},
+					Spec: operatorv1.InfrastructureProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							Version: "v1.0.0",
+							FetchConfig: &operatorv1.FetchConfiguration{
+								OCI:   "registry.example.com/aws",
+								Forge: operatorv1.ForgeGitea,
+							},
+						},
+					},
+				},
+			},
+			expectedCondition: clusterv1.Condition{
+				Type:     operatorv1.PreflightCheckCondition,
+				Reason:   operatorv1.FetchConfigValidationErrorReason,
+				Severity: clusterv1.ConditionSeverityError,
+				Message:  "Forge can only be provided with URL",
+				Status:   corev1.ConditionFalse,
+			},
+			providerList: &operatorv1.InfrastructureProviderList{},
+		},
+		{
+			name:          "fetch config with OCI pull secret and URL, preflight check failed",
+			expectedError: true,
+			providers: []operatorv1.GenericProvider{
+				&operatorv1.InfrastructureProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "aws",
+						Namespace: namespaceName1,
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "InfrastructureProvider",
+						APIVersion: "operator.cluster.x-k8s.io/v1alpha1",
+					},
+					Spec: operatorv1.InfrastructureProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							Version: "v1.0.0",
+							FetchConfig: &operatorv1.FetchConfiguration{
+								URL:           "https://github.com/kubernetes-sigs/cluster-api-provider-aws/releases",
+								OCIPullSecret: &corev1.LocalObjectReference{Name: "registry-credentials"},
+							},
+						},
+					},
+				},
+			},
+			expectedCondition: clusterv1.Condition{
+				Type:     operatorv1.PreflightCheckCondition,
+				Reason:   operatorv1.FetchConfigValidationErrorReason,
+				Severity: clusterv1.ConditionSeverityError,
+				Message:  "OCIPullSecret can only be provided with OCI or Chart",
+				Status:   corev1.ConditionFalse,
+			},
+			providerList: &operatorv1.InfrastructureProviderList{},
+		},
+		{
+			name:          "fetch config with verification and OCI, preflight check failed",
+			expectedError: true,
+			providers: []operatorv1.GenericProvider{
+				&operatorv1.InfrastructureProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "aws",
+						Namespace: namespaceName1,
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "InfrastructureProvider",
+						APIVersion: "operator.cluster.x-k8s.io/v1alpha1",
+					},
+					Spec: operatorv1.InfrastructureProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							Version: "v1.0.0",
+							FetchConfig: &operatorv1.FetchConfiguration{
+								OCI:          "registry.example.com/aws",
+								Verification: &operatorv1.VerificationConfiguration{PublicKey: "key"},
+							},
+						},
+					},
+				},
+			},
+			expectedCondition: clusterv1.Condition{
+				Type:     operatorv1.PreflightCheckCondition,
+				Reason:   operatorv1.FetchConfigValidationErrorReason,
+				Severity: clusterv1.ConditionSeverityError,
+				Message:  "Verification can only be provided with URL, S3 or LocalPath",
+				Status:   corev1.ConditionFalse,
+			},
+			providerList: &operatorv1.InfrastructureProviderList{},
+		},
+		{
+			name:          "fetch config with verification without public key, preflight check failed",
+			expectedError: true,
+			providers: []operatorv1.GenericProvider{
+				&operatorv1.InfrastructureProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "aws",
+						Namespace: namespaceName1,
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "InfrastructureProvider",
+						APIVersion: "operator.cluster.x-k8s.io/v1alpha1",
+					},
+					Spec: operatorv1.InfrastructureProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							Version: "v1.0.0",
+							FetchConfig: &operatorv1.FetchConfiguration{
+								URL:          "https://github.com/kubernetes-sigs/cluster-api-provider-aws/releases",
+								Verification: &operatorv1.VerificationConfiguration{},
+							},
+						},
+					},
+				},
+			},
+			expectedCondition: clusterv1.Condition{
+				Type:     operatorv1.PreflightCheckCondition,
+				Reason:   operatorv1.FetchConfigValidationErrorReason,
+				Severity: clusterv1.ConditionSeverityError,
+				Message:  "PublicKey must be provided in Verification",
+				Status:   corev1.ConditionFalse,
+			},
+			providerList: &operatorv1.InfrastructureProviderList{},
+		},
+		{
+			name:          "fetch config with provenance invalid public key, preflight check failed",
+			expectedError: true,
+			providers: []operatorv1.GenericProvider{
+				&operatorv1.InfrastructureProvider{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "aws",
+						Namespace: namespaceName1,
+					},
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "InfrastructureProvider",
+						APIVersion: "operator.cluster.x-k8s.io/v1alpha1",
+					},
+					Spec: operatorv1.InfrastructureProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							Version: "v1.0.0",
+							FetchConfig: &operatorv1.FetchConfiguration{
+								URL: "https://github.com/kubernetes-sigs/cluster-api-provider-aws/releases",
+								Provenance: &operatorv1.ProvenanceVerification{
+									BuilderID: "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml",
+									PublicKey: "key",
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedCondition: clusterv1.Condition{
+				Type:     operatorv1.PreflightCheckCondition,
+				Reason:   operatorv1.FetchConfigValidationErrorReason,
+				Severity: clusterv1.ConditionSeverityError,
+				Message:  "PublicKey of Provenance must be a PEM encoded public key",
+				Status:   corev1.ConditionFalse,
+			},
+			providerList: &operatorv1.InfrastructureProviderList{},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -607,6 +1062,10 @@ func TestPreflightChecks(t *testing.T) {
 				gs.Expect(fakeclient.Create(ctx, c)).To(Succeed())
 			}
 
+			for i := range tc.policies {
+				gs.Expect(fakeclient.Create(ctx, &tc.policies[i])).To(Succeed())
+			}
+
 			_, err := preflightChecks(context.Background(), fakeclient, tc.providers[0], tc.providerList)
 			if tc.expectedError {
 				gs.Expect(err).To(HaveOccurred())
@@ -0,0 +1,307 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const (
+	// defaultProvenanceAttestation is the name of the attestation file released by the SLSA GitHub generator.
+	defaultProvenanceAttestation = "multiple.intoto.jsonl"
+
+	// inTotoPayloadType is the DSSE payload type of in-toto statements.
+	inTotoPayloadType = "application/vnd.in-toto+json"
+
+	// slsaProvenancePredicatePrefix prefixes the predicate types of all the SLSA provenance versions.
+	slsaProvenancePredicatePrefix = "https://slsa.dev/provenance/"
+)
+
+// dsseEnvelope is a DSSE envelope signing an in-toto statement.
+type dsseEnvelope struct {
+	PayloadType string          `json:"payloadType"`
+	Payload     string          `json:"payload"`
+	Signatures  []dsseSignature `json:"signatures"`
+}
+
+// dsseSignature is a base64 encoded signature of a DSSE envelope.
+type dsseSignature struct {
+	Sig string `json:"sig"`
+}
+
+// inTotoStatement is an in-toto statement with a SLSA provenance predicate.
+type inTotoStatement struct {
+	PredicateType string          `json:"predicateType"`
+	Subject       []inTotoSubject `json:"subject"`
+	Predicate     slsaProvenance  `json:"predicate"`
+}
+
+// inTotoSubject is an artifact attested by an in-toto statement.
+type inTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// slsaProvenance holds the builder and source fields of the v0.2 and v1 SLSA provenance predicates.
+type slsaProvenance struct {
+	// Builder and Invocation are set in v0.2 predicates.
+	Builder struct {
+		ID string `json:"id"`
+	} `json:"builder"`
+	Invocation struct {
+		ConfigSource struct {
+			URI string `json:"uri"`
+		} `json:"configSource"`
+	} `json:"invocation"`
+
+	// RunDetails and BuildDefinition are set in v1 predicates.
+	RunDetails struct {
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+	} `json:"runDetails"`
+	BuildDefinition struct {
+		ExternalParameters struct {
+			Workflow struct {
+				Repository string `json:"repository"`
+			} `json:"workflow"`
+		} `json:"externalParameters"`
+	} `json:"buildDefinition"`
+}
+
+// builderID returns the ID of the builder of the provenance.
+func (p slsaProvenance) builderID() string {
+	if p.RunDetails.Builder.ID != "" {
+		return p.RunDetails.Builder.ID
+	}
+
+	return p.Builder.ID
+}
+
+// sourceRepository returns the repository the artifacts were built from, without the "git+" prefix and the ref.
+func (p slsaProvenance) sourceRepository() string {
+	source := p.BuildDefinition.ExternalParameters.Workflow.Repository
+	if source == "" {
+		source = p.Invocation.ConfigSource.URI
+	}
+
+	source, _, _ = strings.Cut(strings.TrimPrefix(source, "git+"), "@")
+
+	return normalizeSourceRepository(source)
+}
+
+// normalizeSourceRepository returns the repository URL without a trailing slash and .git suffix.
+func normalizeSourceRepository(repository string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(repository, "/"), ".git")
+}
+
+// verifyProvenance verifies that the files are subjects of SLSA provenance attestations of the builder, and reports
+// the result in the ProvenanceVerified condition. Nothing is verified if the provider fetch config has no provenance.
+func (p *phaseReconciler) verifyProvenance(ctx context.Context, files map[string][]byte, getFile signedFileGetter) error {
+	spec := p.provider.GetSpec()
+	if spec.FetchConfig == nil || spec.FetchConfig.Provenance == nil {
+		conditions.Delete(p.provider, operatorv1.ProvenanceVerifiedCondition)
+
+		return nil
+	}
+
+	provenanceErr := func(err error) error {
+		return &PhaseError{
+			Err:      fmt.Errorf("failed to verify the provenance of provider %q: %w", p.provider.GetName(), err),
+			Type:     operatorv1.ProvenanceVerifiedCondition,
+			Reason:   operatorv1.ProvenanceVerificationFailedReason,
+			Severity: clusterv1.ConditionSeverityError,
+		}
+	}
+
+	attested, err := attestedDigests(ctx, spec.FetchConfig.Provenance, getFile)
+	if err != nil {
+		return provenanceErr(err)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !attested[sha256Digest(files[name])] {
+			return provenanceErr(fmt.Errorf("%q is not a subject of the provenance attestations", name))
+		}
+	}
+
+	ctrl.LoggerFrom(ctx).Info("Verified provider manifests provenance", "files", names)
+
+	conditions.MarkTrue(p.provider, operatorv1.ProvenanceVerifiedCondition)
+
+	return nil
+}
+
+// attestedDigests returns the SHA256 digests of the subjects of the attestations in the attestation file. Every
+// attestation must be signed and have a SLSA provenance predicate of the builder and the source repository.
+func attestedDigests(ctx context.Context, provenance *operatorv1.ProvenanceVerification, getFile signedFileGetter) (map[string]bool, error) {
+	name := provenance.Attestation
+	if name == "" {
+		name = defaultProvenanceAttestation
+	}
+
+	data, err := getFile(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attestation %q: %w", name, err)
+	}
+
+	digests := map[string]bool{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		statement, err := verifyAttestation(provenance, scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("attestation %d of %q: %w", line, name, err)
+		}
+
+		for _, subject := range statement.Subject {
+			if digest := subject.Digest["sha256"]; digest != "" {
+				digests[strings.ToLower(digest)] = true
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read attestation %q: %w", name, err)
+	}
+
+	if len(digests) == 0 {
+		return nil, fmt.Errorf("attestation %q has no subjects", name)
+	}
+
+	return digests, nil
+}
+
+// verifyAttestation verifies the signature of the DSSE envelope and returns its statement if it's a SLSA
+// provenance of the builder and the source repository.
+func verifyAttestation(provenance *operatorv1.ProvenanceVerification, data []byte) (*inTotoStatement, error) {
+	envelope := &dsseEnvelope{}
+	if err := json.Unmarshal(data, envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse DSSE envelope: %w", err)
+	}
+
+	if envelope.PayloadType != inTotoPayloadType {
+		return nil, fmt.Errorf("unexpected payload type %q, expected %q", envelope.PayloadType, inTotoPayloadType)
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %w", err)
+	}
+
+	if err := verifyEnvelopeSignatures(provenance, envelope, payload); err != nil {
+		return nil, err
+	}
+
+	statement := &inTotoStatement{}
+	if err := json.Unmarshal(payload, statement); err != nil {
+		return nil, fmt.Errorf("failed to parse in-toto statement: %w", err)
+	}
+
+	if !strings.HasPrefix(statement.PredicateType, slsaProvenancePredicatePrefix) {
+		return nil, fmt.Errorf("unexpected predicate type %q, expected a SLSA provenance", statement.PredicateType)
+	}
+
+	if builderID := statement.Predicate.builderID(); !builderIDMatches(provenance.BuilderID, builderID) {
+		return nil, fmt.Errorf("built by %q, expected %q", builderID, provenance.BuilderID)
+	}
+
+	if provenance.SourceRepository != "" {
+		if source := statement.Predicate.sourceRepository(); source != normalizeSourceRepository(provenance.SourceRepository) {
+			return nil, fmt.Errorf("built from %q, expected %q", source, provenance.SourceRepository)
+		}
+	}
+
+	return statement, nil
+}
+
+// verifyEnvelopeSignatures verifies that one of the signatures of the envelope is valid with the public key of the provenance.
+func verifyEnvelopeSignatures(provenance *operatorv1.ProvenanceVerification, envelope *dsseEnvelope, payload []byte) error {
+	if len(envelope.Signatures) == 0 {
+		return errors.New("DSSE envelope is not signed")
+	}
+
+	publicKey, err := parsePublicKey([]byte(provenance.PublicKey))
+	if err != nil {
+		return err
+	}
+
+	message := dssePreAuthEncoding(envelope.PayloadType, payload)
+
+	var errs []error
+
+	for _, envelopeSignature := range envelope.Signatures {
+		signature, err := base64.StdEncoding.DecodeString(envelopeSignature.Sig)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to decode signature: %w", err))
+
+			continue
+		}
+
+		if err := verifySignature(publicKey, message, signature); err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("no valid signature: %w", errors.Join(errs...))
+}
+
+// dssePreAuthEncoding returns the message signed by the DSSE signatures of the payload.
+func dssePreAuthEncoding(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// builderIDMatches returns true if the builder ID is the expected one, ignoring the builder version if the
+// expected ID has none.
+func builderIDMatches(expected, builderID string) bool {
+	if !strings.Contains(expected, "@") {
+		builderID, _, _ = strings.Cut(builderID, "@")
+	}
+
+	return builderID == expected
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const testBuilderID = "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml@refs/tags/v1.9.0"
+
+// newAttestation returns a DSSE envelope line with the in-toto statement, signed with the key.
+func newAttestation(g *WithT, key *ecdsa.PrivateKey, statement map[string]interface{}) []byte {
+	payload, err := json.Marshal(statement)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	signature := map[string]string{"keyid": "", "sig": string(signBlob(g, key, dssePreAuthEncoding(inTotoPayloadType, payload)))}
+
+	envelope, err := json.Marshal(map[string]interface{}{
+		"payloadType": inTotoPayloadType,
+		"payload":     base64.StdEncoding.EncodeToString(payload),
+		"signatures":  []map[string]string{signature},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	return append(envelope, '\n')
+}
+
+// slsaV02Statement returns a SLSA v0.2 provenance statement of the files.
+func slsaV02Statement(builderID, source string, files ...[]byte) map[string]interface{} {
+	return map[string]interface{}{
+		"_type":         "https://in-toto.io/Statement/v0.1",
+		"predicateType": "https://slsa.dev/provenance/v0.2",
+		"subject":       inTotoSubjects(files...),
+		"predicate": map[string]interface{}{
+			"builder":    map[string]string{"id": builderID},
+			"invocation": map[string]interface{}{"configSource": map[string]string{"uri": "git+" + source + "@refs/tags/v2.3.0"}},
+		},
+	}
+}
+
+// slsaV1Statement returns a SLSA v1 provenance statement of the files.
+func slsaV1Statement(builderID, source string, files ...[]byte) map[string]interface{} {
+	return map[string]interface{}{
+		"_type":         "https://in-toto.io/Statement/v1",
+		"predicateType": "https://slsa.dev/provenance/v1",
+		"subject":       inTotoSubjects(files...),
+		"predicate": map[string]interface{}{
+			"buildDefinition": map[string]interface{}{
+				"externalParameters": map[string]interface{}{"workflow": map[string]string{"repository": source}},
+			},
+			"runDetails": map[string]interface{}{"builder": map[string]string{"id": builderID}},
+		},
+	}
+}
+
+func inTotoSubjects(files ...[]byte) []map[string]interface{} {
+	subjects := []map[string]interface{}{}
+	for i, file := range files {
+		subjects = append(subjects, map[string]interface{}{
+			"name":   fmt.Sprintf("file-%d", i),
+			"digest": map[string]string{"sha256": sha256Digest(file)},
+		})
+	}
+
+	return subjects
+}
+
+func TestVerifyProvenance(t *testing.T) {
+	g := NewWithT(t)
+
+	components := []byte("components v2.3.0")
+	metadata := []byte("metadata v2.3.0")
+	source := "https://github.com/kubernetes-sigs/cluster-api-provider-aws"
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER}))
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	tamperedAttestation := newAttestation(g, key, slsaV02Statement(testBuilderID, source, components))
+	tamperedAttestation[len(tamperedAttestation)/2]++
+
+	tests := []struct {
+		name         string
+		provenance   *operatorv1.ProvenanceVerification
+		attestations map[string][]byte
+		wantErr      bool
+	}{
+		{
+			name: "no provenance",
+		},
+		{
+			name:       "public key",
+			provenance: &operatorv1.ProvenanceVerification{PublicKey: publicKey, BuilderID: testBuilderID, SourceRepository: source + ".git"},
+			attestations: map[string][]byte{
+				defaultProvenanceAttestation: append(
+					newAttestation(g, key, slsaV02Statement(testBuilderID, source, []byte("other release asset"))),
+					newAttestation(g, key, slsaV02Statement(testBuilderID, source, components, metadata))...,
+				),
+			},
+		},
+		{
+			name: "public key with SLSA v1 provenance",
+			provenance: &operatorv1.ProvenanceVerification{
+				Attestation:      "provenance.intoto.jsonl",
+				PublicKey:        publicKey,
+				BuilderID:        "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml",
+				SourceRepository: source,
+			},
+			attestations: map[string][]byte{
+				"provenance.intoto.jsonl": newAttestation(g, key, slsaV1Statement(testBuilderID, source, components, metadata)),
+			},
+		},
+		{
+			name:       "file not attested",
+			provenance: &operatorv1.ProvenanceVerification{PublicKey: publicKey, BuilderID: testBuilderID},
+			attestations: map[string][]byte{
+				defaultProvenanceAttestation: newAttestation(g, key, slsaV02Statement(testBuilderID, source, components)),
+			},
+			wantErr: true,
+		},
+		{
+			name:       "other builder",
+			provenance: &operatorv1.ProvenanceVerification{PublicKey: publicKey, BuilderID: testBuilderID},
+			attestations: map[string][]byte{
+				defaultProvenanceAttestation: newAttestation(g, key, slsaV02Statement("https://github.com/someone/builder", source, components, metadata)),
+			},
+			wantErr: true,
+		},
+		{
+			name:       "other builder version",
+			provenance: &operatorv1.ProvenanceVerification{PublicKey: publicKey, BuilderID: testBuilderID},
+			attestations: map[string][]byte{
+				defaultProvenanceAttestation: newAttestation(g, key, slsaV02Statement(testBuilderID+"-rc.0", source, components, metadata)),
+			},
+			wantErr: true,
+		},
+		{
+			name:       "other source repository",
+			provenance: &operatorv1.ProvenanceVerification{PublicKey: publicKey, BuilderID: testBuilderID, SourceRepository: source},
+			attestations: map[string][]byte{
+				defaultProvenanceAttestation: newAttestation(g, key, slsaV02Statement(testBuilderID, "https://github.com/someone/fork", components, metadata)),
+			},
+			wantErr: true,
+		},
+		{
+			name:       "tampered attestation",
+			provenance: &operatorv1.ProvenanceVerification{PublicKey: publicKey, BuilderID: testBuilderID},
+			attestations: map[string][]byte{
+				defaultProvenanceAttestation: tamperedAttestation,
+			},
+			wantErr: true,
+		},
+		{
+			name:       "signed with another key",
+			provenance: &operatorv1.ProvenanceVerification{PublicKey: publicKey, BuilderID: testBuilderID},
+			attestations: map[string][]byte{
+				defaultProvenanceAttestation: newAttestation(g, otherKey, slsaV1Statement(testBuilderID, source, components, metadata)),
+			},
+			wantErr: true,
+		},
+		{
+			name:         "missing attestation",
+			provenance:   &operatorv1.ProvenanceVerification{PublicKey: publicKey, BuilderID: testBuilderID},
+			attestations: map[string][]byte{},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			p := &phaseReconciler{
+				provider: &operatorv1.InfrastructureProvider{
+					ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "capa-system"},
+					Spec: operatorv1.InfrastructureProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							FetchConfig: &operatorv1.FetchConfiguration{
+								URL:        "https://github.com/kubernetes-sigs/cluster-api-provider-aws/releases",
+								Provenance: tt.provenance,
+							},
+						},
+					},
+				},
+			}
+
+			files := map[string][]byte{"components.yaml": components, "metadata.yaml": metadata}
+
+			err := p.verifyProvenance(context.Background(), files, func(_ context.Context, name string) ([]byte, error) {
+				data, ok := tt.attestations[name]
+				if !ok {
+					return nil, fmt.Errorf("file %q not found", name)
+				}
+
+				return data, nil
+			})
+
+			condition := conditions.Get(p.provider, operatorv1.ProvenanceVerifiedCondition)
+
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				phaseErr := &PhaseError{}
+				g.Expect(errors.As(err, &phaseErr)).To(BeTrue())
+				g.Expect(phaseErr.Type).To(Equal(operatorv1.ProvenanceVerifiedCondition))
+				g.Expect(phaseErr.Reason).To(Equal(operatorv1.ProvenanceVerificationFailedReason))
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+
+			if tt.provenance == nil {
+				g.Expect(condition).To(BeNil())
+
+				return
+			}
+
+			g.Expect(condition).ToNot(BeNil())
+			g.Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+		})
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// pruneRemovedObjects deletes the objects recorded in the inventory of the previously installed version that are
+// no longer part of the components of the new version, e.g. obsolete webhooks and RBAC, or the controllers of a
+// provider switched to the CRDsOnly install mode. CRDs and namespaces are kept like in clusterctl upgrades, as well
+// as objects that are no longer labeled for the provider.
+func (p *phaseReconciler) pruneRemovedObjects(ctx context.Context) (reconcile.Result, error) {
+	installedVersion := p.provider.GetStatus().InstalledVersion
+
+	// Without a version change, only the components dropped by the CRDsOnly install mode are pruned.
+	if installedVersion == nil || (*installedVersion == p.provider.GetSpec().Version && !isCRDsOnly(p.provider)) {
+		return reconcile.Result{}, nil
+	}
+
+	entries, err := p.readInventory(ctx)
+	if err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.OldComponentsDeletionErrorReason, operatorv1.ProviderUpgradedCondition)
+	}
+
+	for _, entry := range removedInventoryEntries(entries, p.components.Objs()) {
+		if err := p.pruneObject(ctx, entry); err != nil {
+			return reconcile.Result{}, wrapPhaseError(err, operatorv1.OldComponentsDeletionErrorReason, operatorv1.ProviderUpgradedCondition)
+		}
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// pruneObject deletes the object of the inventory entry if it still belongs to the provider.
+func (p *phaseReconciler) pruneObject(ctx context.Context, entry inventoryEntry) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{Group: entry.Group, Version: entry.Version, Kind: entry.Kind})
+
+	if err := p.ctrlClient.Get(ctx, client.ObjectKey{Namespace: entry.Namespace, Name: entry.Name}, obj); err != nil {
+		// The API of the object may have been removed with its CRD.
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to get %s %s: %w", entry.Kind, client.ObjectKeyFromObject(obj), err)
+	}
+
+	clusterctlProvider := getProvider(p.provider, "")
+
+	if obj.GetLabels()[clusterv1.ProviderNameLabel] != clusterctlProvider.ManifestLabel() {
+		log.V(5).Info("Keeping removed object not labeled for the provider", "kind", entry.Kind, "object", client.ObjectKeyFromObject(obj))
+
+		return nil
+	}
+
+	log.Info("Deleting object removed from the provider components", "kind", entry.Kind, "object", client.ObjectKeyFromObject(obj))
+
+	if err := p.ctrlClient.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to delete %s %s: %w", entry.Kind, client.ObjectKeyFromObject(obj), err)
+	}
+
+	return nil
+}
+
+// removedInventoryEntries returns the inventory entries of the objects that aren't part of the components, except
+// for CRDs and namespaces. Objects are matched regardless of their API version.
+func removedInventoryEntries(entries []inventoryEntry, objs []unstructured.Unstructured) []inventoryEntry {
+	type objectKey struct {
+		group, kind, namespace, name string
+	}
+
+	current := map[objectKey]bool{}
+
+	for i := range objs {
+		gvk := objs[i].GroupVersionKind()
+		current[objectKey{gvk.Group, gvk.Kind, objs[i].GetNamespace(), objs[i].GetName()}] = true
+	}
+
+	removed := []inventoryEntry{}
+
+	for _, entry := range entries {
+		switch {
+		case entry.Group == crdGVK.Group && entry.Kind == customResourceDefinitionKind:
+			continue
+		case entry.Group == "" && entry.Kind == namespaceKind:
+			continue
+		case current[objectKey{entry.Group, entry.Kind, entry.Namespace, entry.Name}]:
+			continue
+		}
+
+		removed = append(removed, entry)
+	}
+
+	return removed
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestPruneRemovedObjects(t *testing.T) {
+	g := NewWithT(t)
+
+	newObj := func(apiVersion, kind, namespace, name string) unstructured.Unstructured {
+		obj := unstructured.Unstructured{}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+
+		return obj
+	}
+
+	providerLabels := map[string]string{clusterv1.ProviderNameLabel: "cluster-api"}
+
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "capi-system"},
+		Spec: operatorv1.CoreProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{Version: "v1.6.0"},
+		},
+		Status: operatorv1.CoreProviderStatus{
+			ProviderStatus: operatorv1.ProviderStatus{InstalledVersion: pointer.String("v1.5.0")},
+		},
+	}
+
+	fakeclient := fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "capi-system", Labels: providerLabels}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "capi-webhook-service", Namespace: "capi-system", Labels: providerLabels}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "capi-manager", Namespace: "capi-system", Labels: providerLabels}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "capi-shared", Namespace: "capi-system"}},
+	).Build()
+
+	newComponents := []unstructured.Unstructured{
+		newObj("v1", "Namespace", "", "capi-system"),
+		newObj("v1", "Service", "capi-system", "capi-webhook-service"),
+	}
+
+	p := &phaseReconciler{
+		ctrlClient: fakeclient,
+		provider:   provider,
+		components: objsComponents{objs: newComponents},
+	}
+
+	// The inventory of the previously installed version.
+	g.Expect(p.writeInventory(context.TODO(), append([]unstructured.Unstructured{
+		newObj("v1", "ServiceAccount", "capi-system", "capi-manager"),
+		newObj("v1", "ServiceAccount", "capi-system", "capi-shared"),
+		newObj("rbac.authorization.k8s.io/v1", "Role", "capi-system", "capi-leader-election-role"),
+		newObj("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "clusterclasses.cluster.x-k8s.io"),
+	}, newComponents...))).To(Succeed())
+
+	exists := func(obj client.Object, name string) bool {
+		err := fakeclient.Get(context.TODO(), client.ObjectKey{Namespace: "capi-system", Name: name}, obj)
+		g.Expect(client.IgnoreNotFound(err)).ToNot(HaveOccurred())
+
+		return !apierrors.IsNotFound(err)
+	}
+
+	// Objects aren't pruned without a version change.
+	provider.Spec.Version = "v1.5.0"
+
+	_, err := p.pruneRemovedObjects(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(exists(&corev1.ServiceAccount{}, "capi-manager")).To(BeTrue())
+
+	// Only the removed objects still labeled for the provider are pruned after an upgrade.
+	provider.Spec.Version = "v1.6.0"
+
+	_, err = p.pruneRemovedObjects(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(exists(&corev1.ServiceAccount{}, "capi-manager")).To(BeFalse())
+	g.Expect(exists(&corev1.ServiceAccount{}, "capi-shared")).To(BeTrue())
+	g.Expect(exists(&corev1.Service{}, "capi-webhook-service")).To(BeTrue())
+
+	g.Expect(fakeclient.Get(context.TODO(), client.ObjectKey{Name: "capi-system"}, &corev1.Namespace{})).To(Succeed())
+}
+
+func TestPruneRemovedObjectsCRDsOnly(t *testing.T) {
+	g := NewWithT(t)
+
+	newObj := func(apiVersion, kind, namespace, name string) unstructured.Unstructured {
+		obj := unstructured.Unstructured{}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+
+		return obj
+	}
+
+	providerLabels := map[string]string{clusterv1.ProviderNameLabel: "cluster-api"}
+
+	// The provider is switched from the Full to the CRDsOnly install mode without a version change.
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "capi-system"},
+		Spec: operatorv1.CoreProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{Version: "v1.6.0", InstallMode: operatorv1.InstallModeCRDsOnly},
+		},
+		Status: operatorv1.CoreProviderStatus{
+			ProviderStatus: operatorv1.ProviderStatus{InstalledVersion: pointer.String("v1.6.0")},
+		},
+	}
+
+	fakeclient := fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "capi-webhook-service", Namespace: "capi-system", Labels: providerLabels}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "capi-manager", Namespace: "capi-system", Labels: providerLabels}},
+	).Build()
+
+	crdsOnlyComponents := []unstructured.Unstructured{
+		newObj("v1", "Namespace", "", "capi-system"),
+		newObj("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "clusters.cluster.x-k8s.io"),
+		newObj("v1", "Service", "capi-system", "capi-webhook-service"),
+	}
+
+	p := &phaseReconciler{
+		ctrlClient: fakeclient,
+		provider:   provider,
+		components: objsComponents{objs: crdsOnlyComponents},
+	}
+
+	// The inventory of the components installed in the Full install mode.
+	g.Expect(p.writeInventory(context.TODO(), append([]unstructured.Unstructured{
+		newObj("v1", "ServiceAccount", "capi-system", "capi-manager"),
+	}, crdsOnlyComponents...))).To(Succeed())
+
+	_, err := p.pruneRemovedObjects(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(apierrors.IsNotFound(fakeclient.Get(context.TODO(), client.ObjectKey{Namespace: "capi-system", Name: "capi-manager"}, &corev1.ServiceAccount{}))).To(BeTrue())
+	g.Expect(fakeclient.Get(context.TODO(), client.ObjectKey{Namespace: "capi-system", Name: "capi-webhook-service"}, &corev1.Service{})).To(Succeed())
+}
+
+func TestRemovedInventoryEntries(t *testing.T) {
+	g := NewWithT(t)
+
+	entries := []inventoryEntry{
+		{Group: "", Kind: "Namespace", Name: "capi-system"},
+		{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition", Name: "clusterclasses.cluster.x-k8s.io"},
+		{Group: "admissionregistration.k8s.io", Version: "v1beta1", Kind: "ValidatingWebhookConfiguration", Name: "capi-validating-webhook"},
+		{Group: "admissionregistration.k8s.io", Version: "v1", Kind: "MutatingWebhookConfiguration", Name: "capi-mutating-webhook"},
+	}
+
+	webhook := unstructured.Unstructured{}
+	webhook.SetAPIVersion("admissionregistration.k8s.io/v1")
+	webhook.SetKind("ValidatingWebhookConfiguration")
+	webhook.SetName("capi-validating-webhook")
+
+	// Objects are matched regardless of their API version, CRDs and namespaces are never removed.
+	g.Expect(removedInventoryEntries(entries, []unstructured.Unstructured{webhook})).To(Equal(entries[3:]))
+}
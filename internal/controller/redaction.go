@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	redactedValue = "[REDACTED]"
+
+	// minRedactedValueLength is the length of the shortest redacted value. Shorter values, e.g. "true" or "false"
+	// feature toggles, would match unrelated text of the messages.
+	minRedactedValueLength = 6
+)
+
+// redactor replaces the values of sensitive variables, e.g. the ones read from Secrets, in the error messages
+// reported in logs and conditions. Processing errors of the provider components can include substituted values.
+type redactor struct {
+	values []string
+}
+
+// add registers sensitive values to redact.
+func (r *redactor) add(values ...string) {
+	for _, value := range values {
+		if len(strings.TrimSpace(value)) >= minRedactedValueLength {
+			r.values = append(r.values, value)
+		}
+	}
+}
+
+// redact returns the message with all the sensitive values replaced.
+func (r *redactor) redact(message string) string {
+	if len(r.values) == 0 {
+		return message
+	}
+
+	// Longer values are replaced first, so values including other ones are redacted entirely.
+	values := append([]string{}, r.values...)
+	sort.SliceStable(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	oldnew := make([]string, 0, 2*len(values))
+	for _, value := range values {
+		oldnew = append(oldnew, value, redactedValue)
+	}
+
+	return strings.NewReplacer(oldnew...).Replace(message)
+}
+
+// redactError returns the error with a redacted message. The original error is kept in the chain, so it can still be
+// matched, e.g. to set the phase error condition.
+func (r *redactor) redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	message := r.redact(err.Error())
+	if message == err.Error() {
+		return err
+	}
+
+	return &redactedError{message: message, err: err}
+}
+
+type redactedError struct {
+	message string
+	err     error
+}
+
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
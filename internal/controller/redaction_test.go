@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestRedactor(t *testing.T) {
+	testCases := []struct {
+		name    string
+		values  []string
+		message string
+		want    string
+	}{
+		{
+			name:    "no sensitive values",
+			message: "failed to process components: password s3cr3t-value",
+			want:    "failed to process components: password s3cr3t-value",
+		},
+		{
+			name:    "sensitive values are redacted",
+			values:  []string{"s3cr3t-value", "c2VjcmV0LXZhbHVl"},
+			message: "error converting YAML to JSON: s3cr3t-value\nclientSecret: c2VjcmV0LXZhbHVl",
+			want:    "error converting YAML to JSON: [REDACTED]\nclientSecret: [REDACTED]",
+		},
+		{
+			name:    "values including other values are redacted entirely",
+			values:  []string{"secret", "secret-value"},
+			message: "invalid value secret-value",
+			want:    "invalid value [REDACTED]",
+		},
+		{
+			name:    "short values are not redacted",
+			values:  []string{"true", "false", "  "},
+			message: "enableFeature: true, enableOther: false",
+			want:    "enableFeature: true, enableOther: false",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := redactor{}
+			r.add(tc.values...)
+
+			g.Expect(r.redact(tc.message)).To(Equal(tc.want))
+		})
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	g := NewWithT(t)
+
+	r := redactor{}
+	g.Expect(r.redactError(nil)).To(Succeed())
+
+	r.add("s3cr3t-value")
+
+	unchanged := errors.New("failed to fetch components")
+	g.Expect(r.redactError(unchanged)).To(BeIdenticalTo(unchanged))
+
+	err := r.redactError(wrapPhaseError(fmt.Errorf("yaml: line 3: invalid value %q", "s3cr3t-value"),
+		operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition))
+	g.Expect(err).To(MatchError(`yaml: line 3: invalid value "[REDACTED]"`))
+
+	// The phase error is still found to set the condition.
+	var pe *PhaseError
+	g.Expect(errors.As(err, &pe)).To(BeTrue())
+	g.Expect(pe.Reason).To(Equal(operatorv1.ComponentsFetchErrorReason))
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-operator/internal/controller/genericprovider"
+)
+
+const (
+	// appliedReferencesHashAnnotation is the hash of the data of the Secrets and ConfigMaps referenced by the provider
+	// its components were installed with.
+	appliedReferencesHashAnnotation = "operator.cluster.x-k8s.io/applied-references-hash"
+
+	// configSecretsIndexField indexes providers by the "namespace/name" keys of their configuration secrets.
+	configSecretsIndexField = "spec.configSecretRefs"
+
+	// configMapsIndexField indexes providers by the "namespace/name" keys of their variables and additional
+	// manifests ConfigMaps.
+	configMapsIndexField = "spec.configMapRefs"
+
+	// fetchConfigNamespaceIndexField indexes providers fetching their components from the ConfigMaps or Secrets
+	// selected by the fetch config by the namespace of these objects.
+	fetchConfigNamespaceIndexField = "spec.fetchConfig.namespace"
+)
+
+// referencedObjectsIndexes are the fields providers are indexed by to find the ones referencing a Secret or a ConfigMap.
+var referencedObjectsIndexes = map[string]func(operatorv1.GenericProvider) []string{
+	configSecretsIndexField:        configSecretKeys,
+	configMapsIndexField:           configMapKeys,
+	fetchConfigNamespaceIndexField: fetchConfigNamespaces,
+}
+
+// setupReferencedObjectsIndexes indexes the providers by the Secrets and ConfigMaps they reference.
+func (r *GenericProviderReconciler) setupReferencedObjectsIndexes(ctx context.Context, mgr ctrl.Manager) error {
+	for field, keys := range referencedObjectsIndexes {
+		if err := mgr.GetFieldIndexer().IndexField(ctx, r.Provider, field, providerIndexFunc(keys)); err != nil {
+			return fmt.Errorf("failed to index providers by %s: %w", field, err)
+		}
+	}
+
+	return nil
+}
+
+func providerIndexFunc(keys func(operatorv1.GenericProvider) []string) client.IndexerFunc {
+	return func(obj client.Object) []string {
+		provider, ok := obj.(operatorv1.GenericProvider)
+		if !ok {
+			return nil
+		}
+
+		return keys(provider)
+	}
+}
+
+func configSecretKeys(provider operatorv1.GenericProvider) []string {
+	keys := []string{}
+
+	for _, ref := range configSecretRefs(provider.GetSpec()) {
+		keys = append(keys, referenceKey(provider, ref.Namespace, ref.Name))
+	}
+
+	return keys
+}
+
+func configMapKeys(provider operatorv1.GenericProvider) []string {
+	keys := []string{}
+
+	if ref := provider.GetSpec().ConfigMapRef; ref != nil {
+		keys = append(keys, referenceKey(provider, ref.Namespace, ref.Name))
+	}
+
+	if ref := provider.GetSpec().AdditionalManifestsRef; ref != nil {
+		keys = append(keys, referenceKey(provider, ref.Namespace, ref.Name))
+	}
+
+	return keys
+}
+
+func fetchConfigNamespaces(provider operatorv1.GenericProvider) []string {
+	fetchConfig := provider.GetSpec().FetchConfig
+	if fetchConfig == nil || (fetchConfig.Selector == nil && fetchConfig.Secret == nil) {
+		return nil
+	}
+
+	if fetchConfig.Namespace != "" {
+		return []string{fetchConfig.Namespace}
+	}
+
+	return []string{provider.GetNamespace()}
+}
+
+// referenceKey returns the index key of a referenced object, defaulting its namespace to the provider one.
+func referenceKey(provider operatorv1.GenericProvider, namespace, name string) string {
+	if namespace == "" {
+		namespace = provider.GetNamespace()
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: name}.String()
+}
+
+// secretToProviders returns requests for the providers using the Secret as a configuration secret or selecting it
+// with their fetch config.
+func (r *GenericProviderReconciler) secretToProviders(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.referencedObjectToProviders(ctx, obj, configSecretsIndexField, func(fetchConfig *operatorv1.FetchConfiguration) *metav1.LabelSelector {
+		return fetchConfig.Secret
+	})
+}
+
+// configMapToProviders returns requests for the providers referencing the ConfigMap or selecting it with their
+// fetch config.
+func (r *GenericProviderReconciler) configMapToProviders(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.referencedObjectToProviders(ctx, obj, configMapsIndexField, func(fetchConfig *operatorv1.FetchConfiguration) *metav1.LabelSelector {
+		if fetchConfig.Secret != nil {
+			return nil
+		}
+
+		return fetchConfig.Selector
+	})
+}
+
+func (r *GenericProviderReconciler) referencedObjectToProviders(ctx context.Context, obj client.Object, field string,
+	fetchSelector func(*operatorv1.FetchConfiguration) *metav1.LabelSelector,
+) []reconcile.Request {
+	log := ctrl.LoggerFrom(ctx)
+
+	requests := []reconcile.Request{}
+	seen := map[client.ObjectKey]bool{}
+
+	addRequests := func(providerList genericprovider.GenericProviderList, match func(operatorv1.GenericProvider) bool) {
+		for _, provider := range providerList.GetItems() {
+			key := client.ObjectKey{Namespace: provider.GetNamespace(), Name: provider.GetName()}
+			if seen[key] || !match(provider) {
+				continue
+			}
+
+			seen[key] = true
+
+			requests = append(requests, reconcile.Request{NamespacedName: key})
+		}
+	}
+
+	referencing, ok := r.ProviderList.DeepCopyObject().(genericprovider.GenericProviderList)
+	if !ok {
+		return nil
+	}
+
+	if err := r.Client.List(ctx, referencing, client.MatchingFields{field: client.ObjectKeyFromObject(obj).String()}); err != nil {
+		log.Error(err, "failed to list providers referencing the object", "object", client.ObjectKeyFromObject(obj))
+
+		return nil
+	}
+
+	addRequests(referencing, func(operatorv1.GenericProvider) bool { return true })
+
+	selecting, ok := r.ProviderList.DeepCopyObject().(genericprovider.GenericProviderList)
+	if !ok {
+		return nil
+	}
+
+	if err := r.Client.List(ctx, selecting, client.MatchingFields{fetchConfigNamespaceIndexField: obj.GetNamespace()}); err != nil {
+		log.Error(err, "failed to list providers selecting the object", "object", client.ObjectKeyFromObject(obj))
+
+		return nil
+	}
+
+	addRequests(selecting, func(provider operatorv1.GenericProvider) bool {
+		labelSelector := fetchSelector(provider.GetSpec().FetchConfig)
+		if labelSelector == nil {
+			return false
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+
+		return err == nil && selector.Matches(labels.Set(obj.GetLabels()))
+	})
+
+	return requests
+}
+
+// referencedObjectsHash returns the hash of the data of the Secrets and ConfigMaps referenced by the provider, so
+// their changes can be detected without storing the data. Missing objects are skipped, they are reported by the
+// reconciliation phases.
+func referencedObjectsHash(ctx context.Context, c client.Client, provider operatorv1.GenericProvider) (string, error) {
+	data := map[string]interface{}{}
+
+	for _, key := range configSecretKeys(provider) {
+		secret := &corev1.Secret{}
+		if err := getReferencedObject(ctx, c, key, secret); err != nil {
+			return "", err
+		}
+
+		data["Secret/"+key] = secret.Data
+	}
+
+	for _, key := range configMapKeys(provider) {
+		configMap := &corev1.ConfigMap{}
+		if err := getReferencedObject(ctx, c, key, configMap); err != nil {
+			return "", err
+		}
+
+		data["ConfigMap/"+key] = []interface{}{configMap.Data, configMap.BinaryData}
+	}
+
+	for _, namespace := range fetchConfigNamespaces(provider) {
+		fetchConfig := provider.GetSpec().FetchConfig
+
+		if fetchConfig.Secret != nil {
+			secrets := &corev1.SecretList{}
+			if err := listSelected(ctx, c, secrets, namespace, fetchConfig.Secret); err != nil {
+				return "", err
+			}
+
+			for i := range secrets.Items {
+				data["Secret/"+client.ObjectKeyFromObject(&secrets.Items[i]).String()] = secrets.Items[i].Data
+			}
+
+			continue
+		}
+
+		configMaps := &corev1.ConfigMapList{}
+		if err := listSelected(ctx, c, configMaps, namespace, fetchConfig.Selector); err != nil {
+			return "", err
+		}
+
+		for i := range configMaps.Items {
+			data["ConfigMap/"+client.ObjectKeyFromObject(&configMaps.Items[i]).String()] = []interface{}{configMaps.Items[i].Data, configMaps.Items[i].BinaryData}
+		}
+	}
+
+	return calculateHash(data)
+}
+
+// getReferencedObject gets the object of the "namespace/name" key, leaving it empty if it doesn't exist.
+func getReferencedObject(ctx context.Context, c client.Client, key string, obj client.Object) error {
+	namespace, name, _ := strings.Cut(key, string(types.Separator))
+
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, obj); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func listSelected(ctx context.Context, c client.Client, list client.ObjectList, namespace string, labelSelector *metav1.LabelSelector) error {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return err
+	}
+
+	return c.List(ctx, list, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector})
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func newReferencingProviders() []client.Object {
+	return []client.Object{
+		&operatorv1.InfrastructureProvider{
+			ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "capa-system"},
+			Spec: operatorv1.InfrastructureProviderSpec{
+				ProviderSpec: operatorv1.ProviderSpec{
+					ConfigSecret:  &operatorv1.SecretReference{Name: "aws-variables", Namespace: "capa-system"},
+					ConfigSecrets: []operatorv1.SecretReference{{Name: "shared-variables", Namespace: "capi-secrets"}},
+					ConfigMapRef:  &operatorv1.ConfigmapReference{Name: "aws-settings", Namespace: "capa-system"},
+				},
+			},
+		},
+		&operatorv1.InfrastructureProvider{
+			ObjectMeta: metav1.ObjectMeta{Name: "azure", Namespace: "capz-system"},
+			Spec: operatorv1.InfrastructureProviderSpec{
+				ProviderSpec: operatorv1.ProviderSpec{
+					ConfigSecret: &operatorv1.SecretReference{Name: "shared-variables", Namespace: "capi-secrets"},
+					FetchConfig: &operatorv1.FetchConfiguration{
+						Selector:  &metav1.LabelSelector{MatchLabels: map[string]string{"provider": "azure"}},
+						Namespace: "capi-manifests",
+					},
+				},
+			},
+		},
+		&operatorv1.InfrastructureProvider{
+			ObjectMeta: metav1.ObjectMeta{Name: "vsphere", Namespace: "capv-system"},
+			Spec: operatorv1.InfrastructureProviderSpec{
+				ProviderSpec: operatorv1.ProviderSpec{
+					FetchConfig: &operatorv1.FetchConfiguration{
+						Secret: &metav1.LabelSelector{MatchLabels: map[string]string{"provider": "vsphere"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestReferencedObjectToProviders(t *testing.T) {
+	testCases := []struct {
+		name         string
+		object       client.Object
+		wantRequests []reconcile.Request
+	}{
+		{
+			name:         "secret referenced by one provider",
+			object:       &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "aws-variables", Namespace: "capa-system"}},
+			wantRequests: []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "aws", Namespace: "capa-system"}}},
+		},
+		{
+			name:   "secret shared by several providers",
+			object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "shared-variables", Namespace: "capi-secrets"}},
+			wantRequests: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: "aws", Namespace: "capa-system"}},
+				{NamespacedName: types.NamespacedName{Name: "azure", Namespace: "capz-system"}},
+			},
+		},
+		{
+			name: "secret selected by the fetch config",
+			object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Name: "vsphere-v1.8.0", Namespace: "capv-system", Labels: map[string]string{"provider": "vsphere"},
+			}},
+			wantRequests: []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "vsphere", Namespace: "capv-system"}}},
+		},
+		{
+			name:         "configmap referenced by one provider",
+			object:       &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "aws-settings", Namespace: "capa-system"}},
+			wantRequests: []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "aws", Namespace: "capa-system"}}},
+		},
+		{
+			name: "configmap selected by the fetch config of another namespace",
+			object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Name: "azure-v1.12.0", Namespace: "capi-manifests", Labels: map[string]string{"provider": "azure"},
+			}},
+			wantRequests: []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "azure", Namespace: "capz-system"}}},
+		},
+		{
+			name: "configmap not selected by the fetch config",
+			object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Name: "vsphere-v1.8.0", Namespace: "capv-system", Labels: map[string]string{"provider": "vsphere"},
+			}},
+			wantRequests: []reconcile.Request{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			builder := fake.NewClientBuilder().WithObjects(newReferencingProviders()...)
+			for field, keys := range referencedObjectsIndexes {
+				builder = builder.WithIndex(&operatorv1.InfrastructureProvider{}, field, providerIndexFunc(keys))
+			}
+
+			r := &GenericProviderReconciler{
+				Provider:     &operatorv1.InfrastructureProvider{},
+				ProviderList: &operatorv1.InfrastructureProviderList{},
+				Client:       builder.Build(),
+			}
+
+			var requests []reconcile.Request
+			if _, ok := tc.object.(*corev1.Secret); ok {
+				requests = r.secretToProviders(context.Background(), tc.object)
+			} else {
+				requests = r.configMapToProviders(context.Background(), tc.object)
+			}
+
+			g.Expect(requests).To(ConsistOf(tc.wantRequests))
+		})
+	}
+}
+
+func TestReferencedObjectsHash(t *testing.T) {
+	g := NewWithT(t)
+
+	provider, ok := newReferencingProviders()[1].(*operatorv1.InfrastructureProvider)
+	g.Expect(ok).To(BeTrue())
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared-variables", Namespace: "capi-secrets"},
+		Data:       map[string][]byte{"AZURE_CLIENT_SECRET": []byte("secret")},
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "azure-v1.12.0", Namespace: "capi-manifests", Labels: map[string]string{"provider": "azure"}},
+		Data:       map[string]string{"components": "components v1.12.0"},
+	}
+
+	fakeclient := fake.NewClientBuilder().Build()
+
+	// Missing objects are skipped.
+	missingHash, err := referencedObjectsHash(context.Background(), fakeclient, provider)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(fakeclient.Create(ctx, secret)).To(Succeed())
+	g.Expect(fakeclient.Create(ctx, configMap)).To(Succeed())
+
+	hash, err := referencedObjectsHash(context.Background(), fakeclient, provider)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(hash).NotTo(Equal(missingHash))
+
+	// Metadata changes don't change the hash.
+	secret.Labels = map[string]string{"rotated": "false"}
+	g.Expect(fakeclient.Update(ctx, secret)).To(Succeed())
+
+	unchangedHash, err := referencedObjectsHash(context.Background(), fakeclient, provider)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(unchangedHash).To(Equal(hash))
+
+	// Rotated credentials and updated manifests change the hash.
+	secret.Data["AZURE_CLIENT_SECRET"] = []byte("rotated")
+	g.Expect(fakeclient.Update(ctx, secret)).To(Succeed())
+
+	rotatedHash, err := referencedObjectsHash(context.Background(), fakeclient, provider)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rotatedHash).NotTo(Equal(hash))
+
+	configMap.Data["components"] = "components v1.12.0 fixed"
+	g.Expect(fakeclient.Update(ctx, configMap)).To(Succeed())
+
+	updatedHash, err := referencedObjectsHash(context.Background(), fakeclient, provider)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(updatedHash).NotTo(Equal(rotatedHash))
+}
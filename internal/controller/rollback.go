@@ -0,0 +1,384 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-operator/internal/controller/genericprovider"
+)
+
+const (
+	// upgradedFromAnnotation is the version a provider was upgraded from, until its Deployments are ready.
+	upgradedFromAnnotation = "operator.cluster.x-k8s.io/upgraded-from"
+
+	// upgradedAtAnnotation is the time a provider was upgraded, until its Deployments are ready.
+	upgradedAtAnnotation = "operator.cluster.x-k8s.io/upgraded-at"
+
+	// rolledBackVersionAnnotation is the version a provider was rolled back from. The upgrade to this version
+	// isn't attempted again until the provider version is changed.
+	rolledBackVersionAnnotation = "operator.cluster.x-k8s.io/rolled-back-version"
+
+	// defaultUpgradeTimeout is how long the Deployments of an upgraded provider have to become ready by default.
+	defaultUpgradeTimeout = 10 * time.Minute
+
+	// upgradeReadyCheckInterval is how often the Deployments of an upgraded provider are checked until they are ready.
+	upgradeReadyCheckInterval = 30 * time.Second
+)
+
+// upgradeTimeout returns how long the Deployments of the upgraded provider have to become ready.
+func upgradeTimeout(provider operatorv1.GenericProvider) time.Duration {
+	if timeout := provider.GetSpec().UpgradeTimeout; timeout != nil {
+		return timeout.Duration
+	}
+
+	return defaultUpgradeTimeout
+}
+
+// setUpgradedFrom records the version the provider was upgraded from, so it can be reinstalled if the Deployments
+// of the new version don't become ready.
+func setUpgradedFrom(provider operatorv1.GenericProvider, previousVersion string) {
+	annotations := provider.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[upgradedFromAnnotation] = previousVersion
+	annotations[upgradedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	provider.SetAnnotations(annotations)
+}
+
+// clearUpgradedFrom removes the version the provider was upgraded from once the upgrade is complete.
+func clearUpgradedFrom(provider operatorv1.GenericProvider) {
+	annotations := provider.GetAnnotations()
+	delete(annotations, upgradedFromAnnotation)
+	delete(annotations, upgradedAtAnnotation)
+	provider.SetAnnotations(annotations)
+}
+
+// checkRolledBack returns true if the provider was rolled back from its current version. A rollback from another
+// version is forgotten, so the upgrade to the new version is attempted. Versions resolved from the version policy
+// or the newest release skip the version the provider was rolled back from, so the rollback is kept for them.
+func checkRolledBack(provider operatorv1.GenericProvider) bool {
+	annotations := provider.GetAnnotations()
+
+	version, ok := annotations[rolledBackVersionAnnotation]
+	if !ok || checksVersions(provider.GetSpec()) {
+		return false
+	}
+
+	if version == provider.GetSpec().Version {
+		return true
+	}
+
+	delete(annotations, rolledBackVersionAnnotation)
+	provider.SetAnnotations(annotations)
+	conditions.Delete(provider, operatorv1.RollbackCompletedCondition)
+
+	return false
+}
+
+// skipRolledBackVersion keeps the previously installed version of a provider rolled back from its current version.
+func (p *phaseReconciler) skipRolledBackVersion(ctx context.Context) (reconcile.Result, error) {
+	ctrl.LoggerFrom(ctx).Info("Provider was rolled back from this version, change the version to upgrade it again",
+		"version", p.provider.GetSpec().Version)
+
+	return reconcile.Result{}, nil
+}
+
+// verifyUpgrade waits for the Deployments of an upgraded provider to become ready, and rolls the provider back to
+// the previously installed version if they don't within the upgrade timeout.
+func (p *phaseReconciler) verifyUpgrade(ctx context.Context) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	annotations := p.provider.GetAnnotations()
+
+	previousVersion, ok := annotations[upgradedFromAnnotation]
+	if !ok {
+		return reconcile.Result{}, nil
+	}
+
+	// The Deployments of a suspended provider are scaled down, so they can't become ready. Upgrades of providers
+	// skipping the readiness wait are never rolled back.
+	if isSuspended(p.provider) || p.provider.GetSpec().SkipWaitForReadiness {
+		clearUpgradedFrom(p.provider)
+
+		return reconcile.Result{}, nil
+	}
+
+	ready, err := deploymentsReady(ctx, p.ctrlClient, p.components.Objs())
+	if err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsUpgradeErrorReason, operatorv1.ProviderUpgradedCondition)
+	}
+
+	if ready {
+		log.Info("Upgraded provider deployments are ready")
+		clearUpgradedFrom(p.provider)
+
+		return reconcile.Result{}, nil
+	}
+
+	// The deadline is restarted if the upgrade time can't be parsed.
+	upgradedAt, err := time.Parse(time.RFC3339, annotations[upgradedAtAnnotation])
+	if err != nil {
+		setUpgradedFrom(p.provider, previousVersion)
+
+		return reconcile.Result{RequeueAfter: upgradeReadyCheckInterval}, nil
+	}
+
+	if remaining := upgradeTimeout(p.provider) - time.Since(upgradedAt); remaining > 0 {
+		log.Info("Waiting for the upgraded provider deployments to become ready", "timeout", remaining.Round(time.Second))
+
+		return reconcile.Result{RequeueAfter: earliestRequeueAfter(remaining, upgradeReadyCheckInterval)}, nil
+	}
+
+	err = fmt.Errorf("deployments of provider %q did not become ready within %s after the upgrade from version %s to %s",
+		p.provider.GetName(), upgradeTimeout(p.provider), previousVersion, p.provider.GetSpec().Version)
+
+	return reconcile.Result{}, p.rollback(ctx, previousVersion, wrapPhaseError(err, operatorv1.UpgradeTimedOutReason, operatorv1.ProviderUpgradedCondition))
+}
+
+// rollback reinstalls the previously installed version of the provider after a failed upgrade. The upgrade error
+// is returned, so it is reported in the ProviderUpgraded condition.
+func (p *phaseReconciler) rollback(ctx context.Context, previousVersion string, upgradeErr error) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	// The upgrade isn't retried until the version of the spec changes, not only the one of the failed step.
+	version := p.desiredVersion()
+
+	log.Info("Upgrade failed, rolling back the provider to the previous version", "version", version,
+		"previousVersion", previousVersion, "error", p.sensitiveValues.redact(upgradeErr.Error()))
+
+	if err := p.reinstall(ctx, previousVersion); err != nil {
+		message := p.sensitiveValues.redact(err.Error())
+
+		log.Info("Failed to roll back the provider", "previousVersion", previousVersion, "error", message)
+		conditions.MarkFalse(p.provider, operatorv1.RollbackCompletedCondition, operatorv1.RollbackFailedReason, clusterv1.ConditionSeverityError,
+			"Failed to reinstall version %s: %s", previousVersion, message)
+
+		return upgradeErr
+	}
+
+	status := p.provider.GetStatus()
+	status.InstalledVersion = &previousVersion
+	p.provider.SetStatus(status)
+
+	clearUpgradedFrom(p.provider)
+
+	annotations := p.provider.GetAnnotations()
+	annotations[rolledBackVersionAnnotation] = version
+	p.provider.SetAnnotations(annotations)
+
+	log.Info("Provider rolled back to the previous version", "previousVersion", previousVersion)
+	conditions.MarkTrue(p.provider, operatorv1.RollbackCompletedCondition)
+
+	return upgradeErr
+}
+
+// reinstall installs the given version of the provider with a copy of the provider, so the spec isn't changed.
+// The copy keeps the installed version, so components of a completed upgrade are downgraded, while the
+// components of a failed upgrade are installed again.
+func (p *phaseReconciler) reinstall(ctx context.Context, version string) error {
+	provider, ok := p.provider.DeepCopyObject().(genericprovider.GenericProvider)
+	if !ok {
+		return fmt.Errorf("failed to copy provider %q", p.provider.GetName())
+	}
+
+	spec := provider.GetSpec()
+	spec.Version = version
+	spec.VersionPolicy = ""
+	provider.SetSpec(spec)
+
+	reconciler := *p
+	reconciler.provider = provider
+	reconciler.rollingBack = true
+
+	phases := []reconcilePhaseFn{
+		reconciler.initializePhaseReconciler,
+		reconciler.downloadManifests,
+		reconciler.load,
+		reconciler.fetch,
+		reconciler.upgrade,
+		reconciler.install,
+		reconciler.pruneRemovedObjects,
+		reconciler.updateInventory,
+	}
+
+	for _, phase := range phases {
+		res, err := phase(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !res.IsZero() {
+			return fmt.Errorf("version %s of provider %q can't be installed yet, retrying in %s", version, p.provider.GetName(), res.RequeueAfter)
+		}
+	}
+
+	return nil
+}
+
+// readinessPending returns true while the Deployments of an upgraded or newly installed provider, or the hook Jobs
+// of a provider, are waited for.
+func readinessPending(provider operatorv1.GenericProvider) bool {
+	_, ok := provider.GetAnnotations()[upgradedFromAnnotation]
+
+	return ok || waitingForInstall(provider) || crdsPending(provider) || waitingForJobOrDeployments(provider)
+}
+
+// readinessWait returns how long to wait before checking the Deployments of an upgraded or newly installed provider,
+// or the hook Job the provider waits for, again without reconciling the provider. The Deployments are read from the
+// inventory, so the components don't have to be fetched. It returns zero once the provider has to be reconciled to
+// complete the wait, i.e. when the Deployments are ready, the timeout expired, or the wait can't be checked.
+func (p *phaseReconciler) readinessWait(ctx context.Context) (time.Duration, error) {
+	// Providers installing only their CRDs have no Deployments to wait for.
+	if crdsPending(p.provider) {
+		return p.crdsWait(ctx)
+	}
+
+	if name, ok := p.provider.GetAnnotations()[waitingForJobAnnotation]; ok {
+		return p.jobWait(ctx, name)
+	}
+
+	annotations := p.provider.GetAnnotations()
+	spec := p.provider.GetSpec()
+
+	var (
+		startedAt string
+		timeout   time.Duration
+	)
+
+	switch {
+	case isSuspended(p.provider):
+		return 0, nil
+	case annotations[upgradedFromAnnotation] != "" && !spec.SkipWaitForReadiness:
+		startedAt, timeout = annotations[upgradedAtAnnotation], upgradeTimeout(p.provider)
+	case waitingForInstall(p.provider) && installWaitEnabled(p.provider):
+		startedAt, timeout = annotations[installedAtAnnotation], spec.InstallWaitTimeout.Duration
+	case waitingForJobOrDeployments(p.provider):
+		return p.deploymentsWait(ctx)
+	default:
+		return 0, nil
+	}
+
+	started, err := time.Parse(time.RFC3339, startedAt)
+	if err != nil {
+		return 0, nil
+	}
+
+	remaining := timeout - time.Since(started)
+	if remaining <= 0 {
+		return 0, nil
+	}
+
+	deployments, err := p.inventoryDeployments(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	ready, err := deploymentsReady(ctx, p.ctrlClient, deployments)
+	if err != nil || ready {
+		return 0, err
+	}
+
+	return earliestRequeueAfter(remaining, upgradeReadyCheckInterval), nil
+}
+
+// inventoryDeployments returns the Deployments recorded in the inventory of the provider.
+func (p *phaseReconciler) inventoryDeployments(ctx context.Context) ([]unstructured.Unstructured, error) {
+	entries, err := p.readInventory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deployments := []unstructured.Unstructured{}
+
+	for _, entry := range entries {
+		if entry.Group != appsv1.GroupName || entry.Kind != deploymentKind {
+			continue
+		}
+
+		deployment := unstructured.Unstructured{}
+		deployment.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind(deploymentKind))
+		deployment.SetNamespace(entry.Namespace)
+		deployment.SetName(entry.Name)
+		deployments = append(deployments, deployment)
+	}
+
+	return deployments, nil
+}
+
+// deploymentsReady returns true if all the Deployments of the components run the desired number of updated
+// and available replicas.
+func deploymentsReady(ctx context.Context, c client.Client, objs []unstructured.Unstructured) (bool, error) {
+	for i := range objs {
+		if objs[i].GetKind() != deploymentKind {
+			continue
+		}
+
+		deployment := &appsv1.Deployment{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(&objs[i]), deployment); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+
+			return false, err
+		}
+
+		if !deploymentReady(deployment) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func deploymentReady(deployment *appsv1.Deployment) bool {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	if deployment.Status.UpdatedReplicas < replicas || deployment.Status.AvailableReplicas < replicas {
+		return false
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentAvailable {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func newTestDeployment(name string, replicas, updated, available int32, availableCondition corev1.ConditionStatus) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "capi-system", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32(replicas)},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			UpdatedReplicas:    updated,
+			AvailableReplicas:  available,
+			Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentAvailable, Status: availableCondition},
+			},
+		},
+	}
+}
+
+func TestDeploymentsReady(t *testing.T) {
+	testCases := []struct {
+		name        string
+		deployments []client.Object
+		wantReady   bool
+	}{
+		{
+			name: "all deployments are ready",
+			deployments: []client.Object{
+				newTestDeployment("capi-controller-manager", 2, 2, 2, corev1.ConditionTrue),
+				newTestDeployment("capi-webhook", 1, 1, 1, corev1.ConditionTrue),
+			},
+			wantReady: true,
+		},
+		{
+			name: "old replicas are still available",
+			deployments: []client.Object{
+				newTestDeployment("capi-controller-manager", 2, 1, 2, corev1.ConditionTrue),
+				newTestDeployment("capi-webhook", 1, 1, 1, corev1.ConditionTrue),
+			},
+		},
+		{
+			name: "deployment is not available",
+			deployments: []client.Object{
+				newTestDeployment("capi-controller-manager", 2, 2, 2, corev1.ConditionFalse),
+				newTestDeployment("capi-webhook", 1, 1, 1, corev1.ConditionTrue),
+			},
+		},
+		{
+			name: "deployment doesn't exist",
+			deployments: []client.Object{
+				newTestDeployment("capi-controller-manager", 2, 2, 2, corev1.ConditionTrue),
+			},
+		},
+	}
+
+	objs := []unstructured.Unstructured{}
+
+	for _, name := range []string{"capi-controller-manager", "capi-webhook"} {
+		obj := unstructured.Unstructured{}
+		obj.SetKind(deploymentKind)
+		obj.SetNamespace("capi-system")
+		obj.SetName(name)
+		objs = append(objs, obj)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			fakeclient := fake.NewClientBuilder().WithObjects(tc.deployments...).Build()
+
+			ready, err := deploymentsReady(context.Background(), fakeclient, objs)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(ready).To(Equal(tc.wantReady))
+		})
+	}
+}
+
+func TestCheckRolledBack(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "cluster-api",
+			Annotations: map[string]string{rolledBackVersionAnnotation: "v1.6.0"},
+		},
+		Spec: operatorv1.CoreProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{Version: "v1.6.0"},
+		},
+	}
+	conditions.MarkTrue(provider, operatorv1.RollbackCompletedCondition)
+
+	g.Expect(checkRolledBack(provider)).To(BeTrue())
+	g.Expect(conditions.IsTrue(provider, operatorv1.RollbackCompletedCondition)).To(BeTrue())
+
+	// The rollback is forgotten once another version is requested.
+	provider.Spec.Version = "v1.6.1"
+
+	g.Expect(checkRolledBack(provider)).To(BeFalse())
+	g.Expect(provider.GetAnnotations()).NotTo(HaveKey(rolledBackVersionAnnotation))
+	g.Expect(conditions.Has(provider, operatorv1.RollbackCompletedCondition)).To(BeFalse())
+}
+
+func TestReadinessWait(t *testing.T) {
+	g := NewWithT(t)
+
+	deployment := unstructured.Unstructured{}
+	deployment.SetAPIVersion("apps/v1")
+	deployment.SetKind(deploymentKind)
+	deployment.SetNamespace("capi-system")
+	deployment.SetName("capi-controller-manager")
+
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "capi-system"},
+		Spec: operatorv1.CoreProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{Version: "v1.6.0"},
+		},
+	}
+
+	newReconciler := func(deployments ...client.Object) *phaseReconciler {
+		p := &phaseReconciler{
+			ctrlClient: fake.NewClientBuilder().WithObjects(deployments...).Build(),
+			provider:   provider,
+		}
+
+		// The Deployments are read from the inventory, without the components.
+		g.Expect(p.writeInventory(context.TODO(), []unstructured.Unstructured{deployment})).To(Succeed())
+
+		return p
+	}
+
+	notReady := newReconciler(newTestDeployment("capi-controller-manager", 1, 1, 0, corev1.ConditionFalse))
+
+	// Nothing is waited for without a pending upgrade.
+	g.Expect(readinessPending(provider)).To(BeFalse())
+	g.Expect(notReady.readinessWait(context.TODO())).To(BeZero())
+
+	// The Deployments of a pending upgrade are checked again until they are ready.
+	setUpgradedFrom(provider, "v1.5.0")
+	g.Expect(readinessPending(provider)).To(BeTrue())
+	g.Expect(notReady.readinessWait(context.TODO())).To(Equal(upgradeReadyCheckInterval))
+
+	// The provider is reconciled once they are ready.
+	ready := newReconciler(newTestDeployment("capi-controller-manager", 1, 1, 1, corev1.ConditionTrue))
+	g.Expect(ready.readinessWait(context.TODO())).To(BeZero())
+
+	// The provider is reconciled once the upgrade timed out, so it is rolled back.
+	provider.Annotations[upgradedAtAnnotation] = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	g.Expect(notReady.readinessWait(context.TODO())).To(BeZero())
+}
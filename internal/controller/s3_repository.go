@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-operator/util"
+)
+
+// downloadS3Manifests downloads the provider metadata and components from an S3-compatible bucket and stores
+// them in a ConfigMap like the manifests downloaded from a GitHub release.
+func (p *phaseReconciler) downloadS3Manifests(ctx context.Context) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	spec := p.provider.GetSpec()
+	source := spec.FetchConfig.S3
+
+	log.Info("Downloading provider manifests from S3 bucket", "endpoint", source.Endpoint, "bucket", source.Bucket, "prefix", source.Prefix)
+
+	httpClient, err := p.fetchHTTPClient(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to create HTTP client for provider %q: %w", p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	store, err := util.NewS3Store(source, p.configClient.Variables(), httpClient)
+	if err != nil {
+		err = fmt.Errorf("failed to create S3 client for provider %q: %w", p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if spec.Version == "" {
+		// User didn't set the version, use the latest version directory of the bucket.
+		spec.Version, err = util.LatestObjectStoreVersion(ctx, store, source.Prefix)
+		if err != nil {
+			err = fmt.Errorf("failed to get the latest version of provider %q from S3 bucket %s: %w", p.provider.GetName(), source.Bucket, err)
+
+			return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+		}
+
+		// Add version to the provider spec.
+		p.provider.SetSpec(spec)
+	}
+
+	files, err := fetchS3Files(ctx, store, s3VersionPrefix(source.Prefix, spec.Version))
+	if err != nil {
+		err = fmt.Errorf("failed to fetch version %s of provider %q from S3 bucket %s: %w", spec.Version, p.provider.GetName(), source.Bucket, err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	// Metadata set in the provider spec replaces the one from the bucket, which may not have it at all, when loaded.
+	metadata := files[metadataFile]
+	components := files[componentsFileName]
+	signedFiles := map[string][]byte{componentsFileName: components}
+
+	if metadata != nil {
+		signedFiles[metadataFile] = metadata
+	}
+
+	if (metadata == nil && !hasMetadataOverride(spec)) || components == nil {
+		err = fmt.Errorf("S3 bucket %s for provider %q must contain %q and %q files", source.Bucket, p.provider.GetName(),
+			s3VersionPrefix(source.Prefix, spec.Version)+metadataFile, s3VersionPrefix(source.Prefix, spec.Version)+componentsFileName)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	if err := p.verifyManifests(ctx, signedFiles, func(ctx context.Context, name string) ([]byte, error) {
+		return store.GetObject(ctx, s3VersionPrefix(source.Prefix, spec.Version)+name)
+	}); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err := p.createManifestsConfigMap(ctx, metadata, components, needToCompress(metadata, components)); err != nil {
+		err = fmt.Errorf("failed to create config map for provider %q: %w", p.provider.GetName(), err)
+
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// fetchS3Files returns the metadata and components files under the prefix of the bucket, keyed by their names.
+func fetchS3Files(ctx context.Context, store util.ObjectStore, prefix string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+
+	for _, name := range []string{metadataFile, componentsFileName} {
+		data, err := store.GetObject(ctx, prefix+name)
+		if errors.Is(err, util.ErrObjectNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %q: %w", prefix+name, err)
+		}
+
+		files[name] = data
+	}
+
+	return files, nil
+}
+
+// s3VersionPrefix returns the directory of the version under the prefix in the bucket.
+func s3VersionPrefix(prefix, version string) string {
+	return util.ObjectStoreDirectory(prefix) + version + "/"
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-operator/util"
+)
+
+// newFakeS3Server returns an S3 server with a single bucket holding the given objects. It supports listing
+// objects by prefix and delimiter, and getting objects.
+func newFakeS3Server(g *WithT, bucket string, objects map[string]string) *httptest.Server {
+	type commonPrefix struct {
+		Prefix string
+	}
+
+	type listBucketResult struct {
+		XMLName        xml.Name `xml:"ListBucketResult"`
+		Name           string
+		Prefix         string
+		KeyCount       int
+		IsTruncated    bool
+		CommonPrefixes []commonPrefix
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/"+bucket)
+
+		if path == "" || path == "/" {
+			prefix := r.URL.Query().Get("prefix")
+			prefixes := map[string]bool{}
+
+			for key := range objects {
+				if rest, ok := strings.CutPrefix(key, prefix); ok && strings.Contains(rest, "/") {
+					prefixes[prefix+rest[:strings.Index(rest, "/")+1]] = true
+				}
+			}
+
+			result := listBucketResult{Name: bucket, Prefix: prefix}
+			for p := range prefixes {
+				result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: p})
+			}
+
+			sort.Slice(result.CommonPrefixes, func(i, j int) bool { return result.CommonPrefixes[i].Prefix < result.CommonPrefixes[j].Prefix })
+			result.KeyCount = len(result.CommonPrefixes)
+
+			w.Header().Set("Content-Type", "application/xml")
+			g.Expect(xml.NewEncoder(w).Encode(result)).To(Succeed())
+
+			return
+		}
+
+		data, ok := objects[strings.TrimPrefix(path, "/")]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message><Key>%s</Key></Error>", path)
+
+			return
+		}
+
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		fmt.Fprint(w, data)
+	}))
+}
+
+func TestS3Repository(t *testing.T) {
+	testCases := []struct {
+		name        string
+		prefix      string
+		version     string
+		wantVersion string
+		wantFiles   map[string][]byte
+		wantErr     bool
+	}{
+		{
+			name:        "latest version",
+			prefix:      "/providers/aws/",
+			wantVersion: "v2.10.0",
+			wantFiles:   map[string][]byte{metadataFile: []byte("metadata v2.10.0"), componentsFileName: []byte("components v2.10.0")},
+		},
+		{
+			name:        "given version without metadata",
+			prefix:      "providers/aws",
+			version:     "v2.9.0",
+			wantVersion: "v2.9.0",
+			wantFiles:   map[string][]byte{componentsFileName: []byte("components v2.9.0")},
+		},
+		{
+			name:    "no versions",
+			prefix:  "providers/azure",
+			wantErr: true,
+		},
+	}
+
+	g := NewWithT(t)
+
+	s3Server := newFakeS3Server(g, "capi", map[string]string{
+		"providers/aws/v2.9.0/components.yaml":  "components v2.9.0",
+		"providers/aws/v2.10.0/metadata.yaml":   "metadata v2.10.0",
+		"providers/aws/v2.10.0/components.yaml": "components v2.10.0",
+		"providers/aws/latest/components.yaml":  "components latest",
+	})
+	defer s3Server.Close()
+
+	mr := configclient.NewMemoryReader()
+	mr.Set(util.S3AccessKeyIDKey, "access-key")
+	mr.Set(util.S3SecretAccessKeyKey, "secret-key")
+
+	configClient, err := configclient.New(context.TODO(), "", configclient.InjectReader(mr))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			store, err := util.NewS3Store(&operatorv1.S3Source{
+				Endpoint: strings.TrimPrefix(s3Server.URL, "http://"),
+				Bucket:   "capi",
+				Prefix:   tc.prefix,
+				Region:   "us-east-1",
+				Insecure: true,
+			}, configClient.Variables(), http.DefaultClient)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			version := tc.version
+			if version == "" {
+				version, err = util.LatestObjectStoreVersion(context.TODO(), store, tc.prefix)
+				if tc.wantErr {
+					g.Expect(err).To(HaveOccurred())
+
+					return
+				}
+
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+
+			g.Expect(version).To(Equal(tc.wantVersion))
+
+			files, err := fetchS3Files(context.TODO(), store, s3VersionPrefix(tc.prefix, version))
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(files).To(Equal(tc.wantFiles))
+		})
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// signatureSuffix is appended to the name of a signed file for the name of its signature, as written by cosign sign-blob.
+const signatureSuffix = ".sig"
+
+// signedFileGetter returns the file with the name from the source of the provider manifests.
+type signedFileGetter func(ctx context.Context, name string) ([]byte, error)
+
+// verifyManifests verifies the signatures and the provenance of the files, keyed by their names in the source of
+// the provider manifests.
+func (p *phaseReconciler) verifyManifests(ctx context.Context, files map[string][]byte, getFile signedFileGetter) error {
+	if err := p.verifySignatures(ctx, files, getFile); err != nil {
+		return err
+	}
+
+	return p.verifyProvenance(ctx, files, getFile)
+}
+
+// verifySignatures verifies the signatures of the files and reports the result in the ComponentsVerified condition.
+// Nothing is verified if the provider fetch config has no verification.
+func (p *phaseReconciler) verifySignatures(ctx context.Context, files map[string][]byte, getFile signedFileGetter) error {
+	spec := p.provider.GetSpec()
+	if spec.FetchConfig == nil || spec.FetchConfig.Verification == nil {
+		conditions.Delete(p.provider, operatorv1.ComponentsVerifiedCondition)
+
+		return nil
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := verifySignedFile(ctx, spec.FetchConfig.Verification, name, files[name], getFile); err != nil {
+			return &PhaseError{
+				Err:      fmt.Errorf("failed to verify the signature of %q of provider %q: %w", name, p.provider.GetName(), err),
+				Type:     operatorv1.ComponentsVerifiedCondition,
+				Reason:   operatorv1.SignatureVerificationFailedReason,
+				Severity: clusterv1.ConditionSeverityError,
+			}
+		}
+	}
+
+	ctrl.LoggerFrom(ctx).Info("Verified provider manifests signatures", "files", names)
+
+	conditions.MarkTrue(p.provider, operatorv1.ComponentsVerifiedCondition)
+
+	return nil
+}
+
+// verifySignedFile verifies the signature of the file with the public key of the verification.
+func verifySignedFile(ctx context.Context, verification *operatorv1.VerificationConfiguration, name string, data []byte, getFile signedFileGetter) error {
+	signatureFile, err := getFile(ctx, name+signatureSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to get signature %q: %w", name+signatureSuffix, err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signatureFile)))
+	if err != nil {
+		return fmt.Errorf("failed to decode signature %q: %w", name+signatureSuffix, err)
+	}
+
+	publicKey, err := parsePublicKey([]byte(verification.PublicKey))
+	if err != nil {
+		return err
+	}
+
+	return verifySignature(publicKey, data, signature)
+}
+
+// parsePublicKey returns the public key of the PEM encoded data.
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to decode public key: no PEM encoded key found")
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	return publicKey, nil
+}
+
+// verifySignature verifies the signature of the data with the public key, hashed like cosign does for its type.
+func verifySignature(publicKey crypto.PublicKey, data, signature []byte) error {
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		var digest []byte
+
+		switch key.Curve.Params().BitSize {
+		case 384:
+			sum := sha512.Sum384(data)
+			digest = sum[:]
+		case 521:
+			sum := sha512.Sum512(data)
+			digest = sum[:]
+		default:
+			sum := sha256.Sum256(data)
+			digest = sum[:]
+		}
+
+		if !ecdsa.VerifyASN1(key, digest, signature) {
+			return errors.New("invalid signature")
+		}
+
+		return nil
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("invalid signature: %w", err)
+		}
+
+		return nil
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, data, signature) {
+			return errors.New("invalid signature")
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+}
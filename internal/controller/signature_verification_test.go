@@ -0,0 +1,164 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// signBlob returns the base64 encoded signature of the data, as written by cosign sign-blob.
+func signBlob(g *WithT, key *ecdsa.PrivateKey, data []byte) []byte {
+	digest := sha256.Sum256(data)
+
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	g.Expect(err).ToNot(HaveOccurred())
+
+	return []byte(base64.StdEncoding.EncodeToString(signature))
+}
+
+func TestVerifyManifests(t *testing.T) {
+	g := NewWithT(t)
+
+	components := []byte("components v2.3.0")
+	metadata := []byte("metadata v2.3.0")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER}))
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	keySigned := map[string][]byte{
+		"components.yaml.sig": signBlob(g, key, components),
+		"metadata.yaml.sig":   signBlob(g, key, metadata),
+	}
+
+	tests := []struct {
+		name         string
+		verification *operatorv1.VerificationConfiguration
+		signatures   map[string][]byte
+		files        map[string][]byte
+		wantErr      bool
+	}{
+		{
+			name:  "no verification",
+			files: map[string][]byte{"components.yaml": components},
+		},
+		{
+			name:         "public key",
+			verification: &operatorv1.VerificationConfiguration{PublicKey: publicKey},
+			signatures:   keySigned,
+			files:        map[string][]byte{"components.yaml": components, "metadata.yaml": metadata},
+		},
+		{
+			name:         "tampered components",
+			verification: &operatorv1.VerificationConfiguration{PublicKey: publicKey},
+			signatures:   keySigned,
+			files:        map[string][]byte{"components.yaml": []byte("tampered components")},
+			wantErr:      true,
+		},
+		{
+			name:         "missing signature",
+			verification: &operatorv1.VerificationConfiguration{PublicKey: publicKey},
+			signatures:   map[string][]byte{},
+			files:        map[string][]byte{"components.yaml": components},
+			wantErr:      true,
+		},
+		{
+			name:         "signed with another key",
+			verification: &operatorv1.VerificationConfiguration{PublicKey: publicKey},
+			signatures:   map[string][]byte{"components.yaml.sig": signBlob(g, otherKey, components)},
+			files:        map[string][]byte{"components.yaml": components},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			p := &phaseReconciler{
+				provider: &operatorv1.InfrastructureProvider{
+					ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "capa-system"},
+					Spec: operatorv1.InfrastructureProviderSpec{
+						ProviderSpec: operatorv1.ProviderSpec{
+							FetchConfig: &operatorv1.FetchConfiguration{
+								URL:          "https://github.com/kubernetes-sigs/cluster-api-provider-aws/releases",
+								Verification: tt.verification,
+							},
+						},
+					},
+				},
+			}
+
+			err := p.verifyManifests(context.Background(), tt.files, func(_ context.Context, name string) ([]byte, error) {
+				data, ok := tt.signatures[name]
+				if !ok {
+					return nil, fmt.Errorf("file %q not found", name)
+				}
+
+				return data, nil
+			})
+
+			condition := conditions.Get(p.provider, operatorv1.ComponentsVerifiedCondition)
+
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				phaseErr := &PhaseError{}
+				g.Expect(errors.As(err, &phaseErr)).To(BeTrue())
+				g.Expect(phaseErr.Type).To(Equal(operatorv1.ComponentsVerifiedCondition))
+				g.Expect(phaseErr.Reason).To(Equal(operatorv1.SignatureVerificationFailedReason))
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+
+			if tt.verification == nil {
+				g.Expect(condition).To(BeNil())
+
+				return
+			}
+
+			g.Expect(condition).ToNot(BeNil())
+			g.Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+		})
+	}
+}
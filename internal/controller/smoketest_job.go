@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-operator/util"
+)
+
+// smokeTestJobHashLength is the number of spec hash characters used in the smoke test Job name.
+const smokeTestJobHashLength = 8
+
+// runSmokeTest creates the provider smoke test Job after the provider has been installed or upgraded, and its
+// Deployments are ready. The result of the Job is evaluated by the health check controller.
+func (p *phaseReconciler) runSmokeTest(ctx context.Context) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	// The smoke test can't pass while the provider controllers are scaled down.
+	if p.provider.GetSpec().SmokeTest == nil || isSuspended(p.provider) {
+		return reconcile.Result{}, nil
+	}
+
+	// The smoke test is only created once the provider controllers are running.
+	ready, err := deploymentsReady(ctx, p.ctrlClient, p.components.Objs())
+	if err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, "failed to check the provider deployments before the smoke test", operatorv1.ProviderInstalledCondition)
+	}
+
+	if !ready {
+		log.Info("Waiting for the provider deployments to become ready before creating the smoke test job")
+		setWaitingForDeployments(p.provider)
+
+		return reconcile.Result{RequeueAfter: upgradeReadyCheckInterval}, nil
+	}
+
+	log.Info("Creating provider smoke test job")
+
+	job, err := p.smokeTestJob(ctx)
+	if err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, "failed to prepare smoke test job", operatorv1.ProviderInstalledCondition)
+	}
+
+	// Remove jobs created for previous installations of the provider.
+	jobList := &batchv1.JobList{}
+	if err := p.ctrlClient.List(ctx, jobList, client.InNamespace(job.Namespace), client.MatchingLabels{
+		operatorv1.SmokeTestJobLabelName: util.SmokeTestJobLabelValue(p.provider),
+	}); err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to list smoke test jobs: %w", err)
+	}
+
+	for i := range jobList.Items {
+		if jobList.Items[i].Name == job.Name {
+			continue
+		}
+
+		if err := p.ctrlClient.Delete(ctx, &jobList.Items[i], client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to delete smoke test job %s/%s: %w", job.Namespace, jobList.Items[i].Name, err)
+		}
+	}
+
+	if err := p.ctrlClient.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return reconcile.Result{}, wrapPhaseError(err, "failed to create smoke test job", operatorv1.ProviderInstalledCondition)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// smokeTestJob builds the smoke test Job from the manifest stored in the referenced ConfigMap.
+func (p *phaseReconciler) smokeTestJob(ctx context.Context) (*batchv1.Job, error) {
+	// A new Job is created for each applied spec, so the previous result is never reused.
+	specHash, err := calculateHash(p.provider.GetSpec())
+	if err != nil {
+		return nil, err
+	}
+
+	name := boundedJobName(fmt.Sprintf("%s-%s-smoke-test", p.provider.GetType(), p.provider.GetName()), specHash[:smokeTestJobHashLength])
+
+	return p.jobFromTemplate(ctx, p.provider.GetSpec().SmokeTest.JobTemplateRef, name, map[string]string{
+		operatorv1.SmokeTestJobLabelName: util.SmokeTestJobLabelValue(p.provider),
+	})
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-operator/util"
+)
+
+func TestRunSmokeTest(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := "test-namespace"
+
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "docker",
+			Namespace: namespace,
+		},
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "InfrastructureProvider",
+			APIVersion: "operator.cluster.x-k8s.io/v1alpha2",
+		},
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				Version: "v1.5.0",
+				SmokeTest: &operatorv1.SmokeTestSpec{
+					JobTemplateRef: operatorv1.ConfigmapReference{
+						Name: "smoke-test",
+					},
+				},
+			},
+		},
+	}
+
+	oldJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "infrastructure-docker-smoke-test-old",
+			Namespace: namespace,
+			Labels: map[string]string{
+				operatorv1.SmokeTestJobLabelName: "infrastructure-docker",
+			},
+		},
+	}
+
+	otherJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "unrelated",
+			Namespace: namespace,
+		},
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "smoke-test",
+			Namespace: namespace,
+		},
+		Data: map[string]string{
+			"job": `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: ignored
+  labels:
+    app: smoke-test
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+      - name: check
+        image: busybox
+        command: ["true"]
+`,
+		},
+	}
+
+	deployment := unstructured.Unstructured{}
+	deployment.SetKind(deploymentKind)
+	deployment.SetNamespace(namespace)
+	deployment.SetName("capd-controller-manager")
+
+	fakeclient := fake.NewClientBuilder().WithObjects(configMap, oldJob, otherJob).Build()
+
+	p := &phaseReconciler{
+		ctrlClient: fakeclient,
+		provider:   provider,
+		components: objsComponents{objs: []unstructured.Unstructured{deployment}},
+	}
+
+	// The job isn't created until the provider deployments are ready.
+	res, err := p.runSmokeTest(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.RequeueAfter).To(Equal(upgradeReadyCheckInterval))
+	g.Expect(provider.GetAnnotations()).To(HaveKey(waitingForDeploymentsAnnotation))
+
+	jobs := &batchv1.JobList{}
+	g.Expect(fakeclient.List(ctx, jobs, client.InNamespace(namespace))).To(Succeed())
+	g.Expect(jobs.Items).To(HaveLen(2))
+
+	readyDeployment := newTestDeployment("capd-controller-manager", 1, 1, 1, corev1.ConditionTrue)
+	readyDeployment.Namespace = namespace
+	g.Expect(fakeclient.Create(ctx, readyDeployment)).To(Succeed())
+
+	_, err = p.runSmokeTest(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(fakeclient.List(ctx, jobs, client.InNamespace(namespace))).To(Succeed())
+	g.Expect(jobs.Items).To(HaveLen(2))
+
+	var job *batchv1.Job
+
+	for i := range jobs.Items {
+		g.Expect(jobs.Items[i].Name).ToNot(Equal(oldJob.Name))
+
+		if jobs.Items[i].Name != otherJob.Name {
+			job = &jobs.Items[i]
+		}
+	}
+
+	g.Expect(job).ToNot(BeNil())
+	g.Expect(job.Name).To(HavePrefix("infrastructure-docker-smoke-test-"))
+	g.Expect(job.Labels).To(HaveKeyWithValue(operatorv1.SmokeTestJobLabelName, "infrastructure-docker"))
+	g.Expect(job.Labels).To(HaveKeyWithValue("app", "smoke-test"))
+	g.Expect(job.OwnerReferences).To(HaveLen(1))
+	g.Expect(job.OwnerReferences[0].Kind).To(Equal("InfrastructureProvider"))
+	g.Expect(job.Spec.Template.Spec.Containers).To(HaveLen(1))
+
+	// Running the phase again for the same spec must not create another job.
+	_, err = p.runSmokeTest(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(fakeclient.List(ctx, jobs, client.InNamespace(namespace))).To(Succeed())
+	g.Expect(jobs.Items).To(HaveLen(2))
+}
+
+func TestSmokeTestJobLabelValue(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "docker", Namespace: "capd-system"},
+	}
+
+	g.Expect(util.SmokeTestJobLabelValue(provider)).To(Equal("infrastructure-docker"))
+
+	// Long values are truncated to a valid label value, unique per provider.
+	provider.Name = strings.Repeat("docker", 12)
+	value := util.SmokeTestJobLabelValue(provider)
+	g.Expect(validation.IsValidLabelValue(value)).To(BeEmpty())
+	g.Expect(value).To(HavePrefix("infrastructure-docker"))
+
+	provider.Name += "x"
+	g.Expect(util.SmokeTestJobLabelValue(provider)).ToNot(Equal(value))
+}
+
+func TestSmokeTestJobName(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.InfrastructureProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("docker", 10), Namespace: "capd-system"},
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				Version:   "v1.5.0",
+				SmokeTest: &operatorv1.SmokeTestSpec{JobTemplateRef: operatorv1.ConfigmapReference{Name: "smoke-test"}},
+			},
+		},
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "smoke-test", Namespace: "capd-system"},
+		Data:       map[string]string{"job": "apiVersion: batch/v1\nkind: Job\n"},
+	}
+
+	p := &phaseReconciler{
+		ctrlClient: fake.NewClientBuilder().WithObjects(configMap).Build(),
+		provider:   provider,
+	}
+
+	specHash, err := calculateHash(provider.GetSpec())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Long names are truncated to a valid label value, the pods get the Job name as a label, keeping the spec hash suffix.
+	job, err := p.smokeTestJob(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(validation.IsValidLabelValue(job.Name)).To(BeEmpty())
+	g.Expect(job.Name).To(HavePrefix("infrastructure-docker"))
+	g.Expect(job.Name).To(HaveSuffix("-" + specHash[:smokeTestJobHashLength]))
+
+	provider.Name += "x"
+
+	other, err := p.smokeTestJob(context.TODO())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(other.Name).ToNot(Equal(job.Name))
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// isSuspended returns true if the provider manager is suspended.
+func isSuspended(provider operatorv1.GenericProvider) bool {
+	return provider.GetSpec().Manager != nil && provider.GetSpec().Manager.Suspend
+}
+
+// suspendDeploymentsFn scales all the provider Deployments down to zero replicas.
+func suspendDeploymentsFn() func(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+	return func(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+		for i := range objs {
+			if objs[i].GetKind() != deploymentKind {
+				continue
+			}
+
+			if err := unstructured.SetNestedField(objs[i].Object, int64(0), "spec", "replicas"); err != nil {
+				return nil, err
+			}
+		}
+
+		return objs, nil
+	}
+}
+
+// setSuspendedCondition reports whether the provider Deployments are scaled down with the manager suspend field.
+func setSuspendedCondition(provider operatorv1.GenericProvider) {
+	if isSuspended(provider) {
+		conditions.MarkTrue(provider, operatorv1.SuspendedCondition)
+
+		return
+	}
+
+	conditions.Delete(provider, operatorv1.SuspendedCondition)
+}
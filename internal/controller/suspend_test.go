@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestSuspendDeployments(t *testing.T) {
+	g := NewWithT(t)
+
+	deployment := unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+		},
+	}}
+	deployment.SetAPIVersion("apps/v1")
+	deployment.SetKind(deploymentKind)
+	deployment.SetName("capi-controller-manager")
+
+	service := unstructured.Unstructured{}
+	service.SetAPIVersion("v1")
+	service.SetKind(serviceKind)
+	service.SetName("capi-webhook-service")
+
+	objs, err := suspendDeploymentsFn()([]unstructured.Unstructured{deployment, service})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(objs).To(HaveLen(2))
+
+	replicas, found, err := unstructured.NestedInt64(objs[0].Object, "spec", "replicas")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(replicas).To(BeZero())
+
+	g.Expect(objs[1].Object).ToNot(HaveKey("spec"))
+}
+
+func TestSetSuspendedCondition(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-api",
+			Namespace: "capi-system",
+		},
+		Spec: operatorv1.CoreProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				Manager: &operatorv1.ManagerSpec{Suspend: true},
+			},
+		},
+	}
+
+	setSuspendedCondition(provider)
+	g.Expect(conditions.IsTrue(provider, operatorv1.SuspendedCondition)).To(BeTrue())
+
+	// The condition is removed when the provider is resumed.
+	provider.Spec.Manager.Suspend = false
+
+	setSuspendedCondition(provider)
+	g.Expect(conditions.Has(provider, operatorv1.SuspendedCondition)).To(BeFalse())
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// isPaused returns true if the provider is paused with the paused field or the Cluster API paused annotation.
+func isPaused(provider operatorv1.GenericProvider) bool {
+	if provider.GetSpec().Paused {
+		return true
+	}
+
+	_, ok := provider.GetAnnotations()[clusterv1.PausedAnnotation]
+
+	return ok
+}
+
+// setPausedCondition reports whether the reconciliation of the provider is paused.
+func setPausedCondition(provider operatorv1.GenericProvider) {
+	if isPaused(provider) {
+		conditions.MarkTrue(provider, operatorv1.PausedCondition)
+
+		return
+	}
+
+	conditions.Delete(provider, operatorv1.PausedCondition)
+}
+
+// upgradePending returns true if the installed version of the provider differs from its spec version.
+func upgradePending(provider operatorv1.GenericProvider) bool {
+	installedVersion := provider.GetStatus().InstalledVersion
+
+	return installedVersion != nil && *installedVersion != provider.GetSpec().Version
+}
+
+// planUpgrade publishes the changes of a pending upgrade in the provider status before it's applied.
+func (p *phaseReconciler) planUpgrade(ctx context.Context) (reconcile.Result, error) {
+	status := p.provider.GetStatus()
+
+	if !upgradePending(p.provider) {
+		status.UpgradePlan = nil
+		p.provider.SetStatus(status)
+
+		return reconcile.Result{}, nil
+	}
+
+	installedCRDs, err := p.installedCRDs(ctx)
+	if err != nil {
+		return reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsUpgradeErrorReason, operatorv1.ProviderUpgradedCondition)
+	}
+
+	plan := newUpgradePlan(status, p.provider.GetSpec().Version, p.contract, installedCRDs, p.components.Objs())
+
+	ctrl.LoggerFrom(ctx).Info("Planned provider upgrade", "currentVersion", plan.CurrentVersion, "targetVersion", plan.TargetVersion,
+		"currentContract", plan.CurrentContract, "targetContract", plan.TargetContract, "addedCRDs", plan.AddedCRDs, "removedCRDs", plan.RemovedCRDs)
+
+	status.UpgradePlan = plan
+	p.provider.SetStatus(status)
+
+	return reconcile.Result{}, nil
+}
+
+// installedCRDs returns the names of the CustomResourceDefinitions installed for the provider.
+func (p *phaseReconciler) installedCRDs(ctx context.Context) (sets.Set[string], error) {
+	crdList := &unstructured.UnstructuredList{}
+	crdList.SetGroupVersionKind(crdGVK.GroupVersion().WithKind(customResourceDefinitionKind + "List"))
+
+	if err := p.ctrlClient.List(ctx, crdList, client.MatchingLabels{clusterv1.ProviderNameLabel: p.components.ManifestLabel()}); err != nil {
+		return nil, fmt.Errorf("failed to list CustomResourceDefinitions of provider %q: %w", p.provider.GetName(), err)
+	}
+
+	names := sets.New[string]()
+	for i := range crdList.Items {
+		names.Insert(crdList.Items[i].GetName())
+	}
+
+	return names, nil
+}
+
+// newUpgradePlan compares the installed version, contract and CustomResourceDefinitions of the provider with the
+// ones of the target components.
+func newUpgradePlan(status operatorv1.ProviderStatus, targetVersion, targetContract string, installedCRDs sets.Set[string], objs []unstructured.Unstructured) *operatorv1.UpgradePlan {
+	plan := &operatorv1.UpgradePlan{
+		TargetVersion:  targetVersion,
+		TargetContract: targetContract,
+	}
+
+	if status.InstalledVersion != nil {
+		plan.CurrentVersion = *status.InstalledVersion
+	}
+
+	if status.Contract != nil {
+		plan.CurrentContract = *status.Contract
+	}
+
+	targetCRDs := sets.New[string]()
+
+	for i := range objs {
+		if objs[i].GetKind() == customResourceDefinitionKind {
+			targetCRDs.Insert(objs[i].GetName())
+		}
+	}
+
+	plan.AddedCRDs = sets.List(targetCRDs.Difference(installedCRDs))
+	plan.RemovedCRDs = sets.List(installedCRDs.Difference(targetCRDs))
+
+	return plan
+}
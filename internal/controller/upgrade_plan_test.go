@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestNewUpgradePlan(t *testing.T) {
+	newObj := func(kind, name string) unstructured.Unstructured {
+		obj := unstructured.Unstructured{}
+		obj.SetKind(kind)
+		obj.SetName(name)
+
+		return obj
+	}
+
+	objs := []unstructured.Unstructured{
+		newObj(customResourceDefinitionKind, "awsclusters.infrastructure.cluster.x-k8s.io"),
+		newObj(customResourceDefinitionKind, "awsmanagedclusters.infrastructure.cluster.x-k8s.io"),
+		newObj(deploymentKind, "capa-controller-manager"),
+	}
+
+	testCases := []struct {
+		name          string
+		status        operatorv1.ProviderStatus
+		installedCRDs sets.Set[string]
+		wantPlan      *operatorv1.UpgradePlan
+	}{
+		{
+			name: "same contract and CRDs",
+			status: operatorv1.ProviderStatus{
+				InstalledVersion: pointer.String("v2.3.0"),
+				Contract:         pointer.String("v1beta1"),
+			},
+			installedCRDs: sets.New("awsclusters.infrastructure.cluster.x-k8s.io", "awsmanagedclusters.infrastructure.cluster.x-k8s.io"),
+			wantPlan: &operatorv1.UpgradePlan{
+				CurrentVersion:  "v2.3.0",
+				TargetVersion:   "v2.4.0",
+				CurrentContract: "v1beta1",
+				TargetContract:  "v1beta1",
+				AddedCRDs:       []string{},
+				RemovedCRDs:     []string{},
+			},
+		},
+		{
+			name: "CRDs added and removed",
+			status: operatorv1.ProviderStatus{
+				InstalledVersion: pointer.String("v2.3.0"),
+				Contract:         pointer.String("v1beta1"),
+			},
+			installedCRDs: sets.New("awsclusters.infrastructure.cluster.x-k8s.io", "awsfargateprofiles.infrastructure.cluster.x-k8s.io"),
+			wantPlan: &operatorv1.UpgradePlan{
+				CurrentVersion:  "v2.3.0",
+				TargetVersion:   "v2.4.0",
+				CurrentContract: "v1beta1",
+				TargetContract:  "v1beta1",
+				AddedCRDs:       []string{"awsmanagedclusters.infrastructure.cluster.x-k8s.io"},
+				RemovedCRDs:     []string{"awsfargateprofiles.infrastructure.cluster.x-k8s.io"},
+			},
+		},
+		{
+			name: "installed contract unknown",
+			status: operatorv1.ProviderStatus{
+				InstalledVersion: pointer.String("v2.3.0"),
+			},
+			installedCRDs: sets.New[string](),
+			wantPlan: &operatorv1.UpgradePlan{
+				CurrentVersion: "v2.3.0",
+				TargetVersion:  "v2.4.0",
+				TargetContract: "v1beta1",
+				AddedCRDs:      []string{"awsclusters.infrastructure.cluster.x-k8s.io", "awsmanagedclusters.infrastructure.cluster.x-k8s.io"},
+				RemovedCRDs:    []string{},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(newUpgradePlan(tc.status, "v2.4.0", "v1beta1", tc.installedCRDs, objs)).To(Equal(tc.wantPlan))
+		})
+	}
+}
+
+func TestUpgradePending(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.InfrastructureProvider{
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{Version: "v2.4.0"},
+		},
+	}
+
+	// Fresh installations are not upgrades.
+	g.Expect(upgradePending(provider)).To(BeFalse())
+
+	provider.Status.InstalledVersion = pointer.String("v2.4.0")
+	g.Expect(upgradePending(provider)).To(BeFalse())
+
+	provider.Status.InstalledVersion = pointer.String("v2.3.0")
+	g.Expect(upgradePending(provider)).To(BeTrue())
+}
+
+func TestIsPaused(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.InfrastructureProvider{}
+	g.Expect(isPaused(provider)).To(BeFalse())
+
+	provider.Spec.Paused = true
+	g.Expect(isPaused(provider)).To(BeTrue())
+
+	setPausedCondition(provider)
+	g.Expect(conditions.IsTrue(provider, operatorv1.PausedCondition)).To(BeTrue())
+
+	provider.Spec.Paused = false
+	provider.SetAnnotations(map[string]string{clusterv1.PausedAnnotation: ""})
+	g.Expect(isPaused(provider)).To(BeTrue())
+
+	provider.SetAnnotations(nil)
+	setPausedCondition(provider)
+	g.Expect(conditions.Has(provider, operatorv1.PausedCondition)).To(BeFalse())
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	versionutil "k8s.io/apimachinery/pkg/util/version"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// upgradeStepRequeueAfter is how long to wait before installing the next version of a multi-step upgrade.
+const upgradeStepRequeueAfter = 5 * time.Second
+
+// planUpgradeSteps plans the versions a provider is upgraded through when the upgrade crosses Cluster API
+// contracts, using the metadata of the target version. The steps are installed by nextUpgradeStep.
+func (p *phaseReconciler) planUpgradeSteps(ctx context.Context) (reconcile.Result, error) {
+	status := p.provider.GetStatus()
+
+	// Steps are planned for the target version, not while a step or the installed version is reconciled.
+	if p.deferredVersion != "" || !upgradePending(p.provider) || len(status.UpgradeSteps) > 0 {
+		return reconcile.Result{}, nil
+	}
+
+	available, res, err := p.availableVersions(ctx)
+	if err != nil || !res.IsZero() {
+		return res, err
+	}
+
+	steps := contractUpgradeSteps(p.metadata, available, *status.InstalledVersion, p.provider.GetSpec().Version)
+	if len(steps) < 2 {
+		return reconcile.Result{}, nil
+	}
+
+	versions := make([]string, 0, len(steps))
+	for _, step := range steps {
+		versions = append(versions, step.Version)
+	}
+
+	ctrl.LoggerFrom(ctx).Info("Upgrade crosses Cluster API contracts, upgrading provider through intermediate versions", "steps", versions)
+
+	status.UpgradeSteps = steps
+	p.provider.SetStatus(status)
+
+	return reconcile.Result{RequeueAfter: upgradeStepRequeueAfter}, nil
+}
+
+// availableVersions lists the versions available in the repository of the provider. Versions can't be listed for
+// OCI, Git, Helm chart, S3 and local path sources, so nil is returned for them.
+func (p *phaseReconciler) availableVersions(ctx context.Context) ([]string, reconcile.Result, error) {
+	fetchConfig := p.provider.GetSpec().FetchConfig
+
+	if !versionPolicySupported(fetchConfig) {
+		return nil, reconcile.Result{}, nil
+	}
+
+	if fetchConfig != nil && (fetchConfig.Selector != nil || fetchConfig.Secret != nil) {
+		versions, err := p.repo.GetVersions(ctx)
+		if err != nil {
+			err = fmt.Errorf("failed to list the available versions of provider %q: %w", p.provider.GetName(), err)
+
+			return nil, reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+		}
+
+		return versions, reconcile.Result{}, nil
+	}
+
+	return p.remoteVersions(ctx)
+}
+
+// nextUpgradeStep installs the next version of a multi-step upgrade, once the Deployments of the previous one are
+// ready. The version of the spec is restored once the reconciliation completes.
+func (p *phaseReconciler) nextUpgradeStep(ctx context.Context) (reconcile.Result, error) {
+	status := p.provider.GetStatus()
+
+	// The version change is deferred until the maintenance window opens.
+	if p.deferredVersion != "" || len(status.UpgradeSteps) == 0 {
+		return reconcile.Result{}, nil
+	}
+
+	spec := p.provider.GetSpec()
+
+	// Steps are cleared once the target version is installed, and planned again if the target version changes.
+	if !upgradePending(p.provider) || status.UpgradeSteps[len(status.UpgradeSteps)-1].Version != spec.Version {
+		status.UpgradeSteps = nil
+		p.provider.SetStatus(status)
+
+		return reconcile.Result{}, nil
+	}
+
+	installedVersion := *status.InstalledVersion
+	markCompletedUpgradeSteps(status.UpgradeSteps, installedVersion)
+	p.provider.SetStatus(status)
+
+	next := ""
+
+	for _, step := range status.UpgradeSteps {
+		if !step.Completed {
+			next = step.Version
+
+			break
+		}
+	}
+
+	log := ctrl.LoggerFrom(ctx)
+
+	switch _, upgrading := p.provider.GetAnnotations()[upgradedFromAnnotation]; {
+	case upgrading && status.UpgradeSteps[0].Completed:
+		log.Info("Waiting for the deployments of the previous upgrade step to become ready", "installedVersion", installedVersion)
+
+		next = installedVersion
+	case next == spec.Version:
+		// The last step is a regular upgrade to the version of the spec.
+		return reconcile.Result{}, nil
+	default:
+		log.Info("Upgrading provider to an intermediate version", "version", next, "targetVersion", spec.Version)
+	}
+
+	p.deferredVersion = spec.Version
+	p.deferredVersionRequeueAfter = upgradeStepRequeueAfter
+
+	spec.Version = next
+	p.provider.SetSpec(spec)
+
+	return reconcile.Result{}, nil
+}
+
+// markCompletedUpgradeSteps marks the steps up to the installed version as completed.
+func markCompletedUpgradeSteps(steps []operatorv1.UpgradeStep, installedVersion string) {
+	for i := range steps {
+		if steps[i].Version != installedVersion {
+			continue
+		}
+
+		for j := 0; j <= i; j++ {
+			steps[j].Completed = true
+		}
+	}
+}
+
+// contractUpgradeSteps returns the versions to install to upgrade a provider from the installed version to the
+// target version. Before the release series of a new contract, the latest available version of the last release
+// series of the previous contract is installed. The target version is always the last step.
+func contractUpgradeSteps(metadata *clusterctlv1.Metadata, available []string, installedVersion, targetVersion string) []operatorv1.UpgradeStep {
+	target := operatorv1.UpgradeStep{Version: targetVersion}
+
+	if metadata == nil {
+		return []operatorv1.UpgradeStep{target}
+	}
+
+	installed, err := versionutil.ParseSemantic(installedVersion)
+	if err != nil {
+		return []operatorv1.UpgradeStep{target}
+	}
+
+	targetParsed, err := versionutil.ParseSemantic(targetVersion)
+	if err != nil {
+		return []operatorv1.UpgradeStep{target}
+	}
+
+	if releaseSeries := metadata.GetReleaseSeriesForVersion(targetParsed); releaseSeries != nil {
+		target.Contract = releaseSeries.Contract
+	}
+
+	releaseSeries := append([]clusterctlv1.ReleaseSeries{}, metadata.ReleaseSeries...)
+	sort.Slice(releaseSeries, func(i, j int) bool {
+		return compareReleaseSeries(releaseSeries[i], releaseSeries[j].Major, releaseSeries[j].Minor) < 0
+	})
+
+	steps := []operatorv1.UpgradeStep{}
+
+	for i := 0; i < len(releaseSeries)-1; i++ {
+		series := releaseSeries[i]
+
+		// Only the contracts crossed from the installed release series to the target one.
+		if compareReleaseSeries(series, installed.Major(), installed.Minor()) < 0 ||
+			compareReleaseSeries(series, targetParsed.Major(), targetParsed.Minor()) >= 0 {
+			continue
+		}
+
+		if series.Contract == releaseSeries[i+1].Contract {
+			continue
+		}
+
+		if version := latestSeriesVersion(series, installed, available); version != "" {
+			steps = append(steps, operatorv1.UpgradeStep{Version: version, Contract: series.Contract})
+		}
+	}
+
+	return append(steps, target)
+}
+
+// compareReleaseSeries compares the release series with the given major and minor version.
+func compareReleaseSeries(series clusterctlv1.ReleaseSeries, major, minor uint) int {
+	switch {
+	case series.Major < major || (series.Major == major && series.Minor < minor):
+		return -1
+	case series.Major == major && series.Minor == minor:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// latestSeriesVersion returns the latest available version of the release series newer than the installed version.
+// Pre-releases and versions that can't be parsed are ignored.
+func latestSeriesVersion(series clusterctlv1.ReleaseSeries, installed *versionutil.Version, available []string) string {
+	var latest *versionutil.Version
+
+	latestString := ""
+
+	for _, version := range available {
+		parsed, err := versionutil.ParseSemantic(version)
+		if err != nil || parsed.PreRelease() != "" {
+			continue
+		}
+
+		if compareReleaseSeries(series, parsed.Major(), parsed.Minor()) != 0 || !installed.LessThan(parsed) {
+			continue
+		}
+
+		if latest == nil || latest.LessThan(parsed) {
+			latest = parsed
+			latestString = version
+		}
+	}
+
+	return latestString
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestContractUpgradeSteps(t *testing.T) {
+	metadata := &clusterctlv1.Metadata{
+		ReleaseSeries: []clusterctlv1.ReleaseSeries{
+			{Major: 1, Minor: 0, Contract: "v1beta1"},
+			{Major: 0, Minor: 4, Contract: "v1alpha4"},
+			{Major: 0, Minor: 3, Contract: "v1alpha3"},
+			{Major: 1, Minor: 1, Contract: "v1beta1"},
+			{Major: 2, Minor: 0, Contract: "v1beta2"},
+		},
+	}
+
+	available := []string{"v0.3.0", "v0.3.9", "v0.4.0", "v0.4.7", "v0.4.8-rc.0", "v1.0.0", "v1.1.0", "v1.1.5", "v2.0.0"}
+
+	testCases := []struct {
+		name      string
+		installed string
+		target    string
+		metadata  *clusterctlv1.Metadata
+		wantSteps []operatorv1.UpgradeStep
+	}{
+		{
+			name:      "same release series",
+			installed: "v1.1.0",
+			target:    "v1.1.5",
+			metadata:  metadata,
+			wantSteps: []operatorv1.UpgradeStep{{Version: "v1.1.5", Contract: "v1beta1"}},
+		},
+		{
+			name:      "same contract",
+			installed: "v1.0.0",
+			target:    "v1.1.5",
+			metadata:  metadata,
+			wantSteps: []operatorv1.UpgradeStep{{Version: "v1.1.5", Contract: "v1beta1"}},
+		},
+		{
+			name:      "one contract crossed",
+			installed: "v0.4.0",
+			target:    "v1.1.5",
+			metadata:  metadata,
+			wantSteps: []operatorv1.UpgradeStep{
+				{Version: "v0.4.7", Contract: "v1alpha4"},
+				{Version: "v1.1.5", Contract: "v1beta1"},
+			},
+		},
+		{
+			name:      "several contracts crossed",
+			installed: "v0.3.0",
+			target:    "v2.0.0",
+			metadata:  metadata,
+			wantSteps: []operatorv1.UpgradeStep{
+				{Version: "v0.3.9", Contract: "v1alpha3"},
+				{Version: "v0.4.7", Contract: "v1alpha4"},
+				{Version: "v1.1.5", Contract: "v1beta1"},
+				{Version: "v2.0.0", Contract: "v1beta2"},
+			},
+		},
+		{
+			name:      "latest version of the contract installed",
+			installed: "v0.4.7",
+			target:    "v1.1.5",
+			metadata:  metadata,
+			wantSteps: []operatorv1.UpgradeStep{{Version: "v1.1.5", Contract: "v1beta1"}},
+		},
+		{
+			name:      "no metadata",
+			installed: "v0.4.0",
+			target:    "v1.1.5",
+			wantSteps: []operatorv1.UpgradeStep{{Version: "v1.1.5"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(contractUpgradeSteps(tc.metadata, available, tc.installed, tc.target)).To(Equal(tc.wantSteps))
+		})
+	}
+}
+
+func TestNextUpgradeStep(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.InfrastructureProvider{
+		Spec: operatorv1.InfrastructureProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{Version: "v1.1.5"},
+		},
+		Status: operatorv1.InfrastructureProviderStatus{
+			ProviderStatus: operatorv1.ProviderStatus{
+				InstalledVersion: pointer.String("v0.4.0"),
+				UpgradeSteps: []operatorv1.UpgradeStep{
+					{Version: "v0.4.7", Contract: "v1alpha4"},
+					{Version: "v1.1.5", Contract: "v1beta1"},
+				},
+			},
+		},
+	}
+
+	// The first step is installed instead of the version of the spec.
+	p := &phaseReconciler{provider: provider}
+	_, err := p.nextUpgradeStep(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider.Spec.Version).To(Equal("v0.4.7"))
+
+	res := p.restoreDeferredVersion(reconcile.Result{})
+	g.Expect(provider.Spec.Version).To(Equal("v1.1.5"))
+	g.Expect(res.RequeueAfter).To(Equal(upgradeStepRequeueAfter))
+
+	// The installed step is reconciled until its deployments are ready.
+	provider.Status.InstalledVersion = pointer.String("v0.4.7")
+	provider.ObjectMeta = metav1.ObjectMeta{Annotations: map[string]string{upgradedFromAnnotation: "v0.4.0"}}
+
+	p = &phaseReconciler{provider: provider}
+	_, err = p.nextUpgradeStep(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider.Spec.Version).To(Equal("v0.4.7"))
+	g.Expect(provider.Status.UpgradeSteps[0].Completed).To(BeTrue())
+	p.restoreDeferredVersion(reconcile.Result{})
+
+	// The last step is a regular upgrade.
+	provider.ObjectMeta = metav1.ObjectMeta{}
+
+	p = &phaseReconciler{provider: provider}
+	_, err = p.nextUpgradeStep(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider.Spec.Version).To(Equal("v1.1.5"))
+	g.Expect(p.deferredVersion).To(BeEmpty())
+
+	// Steps are cleared when the target version changes.
+	provider.Spec.Version = "v1.1.0"
+
+	p = &phaseReconciler{provider: provider}
+	_, err = p.nextUpgradeStep(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider.Status.UpgradeSteps).To(BeEmpty())
+}
@@ -0,0 +1,323 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	versionutil "k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-operator/util"
+)
+
+const (
+	// versionCheckedAtAnnotation is the time the available versions of a provider with a version policy were last checked.
+	versionCheckedAtAnnotation = "operator.cluster.x-k8s.io/version-checked-at"
+
+	// versionCheckInterval is how often the available versions of a provider with a version policy are checked.
+	versionCheckInterval = time.Hour
+)
+
+// hasVersionPolicy returns true if the provider is upgraded automatically to new versions.
+func hasVersionPolicy(spec operatorv1.ProviderSpec) bool {
+	return spec.VersionPolicy != "" && spec.VersionPolicy != operatorv1.VersionPolicyPinned
+}
+
+// tracksLatestVersion returns true if the provider follows the newest release of its repository.
+func tracksLatestVersion(spec operatorv1.ProviderSpec) bool {
+	return spec.Version == "" || spec.Version == operatorv1.VersionLatest
+}
+
+// checksVersions returns true if the available versions of the provider are checked periodically.
+func checksVersions(spec operatorv1.ProviderSpec) bool {
+	return hasVersionPolicy(spec) || tracksLatestVersion(spec)
+}
+
+// versionPolicySupported returns true if the available versions of the fetch config can be listed for the version policy.
+func versionPolicySupported(fetchConfig *operatorv1.FetchConfiguration) bool {
+	return fetchConfig == nil || (fetchConfig.OCI == "" && fetchConfig.Git == nil && fetchConfig.Chart == nil &&
+		fetchConfig.S3 == nil && fetchConfig.LocalPath == "")
+}
+
+// policyVersion returns the latest of the available versions allowed by the version policy from the current version.
+// Pre-releases and versions that can't be parsed are ignored, and the current version is returned if none is newer.
+func policyVersion(policy operatorv1.VersionPolicy, current string, available []string) string {
+	if policy == "" || policy == operatorv1.VersionPolicyPinned {
+		return current
+	}
+
+	currentVersion, err := versionutil.ParseSemantic(current)
+	if err != nil {
+		return current
+	}
+
+	latest, latestString := currentVersion, current
+
+	for _, v := range available {
+		parsed, err := versionutil.ParseSemantic(v)
+		if err != nil || parsed.PreRelease() != "" || parsed.Major() != currentVersion.Major() {
+			continue
+		}
+
+		if policy == operatorv1.VersionPolicyLatestPatch && parsed.Minor() != currentVersion.Minor() {
+			continue
+		}
+
+		if latest.LessThan(parsed) {
+			latest, latestString = parsed, v
+		}
+	}
+
+	return latestString
+}
+
+// applyVersionPolicy upgrades the version of the provider to the latest version of its repository allowed by the
+// version policy. Versions of the ConfigMaps and Secrets selected by the fetch config are checked when they are loaded.
+func (p *phaseReconciler) applyVersionPolicy(ctx context.Context) (reconcile.Result, error) {
+	spec := p.provider.GetSpec()
+
+	if !hasVersionPolicy(spec) || tracksLatestVersion(spec) {
+		return reconcile.Result{}, nil
+	}
+
+	if spec.FetchConfig != nil && (spec.FetchConfig.Selector != nil || spec.FetchConfig.Secret != nil) {
+		return reconcile.Result{}, nil
+	}
+
+	versions, res, err := p.remoteVersions(ctx)
+	if err != nil || !res.IsZero() {
+		return res, err
+	}
+
+	p.setPolicyVersion(ctx, versions)
+
+	return reconcile.Result{}, nil
+}
+
+// resolveLatestVersion replaces the version of a provider tracking its newest release with the newest version when
+// the versions are checked, and with the installed version in between, or if the newest version was rolled back.
+func (p *phaseReconciler) resolveLatestVersion(ctx context.Context) (reconcile.Result, error) {
+	spec := p.provider.GetSpec()
+
+	if !tracksLatestVersion(spec) {
+		return reconcile.Result{}, nil
+	}
+
+	installedVersion := p.provider.GetStatus().InstalledVersion
+
+	if checkVersion, _ := versionCheckDue(p.provider); installedVersion != nil && !checkVersion {
+		p.setResolvedVersion(*installedVersion)
+
+		return reconcile.Result{}, nil
+	}
+
+	version, res, err := p.latestVersion(ctx)
+	if err != nil || !res.IsZero() {
+		return res, err
+	}
+
+	if rolledBackVersion, ok := p.provider.GetAnnotations()[rolledBackVersionAnnotation]; ok && installedVersion != nil && version == rolledBackVersion {
+		version = *installedVersion
+	}
+
+	p.setResolvedVersion(version)
+
+	if version != "" && (installedVersion == nil || *installedVersion != version) {
+		ctrl.LoggerFrom(ctx).Info("Resolved the newest version of the provider", "version", version)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// setResolvedVersion replaces the version of the spec with the version resolved for the reconciliation. The version
+// of the spec is restored once the reconciliation completes, the resolved one is only recorded in the status.
+func (p *phaseReconciler) setResolvedVersion(version string) {
+	spec := p.provider.GetSpec()
+
+	if p.specVersion == nil {
+		specVersion := spec.Version
+		p.specVersion = &specVersion
+	}
+
+	spec.Version = version
+	p.provider.SetSpec(spec)
+}
+
+// latestVersion returns the default version of the repository of the provider URL, which is the newest release
+// unless the URL contains a version. An empty version is returned for the other sources, which are resolved to
+// their newest or default version once loaded.
+func (p *phaseReconciler) latestVersion(ctx context.Context) (string, reconcile.Result, error) {
+	fetchConfig := p.provider.GetSpec().FetchConfig
+
+	if !versionPolicySupported(fetchConfig) || (fetchConfig != nil && (fetchConfig.Selector != nil || fetchConfig.Secret != nil)) {
+		return "", reconcile.Result{}, nil
+	}
+
+	repo, res, err := p.remoteRepository(ctx)
+	if err != nil || !res.IsZero() {
+		return "", res, err
+	}
+
+	return repo.DefaultVersion(), reconcile.Result{}, nil
+}
+
+// restoreSpecVersion restores the version of the spec replaced with a resolved version.
+func (p *phaseReconciler) restoreSpecVersion() {
+	if p.specVersion == nil {
+		return
+	}
+
+	spec := p.provider.GetSpec()
+	spec.Version = *p.specVersion
+	p.provider.SetSpec(spec)
+}
+
+// remoteVersions lists the versions available in the repository of the provider URL.
+func (p *phaseReconciler) remoteVersions(ctx context.Context) ([]string, reconcile.Result, error) {
+	repo, res, err := p.remoteRepository(ctx)
+	if err != nil || !res.IsZero() {
+		return nil, res, err
+	}
+
+	versions, err := repo.GetVersions(ctx)
+	if err != nil {
+		if res, ok := p.rateLimited(ctx, err); ok {
+			return nil, res, nil
+		}
+
+		err = fmt.Errorf("failed to list the available versions of provider %q: %w", p.provider.GetName(), err)
+
+		return nil, reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	return versions, reconcile.Result{}, nil
+}
+
+// remoteRepository returns the repository of the provider URL.
+func (p *phaseReconciler) remoteRepository(ctx context.Context) (repository.Repository, reconcile.Result, error) {
+	spec := p.provider.GetSpec()
+
+	var forge operatorv1.ForgeType
+	if spec.FetchConfig != nil {
+		forge = spec.FetchConfig.Forge
+	}
+
+	httpClient, err := p.fetchHTTPClient(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to create HTTP client for provider %q: %w", p.provider.GetName(), err)
+
+		return nil, reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	repo, err := util.RepositoryFactory(ctx, p.providerConfig, forge, httpClient, p.configClient.Variables())
+	if err != nil {
+		if res, ok := p.rateLimited(ctx, err); ok {
+			return nil, res, nil
+		}
+
+		err = fmt.Errorf("failed to create repo from provider url for provider %q: %w", p.provider.GetName(), err)
+
+		return nil, reconcile.Result{}, wrapPhaseError(err, operatorv1.ComponentsFetchErrorReason, operatorv1.ProviderInstalledCondition)
+	}
+
+	return repo, reconcile.Result{}, nil
+}
+
+// setPolicyVersion resolves the version of the provider to the latest of the available versions allowed by the
+// version policy. The installed version is never downgraded, and the version the provider was rolled back from is
+// skipped until a newer one is available.
+func (p *phaseReconciler) setPolicyVersion(ctx context.Context, available []string) {
+	spec := p.provider.GetSpec()
+	rolledBackVersion := p.provider.GetAnnotations()[rolledBackVersionAnnotation]
+
+	candidates := []string{}
+
+	for _, v := range available {
+		if v != rolledBackVersion {
+			candidates = append(candidates, v)
+		}
+	}
+
+	installedVersion := p.provider.GetStatus().InstalledVersion
+	if installedVersion != nil {
+		candidates = append(candidates, *installedVersion)
+	}
+
+	version := policyVersion(spec.VersionPolicy, spec.Version, candidates)
+	if version == spec.Version {
+		return
+	}
+
+	if installedVersion == nil || *installedVersion != version {
+		ctrl.LoggerFrom(ctx).Info("Upgrading provider to the latest version allowed by the version policy",
+			"versionPolicy", spec.VersionPolicy, "currentVersion", spec.Version, "version", version)
+	}
+
+	p.setResolvedVersion(version)
+}
+
+// versionCheckDue returns true if the available versions of a provider with a version policy or tracking its newest
+// release must be checked, otherwise how long to wait before checking them.
+func versionCheckDue(provider operatorv1.GenericProvider) (bool, time.Duration) {
+	if !checksVersions(provider.GetSpec()) {
+		return false, 0
+	}
+
+	checkedAt, err := time.Parse(time.RFC3339, provider.GetAnnotations()[versionCheckedAtAnnotation])
+	if err != nil {
+		return true, 0
+	}
+
+	if elapsed := time.Since(checkedAt); elapsed < versionCheckInterval {
+		return false, versionCheckInterval - elapsed
+	}
+
+	return true, 0
+}
+
+// setVersionChecked records the time the available versions of a provider with a version policy or tracking its
+// newest release were checked, and returns how long to wait before checking them again, zero otherwise.
+func setVersionChecked(annotations map[string]string, provider operatorv1.GenericProvider) time.Duration {
+	if !checksVersions(provider.GetSpec()) {
+		delete(annotations, versionCheckedAtAnnotation)
+
+		return 0
+	}
+
+	annotations[versionCheckedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+
+	return versionCheckInterval
+}
+
+// earliestRequeueAfter returns the shortest of the positive durations, zero if there are none.
+func earliestRequeueAfter(durations ...time.Duration) time.Duration {
+	var earliest time.Duration
+
+	for _, d := range durations {
+		if d > 0 && (earliest == 0 || d < earliest) {
+			earliest = d
+		}
+	}
+
+	return earliest
+}
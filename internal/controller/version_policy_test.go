@@ -0,0 +1,230 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestPolicyVersion(t *testing.T) {
+	available := []string{"v1.4.9", "v1.5.0", "v1.5.3", "v1.5.4-rc.0", "v1.6.1", "v1.6.0", "v2.0.0", "latest"}
+
+	testCases := []struct {
+		name    string
+		policy  operatorv1.VersionPolicy
+		current string
+		want    string
+	}{
+		{
+			name:    "no policy",
+			current: "v1.5.0",
+			want:    "v1.5.0",
+		},
+		{
+			name:    "pinned",
+			policy:  operatorv1.VersionPolicyPinned,
+			current: "v1.5.0",
+			want:    "v1.5.0",
+		},
+		{
+			name:    "latest patch",
+			policy:  operatorv1.VersionPolicyLatestPatch,
+			current: "v1.5.0",
+			want:    "v1.5.3",
+		},
+		{
+			name:    "latest minor",
+			policy:  operatorv1.VersionPolicyLatestMinor,
+			current: "v1.5.0",
+			want:    "v1.6.1",
+		},
+		{
+			name:    "already the latest",
+			policy:  operatorv1.VersionPolicyLatestMinor,
+			current: "v2.0.0",
+			want:    "v2.0.0",
+		},
+		{
+			name:    "newer than all the available versions",
+			policy:  operatorv1.VersionPolicyLatestPatch,
+			current: "v1.4.10",
+			want:    "v1.4.10",
+		},
+		{
+			name:    "pre-release upgraded to a release",
+			policy:  operatorv1.VersionPolicyLatestPatch,
+			current: "v1.6.0-rc.1",
+			want:    "v1.6.1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(policyVersion(tc.policy, tc.current, available)).To(Equal(tc.want))
+		})
+	}
+}
+
+func TestVersionCheckDue(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "capi-system"},
+		Spec: operatorv1.CoreProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{Version: "v1.5.0"},
+		},
+	}
+
+	// Pinned providers are never checked.
+	due, after := versionCheckDue(provider)
+	g.Expect(due).To(BeFalse())
+	g.Expect(after).To(BeZero())
+
+	annotations := map[string]string{}
+	g.Expect(setVersionChecked(annotations, provider)).To(BeZero())
+	g.Expect(annotations).To(BeEmpty())
+
+	// Providers with a version policy are checked first, then hourly.
+	provider.Spec.VersionPolicy = operatorv1.VersionPolicyLatestPatch
+
+	due, _ = versionCheckDue(provider)
+	g.Expect(due).To(BeTrue())
+
+	g.Expect(setVersionChecked(annotations, provider)).To(Equal(versionCheckInterval))
+	provider.SetAnnotations(annotations)
+
+	due, after = versionCheckDue(provider)
+	g.Expect(due).To(BeFalse())
+	g.Expect(after).To(BeNumerically("~", versionCheckInterval, time.Minute))
+
+	provider.Annotations[versionCheckedAtAnnotation] = time.Now().Add(-2 * versionCheckInterval).UTC().Format(time.RFC3339)
+
+	due, _ = versionCheckDue(provider)
+	g.Expect(due).To(BeTrue())
+
+	// Providers tracking the newest release are checked like the ones with a version policy.
+	provider.Spec.VersionPolicy = ""
+	provider.Spec.Version = operatorv1.VersionLatest
+
+	due, _ = versionCheckDue(provider)
+	g.Expect(due).To(BeTrue())
+}
+
+func TestResolveLatestVersion(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "cluster-api",
+			Namespace:   "capi-system",
+			Annotations: map[string]string{versionCheckedAtAnnotation: time.Now().UTC().Format(time.RFC3339)},
+		},
+		Spec: operatorv1.CoreProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{
+				Version:     operatorv1.VersionLatest,
+				FetchConfig: &operatorv1.FetchConfiguration{OCI: "registry.example.com/cluster-api"},
+			},
+		},
+		Status: operatorv1.CoreProviderStatus{
+			ProviderStatus: operatorv1.ProviderStatus{InstalledVersion: pointer.String("v1.5.0")},
+		},
+	}
+
+	// The installed version is kept until the versions are checked.
+	p := &phaseReconciler{provider: provider}
+	_, err := p.resolveLatestVersion(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider.Spec.Version).To(Equal("v1.5.0"))
+
+	p.restoreSpecVersion()
+	g.Expect(provider.Spec.Version).To(Equal(operatorv1.VersionLatest))
+
+	// Sources whose versions can't be listed install their default version once the versions are checked.
+	provider.Annotations = nil
+
+	p = &phaseReconciler{provider: provider}
+	_, err = p.resolveLatestVersion(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider.Spec.Version).To(BeEmpty())
+
+	p.restoreSpecVersion()
+	g.Expect(provider.Spec.Version).To(Equal(operatorv1.VersionLatest))
+
+	// Pinned versions are left alone.
+	provider.Spec.Version = "v1.5.0"
+
+	p = &phaseReconciler{provider: provider}
+	_, err = p.resolveLatestVersion(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	p.restoreSpecVersion()
+	g.Expect(provider.Spec.Version).To(Equal("v1.5.0"))
+}
+
+func TestSetPolicyVersion(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := &operatorv1.CoreProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "capi-system"},
+		Spec: operatorv1.CoreProviderSpec{
+			ProviderSpec: operatorv1.ProviderSpec{Version: "v1.5.0", VersionPolicy: operatorv1.VersionPolicyLatestPatch},
+		},
+		Status: operatorv1.CoreProviderStatus{
+			ProviderStatus: operatorv1.ProviderStatus{InstalledVersion: pointer.String("v1.5.3")},
+		},
+	}
+
+	available := []string{"v1.5.1", "v1.5.4", "v1.6.0"}
+
+	// The resolved version is only used for the reconciliation, the version of the spec is restored.
+	p := &phaseReconciler{provider: provider}
+	p.setPolicyVersion(context.Background(), available)
+	g.Expect(provider.Spec.Version).To(Equal("v1.5.4"))
+
+	p.restoreSpecVersion()
+	g.Expect(provider.Spec.Version).To(Equal("v1.5.0"))
+
+	// The version the provider was rolled back from is skipped, without downgrading the installed version.
+	provider.Annotations = map[string]string{rolledBackVersionAnnotation: "v1.5.4"}
+	g.Expect(checkRolledBack(provider)).To(BeFalse())
+	g.Expect(provider.Annotations).To(HaveKey(rolledBackVersionAnnotation))
+
+	p = &phaseReconciler{provider: provider}
+	p.setPolicyVersion(context.Background(), available)
+	g.Expect(provider.Spec.Version).To(Equal("v1.5.3"))
+
+	p.restoreSpecVersion()
+	g.Expect(provider.Spec.Version).To(Equal("v1.5.0"))
+}
+
+func TestEarliestRequeueAfter(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(earliestRequeueAfter()).To(BeZero())
+	g.Expect(earliestRequeueAfter(0, 0)).To(BeZero())
+	g.Expect(earliestRequeueAfter(0, time.Hour, 10*time.Minute)).To(Equal(10 * time.Minute))
+}
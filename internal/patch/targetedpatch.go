@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package patch
+
+import (
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// ApplyTargetedPatches patches the objects matching the targets of the RFC 6902 JSON patches and strategic
+// merge patches. Strategic merge patches of kinds that are not registered in the client-go scheme, like custom
+// resources, are applied as RFC 7386 merge patches.
+func ApplyTargetedPatches(objs []unstructured.Unstructured, patches []operatorv1.ManifestPatch) ([]unstructured.Unstructured, error) {
+	result := make([]unstructured.Unstructured, 0, len(objs))
+
+	for i := range objs {
+		obj := objs[i]
+
+		for j, p := range patches {
+			if !targetMatches(p.Target, obj) {
+				continue
+			}
+
+			if err := applyTargetedPatch(&obj, p); err != nil {
+				return nil, fmt.Errorf("failed to apply patch %d to %s %q: %w", j, obj.GetKind(), obj.GetName(), err)
+			}
+		}
+
+		result = append(result, obj)
+	}
+
+	return result, nil
+}
+
+func targetMatches(target operatorv1.PatchTarget, obj unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+
+	return gvk.Kind == target.Kind &&
+		(target.Group == "" || gvk.Group == target.Group) &&
+		(target.Version == "" || gvk.Version == target.Version) &&
+		(target.Name == "" || obj.GetName() == target.Name) &&
+		(target.Namespace == "" || obj.GetNamespace() == target.Namespace)
+}
+
+func applyTargetedPatch(obj *unstructured.Unstructured, p operatorv1.ManifestPatch) error {
+	original, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	patchJSON, err := yaml.YAMLToJSON([]byte(p.Patch))
+	if err != nil {
+		return fmt.Errorf("failed to convert patch to JSON: %w", err)
+	}
+
+	var patched []byte
+
+	switch p.Type {
+	case operatorv1.PatchTypeJSON6902:
+		jsonPatch, err := jsonpatch.DecodePatch(patchJSON)
+		if err != nil {
+			return fmt.Errorf("failed to parse JSON patch: %w", err)
+		}
+
+		patched, err = jsonPatch.Apply(original)
+		if err != nil {
+			return err
+		}
+	case operatorv1.PatchTypeStrategicMerge:
+		dataStruct, err := scheme.Scheme.New(obj.GroupVersionKind())
+		if err != nil {
+			patched, err = jsonpatch.MergePatch(original, patchJSON)
+		} else {
+			patched, err = strategicpatch.StrategicMergePatch(original, patchJSON, dataStruct)
+		}
+
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown patch type %q", p.Type)
+	}
+
+	patchedObj := unstructured.Unstructured{}
+	if err := patchedObj.UnmarshalJSON(patched); err != nil {
+		return fmt.Errorf("failed to parse patched object: %w", err)
+	}
+
+	*obj = patchedObj
+
+	return nil
+}
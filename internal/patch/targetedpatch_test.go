@@ -0,0 +1,208 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package patch
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const testTargetedObjectsYaml = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: capa-controller-manager
+  namespace: capa-system
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - args:
+        - --leader-elect
+        image: registry.k8s.io/cluster-api-aws/cluster-api-aws-controller:v2.3.0
+        name: manager
+      - image: registry.k8s.io/kube-rbac-proxy:v0.15.0
+        name: kube-rbac-proxy
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: other-controller-manager
+  namespace: capa-system
+spec:
+  replicas: 1
+---
+apiVersion: infrastructure.cluster.x-k8s.io/v1beta2
+kind: AWSClusterControllerIdentity
+metadata:
+  name: default
+spec:
+  allowedNamespaces: {}
+`
+
+func TestApplyTargetedPatches(t *testing.T) {
+	testCases := []struct {
+		name                       string
+		patches                    []operatorv1.ManifestPatch
+		expectedPatchedObjectsYaml string
+		expectedError              bool
+	}{
+		{
+			name: "should apply JSON patches to the target",
+			patches: []operatorv1.ManifestPatch{
+				{
+					Target: operatorv1.PatchTarget{Group: "apps", Kind: "Deployment", Name: "capa-controller-manager"},
+					Type:   operatorv1.PatchTypeJSON6902,
+					Patch: `- op: replace
+  path: /spec/replicas
+  value: 2
+- op: add
+  path: /spec/template/spec/containers/0/args/-
+  value: --v=4`,
+				},
+			},
+			expectedPatchedObjectsYaml: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: capa-controller-manager
+  namespace: capa-system
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - args:
+        - --leader-elect
+        - --v=4
+        image: registry.k8s.io/cluster-api-aws/cluster-api-aws-controller:v2.3.0
+        name: manager
+      - image: registry.k8s.io/kube-rbac-proxy:v0.15.0
+        name: kube-rbac-proxy
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: other-controller-manager
+  namespace: capa-system
+spec:
+  replicas: 1
+---
+apiVersion: infrastructure.cluster.x-k8s.io/v1beta2
+kind: AWSClusterControllerIdentity
+metadata:
+  name: default
+spec:
+  allowedNamespaces: {}`,
+		},
+		{
+			name: "should apply strategic merge patches and merge patches to unknown kinds",
+			patches: []operatorv1.ManifestPatch{
+				{
+					Target: operatorv1.PatchTarget{Kind: "Deployment", Namespace: "capa-system"},
+					Type:   operatorv1.PatchTypeStrategicMerge,
+					Patch: `spec:
+  template:
+    spec:
+      containers:
+      - name: kube-rbac-proxy
+        image: registry.example.com/kube-rbac-proxy:v0.15.0`,
+				},
+				{
+					Target: operatorv1.PatchTarget{Group: "infrastructure.cluster.x-k8s.io", Version: "v1beta2", Kind: "AWSClusterControllerIdentity"},
+					Type:   operatorv1.PatchTypeStrategicMerge,
+					Patch:  `{"spec": {"allowedNamespaces": {"list": ["default"]}}}`,
+				},
+			},
+			expectedPatchedObjectsYaml: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: capa-controller-manager
+  namespace: capa-system
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - args:
+        - --leader-elect
+        image: registry.k8s.io/cluster-api-aws/cluster-api-aws-controller:v2.3.0
+        name: manager
+      - image: registry.example.com/kube-rbac-proxy:v0.15.0
+        name: kube-rbac-proxy
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: other-controller-manager
+  namespace: capa-system
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - image: registry.example.com/kube-rbac-proxy:v0.15.0
+        name: kube-rbac-proxy
+---
+apiVersion: infrastructure.cluster.x-k8s.io/v1beta2
+kind: AWSClusterControllerIdentity
+metadata:
+  name: default
+spec:
+  allowedNamespaces:
+    list:
+    - default`,
+		},
+		{
+			name: "should fail on a JSON patch of a missing path",
+			patches: []operatorv1.ManifestPatch{
+				{
+					Target: operatorv1.PatchTarget{Kind: "Deployment", Name: "capa-controller-manager"},
+					Type:   operatorv1.PatchTypeJSON6902,
+					Patch:  `[{"op": "replace", "path": "/spec/strategy/type", "value": "Recreate"}]`,
+				},
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			objectToPatch, err := utilyaml.ToUnstructured([]byte(testTargetedObjectsYaml))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			result, err := ApplyTargetedPatches(objectToPatch, tc.patches)
+			if tc.expectedError {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).NotTo(HaveOccurred())
+
+			resultYaml, err := utilyaml.FromUnstructured(result)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(string(resultYaml)).To(Equal(tc.expectedPatchedObjectsYaml))
+		})
+	}
+}
@@ -23,13 +23,17 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
 )
 
-type AddonProviderWebhook struct{}
+type AddonProviderWebhook struct {
+	// Client is used to read the ProviderPolicies, they are not checked if it's nil.
+	Client client.Reader
+}
 
 func (r *AddonProviderWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
@@ -49,12 +53,20 @@ var (
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
 func (r *AddonProviderWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
-	return nil, nil
+	return nil, validateProviderPolicies(ctx, r.Client, nil, obj)
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
 func (r *AddonProviderWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
-	return nil, nil
+	if err := validateTargetNamespaceUnchanged(oldObj, newObj); err != nil {
+		return nil, err
+	}
+
+	if err := validateNoDowngrade(oldObj, newObj); err != nil {
+		return nil, err
+	}
+
+	return nil, validateProviderPolicies(ctx, r.Client, oldObj, newObj)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// CustomResourceDefinitionWebhook blocks the deletion of provider CRDs while instances of them exist,
+// as deleting a CRD deletes all its instances. Providers deleted with their CRDs wait for the instances
+// to be deleted before deleting any component, so the operator's own deletions are not denied.
+type CustomResourceDefinitionWebhook struct {
+	// Reader is used to look for instances of the CRDs, it should not be cached
+	// to avoid starting informers for every provider CRD.
+	Reader client.Reader
+}
+
+func (r *CustomResourceDefinitionWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		WithValidator(r).
+		For(&apiextensionsv1.CustomResourceDefinition{}).
+		Complete()
+}
+
+//+kubebuilder:webhook:verbs=delete,path=/validate-apiextensions-k8s-io-v1-customresourcedefinition,mutating=false,failurePolicy=ignore,matchPolicy=Equivalent,groups=apiextensions.k8s.io,resources=customresourcedefinitions,versions=v1,name=vcustomresourcedefinition.kb.io,sideEffects=None,admissionReviewVersions=v1;v1beta1
+
+var _ webhook.CustomValidator = &CustomResourceDefinitionWebhook{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
+func (r *CustomResourceDefinitionWebhook) ValidateCreate(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+func (r *CustomResourceDefinitionWebhook) ValidateUpdate(_ context.Context, _, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateDelete denies the deletion of a provider CRD while instances of it exist.
+func (r *CustomResourceDefinitionWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected a CustomResourceDefinition but got a %T", obj))
+	}
+
+	// Only CRDs installed with a provider are protected.
+	if _, ok := crd.Labels[clusterv1.ProviderNameLabel]; !ok {
+		return nil, nil
+	}
+
+	version := storageVersion(crd)
+	if version == "" {
+		return nil, nil
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   crd.Spec.Group,
+		Version: version,
+		Kind:    crd.Spec.Names.ListKind,
+	})
+
+	if err := r.Reader.List(ctx, list, client.Limit(1)); err != nil {
+		return nil, apierrors.NewInternalError(fmt.Errorf("failed to list %s: %w", crd.Name, err))
+	}
+
+	if len(list.Items) > 0 {
+		return nil, apierrors.NewForbidden(
+			apiextensionsv1.Resource("customresourcedefinitions"),
+			crd.Name,
+			fmt.Errorf("%s still exist, they must be deleted before the CustomResourceDefinition", crd.Spec.Names.Plural),
+		)
+	}
+
+	return nil, nil
+}
+
+// storageVersion returns the version the CRD instances are stored in.
+func storageVersion(crd *apiextensionsv1.CustomResourceDefinition) string {
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			return v.Name
+		}
+	}
+
+	return ""
+}
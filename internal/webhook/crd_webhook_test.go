@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCustomResourceDefinitionValidateDelete(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "infrastructure.cluster.x-k8s.io", Version: "v1beta1", Kind: "AWSCluster"}
+
+	newCRD := func(labels map[string]string) *apiextensionsv1.CustomResourceDefinition {
+		return &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "awsclusters.infrastructure.cluster.x-k8s.io",
+				Labels: labels,
+			},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Group: gvk.Group,
+				Names: apiextensionsv1.CustomResourceDefinitionNames{
+					Kind:     gvk.Kind,
+					ListKind: gvk.Kind + "List",
+					Plural:   "awsclusters",
+				},
+				Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+					{Name: "v1beta2", Storage: false},
+					{Name: gvk.Version, Storage: true},
+				},
+			},
+		}
+	}
+
+	instance := &unstructured.Unstructured{}
+	instance.SetGroupVersionKind(gvk)
+	instance.SetName("my-cluster")
+	instance.SetNamespace("default")
+
+	tests := []struct {
+		name        string
+		crd         *apiextensionsv1.CustomResourceDefinition
+		objs        []client.Object
+		expectError bool
+	}{
+		{
+			name: "CRD not installed with a provider",
+			crd:  newCRD(nil),
+			objs: []client.Object{instance},
+		},
+		{
+			name: "provider CRD without instances",
+			crd:  newCRD(map[string]string{clusterv1.ProviderNameLabel: "infrastructure-aws"}),
+		},
+		{
+			name:        "provider CRD with instances",
+			crd:         newCRD(map[string]string{clusterv1.ProviderNameLabel: "infrastructure-aws"}),
+			objs:        []client.Object{instance},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gvk.GroupVersion()})
+			mapper.Add(gvk, meta.RESTScopeNamespace)
+
+			reader := fake.NewClientBuilder().
+				WithScheme(runtime.NewScheme()).
+				WithRESTMapper(mapper).
+				WithObjects(tt.objs...).
+				Build()
+
+			w := &CustomResourceDefinitionWebhook{Reader: reader}
+
+			_, err := w.ValidateDelete(context.TODO(), tt.crd)
+			if tt.expectError {
+				g.Expect(apierrors.IsForbidden(err)).To(BeTrue())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
@@ -17,7 +17,17 @@ limitations under the License.
 package webhook
 
 import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	versionutil "k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
 	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-operator/util"
 )
 
 // setDefaultProviderSpec sets the default values for the provider spec.
@@ -26,7 +36,137 @@ func setDefaultProviderSpec(providerSpec *operatorv1.ProviderSpec, providerNames
 		providerSpec.ConfigSecret.Namespace = providerNamespace
 	}
 
+	for i := range providerSpec.ConfigSecrets {
+		if providerSpec.ConfigSecrets[i].Namespace == "" {
+			providerSpec.ConfigSecrets[i].Namespace = providerNamespace
+		}
+	}
+
+	if providerSpec.ConfigMapRef != nil && providerSpec.ConfigMapRef.Namespace == "" {
+		providerSpec.ConfigMapRef.Namespace = providerNamespace
+	}
+
 	if providerSpec.AdditionalManifestsRef != nil && providerSpec.AdditionalManifestsRef.Namespace == "" {
 		providerSpec.AdditionalManifestsRef.Namespace = providerNamespace
 	}
+
+	if providerSpec.SmokeTest != nil && providerSpec.SmokeTest.JobTemplateRef.Namespace == "" {
+		providerSpec.SmokeTest.JobTemplateRef.Namespace = providerNamespace
+	}
+
+	if providerSpec.Hooks != nil {
+		for _, hooks := range [][]operatorv1.HookSpec{providerSpec.Hooks.PreInstall, providerSpec.Hooks.PostInstall, providerSpec.Hooks.PreDelete} {
+			for i := range hooks {
+				if hooks[i].JobTemplateRef.Namespace == "" {
+					hooks[i].JobTemplateRef.Namespace = providerNamespace
+				}
+			}
+		}
+	}
+}
+
+// validateProviderPolicies denies providers that are not allowed by the ProviderPolicies of the cluster. Updates
+// are only checked if they change the provider version, so providers created before a policy can still be
+// reconciled and deleted.
+func validateProviderPolicies(ctx context.Context, c client.Reader, oldObj, newObj runtime.Object) error {
+	if c == nil {
+		return nil
+	}
+
+	provider, ok := newObj.(operatorv1.GenericProvider)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a provider but got a %T", newObj))
+	}
+
+	if oldProvider, ok := oldObj.(operatorv1.GenericProvider); ok && oldProvider.GetSpec().Version == provider.GetSpec().Version {
+		return nil
+	}
+
+	violation, err := util.ProviderPolicyViolation(ctx, c, provider, provider.GetSpec().Version)
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+
+	if violation == "" {
+		return nil
+	}
+
+	return apierrors.NewInvalid(
+		operatorv1.GroupVersion.WithKind(string(util.ClusterctlProviderType(provider))).GroupKind(),
+		provider.GetName(),
+		field.ErrorList{field.Forbidden(field.NewPath("spec"), violation)},
+	)
+}
+
+// validateNoDowngrade denies changing the version of a provider to an older one than the installed version, or the
+// previous version if none is installed yet, unless the provider has the allow-downgrade annotation. Downgrades
+// delete the components and install the older ones, which can corrupt the CRDs. Reverting an upgrade that wasn't
+// installed, e.g. after a rollback, is allowed, as well as updates that don't change the version, e.g. of providers
+// upgraded by their version policy past the version of their spec.
+func validateNoDowngrade(oldObj, newObj runtime.Object) error {
+	oldProvider, ok := oldObj.(operatorv1.GenericProvider)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a provider but got a %T", oldObj))
+	}
+
+	provider, ok := newObj.(operatorv1.GenericProvider)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a provider but got a %T", newObj))
+	}
+
+	if _, ok := provider.GetAnnotations()[operatorv1.AllowDowngradeAnnotation]; ok {
+		return nil
+	}
+
+	if provider.GetSpec().Version == oldProvider.GetSpec().Version {
+		return nil
+	}
+
+	currentVersion := oldProvider.GetSpec().Version
+	if installedVersion := oldProvider.GetStatus().InstalledVersion; installedVersion != nil {
+		currentVersion = *installedVersion
+	}
+
+	// Versions that can't be compared are left to the preflight checks.
+	current, err := versionutil.ParseSemantic(currentVersion)
+	if err != nil {
+		return nil
+	}
+
+	version, err := versionutil.ParseSemantic(provider.GetSpec().Version)
+	if err != nil || !version.LessThan(current) {
+		return nil
+	}
+
+	return apierrors.NewInvalid(
+		operatorv1.GroupVersion.WithKind(string(util.ClusterctlProviderType(provider))).GroupKind(),
+		provider.GetName(),
+		field.ErrorList{field.Forbidden(field.NewPath("spec", "version"), fmt.Sprintf(
+			"downgrading from version %s to %s can corrupt the provider CRDs, set the %s annotation to downgrade anyway",
+			currentVersion, provider.GetSpec().Version, operatorv1.AllowDowngradeAnnotation))},
+	)
+}
+
+// validateTargetNamespaceUnchanged denies changes of the target namespace, which would leave the components
+// installed in the previous namespace behind.
+func validateTargetNamespaceUnchanged(oldObj, newObj runtime.Object) error {
+	oldProvider, ok := oldObj.(operatorv1.GenericProvider)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a provider but got a %T", oldObj))
+	}
+
+	provider, ok := newObj.(operatorv1.GenericProvider)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a provider but got a %T", newObj))
+	}
+
+	if oldProvider.GetSpec().TargetNamespace == provider.GetSpec().TargetNamespace {
+		return nil
+	}
+
+	return apierrors.NewInvalid(
+		operatorv1.GroupVersion.WithKind(string(util.ClusterctlProviderType(provider))).GroupKind(),
+		provider.GetName(),
+		field.ErrorList{field.Invalid(field.NewPath("spec", "targetNamespace"), provider.GetSpec().TargetNamespace, "field is immutable")},
+	)
 }
@@ -17,10 +17,17 @@ limitations under the License.
 package webhook
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
 	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
 )
@@ -63,6 +70,37 @@ func TestSetDefaultProviderSpec(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "shoud default config secrets namespace if not specified",
+			providerSpec: &operatorv1.ProviderSpec{
+				ConfigSecrets: []operatorv1.SecretReference{
+					{Name: "test-secret-1"},
+					{Name: "test-secret-2", Namespace: "test-namespace-1"},
+				},
+			},
+			namespace: "test-namespace",
+			expectedProviderSpec: &operatorv1.ProviderSpec{
+				ConfigSecrets: []operatorv1.SecretReference{
+					{Name: "test-secret-1", Namespace: "test-namespace"},
+					{Name: "test-secret-2", Namespace: "test-namespace-1"},
+				},
+			},
+		},
+		{
+			name: "shoud default config map namespace if not specified",
+			providerSpec: &operatorv1.ProviderSpec{
+				ConfigMapRef: &operatorv1.ConfigmapReference{
+					Name: "test-configmap",
+				},
+			},
+			namespace: "test-namespace",
+			expectedProviderSpec: &operatorv1.ProviderSpec{
+				ConfigMapRef: &operatorv1.ConfigmapReference{
+					Name:      "test-configmap",
+					Namespace: "test-namespace",
+				},
+			},
+		},
 		{
 			name: "shoud default additional manifests namespace if not specified",
 			providerSpec: &operatorv1.ProviderSpec{
@@ -94,6 +132,93 @@ func TestSetDefaultProviderSpec(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "shoud default smoke test job template namespace if not specified",
+			providerSpec: &operatorv1.ProviderSpec{
+				SmokeTest: &operatorv1.SmokeTestSpec{
+					JobTemplateRef: operatorv1.ConfigmapReference{
+						Name: "test-configmap",
+					},
+				},
+			},
+			namespace: "test-namespace",
+			expectedProviderSpec: &operatorv1.ProviderSpec{
+				SmokeTest: &operatorv1.SmokeTestSpec{
+					JobTemplateRef: operatorv1.ConfigmapReference{
+						Name:      "test-configmap",
+						Namespace: "test-namespace",
+					},
+				},
+			},
+		},
+		{
+			name: "shoud default pre-delete hook job template namespace if not specified",
+			providerSpec: &operatorv1.ProviderSpec{
+				Hooks: &operatorv1.ProviderHooks{
+					PreDelete: []operatorv1.HookSpec{
+						{
+							Name:           "check-machines",
+							JobTemplateRef: operatorv1.ConfigmapReference{Name: "test-configmap"},
+						},
+						{
+							Name:           "backup",
+							JobTemplateRef: operatorv1.ConfigmapReference{Name: "test-configmap", Namespace: "test-namespace-1"},
+						},
+					},
+				},
+			},
+			namespace: "test-namespace",
+			expectedProviderSpec: &operatorv1.ProviderSpec{
+				Hooks: &operatorv1.ProviderHooks{
+					PreDelete: []operatorv1.HookSpec{
+						{
+							Name:           "check-machines",
+							JobTemplateRef: operatorv1.ConfigmapReference{Name: "test-configmap", Namespace: "test-namespace"},
+						},
+						{
+							Name:           "backup",
+							JobTemplateRef: operatorv1.ConfigmapReference{Name: "test-configmap", Namespace: "test-namespace-1"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "shoud default install hook job template namespaces if not specified",
+			providerSpec: &operatorv1.ProviderSpec{
+				Hooks: &operatorv1.ProviderHooks{
+					PreInstall: []operatorv1.HookSpec{
+						{
+							Name:           "backup",
+							JobTemplateRef: operatorv1.ConfigmapReference{Name: "test-configmap"},
+						},
+					},
+					PostInstall: []operatorv1.HookSpec{
+						{
+							Name:           "migrate",
+							JobTemplateRef: operatorv1.ConfigmapReference{Name: "test-configmap", Namespace: "test-namespace-1"},
+						},
+					},
+				},
+			},
+			namespace: "test-namespace",
+			expectedProviderSpec: &operatorv1.ProviderSpec{
+				Hooks: &operatorv1.ProviderHooks{
+					PreInstall: []operatorv1.HookSpec{
+						{
+							Name:           "backup",
+							JobTemplateRef: operatorv1.ConfigmapReference{Name: "test-configmap", Namespace: "test-namespace"},
+						},
+					},
+					PostInstall: []operatorv1.HookSpec{
+						{
+							Name:           "migrate",
+							JobTemplateRef: operatorv1.ConfigmapReference{Name: "test-configmap", Namespace: "test-namespace-1"},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -104,3 +229,220 @@ func TestSetDefaultProviderSpec(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateProviderPolicies(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(operatorv1.AddToScheme(scheme)).To(Succeed())
+
+	policy := &operatorv1.ProviderPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "infrastructure"},
+		Spec: operatorv1.ProviderPolicySpec{
+			Rules: []operatorv1.ProviderPolicyRule{
+				{Type: "InfrastructureProvider", Names: []string{"aws"}, Versions: ">= v2.0.0"},
+			},
+		},
+	}
+
+	infrastructureProvider := func(version string) *operatorv1.InfrastructureProvider {
+		return &operatorv1.InfrastructureProvider{
+			ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "capa-system"},
+			Spec: operatorv1.InfrastructureProviderSpec{
+				ProviderSpec: operatorv1.ProviderSpec{Version: version},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		policies    []client.Object
+		oldProvider runtime.Object
+		newProvider runtime.Object
+		expectedErr bool
+	}{
+		{
+			name:        "no policies",
+			newProvider: infrastructureProvider("v1.5.0"),
+		},
+		{
+			name:        "allowed provider",
+			policies:    []client.Object{policy},
+			newProvider: infrastructureProvider("v2.3.0"),
+		},
+		{
+			name:        "provider without version",
+			policies:    []client.Object{policy},
+			newProvider: infrastructureProvider(""),
+		},
+		{
+			name:        "version not allowed",
+			policies:    []client.Object{policy},
+			newProvider: infrastructureProvider("v1.5.0"),
+			expectedErr: true,
+		},
+		{
+			name:     "provider type not allowed",
+			policies: []client.Object{policy},
+			newProvider: &operatorv1.BootstrapProvider{
+				ObjectMeta: metav1.ObjectMeta{Name: "kubeadm", Namespace: "capi-kubeadm-bootstrap-system"},
+			},
+			expectedErr: true,
+		},
+		{
+			name:        "update without version change",
+			policies:    []client.Object{policy},
+			oldProvider: infrastructureProvider("v1.5.0"),
+			newProvider: infrastructureProvider("v1.5.0"),
+		},
+		{
+			name:        "update to a version not allowed",
+			policies:    []client.Object{policy},
+			oldProvider: infrastructureProvider("v2.3.0"),
+			newProvider: infrastructureProvider("v1.5.0"),
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.policies...).Build()
+
+			err := validateProviderPolicies(context.Background(), c, tc.oldProvider, tc.newProvider)
+			if tc.expectedErr {
+				g.Expect(apierrors.IsInvalid(err)).To(BeTrue())
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
+
+func TestValidateTargetNamespaceUnchanged(t *testing.T) {
+	coreProvider := func(targetNamespace string) *operatorv1.CoreProvider {
+		return &operatorv1.CoreProvider{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "operators"},
+			Spec: operatorv1.CoreProviderSpec{
+				ProviderSpec: operatorv1.ProviderSpec{TargetNamespace: targetNamespace},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		oldProvider runtime.Object
+		newProvider runtime.Object
+		expectedErr bool
+	}{
+		{
+			name:        "unchanged",
+			oldProvider: coreProvider("capi-system"),
+			newProvider: coreProvider("capi-system"),
+		},
+		{
+			name:        "changed",
+			oldProvider: coreProvider("capi-system"),
+			newProvider: coreProvider("capi"),
+			expectedErr: true,
+		},
+		{
+			name:        "set after creation",
+			oldProvider: coreProvider(""),
+			newProvider: coreProvider("capi-system"),
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := validateTargetNamespaceUnchanged(tc.oldProvider, tc.newProvider)
+			if tc.expectedErr {
+				g.Expect(apierrors.IsInvalid(err)).To(BeTrue())
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
+
+func TestValidateNoDowngrade(t *testing.T) {
+	coreProvider := func(version string, installedVersion *string, annotations map[string]string) *operatorv1.CoreProvider {
+		return &operatorv1.CoreProvider{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "capi-system", Annotations: annotations},
+			Spec: operatorv1.CoreProviderSpec{
+				ProviderSpec: operatorv1.ProviderSpec{Version: version},
+			},
+			Status: operatorv1.CoreProviderStatus{
+				ProviderStatus: operatorv1.ProviderStatus{InstalledVersion: installedVersion},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		oldProvider runtime.Object
+		newProvider runtime.Object
+		expectedErr bool
+	}{
+		{
+			name:        "upgrade",
+			oldProvider: coreProvider("v1.5.0", pointer.String("v1.5.0"), nil),
+			newProvider: coreProvider("v1.6.0", nil, nil),
+		},
+		{
+			name:        "downgrade",
+			oldProvider: coreProvider("v1.6.0", pointer.String("v1.6.0"), nil),
+			newProvider: coreProvider("v1.5.0", nil, nil),
+			expectedErr: true,
+		},
+		{
+			name:        "downgrade of a provider not installed yet",
+			oldProvider: coreProvider("v1.6.0", nil, nil),
+			newProvider: coreProvider("v1.5.3", nil, nil),
+			expectedErr: true,
+		},
+		{
+			name:        "downgrade with the allow-downgrade annotation",
+			oldProvider: coreProvider("v1.6.0", pointer.String("v1.6.0"), nil),
+			newProvider: coreProvider("v1.5.0", nil, map[string]string{operatorv1.AllowDowngradeAnnotation: ""}),
+		},
+		{
+			name:        "revert of an upgrade that wasn't installed",
+			oldProvider: coreProvider("v1.6.0", pointer.String("v1.5.0"), nil),
+			newProvider: coreProvider("v1.5.0", nil, nil),
+		},
+		{
+			name:        "version unchanged below the installed version",
+			oldProvider: coreProvider("v1.5.0", pointer.String("v1.5.3"), nil),
+			newProvider: coreProvider("v1.5.0", nil, map[string]string{"foo": "bar"}),
+		},
+		{
+			name:        "version removed",
+			oldProvider: coreProvider("v1.6.0", pointer.String("v1.6.0"), nil),
+			newProvider: coreProvider("", nil, nil),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := validateNoDowngrade(tc.oldProvider, tc.newProvider)
+			if tc.expectedErr {
+				g.Expect(apierrors.IsInvalid(err)).To(BeTrue())
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+)
+
+const azureBlobHostSuffix = "blob.core.windows.net"
+
+// azureBlobStore is an Azure Blob Storage container.
+type azureBlobStore struct {
+	client *container.Client
+}
+
+// newAzureBlobStore returns the Azure Blob Storage container of the storage account, authenticated with the default
+// Azure credentials, e.g. the AKS workload identity of the operator. The account can be given as a storage account
+// name or as the blob service host for other Azure clouds. Requests are sent with the HTTP client.
+func newAzureBlobStore(account, containerName string, httpClient *http.Client) (*azureBlobStore, error) {
+	clientOptions := azcore.ClientOptions{Transport: httpClient}
+
+	credential, err := azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{ClientOptions: clientOptions})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Azure credentials: %w", err)
+	}
+
+	host := account
+	if !strings.Contains(host, ".") {
+		host = fmt.Sprintf("%s.%s", account, azureBlobHostSuffix)
+	}
+
+	client, err := container.NewClient(fmt.Sprintf("https://%s/%s", host, containerName), credential, &container.ClientOptions{ClientOptions: clientOptions})
+	if err != nil {
+		return nil, err
+	}
+
+	return &azureBlobStore{client: client}, nil
+}
+
+// newAzureBlobStoreFromURL returns the Azure Blob Storage container of an
+// azblob://{account}/{container}/{prefix}/{latest|version}/{components file} URL.
+func newAzureBlobStoreFromURL(_ context.Context, u *url.URL, httpClient *http.Client, _ configclient.VariablesClient) (ObjectStore, string, error) {
+	containerName, path, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+
+	store, err := newAzureBlobStore(u.Host, containerName, httpClient)
+	if err != nil {
+		return nil, "", fmt.Errorf("error creating the Azure Blob Storage client: %w", err)
+	}
+
+	return store, path, nil
+}
+
+func (s *azureBlobStore) GetObject(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.client.NewBlobClient(key).DownloadStream(ctx, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+func (s *azureBlobStore) ListDirectories(ctx context.Context, prefix string) ([]string, error) {
+	directories := []string{}
+
+	pager := s.client.NewListBlobsHierarchyPager("/", &container.ListBlobsHierarchyOptions{Prefix: &prefix})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, blobPrefix := range page.Segment.BlobPrefixes {
+			if blobPrefix.Name != nil {
+				directories = append(directories, path.Base(strings.TrimSuffix(*blobPrefix.Name, "/")))
+			}
+		}
+	}
+
+	return directories, nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+)
+
+const (
+	gcsEndpoint       = "https://storage.googleapis.com/storage/v1"
+	gcsReadOnlyScope  = "https://www.googleapis.com/auth/devstorage.read_only"
+	gcsListObjectsMax = "1000"
+)
+
+// gcsStore is a Google Cloud Storage bucket, accessed with the JSON API.
+type gcsStore struct {
+	client   *http.Client
+	endpoint string
+	bucket   string
+}
+
+// newGCSStore returns the Google Cloud Storage bucket, authenticated with the application default credentials,
+// e.g. the GKE workload identity of the operator. Requests are sent with the transport of the HTTP client.
+func newGCSStore(ctx context.Context, bucket string, httpClient *http.Client) (*gcsStore, error) {
+	client, err := google.DefaultClient(context.WithValue(ctx, oauth2.HTTPClient, httpClient), gcsReadOnlyScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Google Cloud credentials: %w", err)
+	}
+
+	return &gcsStore{client: client, endpoint: gcsEndpoint, bucket: bucket}, nil
+}
+
+// newGCSStoreFromURL returns the Google Cloud Storage bucket of a gs://{bucket}/{prefix}/{latest|version}/{components file} URL.
+func newGCSStoreFromURL(ctx context.Context, u *url.URL, httpClient *http.Client, _ configclient.VariablesClient) (ObjectStore, string, error) {
+	store, err := newGCSStore(ctx, u.Host, httpClient)
+	if err != nil {
+		return nil, "", fmt.Errorf("error creating the Google Cloud Storage client: %w", err)
+	}
+
+	return store, u.Path, nil
+}
+
+func (s *gcsStore) GetObject(ctx context.Context, key string) ([]byte, error) {
+	return s.get(ctx, fmt.Sprintf("%s/b/%s/o/%s?alt=media", s.endpoint, url.PathEscape(s.bucket), url.PathEscape(key)))
+}
+
+func (s *gcsStore) ListDirectories(ctx context.Context, prefix string) ([]string, error) {
+	directories := []string{}
+	pageToken := ""
+
+	for {
+		query := url.Values{
+			"prefix":     {prefix},
+			"delimiter":  {"/"},
+			"maxResults": {gcsListObjectsMax},
+			"fields":     {"prefixes,nextPageToken"},
+		}
+
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		data, err := s.get(ctx, fmt.Sprintf("%s/b/%s/o?%s", s.endpoint, url.PathEscape(s.bucket), query.Encode()))
+		if err != nil {
+			return nil, err
+		}
+
+		objects := struct {
+			Prefixes      []string `json:"prefixes"`
+			NextPageToken string   `json:"nextPageToken"`
+		}{}
+
+		if err := json.Unmarshal(data, &objects); err != nil {
+			return nil, fmt.Errorf("failed to decode objects of bucket %s: %w", s.bucket, err)
+		}
+
+		for _, p := range objects.Prefixes {
+			directories = append(directories, path.Base(strings.TrimSuffix(p, "/")))
+		}
+
+		if objects.NextPageToken == "" {
+			return directories, nil
+		}
+
+		pageToken = objects.NextPageToken
+	}
+}
+
+func (s *gcsStore) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, url)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response from %s: %s", url, resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
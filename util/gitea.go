@@ -0,0 +1,190 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	versionutil "k8s.io/apimachinery/pkg/util/version"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+)
+
+const (
+	// giteaTokenKey is the variable with the Gitea or Forgejo access token.
+	giteaTokenKey = "GITEA_TOKEN"
+
+	giteaReleasesPath     = "releases"
+	giteaReleasesPageSize = 50
+)
+
+// giteaRepository is a repository of provider releases published as release attachments on a Gitea
+// or Forgejo instance, which share the same API.
+type giteaRepository struct {
+	httpClient     *http.Client
+	baseURL        string
+	owner          string
+	repository     string
+	token          string
+	defaultVersion string
+	componentsPath string
+}
+
+var _ repository.Repository = &giteaRepository{}
+
+// giteaRelease is a release returned by the Gitea releases API.
+type giteaRelease struct {
+	TagName string `json:"tag_name"`
+	Draft   bool   `json:"draft"`
+	Assets  []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// newGiteaRepository returns a repository for the releases of the Gitea repository. The url must be in the
+// form https://{host}/{owner}/{repository}/releases/{latest|version}/{components file}, the same as for GitHub.
+func newGiteaRepository(ctx context.Context, rURL *url.URL, httpClient *http.Client, configVariablesClient configclient.VariablesClient) (*giteaRepository, error) {
+	urlSplit := strings.Split(strings.Trim(rURL.Path, "/"), "/")
+	if len(urlSplit) != 5 || urlSplit[2] != giteaReleasesPath {
+		return nil, fmt.Errorf("invalid url %q: a Gitea repository url should be in the form https://{host}/{owner}/{repository}/releases/{latest|version}/{components file}", rURL)
+	}
+
+	// A missing variable is returned as an error, the token is left empty then.
+	token, _ := configVariablesClient.Get(giteaTokenKey)
+
+	repo := &giteaRepository{
+		httpClient:     httpClient,
+		baseURL:        fmt.Sprintf("%s://%s", rURL.Scheme, rURL.Host),
+		owner:          urlSplit[0],
+		repository:     urlSplit[1],
+		token:          token,
+		defaultVersion: urlSplit[3],
+		componentsPath: urlSplit[4],
+	}
+
+	if repo.defaultVersion == latestVersionLabel {
+		versions, err := repo.GetVersions(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest version: %w", err)
+		}
+
+		repo.defaultVersion, err = latestVersion(versions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest version: %w", err)
+		}
+	}
+
+	return repo, nil
+}
+
+// DefaultVersion returns the version from the repository url, or the latest version if the url contains "latest".
+func (g *giteaRepository) DefaultVersion() string {
+	return g.defaultVersion
+}
+
+// RootPath returns the root of the release attachments, which is always the release itself.
+func (g *giteaRepository) RootPath() string {
+	return ""
+}
+
+// ComponentsPath returns the name of the components attachment in the releases.
+func (g *giteaRepository) ComponentsPath() string {
+	return g.componentsPath
+}
+
+// GetFile returns the attachment with the given name of the release of the version.
+func (g *giteaRepository) GetFile(ctx context.Context, version, path string) ([]byte, error) {
+	if version == latestVersionLabel {
+		version = g.defaultVersion
+	}
+
+	data, _, err := g.get(ctx, fmt.Sprintf("%s/releases/tags/%s", g.repositoryAPIURL(), url.PathEscape(version)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release %q: %w", version, err)
+	}
+
+	release := giteaRelease{}
+	if err := json.Unmarshal(data, &release); err != nil {
+		return nil, fmt.Errorf("failed to decode release %q: %w", version, err)
+	}
+
+	for _, asset := range release.Assets {
+		if asset.Name != path {
+			continue
+		}
+
+		data, _, err := g.get(ctx, asset.BrowserDownloadURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get attachment %q of release %q: %w", path, version, err)
+		}
+
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("failed to get file %q: release %q has no such attachment", path, version)
+}
+
+// GetVersions returns the tags of the published repository releases that are semver versions.
+func (g *giteaRepository) GetVersions(ctx context.Context) ([]string, error) {
+	versions := []string{}
+
+	for page := 1; ; page++ {
+		query := url.Values{"limit": {strconv.Itoa(giteaReleasesPageSize)}, "page": {strconv.Itoa(page)}}
+
+		data, _, err := g.get(ctx, fmt.Sprintf("%s/releases?%s", g.repositoryAPIURL(), query.Encode()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list releases: %w", err)
+		}
+
+		releases := []giteaRelease{}
+		if err := json.Unmarshal(data, &releases); err != nil {
+			return nil, fmt.Errorf("failed to decode releases: %w", err)
+		}
+
+		// The instance can limit the page size below the requested one, so only an empty page ends the list.
+		if len(releases) == 0 {
+			return versions, nil
+		}
+
+		// Drafts and releases that are not versions are ignored.
+		for _, release := range releases {
+			if _, err := versionutil.ParseSemantic(release.TagName); err == nil && !release.Draft {
+				versions = append(versions, release.TagName)
+			}
+		}
+	}
+}
+
+func (g *giteaRepository) repositoryAPIURL() string {
+	return fmt.Sprintf("%s/api/v1/repos/%s/%s", g.baseURL, url.PathEscape(g.owner), url.PathEscape(g.repository))
+}
+
+func (g *giteaRepository) get(ctx context.Context, rawURL string) ([]byte, http.Header, error) {
+	auth := forgeAuth{baseURL: g.baseURL, header: "Authorization"}
+	if g.token != "" {
+		auth.value = "token " + g.token
+	}
+
+	return forgeGet(ctx, g.httpClient, rawURL, auth, "")
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+)
+
+// newFakeGiteaServer returns a Gitea API server with a single private repository holding releases with the given
+// attachments. Releases are listed one per page to exercise pagination, tags suffixed with "-draft" are drafts.
+func newFakeGiteaServer(g *WithT, owner, repository, token string, releases map[string]map[string]string) *httptest.Server {
+	var server *httptest.Server
+
+	tags := []string{}
+	for tag := range releases {
+		tags = append(tags, tag)
+	}
+
+	sort.Strings(tags)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token "+token {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		releaseFor := func(tag string) map[string]interface{} {
+			assets := []map[string]string{}
+			for name := range releases[tag] {
+				assets = append(assets, map[string]string{
+					"name":                 name,
+					"browser_download_url": server.URL + "/" + owner + "/" + repository + "/releases/download/" + tag + "/" + name,
+				})
+			}
+
+			return map[string]interface{}{"tag_name": tag, "draft": strings.HasSuffix(tag, "-draft"), "assets": assets}
+		}
+
+		releasesPath := "/api/v1/repos/" + owner + "/" + repository + "/releases"
+
+		switch {
+		case r.URL.Path == releasesPath:
+			page, err := strconv.Atoi(r.URL.Query().Get("page"))
+			g.Expect(err).ToNot(HaveOccurred())
+
+			list := []interface{}{}
+			if page <= len(tags) {
+				list = append(list, releaseFor(tags[page-1]))
+			}
+
+			g.Expect(json.NewEncoder(w).Encode(list)).To(Succeed())
+		case strings.HasPrefix(r.URL.Path, releasesPath+"/tags/"):
+			tag := strings.TrimPrefix(r.URL.Path, releasesPath+"/tags/")
+			if _, ok := releases[tag]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			g.Expect(json.NewEncoder(w).Encode(releaseFor(tag))).To(Succeed())
+		case strings.HasPrefix(r.URL.Path, "/"+owner+"/"+repository+"/releases/download/"):
+			tag, name, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"+owner+"/"+repository+"/releases/download/"), "/")
+			_, _ = w.Write([]byte(releases[tag][name]))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return server
+}
+
+func TestGiteaRepository(t *testing.T) {
+	testCases := []struct {
+		name               string
+		path               string
+		token              string
+		wantDefaultVersion string
+		wantComponents     string
+		wantErr            bool
+	}{
+		{
+			name:               "latest version",
+			path:               "/infra/provider/releases/latest/infrastructure-components.yaml",
+			token:              "token",
+			wantDefaultVersion: "v0.10.0",
+			wantComponents:     "components v0.10.0",
+		},
+		{
+			name:               "given version",
+			path:               "/infra/provider/releases/v0.9.0/infrastructure-components.yaml",
+			token:              "token",
+			wantDefaultVersion: "v0.9.0",
+			wantComponents:     "components v0.9.0",
+		},
+		{
+			name:    "missing token",
+			path:    "/infra/provider/releases/latest/infrastructure-components.yaml",
+			wantErr: true,
+		},
+		{
+			name:    "invalid url",
+			path:    "/infra/provider/latest/infrastructure-components.yaml",
+			token:   "token",
+			wantErr: true,
+		},
+	}
+
+	g := NewWithT(t)
+
+	server := newFakeGiteaServer(g, "infra", "provider", "token", map[string]map[string]string{
+		"v0.9.0":        {"infrastructure-components.yaml": "components v0.9.0"},
+		"v0.10.0":       {"infrastructure-components.yaml": "components v0.10.0", "metadata.yaml": "metadata v0.10.0"},
+		"v0.11.0-draft": {"infrastructure-components.yaml": "components draft"},
+		"nightly":       {"infrastructure-components.yaml": "components nightly"},
+	})
+	defer server.Close()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mr := configclient.NewMemoryReader()
+			if tc.token != "" {
+				mr.Set(giteaTokenKey, tc.token)
+			}
+
+			configClient, err := configclient.New(context.TODO(), "", configclient.InjectReader(mr))
+			g.Expect(err).ToNot(HaveOccurred())
+
+			rURL, err := url.Parse(server.URL + tc.path)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			repo, err := newGiteaRepository(context.TODO(), rURL, server.Client(), configClient.Variables())
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(repo.DefaultVersion()).To(Equal(tc.wantDefaultVersion))
+
+			versions, err := repo.GetVersions(context.TODO())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(versions).To(ConsistOf("v0.9.0", "v0.10.0"))
+
+			components, err := repo.GetFile(context.TODO(), repo.DefaultVersion(), repo.ComponentsPath())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(components)).To(Equal(tc.wantComponents))
+
+			_, err = repo.GetFile(context.TODO(), repo.DefaultVersion(), "missing.yaml")
+			g.Expect(err).To(HaveOccurred())
+		})
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	versionutil "k8s.io/apimachinery/pkg/util/version"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+)
+
+const (
+	// githubEnterpriseTokenKey is the variable with the GitHub Enterprise Server access token. It's separate from
+	// the github-token variable, which is validated against github.com.
+	githubEnterpriseTokenKey = "GITHUB_ENTERPRISE_TOKEN"
+
+	// githubEnterpriseAPIURLKey is the variable with the API base URL of the GitHub Enterprise Server instance,
+	// https://{host}/api/v3 by default.
+	githubEnterpriseAPIURLKey = "GITHUB_ENTERPRISE_API_URL"
+
+	githubEnterpriseAPIPath     = "/api/v3"
+	githubReleasesPath          = "releases"
+	githubReleasesPageSize      = 100
+	githubAssetMediaType        = "application/octet-stream"
+	githubEnterpriseHostPrefix  = "github."
+	githubReleasesPathSeparator = "/releases/"
+)
+
+// gitHubEnterpriseRepository is a repository of provider releases published as release assets on a
+// GitHub Enterprise Server instance.
+type gitHubEnterpriseRepository struct {
+	httpClient     *http.Client
+	apiURL         string
+	owner          string
+	repository     string
+	token          string
+	defaultVersion string
+	componentsPath string
+}
+
+var _ repository.Repository = &gitHubEnterpriseRepository{}
+
+// gitHubRelease is a release returned by the GitHub releases API.
+type gitHubRelease struct {
+	TagName string `json:"tag_name"`
+	Draft   bool   `json:"draft"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"assets"`
+}
+
+// newGitHubEnterpriseRepository returns a repository for the releases of the GitHub Enterprise Server repository.
+// The url must be in the form https://{host}/{owner}/{repository}/releases/{latest|version}/{components file},
+// the same as for github.com.
+func newGitHubEnterpriseRepository(ctx context.Context, rURL *url.URL, httpClient *http.Client, configVariablesClient configclient.VariablesClient) (*gitHubEnterpriseRepository, error) {
+	urlSplit := strings.Split(strings.Trim(rURL.Path, "/"), "/")
+	if len(urlSplit) != 5 || urlSplit[2] != githubReleasesPath {
+		return nil, fmt.Errorf("invalid url %q: a GitHub Enterprise repository url should be in the form https://{host}/{owner}/{repository}/releases/{latest|version}/{components file}", rURL)
+	}
+
+	// A missing variable is returned as an error, the token is left empty then.
+	token, _ := configVariablesClient.Get(githubEnterpriseTokenKey)
+
+	// A missing variable is returned as an error, the default API url of the instance is used then.
+	apiURL, _ := configVariablesClient.Get(githubEnterpriseAPIURLKey)
+	if apiURL == "" {
+		apiURL = fmt.Sprintf("%s://%s%s", rURL.Scheme, rURL.Host, githubEnterpriseAPIPath)
+	}
+
+	repo := &gitHubEnterpriseRepository{
+		httpClient:     httpClient,
+		apiURL:         strings.TrimSuffix(apiURL, "/"),
+		owner:          urlSplit[0],
+		repository:     urlSplit[1],
+		token:          token,
+		defaultVersion: urlSplit[3],
+		componentsPath: urlSplit[4],
+	}
+
+	if repo.defaultVersion == latestVersionLabel {
+		versions, err := repo.GetVersions(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest version: %w", err)
+		}
+
+		repo.defaultVersion, err = latestVersion(versions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest version: %w", err)
+		}
+	}
+
+	return repo, nil
+}
+
+// isGitHubEnterpriseURL returns true if the url looks like a release url of a GitHub Enterprise Server instance.
+func isGitHubEnterpriseURL(rURL *url.URL) bool {
+	return strings.HasPrefix(rURL.Host, githubEnterpriseHostPrefix) && strings.Contains(rURL.Path, githubReleasesPathSeparator)
+}
+
+// DefaultVersion returns the version from the repository url, or the latest version if the url contains "latest".
+func (g *gitHubEnterpriseRepository) DefaultVersion() string {
+	return g.defaultVersion
+}
+
+// RootPath returns the root of the release assets, which is always the release itself.
+func (g *gitHubEnterpriseRepository) RootPath() string {
+	return ""
+}
+
+// ComponentsPath returns the name of the components asset in the releases.
+func (g *gitHubEnterpriseRepository) ComponentsPath() string {
+	return g.componentsPath
+}
+
+// GetFile returns the asset with the given name of the release of the version.
+func (g *gitHubEnterpriseRepository) GetFile(ctx context.Context, version, path string) ([]byte, error) {
+	if version == latestVersionLabel {
+		version = g.defaultVersion
+	}
+
+	data, _, err := g.get(ctx, fmt.Sprintf("%s/releases/tags/%s", g.repositoryAPIURL(), url.PathEscape(version)), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release %q: %w", version, err)
+	}
+
+	release := gitHubRelease{}
+	if err := json.Unmarshal(data, &release); err != nil {
+		return nil, fmt.Errorf("failed to decode release %q: %w", version, err)
+	}
+
+	for _, asset := range release.Assets {
+		if asset.Name != path {
+			continue
+		}
+
+		// The browser download url of private repositories doesn't accept tokens, so the asset is
+		// downloaded from the API, which redirects to its storage.
+		data, _, err := g.get(ctx, asset.URL, githubAssetMediaType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get asset %q of release %q: %w", path, version, err)
+		}
+
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("failed to get file %q: release %q has no such asset", path, version)
+}
+
+// GetVersions returns the tags of the published repository releases that are semver versions.
+func (g *gitHubEnterpriseRepository) GetVersions(ctx context.Context) ([]string, error) {
+	versions := []string{}
+
+	for page := 1; ; page++ {
+		query := url.Values{"per_page": {strconv.Itoa(githubReleasesPageSize)}, "page": {strconv.Itoa(page)}}
+
+		data, _, err := g.get(ctx, fmt.Sprintf("%s/releases?%s", g.repositoryAPIURL(), query.Encode()), "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list releases: %w", err)
+		}
+
+		releases := []gitHubRelease{}
+		if err := json.Unmarshal(data, &releases); err != nil {
+			return nil, fmt.Errorf("failed to decode releases: %w", err)
+		}
+
+		// Drafts and releases that are not versions are ignored.
+		for _, release := range releases {
+			if _, err := versionutil.ParseSemantic(release.TagName); err == nil && !release.Draft {
+				versions = append(versions, release.TagName)
+			}
+		}
+
+		if len(releases) < githubReleasesPageSize {
+			return versions, nil
+		}
+	}
+}
+
+func (g *gitHubEnterpriseRepository) repositoryAPIURL() string {
+	return fmt.Sprintf("%s/repos/%s/%s", g.apiURL, url.PathEscape(g.owner), url.PathEscape(g.repository))
+}
+
+func (g *gitHubEnterpriseRepository) get(ctx context.Context, rawURL, accept string) ([]byte, http.Header, error) {
+	auth := forgeAuth{baseURL: g.apiURL, header: "Authorization"}
+	if g.token != "" {
+		auth.value = "Bearer " + g.token
+	}
+
+	return forgeGet(ctx, g.httpClient, rawURL, auth, accept)
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+)
+
+// newFakeGitHubEnterpriseServer returns a GitHub Enterprise Server API with a single private repository holding
+// releases with the given assets. Assets are redirected to a storage path, tags suffixed with "-draft" are drafts.
+func newFakeGitHubEnterpriseServer(g *WithT, owner, repository, token string, releases map[string]map[string]string) *httptest.Server {
+	var server *httptest.Server
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		releasesPath := "/api/v3/repos/" + owner + "/" + repository + "/releases"
+
+		// Storage urls are signed and don't need the token.
+		if strings.HasPrefix(r.URL.Path, "/storage/") {
+			tag, name, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/storage/"), "/")
+			_, _ = w.Write([]byte(releases[tag][name]))
+
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		releaseFor := func(tag string) map[string]interface{} {
+			assets := []map[string]string{}
+			for name := range releases[tag] {
+				assets = append(assets, map[string]string{
+					"name": name,
+					"url":  server.URL + releasesPath + "/assets/" + tag + "/" + name,
+				})
+			}
+
+			return map[string]interface{}{"tag_name": tag, "draft": strings.HasSuffix(tag, "-draft"), "assets": assets}
+		}
+
+		switch {
+		case r.URL.Path == releasesPath:
+			list := []interface{}{}
+			if r.URL.Query().Get("page") == "1" {
+				for tag := range releases {
+					list = append(list, releaseFor(tag))
+				}
+			}
+
+			g.Expect(json.NewEncoder(w).Encode(list)).To(Succeed())
+		case strings.HasPrefix(r.URL.Path, releasesPath+"/tags/"):
+			tag := strings.TrimPrefix(r.URL.Path, releasesPath+"/tags/")
+			if _, ok := releases[tag]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			g.Expect(json.NewEncoder(w).Encode(releaseFor(tag))).To(Succeed())
+		case strings.HasPrefix(r.URL.Path, releasesPath+"/assets/"):
+			if r.Header.Get("Accept") != githubAssetMediaType {
+				w.WriteHeader(http.StatusNotAcceptable)
+
+				return
+			}
+
+			http.Redirect(w, r, "/storage/"+strings.TrimPrefix(r.URL.Path, releasesPath+"/assets/"), http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return server
+}
+
+func TestGitHubEnterpriseRepository(t *testing.T) {
+	g := NewWithT(t)
+
+	server := newFakeGitHubEnterpriseServer(g, "infra", "provider", "token", map[string]map[string]string{
+		"v0.9.0":        {"infrastructure-components.yaml": "components v0.9.0"},
+		"v0.10.0":       {"infrastructure-components.yaml": "components v0.10.0", "metadata.yaml": "metadata v0.10.0"},
+		"v0.11.0-draft": {"infrastructure-components.yaml": "components draft"},
+		"nightly":       {"infrastructure-components.yaml": "components nightly"},
+	})
+	defer server.Close()
+
+	testCases := []struct {
+		name               string
+		url                string
+		token              string
+		apiURL             string
+		wantDefaultVersion string
+		wantComponents     string
+		wantErr            bool
+	}{
+		{
+			name:               "latest version",
+			url:                server.URL + "/infra/provider/releases/latest/infrastructure-components.yaml",
+			token:              "token",
+			wantDefaultVersion: "v0.10.0",
+			wantComponents:     "components v0.10.0",
+		},
+		{
+			name:               "given version",
+			url:                server.URL + "/infra/provider/releases/v0.9.0/infrastructure-components.yaml",
+			token:              "token",
+			wantDefaultVersion: "v0.9.0",
+			wantComponents:     "components v0.9.0",
+		},
+		{
+			name:               "api url on another host",
+			url:                "https://github.example.com/infra/provider/releases/latest/infrastructure-components.yaml",
+			token:              "token",
+			apiURL:             server.URL + "/api/v3/",
+			wantDefaultVersion: "v0.10.0",
+			wantComponents:     "components v0.10.0",
+		},
+		{
+			name:    "missing token",
+			url:     server.URL + "/infra/provider/releases/latest/infrastructure-components.yaml",
+			wantErr: true,
+		},
+		{
+			name:    "invalid url",
+			url:     server.URL + "/infra/provider/latest/infrastructure-components.yaml",
+			token:   "token",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mr := configclient.NewMemoryReader()
+			if tc.token != "" {
+				mr.Set(githubEnterpriseTokenKey, tc.token)
+			}
+
+			if tc.apiURL != "" {
+				mr.Set(githubEnterpriseAPIURLKey, tc.apiURL)
+			}
+
+			configClient, err := configclient.New(context.TODO(), "", configclient.InjectReader(mr))
+			g.Expect(err).ToNot(HaveOccurred())
+
+			rURL, err := url.Parse(tc.url)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			repo, err := newGitHubEnterpriseRepository(context.TODO(), rURL, server.Client(), configClient.Variables())
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(repo.DefaultVersion()).To(Equal(tc.wantDefaultVersion))
+
+			versions, err := repo.GetVersions(context.TODO())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(versions).To(ConsistOf("v0.9.0", "v0.10.0"))
+
+			components, err := repo.GetFile(context.TODO(), repo.DefaultVersion(), repo.ComponentsPath())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(components)).To(Equal(tc.wantComponents))
+
+			_, err = repo.GetFile(context.TODO(), repo.DefaultVersion(), "missing.yaml")
+			g.Expect(err).To(HaveOccurred())
+		})
+	}
+}
+
+func TestIsGitHubEnterpriseURL(t *testing.T) {
+	testCases := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://github.example.com/infra/provider/releases/latest/infrastructure-components.yaml", want: true},
+		{url: "https://git.example.com/infra/provider/releases/latest/infrastructure-components.yaml", want: false},
+		{url: "https://github.example.com/providers/latest/infrastructure-components.yaml", want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			g := NewWithT(t)
+
+			rURL, err := url.Parse(tc.url)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(isGitHubEnterpriseURL(rURL)).To(Equal(tc.want))
+		})
+	}
+}
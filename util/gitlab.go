@@ -0,0 +1,201 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	versionutil "k8s.io/apimachinery/pkg/util/version"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+)
+
+const (
+	// gitlabReleasesPathSeparator separates the project path from the releases path in GitLab release URLs.
+	gitlabReleasesPathSeparator = "/-/releases/"
+
+	// gitlabPrivateTokenKey is the variable with the GitLab personal, group or project access token.
+	gitlabPrivateTokenKey = "GITLAB_PRIVATE_TOKEN"
+
+	gitlabPrivateTokenHeader = "PRIVATE-TOKEN"
+	gitlabNextPageHeader     = "X-Next-Page"
+	gitlabReleasesPerPage    = "100"
+)
+
+// gitLabReleasesRepository is a repository of provider releases published as GitLab release assets,
+// on gitlab.com or on a self-hosted GitLab instance.
+type gitLabReleasesRepository struct {
+	httpClient     *http.Client
+	baseURL        string
+	projectPath    string
+	token          string
+	defaultVersion string
+	componentsPath string
+}
+
+var _ repository.Repository = &gitLabReleasesRepository{}
+
+// gitLabRelease is a release returned by the GitLab releases API.
+type gitLabRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  struct {
+		Links []struct {
+			Name           string `json:"name"`
+			URL            string `json:"url"`
+			DirectAssetURL string `json:"direct_asset_url"`
+		} `json:"links"`
+	} `json:"assets"`
+}
+
+// isGitLabReleasesURL returns true if the url points to the releases of a GitLab project.
+func isGitLabReleasesURL(rURL *url.URL) bool {
+	return strings.Contains(rURL.Path, gitlabReleasesPathSeparator)
+}
+
+// newGitLabReleasesRepository returns a repository for the releases of the GitLab project. The url must be in the
+// form https://{host}/{project path}/-/releases/{latest|version}/{components file}, where the project path
+// can contain groups and subgroups. The private token is used for projects that are not public.
+func newGitLabReleasesRepository(ctx context.Context, rURL *url.URL, httpClient *http.Client, configVariablesClient configclient.VariablesClient) (*gitLabReleasesRepository, error) {
+	projectPath, releasePath, _ := strings.Cut(rURL.Path, gitlabReleasesPathSeparator)
+
+	releaseSplit := strings.Split(releasePath, "/")
+	if strings.Trim(projectPath, "/") == "" || len(releaseSplit) != 2 || releaseSplit[0] == "" || releaseSplit[1] == "" {
+		return nil, fmt.Errorf("invalid url %q: a GitLab releases url should be in the form https://{host}/{project path}/-/releases/{latest|version}/{components file}", rURL)
+	}
+
+	// A missing variable is returned as an error, the token is left empty then.
+	token, _ := configVariablesClient.Get(gitlabPrivateTokenKey)
+
+	repo := &gitLabReleasesRepository{
+		httpClient:     httpClient,
+		baseURL:        fmt.Sprintf("%s://%s", rURL.Scheme, rURL.Host),
+		projectPath:    strings.Trim(projectPath, "/"),
+		token:          token,
+		defaultVersion: releaseSplit[0],
+		componentsPath: releaseSplit[1],
+	}
+
+	if repo.defaultVersion == latestVersionLabel {
+		versions, err := repo.GetVersions(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest version: %w", err)
+		}
+
+		repo.defaultVersion, err = latestVersion(versions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest version: %w", err)
+		}
+	}
+
+	return repo, nil
+}
+
+// DefaultVersion returns the version from the repository url, or the latest version if the url contains "latest".
+func (g *gitLabReleasesRepository) DefaultVersion() string {
+	return g.defaultVersion
+}
+
+// RootPath returns the root of the release assets, which is always the release itself.
+func (g *gitLabReleasesRepository) RootPath() string {
+	return ""
+}
+
+// ComponentsPath returns the name of the components asset in the releases.
+func (g *gitLabReleasesRepository) ComponentsPath() string {
+	return g.componentsPath
+}
+
+// GetFile returns the asset with the given name of the release of the version.
+func (g *gitLabReleasesRepository) GetFile(ctx context.Context, version, path string) ([]byte, error) {
+	if version == latestVersionLabel {
+		version = g.defaultVersion
+	}
+
+	data, _, err := g.get(ctx, fmt.Sprintf("%s/releases/%s", g.projectAPIURL(), url.PathEscape(version)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release %q: %w", version, err)
+	}
+
+	release := gitLabRelease{}
+	if err := json.Unmarshal(data, &release); err != nil {
+		return nil, fmt.Errorf("failed to decode release %q: %w", version, err)
+	}
+
+	for _, link := range release.Assets.Links {
+		if link.Name != path {
+			continue
+		}
+
+		assetURL := link.DirectAssetURL
+		if assetURL == "" {
+			assetURL = link.URL
+		}
+
+		data, _, err := g.get(ctx, assetURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get asset %q of release %q: %w", path, version, err)
+		}
+
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("failed to get file %q: release %q has no such asset", path, version)
+}
+
+// GetVersions returns the tags of the project releases that are semver versions.
+func (g *gitLabReleasesRepository) GetVersions(ctx context.Context) ([]string, error) {
+	versions := []string{}
+	page := "1"
+
+	for page != "" {
+		query := url.Values{"per_page": {gitlabReleasesPerPage}, "page": {page}}
+
+		data, header, err := g.get(ctx, fmt.Sprintf("%s/releases?%s", g.projectAPIURL(), query.Encode()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list releases: %w", err)
+		}
+
+		releases := []gitLabRelease{}
+		if err := json.Unmarshal(data, &releases); err != nil {
+			return nil, fmt.Errorf("failed to decode releases: %w", err)
+		}
+
+		// Releases that are not versions are ignored.
+		for _, release := range releases {
+			if _, err := versionutil.ParseSemantic(release.TagName); err == nil {
+				versions = append(versions, release.TagName)
+			}
+		}
+
+		page = header.Get(gitlabNextPageHeader)
+	}
+
+	return versions, nil
+}
+
+func (g *gitLabReleasesRepository) projectAPIURL() string {
+	return fmt.Sprintf("%s/api/v4/projects/%s", g.baseURL, url.PathEscape(g.projectPath))
+}
+
+func (g *gitLabReleasesRepository) get(ctx context.Context, rawURL string) ([]byte, http.Header, error) {
+	return forgeGet(ctx, g.httpClient, rawURL, forgeAuth{baseURL: g.baseURL, header: gitlabPrivateTokenHeader, value: g.token}, "")
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+)
+
+// newFakeGitLabServer returns a GitLab API server with a single private project holding releases with the given
+// assets. Releases are listed one per page to exercise pagination.
+func newFakeGitLabServer(g *WithT, projectPath, token string, releases map[string]map[string]string) *httptest.Server {
+	var server *httptest.Server
+
+	tags := []string{}
+	for tag := range releases {
+		tags = append(tags, tag)
+	}
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(gitlabPrivateTokenHeader) != token {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		releaseFor := func(tag string) map[string]interface{} {
+			links := []map[string]string{}
+			for name := range releases[tag] {
+				links = append(links, map[string]string{
+					"name":             name,
+					"url":              "https://example.com/not-used",
+					"direct_asset_url": server.URL + "/" + projectPath + "/-/releases/" + tag + "/downloads/" + name,
+				})
+			}
+
+			return map[string]interface{}{"tag_name": tag, "assets": map[string]interface{}{"links": links}}
+		}
+
+		releasesPath := "/api/v4/projects/" + url.PathEscape(projectPath) + "/releases"
+
+		switch {
+		case r.URL.EscapedPath() == releasesPath:
+			page, err := strconv.Atoi(r.URL.Query().Get("page"))
+			g.Expect(err).ToNot(HaveOccurred())
+
+			if page < len(tags) {
+				w.Header().Set(gitlabNextPageHeader, strconv.Itoa(page+1))
+			}
+
+			g.Expect(json.NewEncoder(w).Encode([]interface{}{releaseFor(tags[page-1])})).To(Succeed())
+		case strings.HasPrefix(r.URL.EscapedPath(), releasesPath+"/"):
+			tag := strings.TrimPrefix(r.URL.EscapedPath(), releasesPath+"/")
+			if _, ok := releases[tag]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			g.Expect(json.NewEncoder(w).Encode(releaseFor(tag))).To(Succeed())
+		case strings.HasPrefix(r.URL.Path, "/"+projectPath+"/-/releases/"):
+			tag, name, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"+projectPath+"/-/releases/"), "/downloads/")
+			_, _ = w.Write([]byte(releases[tag][name]))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return server
+}
+
+func TestGitLabReleasesRepository(t *testing.T) {
+	testCases := []struct {
+		name               string
+		path               string
+		token              string
+		wantDefaultVersion string
+		wantComponents     string
+		wantErr            bool
+	}{
+		{
+			name:               "latest version",
+			path:               "/group/subgroup/provider/-/releases/latest/infrastructure-components.yaml",
+			token:              "token",
+			wantDefaultVersion: "v0.10.0",
+			wantComponents:     "components v0.10.0",
+		},
+		{
+			name:               "given version",
+			path:               "/group/subgroup/provider/-/releases/v0.9.0/infrastructure-components.yaml",
+			token:              "token",
+			wantDefaultVersion: "v0.9.0",
+			wantComponents:     "components v0.9.0",
+		},
+		{
+			name:    "missing token",
+			path:    "/group/subgroup/provider/-/releases/latest/infrastructure-components.yaml",
+			wantErr: true,
+		},
+		{
+			name:    "invalid url",
+			path:    "/group/subgroup/provider/-/releases/latest",
+			token:   "token",
+			wantErr: true,
+		},
+	}
+
+	g := NewWithT(t)
+
+	server := newFakeGitLabServer(g, "group/subgroup/provider", "token", map[string]map[string]string{
+		"v0.9.0":  {"infrastructure-components.yaml": "components v0.9.0"},
+		"v0.10.0": {"infrastructure-components.yaml": "components v0.10.0", "metadata.yaml": "metadata v0.10.0"},
+		"nightly": {"infrastructure-components.yaml": "components nightly"},
+	})
+	defer server.Close()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mr := configclient.NewMemoryReader()
+			if tc.token != "" {
+				mr.Set(gitlabPrivateTokenKey, tc.token)
+			}
+
+			configClient, err := configclient.New(context.TODO(), "", configclient.InjectReader(mr))
+			g.Expect(err).ToNot(HaveOccurred())
+
+			rURL, err := url.Parse(server.URL + tc.path)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(isGitLabReleasesURL(rURL)).To(BeTrue())
+
+			repo, err := newGitLabReleasesRepository(context.TODO(), rURL, server.Client(), configClient.Variables())
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(repo.DefaultVersion()).To(Equal(tc.wantDefaultVersion))
+
+			versions, err := repo.GetVersions(context.TODO())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(versions).To(ConsistOf("v0.9.0", "v0.10.0"))
+
+			components, err := repo.GetFile(context.TODO(), repo.DefaultVersion(), repo.ComponentsPath())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(components)).To(Equal(tc.wantComponents))
+
+			_, err = repo.GetFile(context.TODO(), repo.DefaultVersion(), "missing.yaml")
+			g.Expect(err).To(HaveOccurred())
+		})
+	}
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+)
+
+const (
+	// conditionalResponsesCacheSize is the number of responses kept in memory for conditional requests.
+	conditionalResponsesCacheSize = 256
+
+	// conditionalResponsesCacheTTL is how long the responses are kept in memory for conditional requests.
+	conditionalResponsesCacheTTL = 24 * time.Hour
+
+	// conditionalResponseMaxSize is the size of the largest response kept in memory for conditional requests,
+	// release files are kept by the components cache of the controller instead.
+	conditionalResponseMaxSize = 1 << 20
+
+	// githubRateLimitMessage is the error of the clusterctl GitHub repository when the rate limit is reached.
+	githubRateLimitMessage = "rate limit for github api has been reached"
+)
+
+// conditionalResponses are the responses with an ETag received by all the conditional transports.
+var conditionalResponses = utilcache.NewLRUExpireCache(conditionalResponsesCacheSize)
+
+// RateLimitError is returned when a forge API rejects a request because its rate limit was reached.
+type RateLimitError struct {
+	// Message describes the rejected request.
+	Message string
+
+	// Reset is when the rate limit resets, it's zero if unknown.
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Reset.IsZero() {
+		return e.Message
+	}
+
+	return fmt.Sprintf("%s, the rate limit resets at %s", e.Message, e.Reset.UTC().Format(time.RFC3339))
+}
+
+// AsRateLimitError returns the rate limit error in the chain of err. The rate limit error of the
+// clusterctl GitHub repository is only known by its message, so it has no reset time.
+func AsRateLimitError(err error) (*RateLimitError, bool) {
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr, true
+	}
+
+	if err != nil && strings.Contains(err.Error(), githubRateLimitMessage) {
+		return &RateLimitError{Message: err.Error()}, true
+	}
+
+	return nil, false
+}
+
+// rateLimitError returns the rate limit error of the response, or nil if the request wasn't rate limited.
+// GitHub and Gitea send the X-RateLimit-* headers, GitLab the RateLimit-* ones, and all of them can send Retry-After.
+func rateLimitError(resp *http.Response) *RateLimitError {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+
+	err := &RateLimitError{Message: fmt.Sprintf("rate limit reached for %s", resp.Request.URL)}
+
+	if retryAfter, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil {
+		err.Reset = time.Now().Add(time.Duration(retryAfter) * time.Second)
+
+		return err
+	}
+
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		if resp.Header.Get(prefix+"Remaining") != "0" {
+			continue
+		}
+
+		if reset, parseErr := strconv.ParseInt(resp.Header.Get(prefix+"Reset"), 10, 64); parseErr == nil {
+			err.Reset = time.Unix(reset, 0)
+		}
+
+		return err
+	}
+
+	// A forbidden response without rate limit headers is an authorization error.
+	if resp.StatusCode == http.StatusForbidden {
+		return nil
+	}
+
+	return err
+}
+
+// withConditionalRequests returns a copy of the HTTP client sending conditional requests.
+func withConditionalRequests(httpClient *http.Client) *http.Client {
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	client := *httpClient
+	client.Transport = &conditionalTransport{base: base}
+
+	return &client
+}
+
+// conditionalTransport sends GET requests with the ETag of the last response to the same request, and serves
+// the response from memory when it's not modified. Not modified responses don't count against the GitHub rate limit.
+type conditionalTransport struct {
+	base http.RoundTripper
+}
+
+// conditionalResponse is a response with an ETag.
+type conditionalResponse struct {
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+func (t *conditionalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("If-None-Match") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	key := conditionalResponseKey(req)
+
+	value, _ := conditionalResponses.Get(key)
+	if cached, ok := value.(conditionalResponse); ok {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", cached.etag)
+
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusNotModified {
+			return t.store(key, resp, err)
+		}
+
+		resp.Body.Close()
+
+		// The not modified response has the current rate limit headers.
+		header := cached.header.Clone()
+		for name, values := range resp.Header {
+			header[name] = values
+		}
+
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(cached.body)),
+			ContentLength: int64(len(cached.body)),
+			Request:       req,
+		}, nil
+	}
+
+	resp, err := t.base.RoundTrip(req)
+
+	return t.store(key, resp, err)
+}
+
+// store keeps the response in memory if it has an ETag and is small enough.
+func (t *conditionalTransport) store(key string, resp *http.Response, err error) (*http.Response, error) {
+	if err != nil || resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == "" || resp.ContentLength > conditionalResponseMaxSize {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, conditionalResponseMaxSize+1))
+	resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) <= conditionalResponseMaxSize {
+		conditionalResponses.Add(key, conditionalResponse{
+			etag:   resp.Header.Get("ETag"),
+			header: resp.Header.Clone(),
+			body:   body,
+		}, conditionalResponsesCacheTTL)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
+
+// conditionalResponseKey returns the key of the request responses. Responses depend on the credentials,
+// which are hashed so they are not kept in memory.
+func conditionalResponseKey(req *http.Request) string {
+	credentials := sha256.Sum256([]byte(req.Header.Get("Authorization") + "\n" + req.Header.Get(gitlabPrivateTokenHeader)))
+
+	return req.URL.String() + "\n" + req.Header.Get("Accept") + "\n" + hex.EncodeToString(credentials[:])
+}
+
+// forgeAuth is the authorization header sent to the API of a forge hosting provider releases.
+type forgeAuth struct {
+	// baseURL of the forge, the header is only sent to URLs under it as release assets can link to other hosts.
+	baseURL string
+	header  string
+	value   string
+}
+
+// forgeGet returns the response body and headers for the url, requesting the accept media type if set.
+func forgeGet(ctx context.Context, client *http.Client, rawURL string, auth forgeAuth, accept string) ([]byte, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, http.NoBody)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	if auth.value != "" && strings.HasPrefix(rawURL, auth.baseURL+"/") {
+		req.Header.Set(auth.header, auth.value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := rateLimitError(resp); err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil, fmt.Errorf("%w: unexpected response from %s: %s", ErrObjectNotFound, rawURL, resp.Status)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected response from %s: %s", rawURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, resp.Header, nil
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestConditionalTransport(t *testing.T) {
+	g := NewWithT(t)
+
+	body := "releases v1"
+	notModified := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf("%q", r.Header.Get("Authorization")+body)
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := withConditionalRequests(server.Client())
+
+	get := func(token string) string {
+		data, _, err := forgeGet(context.TODO(), client, server.URL+"/releases", forgeAuth{baseURL: server.URL, header: "Authorization", value: token}, "")
+		g.Expect(err).ToNot(HaveOccurred())
+
+		return string(data)
+	}
+
+	g.Expect(get("token")).To(Equal("releases v1"))
+	g.Expect(notModified).To(Equal(0))
+
+	// The unchanged response is served from memory.
+	g.Expect(get("token")).To(Equal("releases v1"))
+	g.Expect(notModified).To(Equal(1))
+
+	// Responses to other credentials are not shared.
+	g.Expect(get("other-token")).To(Equal("releases v1"))
+	g.Expect(notModified).To(Equal(1))
+
+	// A changed response replaces the one in memory.
+	body = "releases v2"
+	g.Expect(get("token")).To(Equal("releases v2"))
+	g.Expect(get("token")).To(Equal("releases v2"))
+	g.Expect(notModified).To(Equal(2))
+}
+
+func TestRateLimitError(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	testCases := []struct {
+		name       string
+		statusCode int
+		header     http.Header
+		wantErr    bool
+		wantReset  time.Time
+	}{
+		{
+			name:       "GitHub rate limit",
+			statusCode: http.StatusForbidden,
+			header:     http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {strconv.FormatInt(reset.Unix(), 10)}},
+			wantErr:    true,
+			wantReset:  reset,
+		},
+		{
+			name:       "GitLab rate limit",
+			statusCode: http.StatusTooManyRequests,
+			header:     http.Header{"Ratelimit-Remaining": {"0"}, "Ratelimit-Reset": {strconv.FormatInt(reset.Unix(), 10)}},
+			wantErr:    true,
+			wantReset:  reset,
+		},
+		{
+			name:       "too many requests without headers",
+			statusCode: http.StatusTooManyRequests,
+			wantErr:    true,
+		},
+		{
+			name:       "forbidden with remaining requests",
+			statusCode: http.StatusForbidden,
+			header:     http.Header{"X-Ratelimit-Remaining": {"10"}},
+		},
+		{
+			name:       "not found",
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			header := tc.header
+			if header == nil {
+				header = http.Header{}
+			}
+
+			err := rateLimitError(&http.Response{
+				StatusCode: tc.statusCode,
+				Header:     header,
+				Request:    &http.Request{URL: &url.URL{Scheme: "https", Host: "api.github.com", Path: "/repos"}},
+			})
+			if !tc.wantErr {
+				g.Expect(err).To(BeNil())
+
+				return
+			}
+
+			g.Expect(err).ToNot(BeNil())
+			g.Expect(err.Reset).To(BeTemporally("==", tc.wantReset))
+		})
+	}
+}
+
+func TestAsRateLimitError(t *testing.T) {
+	g := NewWithT(t)
+
+	reset := time.Now().Add(time.Hour)
+
+	rateLimitErr, ok := AsRateLimitError(fmt.Errorf("failed to get release: %w", &RateLimitError{Message: "rate limit reached", Reset: reset}))
+	g.Expect(ok).To(BeTrue())
+	g.Expect(rateLimitErr.Reset).To(Equal(reset))
+
+	rateLimitErr, ok = AsRateLimitError(errors.New("failed to get latest release: rate limit for github api has been reached. Please wait one hour or get a personal API token"))
+	g.Expect(ok).To(BeTrue())
+	g.Expect(rateLimitErr.Reset.IsZero()).To(BeTrue())
+
+	_, ok = AsRateLimitError(errors.New("failed to get latest release: not found"))
+	g.Expect(ok).To(BeFalse())
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	versionutil "k8s.io/apimachinery/pkg/util/version"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+)
+
+const latestVersionLabel = "latest"
+
+// ErrObjectNotFound is returned by object stores for objects that don't exist.
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectStore is a bucket of an object storage service, where each provider release is a directory
+// named after the release version.
+type ObjectStore interface {
+	// GetObject returns the data of the object with the given key, or an error wrapping ErrObjectNotFound
+	// if it doesn't exist.
+	GetObject(ctx context.Context, key string) ([]byte, error)
+
+	// ListDirectories returns the names of the directories directly under the given prefix.
+	ListDirectories(ctx context.Context, prefix string) ([]string, error)
+}
+
+// objectStoreFactory returns the object store of a bucket URL, and the path of the components file in the bucket.
+type objectStoreFactory func(ctx context.Context, u *url.URL, httpClient *http.Client, variables configclient.VariablesClient) (ObjectStore, string, error)
+
+// objectStoreSchemes are the URL schemes of the object storage services, with the factories of their buckets.
+var objectStoreSchemes = map[string]objectStoreFactory{
+	gcsScheme:       newGCSStoreFromURL,
+	azureBlobScheme: newAzureBlobStoreFromURL,
+	s3Scheme:        newS3StoreFromURL,
+}
+
+// ObjectStoreDirectory returns the prefix as a directory of an object store, or an empty string for its root.
+func ObjectStoreDirectory(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+
+	return prefix + "/"
+}
+
+// ObjectStoreVersions returns the versions of the release directories under the prefix of the object store.
+// Directories that are not versions are ignored.
+func ObjectStoreVersions(ctx context.Context, store ObjectStore, prefix string) ([]string, error) {
+	directories, err := store.ListDirectories(ctx, ObjectStoreDirectory(prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+
+	for _, directory := range directories {
+		if _, err := versionutil.ParseSemantic(directory); err == nil {
+			versions = append(versions, directory)
+		}
+	}
+
+	return versions, nil
+}
+
+// LatestObjectStoreVersion returns the latest version of the release directories under the prefix of the object store.
+func LatestObjectStoreVersion(ctx context.Context, store ObjectStore, prefix string) (string, error) {
+	versions, err := ObjectStoreVersions(ctx, store, prefix)
+	if err != nil {
+		return "", err
+	}
+
+	return latestVersion(versions)
+}
+
+// objectStoreRepository is a repository of provider releases stored in an object storage bucket.
+type objectStoreRepository struct {
+	store          ObjectStore
+	prefix         string
+	defaultVersion string
+	componentsPath string
+}
+
+var _ repository.Repository = &objectStoreRepository{}
+
+// newObjectStoreRepository returns a repository for the releases in the bucket. The path in the bucket must be in the
+// form {prefix}/{latest|version}/{components file}, where the prefix is optional.
+func newObjectStoreRepository(ctx context.Context, store ObjectStore, path string) (*objectStoreRepository, error) {
+	urlSplit := strings.Split(strings.Trim(path, "/"), "/")
+	if len(urlSplit) < 2 {
+		return nil, fmt.Errorf("invalid path %q: it should be in the form {prefix}/{latest|version}/{components file}", path)
+	}
+
+	repo := &objectStoreRepository{
+		store:          store,
+		prefix:         strings.Join(urlSplit[:len(urlSplit)-2], "/"),
+		defaultVersion: urlSplit[len(urlSplit)-2],
+		componentsPath: urlSplit[len(urlSplit)-1],
+	}
+
+	if repo.defaultVersion == latestVersionLabel {
+		var err error
+
+		repo.defaultVersion, err = LatestObjectStoreVersion(ctx, store, repo.prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest version: %w", err)
+		}
+	}
+
+	return repo, nil
+}
+
+// DefaultVersion returns the version from the repository path, or the latest version if the path contains "latest".
+func (r *objectStoreRepository) DefaultVersion() string {
+	return r.defaultVersion
+}
+
+// RootPath returns the root of the release directories, which is always the release directory itself.
+func (r *objectStoreRepository) RootPath() string {
+	return ""
+}
+
+// ComponentsPath returns the name of the components file in the release directories.
+func (r *objectStoreRepository) ComponentsPath() string {
+	return r.componentsPath
+}
+
+// GetFile returns the file in the release directory of the version.
+func (r *objectStoreRepository) GetFile(ctx context.Context, version, path string) ([]byte, error) {
+	if version == latestVersionLabel {
+		version = r.defaultVersion
+	}
+
+	key := ObjectStoreDirectory(r.prefix) + version + "/" + path
+
+	data, err := r.store.GetObject(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file %q: %w", key, err)
+	}
+
+	return data, nil
+}
+
+// GetVersions returns the versions of the release directories.
+func (r *objectStoreRepository) GetVersions(ctx context.Context) ([]string, error) {
+	return ObjectStoreVersions(ctx, r.store, r.prefix)
+}
+
+// latestVersion returns the latest of the semver versions.
+func latestVersion(versions []string) (string, error) {
+	var (
+		latest       string
+		latestParsed *versionutil.Version
+	)
+
+	for _, version := range versions {
+		parsed, err := versionutil.ParseSemantic(version)
+		if err != nil {
+			return "", err
+		}
+
+		if latestParsed == nil || latestParsed.LessThan(parsed) {
+			latest, latestParsed = version, parsed
+		}
+	}
+
+	if latestParsed == nil {
+		return "", fmt.Errorf("no versions available")
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// newFakeGCSServer returns a Google Cloud Storage JSON API server with a single bucket holding the given objects.
+// Listing returns one directory per page to exercise pagination.
+func newFakeGCSServer(g *WithT, bucket string, objects map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		objectsPath := "/b/" + bucket + "/o"
+
+		if r.URL.Path == objectsPath {
+			prefix := r.URL.Query().Get("prefix")
+			prefixes := map[string]bool{}
+
+			for key := range objects {
+				if rest, ok := strings.CutPrefix(key, prefix); ok && strings.Contains(rest, "/") {
+					prefixes[prefix+rest[:strings.Index(rest, "/")+1]] = true
+				}
+			}
+
+			sorted := []string{}
+			for p := range prefixes {
+				sorted = append(sorted, p)
+			}
+
+			sort.Strings(sorted)
+
+			page := map[string]interface{}{"prefixes": []string{}}
+
+			for i, p := range sorted {
+				if p > r.URL.Query().Get("pageToken") {
+					page["prefixes"] = []string{p}
+					if i < len(sorted)-1 {
+						page["nextPageToken"] = p
+					}
+
+					break
+				}
+			}
+
+			g.Expect(json.NewEncoder(w).Encode(page)).To(Succeed())
+
+			return
+		}
+
+		data, ok := objects[strings.TrimPrefix(r.URL.Path, objectsPath+"/")]
+		if !ok || r.URL.Query().Get("alt") != "media" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(data))
+	}))
+}
+
+func TestObjectStoreRepository(t *testing.T) {
+	testCases := []struct {
+		name               string
+		path               string
+		wantDefaultVersion string
+		wantVersions       []string
+		wantComponents     string
+		wantErr            bool
+	}{
+		{
+			name:               "latest version with prefix",
+			path:               "/providers/aws/latest/infrastructure-components.yaml",
+			wantDefaultVersion: "v2.10.0",
+			wantVersions:       []string{"v2.10.0", "v2.9.0"},
+			wantComponents:     "components v2.10.0",
+		},
+		{
+			name:               "given version without prefix",
+			path:               "/v1.0.0/components.yaml",
+			wantDefaultVersion: "v1.0.0",
+			wantVersions:       []string{"v1.0.0"},
+			wantComponents:     "components v1.0.0",
+		},
+		{
+			name:    "no versions",
+			path:    "/providers/azure/latest/components.yaml",
+			wantErr: true,
+		},
+		{
+			name:    "invalid path",
+			path:    "/components.yaml",
+			wantErr: true,
+		},
+	}
+
+	g := NewWithT(t)
+
+	server := newFakeGCSServer(g, "capi", map[string]string{
+		"providers/aws/v2.9.0/infrastructure-components.yaml":  "components v2.9.0",
+		"providers/aws/v2.10.0/infrastructure-components.yaml": "components v2.10.0",
+		"providers/aws/v2.10.0/metadata.yaml":                  "metadata v2.10.0",
+		"providers/aws/dev/infrastructure-components.yaml":     "components dev",
+		"v1.0.0/components.yaml":                               "components v1.0.0",
+	})
+	defer server.Close()
+
+	store := &gcsStore{client: server.Client(), endpoint: server.URL, bucket: "capi"}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			repo, err := newObjectStoreRepository(context.TODO(), store, tc.path)
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(repo.DefaultVersion()).To(Equal(tc.wantDefaultVersion))
+
+			versions, err := repo.GetVersions(context.TODO())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(versions).To(ConsistOf(tc.wantVersions))
+
+			components, err := repo.GetFile(context.TODO(), repo.DefaultVersion(), repo.ComponentsPath())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(components)).To(Equal(tc.wantComponents))
+
+			_, err = repo.GetFile(context.TODO(), repo.DefaultVersion(), "missing.yaml")
+			g.Expect(err).To(MatchError(ErrObjectNotFound))
+		})
+	}
+}
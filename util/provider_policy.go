@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+// ProviderPolicyViolation returns why the provider with the version isn't allowed by the ProviderPolicies of the
+// cluster, or an empty string if it is. Any provider is allowed if there are no policies, and the version isn't
+// checked if it's empty or "latest", until it's resolved.
+func ProviderPolicyViolation(ctx context.Context, c client.Reader, provider operatorv1.GenericProvider, version string) (string, error) {
+	policies := &operatorv1.ProviderPolicyList{}
+	if err := c.List(ctx, policies); err != nil {
+		return "", fmt.Errorf("failed to list provider policies: %w", err)
+	}
+
+	if version == operatorv1.VersionLatest {
+		version = ""
+	}
+
+	return providerPoliciesViolation(policies.Items, string(ClusterctlProviderType(provider)), provider.GetName(), version), nil
+}
+
+// providerPoliciesViolation returns why the provider isn't allowed by any rule of the policies, or an empty string.
+func providerPoliciesViolation(policies []operatorv1.ProviderPolicy, providerType, name, version string) string {
+	if len(policies) == 0 {
+		return ""
+	}
+
+	for _, policy := range policies {
+		for _, rule := range policy.Spec.Rules {
+			if providerPolicyRuleAllows(rule, providerType, name, version) {
+				return ""
+			}
+		}
+	}
+
+	if version == "" {
+		return fmt.Sprintf("%s %q is not allowed by any ProviderPolicy", providerType, name)
+	}
+
+	return fmt.Sprintf("%s %q with version %s is not allowed by any ProviderPolicy", providerType, name, version)
+}
+
+// providerPolicyRuleAllows returns true if the rule allows the provider. Rules with an invalid version range
+// don't allow any provider.
+func providerPolicyRuleAllows(rule operatorv1.ProviderPolicyRule, providerType, name, version string) bool {
+	if rule.Type != "" && rule.Type != providerType {
+		return false
+	}
+
+	if len(rule.Names) > 0 {
+		found := false
+
+		for _, allowed := range rule.Names {
+			if allowed == name {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if rule.Versions == "" || version == "" {
+		return true
+	}
+
+	constraints, err := semver.NewConstraint(rule.Versions)
+	if err != nil {
+		return false
+	}
+
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+
+	return constraints.Check(v)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+func TestProviderPoliciesViolation(t *testing.T) {
+	policies := func(rules ...operatorv1.ProviderPolicyRule) []operatorv1.ProviderPolicy {
+		result := []operatorv1.ProviderPolicy{}
+		for _, rule := range rules {
+			result = append(result, operatorv1.ProviderPolicy{
+				Spec: operatorv1.ProviderPolicySpec{Rules: []operatorv1.ProviderPolicyRule{rule}},
+			})
+		}
+
+		return result
+	}
+
+	tests := []struct {
+		name          string
+		policies      []operatorv1.ProviderPolicy
+		providerType  string
+		providerName  string
+		version       string
+		wantViolation string
+	}{
+		{
+			name:         "no policies",
+			providerType: "InfrastructureProvider",
+			providerName: "aws",
+			version:      "v2.3.0",
+		},
+		{
+			name:         "allowed by type, name and version",
+			policies:     policies(operatorv1.ProviderPolicyRule{Type: "InfrastructureProvider", Names: []string{"azure", "aws"}, Versions: ">= v2.0.0, < v3.0.0"}),
+			providerType: "InfrastructureProvider",
+			providerName: "aws",
+			version:      "v2.3.0",
+		},
+		{
+			name: "allowed by any policy",
+			policies: policies(
+				operatorv1.ProviderPolicyRule{Type: "CoreProvider"},
+				operatorv1.ProviderPolicyRule{Names: []string{"aws"}, Versions: "~v2.3"},
+			),
+			providerType: "InfrastructureProvider",
+			providerName: "aws",
+			version:      "v2.3.1",
+		},
+		{
+			name:         "version checked once resolved",
+			policies:     policies(operatorv1.ProviderPolicyRule{Names: []string{"aws"}, Versions: ">= v2.0.0"}),
+			providerType: "InfrastructureProvider",
+			providerName: "aws",
+		},
+		{
+			name:          "other type",
+			policies:      policies(operatorv1.ProviderPolicyRule{Type: "BootstrapProvider", Names: []string{"aws"}}),
+			providerType:  "InfrastructureProvider",
+			providerName:  "aws",
+			wantViolation: `InfrastructureProvider "aws" is not allowed by any ProviderPolicy`,
+		},
+		{
+			name:          "other name",
+			policies:      policies(operatorv1.ProviderPolicyRule{Type: "InfrastructureProvider", Names: []string{"azure"}}),
+			providerType:  "InfrastructureProvider",
+			providerName:  "aws",
+			version:       "v2.3.0",
+			wantViolation: `InfrastructureProvider "aws" with version v2.3.0 is not allowed by any ProviderPolicy`,
+		},
+		{
+			name:          "version out of range",
+			policies:      policies(operatorv1.ProviderPolicyRule{Names: []string{"aws"}, Versions: ">= v2.0.0, < v2.3.0"}),
+			providerType:  "InfrastructureProvider",
+			providerName:  "aws",
+			version:       "v2.3.0",
+			wantViolation: `InfrastructureProvider "aws" with version v2.3.0 is not allowed by any ProviderPolicy`,
+		},
+		{
+			name:          "pre-release version",
+			policies:      policies(operatorv1.ProviderPolicyRule{Names: []string{"aws"}, Versions: ">= v2.0.0"}),
+			providerType:  "InfrastructureProvider",
+			providerName:  "aws",
+			version:       "v2.4.0-rc.0",
+			wantViolation: `InfrastructureProvider "aws" with version v2.4.0-rc.0 is not allowed by any ProviderPolicy`,
+		},
+		{
+			name:          "invalid version range",
+			policies:      policies(operatorv1.ProviderPolicyRule{Names: []string{"aws"}, Versions: "latest"}),
+			providerType:  "InfrastructureProvider",
+			providerName:  "aws",
+			version:       "v2.3.0",
+			wantViolation: `InfrastructureProvider "aws" with version v2.3.0 is not allowed by any ProviderPolicy`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(providerPoliciesViolation(tt.policies, tt.providerType, tt.providerName, tt.version)).To(Equal(tt.wantViolation))
+		})
+	}
+}
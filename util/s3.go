@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	configclient "sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+)
+
+const (
+	// S3AccessKeyIDKey, S3SecretAccessKeyKey and S3SessionTokenKey are the config secret variables with the S3 credentials.
+	S3AccessKeyIDKey     = "S3_ACCESS_KEY_ID"
+	S3SecretAccessKeyKey = "S3_SECRET_ACCESS_KEY"
+	S3SessionTokenKey    = "S3_SESSION_TOKEN"
+
+	// s3Endpoint is the endpoint of the buckets of s3:// URLs.
+	s3Endpoint = "s3.amazonaws.com"
+
+	// s3NoSuchKeyCode is the error code returned for missing objects.
+	s3NoSuchKeyCode = "NoSuchKey"
+)
+
+// s3Store is a bucket of an S3-compatible storage.
+type s3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3Store returns the bucket of the S3-compatible storage. It uses the static credentials from the variables
+// if set, the credentials of the environment otherwise, e.g. the AWS IAM role of the operator, falling back to
+// anonymous access. The storage is accessed with the transport of the HTTP client.
+func NewS3Store(source *operatorv1.S3Source, variables configclient.VariablesClient, httpClient *http.Client) (ObjectStore, error) {
+	// A missing variable is returned as an error, the credential is left empty then.
+	accessKeyID, _ := variables.Get(S3AccessKeyIDKey)
+	secretAccessKey, _ := variables.Get(S3SecretAccessKeyKey)
+	sessionToken, _ := variables.Get(S3SessionTokenKey)
+
+	creds := credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvAWS{},
+		&credentials.IAM{Client: &http.Client{Transport: http.DefaultTransport}},
+	})
+
+	if accessKeyID != "" {
+		creds = credentials.NewStaticV4(accessKeyID, secretAccessKey, sessionToken)
+	}
+
+	client, err := minio.New(source.Endpoint, &minio.Options{
+		Creds:     creds,
+		Secure:    !source.Insecure,
+		Region:    source.Region,
+		Transport: httpClient.Transport,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3Store{client: client, bucket: source.Bucket}, nil
+}
+
+// newS3StoreFromURL returns the Amazon S3 bucket of an s3://{bucket}/{prefix}/{latest|version}/{components file} URL.
+func newS3StoreFromURL(_ context.Context, u *url.URL, httpClient *http.Client, variables configclient.VariablesClient) (ObjectStore, string, error) {
+	store, err := NewS3Store(&operatorv1.S3Source{Endpoint: s3Endpoint, Bucket: u.Host}, variables, httpClient)
+	if err != nil {
+		return nil, "", fmt.Errorf("error creating the S3 client: %w", err)
+	}
+
+	return store, u.Path, nil
+}
+
+func (s *s3Store) GetObject(ctx context.Context, key string) ([]byte, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	// The object is only requested when it's read, so a missing object is reported here.
+	data, err := io.ReadAll(object)
+	if minio.ToErrorResponse(err).Code == s3NoSuchKeyCode {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+
+	return data, err
+}
+
+func (s *s3Store) ListDirectories(ctx context.Context, prefix string) ([]string, error) {
+	directories := []string{}
+
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+
+		// Only directories are listed as keys ending with a slash, other objects are ignored.
+		if strings.HasSuffix(object.Key, "/") {
+			directories = append(directories, path.Base(object.Key))
+		}
+	}
+
+	return directories, nil
+}
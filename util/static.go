@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"sigs.k8s.io/yaml"
+)
+
+// staticIndexFile is the name of the index file listing the release versions served by a static web server.
+const staticIndexFile = "index.yaml"
+
+// staticIndex is the content of the index file.
+type staticIndex struct {
+	Versions []string `json:"versions"`
+}
+
+// staticStore is a plain web server serving the provider releases as files. As web servers can't list directories,
+// the release versions are read from the index file next to the release directories.
+type staticStore struct {
+	client  *http.Client
+	baseURL string
+}
+
+// newStaticStore returns the web server of the url, accessed with the HTTP client.
+func newStaticStore(rURL *url.URL, httpClient *http.Client) *staticStore {
+	return &staticStore{client: httpClient, baseURL: fmt.Sprintf("%s://%s", rURL.Scheme, rURL.Host)}
+}
+
+func (s *staticStore) GetObject(ctx context.Context, key string) ([]byte, error) {
+	data, _, err := forgeGet(ctx, s.client, s.baseURL+"/"+key, forgeAuth{}, "")
+
+	return data, err
+}
+
+func (s *staticStore) ListDirectories(ctx context.Context, prefix string) ([]string, error) {
+	data, err := s.GetObject(ctx, prefix+staticIndexFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index file: %w", err)
+	}
+
+	index := staticIndex{}
+	if err := yaml.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to decode index file: %w", err)
+	}
+
+	return index.Versions, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestStaticRepository(t *testing.T) {
+	testCases := []struct {
+		name               string
+		path               string
+		wantDefaultVersion string
+		wantComponents     string
+		wantErr            bool
+	}{
+		{
+			name:               "latest version",
+			path:               "/providers/my-provider/latest/infrastructure-components.yaml",
+			wantDefaultVersion: "v0.10.0",
+			wantComponents:     "components v0.10.0",
+		},
+		{
+			name:               "given version",
+			path:               "/providers/my-provider/v0.9.0/infrastructure-components.yaml",
+			wantDefaultVersion: "v0.9.0",
+			wantComponents:     "components v0.9.0",
+		},
+		{
+			name:    "missing index file",
+			path:    "/providers/other-provider/latest/infrastructure-components.yaml",
+			wantErr: true,
+		},
+	}
+
+	files := map[string]string{
+		"/providers/my-provider/index.yaml":                             "versions:\n- v0.9.0\n- v0.10.0\n- nightly\n",
+		"/providers/my-provider/v0.9.0/infrastructure-components.yaml":  "components v0.9.0",
+		"/providers/my-provider/v0.10.0/infrastructure-components.yaml": "components v0.10.0",
+		"/providers/my-provider/v0.10.0/metadata.yaml":                  "metadata v0.10.0",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(data))
+	}))
+	defer server.Close()
+
+	store := &staticStore{client: server.Client(), baseURL: server.URL}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			repo, err := newObjectStoreRepository(context.TODO(), store, tc.path)
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(repo.DefaultVersion()).To(Equal(tc.wantDefaultVersion))
+
+			versions, err := repo.GetVersions(context.TODO())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(versions).To(ConsistOf("v0.9.0", "v0.10.0"))
+
+			components, err := repo.GetFile(context.TODO(), repo.DefaultVersion(), repo.ComponentsPath())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(components)).To(Equal(tc.wantComponents))
+
+			_, err = repo.GetFile(context.TODO(), repo.DefaultVersion(), "missing.yaml")
+			g.Expect(err).To(MatchError(ContainSubstring("missing.yaml")))
+		})
+	}
+}
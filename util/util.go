@@ -18,10 +18,14 @@ package util
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 
+	"golang.org/x/oauth2"
+	"k8s.io/apimachinery/pkg/util/validation"
 	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
 	"sigs.k8s.io/cluster-api-operator/internal/controller/genericprovider"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
@@ -31,9 +35,15 @@ import (
 
 const (
 	httpsScheme             = "https"
+	gcsScheme               = "gs"
+	azureBlobScheme         = "azblob"
+	s3Scheme                = "s3"
 	githubDomain            = "github.com"
 	gitlabHostPrefix        = "gitlab."
 	gitlabPackagesAPIPrefix = "/api/v4/projects/"
+
+	// smokeTestLabelHashLength is the number of hash characters suffixing truncated smoke test label values.
+	smokeTestLabelHashLength = 8
 )
 
 func IsCoreProvider(p genericprovider.GenericProvider) bool {
@@ -61,38 +71,123 @@ func ClusterctlProviderType(genericProvider operatorv1.GenericProvider) clusterc
 	return clusterctlv1.ProviderTypeUnknown
 }
 
+// SmokeTestJobLabelValue returns the value of the label identifying the smoke test Jobs of a provider,
+// "<provider type>-<provider name>". Values longer than a label value can be are truncated and suffixed with
+// a hash of the full value, so they stay unique.
+func SmokeTestJobLabelValue(provider operatorv1.GenericProvider) string {
+	value := provider.GetType() + "-" + provider.GetName()
+	if len(value) <= validation.LabelValueMaxLength {
+		return value
+	}
+
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(value)))[:smokeTestLabelHashLength]
+
+	// Label values must end with an alphanumeric character, the hash is one.
+	return value[:validation.LabelValueMaxLength-smokeTestLabelHashLength-1] + "-" + hash
+}
+
 // RepositoryFactory returns the repository implementation corresponding to the provider URL.
+// The forge type of the URL is detected from it if not set. The HTTP client is used by the repositories
+// implemented in this package and for the API requests of the github.com repository of clusterctl when it has a
+// token, the GitLab packages repository of clusterctl uses its own client. Requests are sent as conditional
+// requests, so unchanged responses are served from memory, and a rate limited request returns a RateLimitError.
 // inspired by https://github.com/kubernetes-sigs/cluster-api/blob/124d9be7035e492f027cdc7a701b6b179451190a/cmd/clusterctl/client/repository/client.go#L170
-func RepositoryFactory(ctx context.Context, providerConfig configclient.Provider, configVariablesClient configclient.VariablesClient) (repository.Repository, error) {
+func RepositoryFactory(ctx context.Context, providerConfig configclient.Provider, forge operatorv1.ForgeType, httpClient *http.Client, configVariablesClient configclient.VariablesClient) (repository.Repository, error) {
 	// parse the repository url
 	rURL, err := url.Parse(providerConfig.URL())
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse repository url %q", providerConfig.URL())
 	}
 
+	httpClient = withConditionalRequests(httpClient)
+
+	// if the url is an object storage bucket, e.g. gs://{bucket}/{prefix}/{latest|version}/{components file}
+	if newObjectStore, ok := objectStoreSchemes[rURL.Scheme]; ok {
+		store, path, err := newObjectStore(ctx, rURL, httpClient, configVariablesClient)
+		if err != nil {
+			return nil, err
+		}
+
+		return newObjectStoreRepository(ctx, store, path)
+	}
+
 	if rURL.Scheme != httpsScheme {
 		return nil, fmt.Errorf("invalid provider url. there are no provider implementation for %q schema", rURL.Scheme)
 	}
 
-	// if the url is a GitHub repository
-	if rURL.Host == githubDomain {
-		repo, err := repository.NewGitHubRepository(ctx, providerConfig, configVariablesClient)
+	if forge == "" {
+		forge = detectForge(rURL)
+	}
+
+	switch forge {
+	case operatorv1.ForgeGitHub:
+		// if the url is not on github.com, it's a GitHub Enterprise Server instance
+		if rURL.Host != githubDomain {
+			repo, err := newGitHubEnterpriseRepository(ctx, rURL, httpClient, configVariablesClient)
+			if err != nil {
+				return nil, fmt.Errorf("error creating the GitHub Enterprise repository client: %w", err)
+			}
+
+			return repo, nil
+		}
+
+		// The client authenticated with the GitHub token is built on top of the HTTP client of the context.
+		repo, err := repository.NewGitHubRepository(context.WithValue(ctx, oauth2.HTTPClient, httpClient), providerConfig, configVariablesClient)
 		if err != nil {
 			return nil, fmt.Errorf("error creating the GitHub repository client: %w", err)
 		}
 
 		return repo, err
-	}
+	case operatorv1.ForgeGitLab:
+		// if the url is a GitLab project releases page, e.g. https://{host}/{project path}/-/releases/{latest|version}/{components file}
+		if isGitLabReleasesURL(rURL) {
+			repo, err := newGitLabReleasesRepository(ctx, rURL, httpClient, configVariablesClient)
+			if err != nil {
+				return nil, fmt.Errorf("error creating the GitLab releases repository client: %w", err)
+			}
+
+			return repo, nil
+		}
 
-	// if the url is a GitLab repository
-	if strings.HasPrefix(rURL.Host, gitlabHostPrefix) && strings.HasPrefix(rURL.Path, gitlabPackagesAPIPrefix) {
 		repo, err := repository.NewGitLabRepository(providerConfig, configVariablesClient)
 		if err != nil {
 			return nil, fmt.Errorf("error creating the GitLab repository client: %w", err)
 		}
 
 		return repo, err
+	case operatorv1.ForgeGitea:
+		repo, err := newGiteaRepository(ctx, rURL, httpClient, configVariablesClient)
+		if err != nil {
+			return nil, fmt.Errorf("error creating the Gitea repository client: %w", err)
+		}
+
+		return repo, nil
+	case operatorv1.ForgeStatic:
+		// the url is a release file served by a web server, e.g. https://{host}/{path}/{latest|version}/{components file}
+		repo, err := newObjectStoreRepository(ctx, newStaticStore(rURL, httpClient), rURL.Path)
+		if err != nil {
+			return nil, fmt.Errorf("error creating the static repository client: %w", err)
+		}
+
+		return repo, nil
+	}
+
+	return nil, fmt.Errorf("invalid provider url. Only GitHub, GitLab, Gitea and static repositories are supported for %q schema", rURL.Scheme)
+}
+
+// detectForge returns the forge type of the url, or an empty type if it can't be detected.
+// Gitea and Forgejo instances can't be detected as their release urls have the same form as GitHub ones.
+func detectForge(rURL *url.URL) operatorv1.ForgeType {
+	switch {
+	case rURL.Host == githubDomain:
+		return operatorv1.ForgeGitHub
+	case isGitHubEnterpriseURL(rURL):
+		return operatorv1.ForgeGitHub
+	case isGitLabReleasesURL(rURL):
+		return operatorv1.ForgeGitLab
+	case strings.HasPrefix(rURL.Host, gitlabHostPrefix) && strings.HasPrefix(rURL.Path, gitlabPackagesAPIPrefix):
+		return operatorv1.ForgeGitLab
 	}
 
-	return nil, fmt.Errorf("invalid provider url. Only GitHub and GitLab are supported for %q schema", rURL.Scheme)
+	return ""
 }